@@ -9,6 +9,20 @@
 
 package glusterfs
 
+// Restricts the devices returned by the allocator.
+// A nil filter allows every device.
+type PlacementFilter struct {
+	// Only allow devices of this class, any class if empty
+	DeviceClass string
+}
+
+func (f *PlacementFilter) Allows(class string) bool {
+	if f == nil {
+		return true
+	}
+	return f.DeviceClass == "" || f.DeviceClass == class
+}
+
 type Allocator interface {
 
 	// Inform the brick allocator to include device
@@ -22,8 +36,13 @@ type Allocator interface {
 
 	// Returns a generator, done, and error channel.
 	// The generator returns the location for the brick, then the possible locations
-	// of its replicas. The caller must close() the done channel when it no longer
+	// of its replicas, skipping the devices not allowed by the filter.
+	// The caller must close() the done channel when it no longer
 	// needs to read from the generator.
-	GetNodes(clusterId, brickId string) (<-chan string,
+	GetNodes(clusterId, brickId string, filter *PlacementFilter) (<-chan string,
 		chan<- struct{}, <-chan error)
+
+	// Returns the limits on the bricks of the volumes which neither
+	// the volume nor its cluster override
+	BrickLimits() BrickLimits
 }
@@ -18,13 +18,17 @@ import (
 
 type MockAllocator struct {
 	clustermap map[string]sort.StringSlice
+	classes    map[string]string
 	lock       sync.Mutex
 	db         bolt.DB
+	limits     BrickLimits
 }
 
-func NewMockAllocator(db *bolt.DB) *MockAllocator {
+func NewMockAllocator(db *bolt.DB, limits BrickLimits) *MockAllocator {
 	d := &MockAllocator{}
 	d.clustermap = make(map[string]sort.StringSlice)
+	d.classes = make(map[string]string)
+	d.limits = limits
 
 	var clusters []string
 	err := db.View(func(tx *bolt.Tx) error {
@@ -59,6 +63,7 @@ func (d *MockAllocator) AddDevice(cluster *ClusterEntry,
 
 	clusterId := cluster.Info.Id
 	deviceId := device.Info.Id
+	d.classes[deviceId] = device.Info.Class
 
 	if devicelist, ok := d.clustermap[clusterId]; ok {
 		devicelist = append(devicelist, deviceId)
@@ -82,6 +87,7 @@ func (d *MockAllocator) RemoveDevice(cluster *ClusterEntry,
 	deviceId := device.Info.Id
 
 	d.clustermap[clusterId] = utils.SortedStringsDelete(d.clustermap[clusterId], deviceId)
+	delete(d.classes, deviceId)
 
 	return nil
 }
@@ -96,8 +102,8 @@ func (d *MockAllocator) RemoveCluster(clusterId string) error {
 	return nil
 }
 
-func (d *MockAllocator) GetNodes(clusterId, brickId string) (<-chan string,
-	chan<- struct{}, <-chan error) {
+func (d *MockAllocator) GetNodes(clusterId, brickId string,
+	filter *PlacementFilter) (<-chan string, chan<- struct{}, <-chan error) {
 
 	// Initialize channels
 	device, done := make(chan string), make(chan struct{})
@@ -107,7 +113,12 @@ func (d *MockAllocator) GetNodes(clusterId, brickId string) (<-chan string,
 	errc := make(chan error, 1)
 
 	d.lock.Lock()
-	devicelist := d.clustermap[clusterId]
+	devicelist := make(sort.StringSlice, 0)
+	for _, id := range d.clustermap[clusterId] {
+		if filter.Allows(d.classes[id]) {
+			devicelist = append(devicelist, id)
+		}
+	}
 	d.lock.Unlock()
 
 	// Start generator in a new goroutine
@@ -130,9 +141,14 @@ func (d *MockAllocator) GetNodes(clusterId, brickId string) (<-chan string,
 	return device, done, errc
 }
 
+func (d *MockAllocator) BrickLimits() BrickLimits {
+	return d.limits
+}
+
 func (d *MockAllocator) addDevicesFromDb(tx *bolt.Tx, clusterId string) error {
 	// Get data from the DB
 	devicelist := make(sort.StringSlice, 0)
+	classes := make(map[string]string)
 
 	// Get cluster info
 	cluster, err := NewClusterEntryFromId(tx, clusterId)
@@ -146,6 +162,14 @@ func (d *MockAllocator) addDevicesFromDb(tx *bolt.Tx, clusterId string) error {
 			return err
 		}
 
+		for _, deviceId := range node.Devices {
+			device, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return err
+			}
+			classes[deviceId] = device.Info.Class
+		}
+
 		devicelist = append(devicelist, node.Devices...)
 	}
 
@@ -157,5 +181,8 @@ func (d *MockAllocator) addDevicesFromDb(tx *bolt.Tx, clusterId string) error {
 	defer d.lock.Unlock()
 
 	d.clustermap[clusterId] = devicelist
+	for deviceId, class := range classes {
+		d.classes[deviceId] = class
+	}
 	return nil
 }
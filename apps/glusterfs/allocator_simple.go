@@ -16,21 +16,24 @@ import (
 
 // Simple allocator contains a map to rings of clusters
 type SimpleAllocator struct {
-	rings map[string]*SimpleAllocatorRing
-	lock  sync.Mutex
+	rings  map[string]*SimpleAllocatorRing
+	lock   sync.Mutex
+	limits BrickLimits
 }
 
-// Create a new simple allocator
+// Create a new simple allocator with the default brick limits
 func NewSimpleAllocator() *SimpleAllocator {
 	s := &SimpleAllocator{}
 	s.rings = make(map[string]*SimpleAllocatorRing)
+	s.limits = DefaultBrickLimits()
 	return s
 }
 
 // Create a new simple allocator and initialize it with data from the db
-func NewSimpleAllocatorFromDb(db *bolt.DB) *SimpleAllocator {
+func NewSimpleAllocatorFromDb(db *bolt.DB, limits BrickLimits) *SimpleAllocator {
 
 	s := NewSimpleAllocator()
+	s.limits = limits
 
 	err := db.View(func(tx *bolt.Tx) error {
 		clusters, err := ClusterList(tx)
@@ -102,6 +105,7 @@ func (s *SimpleAllocator) AddDevice(cluster *ClusterEntry,
 		zone:     node.Info.Zone,
 		nodeId:   node.Info.Id,
 		deviceId: device.Info.Id,
+		class:    device.Info.Class,
 	})
 
 	return nil
@@ -149,6 +153,10 @@ func (s *SimpleAllocator) RemoveCluster(clusterId string) error {
 	return nil
 }
 
+func (s *SimpleAllocator) BrickLimits() BrickLimits {
+	return s.limits
+}
+
 func (s *SimpleAllocator) getDeviceList(clusterId, brickId string) (SimpleDevices, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -166,8 +174,8 @@ func (s *SimpleAllocator) getDeviceList(clusterId, brickId string) (SimpleDevice
 
 }
 
-func (s *SimpleAllocator) GetNodes(clusterId, brickId string) (<-chan string,
-	chan<- struct{}, <-chan error) {
+func (s *SimpleAllocator) GetNodes(clusterId, brickId string,
+	filter *PlacementFilter) (<-chan string, chan<- struct{}, <-chan error) {
 
 	// Initialize channels
 	device, done := make(chan string), make(chan struct{})
@@ -193,6 +201,10 @@ func (s *SimpleAllocator) GetNodes(clusterId, brickId string) (<-chan string,
 		}()
 
 		for _, d := range devicelist {
+			if !filter.Allows(d.class) {
+				continue
+			}
+
 			select {
 			case device <- d.deviceId:
 			case <-done:
@@ -18,6 +18,7 @@ import (
 type SimpleDevice struct {
 	zone             int
 	nodeId, deviceId string
+	class            string
 }
 
 // Pretty pring a SimpleDevice
@@ -39,7 +39,7 @@ func TestSimpleAllocatorEmpty(t *testing.T) {
 	err = a.RemoveCluster("aaa")
 	tests.Assert(t, err == ErrNotFound)
 
-	ch, _, errc := a.GetNodes(utils.GenUUID(), utils.GenUUID())
+	ch, _, errc := a.GetNodes(utils.GenUUID(), utils.GenUUID(), nil)
 	for d := range ch {
 		tests.Assert(t, false, d)
 	}
@@ -63,7 +63,7 @@ func TestSimpleAllocatorAddRemoveDevice(t *testing.T) {
 	tests.Assert(t, a.rings[cluster.Info.Id] != nil)
 
 	// Get the nodes from the ring
-	ch, _, errc := a.GetNodes(cluster.Info.Id, utils.GenUUID())
+	ch, _, errc := a.GetNodes(cluster.Info.Id, utils.GenUUID(), nil)
 
 	var devices int
 	for d := range ch {
@@ -80,7 +80,7 @@ func TestSimpleAllocatorAddRemoveDevice(t *testing.T) {
 	tests.Assert(t, len(a.rings) == 1)
 
 	// Get the nodes from the ring
-	ch, _, errc = a.GetNodes(cluster.Info.Id, utils.GenUUID())
+	ch, _, errc = a.GetNodes(cluster.Info.Id, utils.GenUUID(), nil)
 
 	devices = 0
 	for d := range ch {
@@ -93,6 +93,43 @@ func TestSimpleAllocatorAddRemoveDevice(t *testing.T) {
 
 }
 
+func TestSimpleAllocatorGetNodesWithFilter(t *testing.T) {
+	a := NewSimpleAllocator()
+	tests.Assert(t, a != nil)
+
+	cluster := createSampleClusterEntry()
+	node := createSampleNodeEntry()
+	node.Info.ClusterId = cluster.Info.Id
+
+	classes := map[string]string{}
+	for _, class := range []string{"ssd", "ssd", "hdd", ""} {
+		device := createSampleDeviceEntry(node.Info.Id, 10000)
+		device.Info.Class = class
+		classes[device.Info.Id] = class
+		err := a.AddDevice(cluster, node, device)
+		tests.Assert(t, err == nil)
+	}
+
+	count := func(filter *PlacementFilter) int {
+		ch, _, errc := a.GetNodes(cluster.Info.Id, utils.GenUUID(), filter)
+
+		var devices int
+		for d := range ch {
+			devices++
+			tests.Assert(t, filter.Allows(classes[d]), d)
+		}
+		err := <-errc
+		tests.Assert(t, err == nil)
+		return devices
+	}
+
+	tests.Assert(t, count(nil) == 4)
+	tests.Assert(t, count(&PlacementFilter{}) == 4)
+	tests.Assert(t, count(&PlacementFilter{DeviceClass: "ssd"}) == 2)
+	tests.Assert(t, count(&PlacementFilter{DeviceClass: "hdd"}) == 1)
+	tests.Assert(t, count(&PlacementFilter{DeviceClass: "nvme"}) == 0)
+}
+
 func TestSimpleAllocatorInitFromDb(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -125,11 +162,11 @@ func TestSimpleAllocatorInitFromDb(t *testing.T) {
 	tests.Assert(t, err == nil)
 
 	// Create an allocator and initialize it from the DB
-	a := NewSimpleAllocatorFromDb(app.db)
+	a := NewSimpleAllocatorFromDb(app.db, app.brickLimits)
 	tests.Assert(t, a != nil)
 
 	// Get the nodes from the ring
-	ch, _, errc := a.GetNodes(clusterId, utils.GenUUID())
+	ch, _, errc := a.GetNodes(clusterId, utils.GenUUID(), nil)
 
 	var devices int
 	for d := range ch {
@@ -191,11 +228,11 @@ func TestSimpleAllocatorInitFromDbWithOfflineDevices(t *testing.T) {
 	tests.Assert(t, err == nil)
 
 	// Create an allocator and initialize it from the DB
-	a := NewSimpleAllocatorFromDb(app.db)
+	a := NewSimpleAllocatorFromDb(app.db, app.brickLimits)
 	tests.Assert(t, a != nil)
 
 	// Get the nodes from the ring
-	ch, _, errc := a.GetNodes(clusterId, utils.GenUUID())
+	ch, _, errc := a.GetNodes(clusterId, utils.GenUUID(), nil)
 
 	var devices int
 	for d := range ch {
@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -26,12 +27,15 @@ import (
 )
 
 const (
-	ASYNC_ROUTE           = "/queue"
-	BOLTDB_BUCKET_CLUSTER = "CLUSTER"
-	BOLTDB_BUCKET_NODE    = "NODE"
-	BOLTDB_BUCKET_VOLUME  = "VOLUME"
-	BOLTDB_BUCKET_DEVICE  = "DEVICE"
-	BOLTDB_BUCKET_BRICK   = "BRICK"
+	ASYNC_ROUTE               = "/queue"
+	BOLTDB_BUCKET_CLUSTER     = "CLUSTER"
+	BOLTDB_BUCKET_NODE        = "NODE"
+	BOLTDB_BUCKET_VOLUME      = "VOLUME"
+	BOLTDB_BUCKET_DEVICE      = "DEVICE"
+	BOLTDB_BUCKET_BRICK       = "BRICK"
+	BOLTDB_BUCKET_SNAPSHOT    = "SNAPSHOT"
+	BOLTDB_BUCKET_BLOCKVOLUME = "BLOCKVOLUME"
+	BOLTDB_BUCKET_IDEMPOTENCY = "IDEMPOTENCY"
 )
 
 var (
@@ -47,6 +51,17 @@ type App struct {
 	allocator    Allocator
 	conf         *GlusterFSConfig
 
+	// Limits on the bricks of volumes, unless set on the
+	// volume or its cluster
+	brickLimits BrickLimits
+
+	thinPoolMonitor *ThinPoolMonitor
+	volumeReaper    *VolumeReaper
+
+	// Requests with an idempotency key which are still running
+	idempotencyLock    sync.Mutex
+	idempotentRequests map[string]*idempotentRequest
+
 	// For testing only.  Keep access to the object
 	// not through the interface
 	xo *mockexec.MockExecutor
@@ -67,6 +82,7 @@ func NewApp(configIo io.Reader) *App {
 
 	// Setup asynchronous manager
 	app.asyncManager = rest.NewAsyncHttpManager(ASYNC_ROUTE)
+	app.idempotentRequests = make(map[string]*idempotentRequest)
 
 	// Setup executor
 	var err error
@@ -143,6 +159,27 @@ func NewApp(configIo io.Reader) *App {
 				return err
 			}
 
+			// Create Snapshot Bucket
+			_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_SNAPSHOT))
+			if err != nil {
+				logger.LogError("Unable to create snapshot bucket in DB")
+				return err
+			}
+
+			// Create Block Volume Bucket
+			_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_BLOCKVOLUME))
+			if err != nil {
+				logger.LogError("Unable to create block volume bucket in DB")
+				return err
+			}
+
+			// Create Idempotency Key Bucket
+			_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_IDEMPOTENCY))
+			if err != nil {
+				logger.LogError("Unable to create idempotency key bucket in DB")
+				return err
+			}
+
 			// Handle Upgrade Changes
 			err = app.Upgrade(tx)
 			if err != nil {
@@ -165,15 +202,26 @@ func NewApp(configIo io.Reader) *App {
 	// Setup allocator
 	switch {
 	case app.conf.Allocator == "mock":
-		app.allocator = NewMockAllocator(app.db)
+		app.allocator = NewMockAllocator(app.db, app.brickLimits)
 	case app.conf.Allocator == "simple" || app.conf.Allocator == "":
 		app.conf.Allocator = "simple"
-		app.allocator = NewSimpleAllocatorFromDb(app.db)
+		app.allocator = NewSimpleAllocatorFromDb(app.db, app.brickLimits)
 	default:
 		return nil
 	}
 	logger.Info("Loaded %v allocator", app.conf.Allocator)
 
+	// Watch how full the thin pools of the bricks are
+	app.thinPoolMonitor = NewThinPoolMonitor(app.db, app.executor, app.conf)
+	if app.thinPoolMonitor == nil {
+		return nil
+	}
+	app.thinPoolMonitor.Start()
+
+	// Destroy the volumes whose time in the trash expired
+	app.volumeReaper = NewVolumeReaper(app.db, app.executor)
+	app.volumeReaper.Start()
+
 	// Show application has loaded
 	logger.Info("GlusterFS Application Loaded")
 
@@ -230,29 +278,57 @@ func (a *App) Upgrade(tx *bolt.Tx) error {
 		return err
 	}
 
+	err = SnapshotEntryUpgrade(tx)
+	if err != nil {
+		logger.LogError("Failed to upgrade db for snapshot entries: %v", err)
+		return err
+	}
+
+	err = BlockVolumeEntryUpgrade(tx)
+	if err != nil {
+		logger.LogError("Failed to upgrade db for block volume entries: %v", err)
+		return err
+	}
+
 	return nil
 }
 
 func (a *App) setAdvSettings() {
+	a.brickLimits = DefaultBrickLimits()
 	if a.conf.BrickMaxNum != 0 {
 		logger.Info("Adv: Max bricks per volume set to %v", a.conf.BrickMaxNum)
 
-		// From volume_entry.go
-		BrickMaxNum = a.conf.BrickMaxNum
+		a.brickLimits.MaxNum = a.conf.BrickMaxNum
 	}
 	if a.conf.BrickMaxSize != 0 {
 		logger.Info("Adv: Max brick size %v GB", a.conf.BrickMaxSize)
 
-		// From volume_entry.go
 		// Convert to KB
-		BrickMaxSize = uint64(a.conf.BrickMaxSize) * 1024 * 1024
+		a.brickLimits.MaxSize = uint64(a.conf.BrickMaxSize) * 1024 * 1024
 	}
 	if a.conf.BrickMinSize != 0 {
 		logger.Info("Adv: Min brick size %v GB", a.conf.BrickMinSize)
 
-		// From volume_entry.go
 		// Convert to KB
-		BrickMinSize = uint64(a.conf.BrickMinSize) * 1024 * 1024
+		a.brickLimits.MinSize = uint64(a.conf.BrickMinSize) * 1024 * 1024
+	}
+	if a.conf.ArbiterBrickRatio != 0 {
+		logger.Info("Adv: Arbiter brick ratio %v", a.conf.ArbiterBrickRatio)
+
+		// From limits.go
+		ArbiterBrickRatio = a.conf.ArbiterBrickRatio
+	}
+	if a.conf.CreateBlockHostingVolumes {
+		logger.Info("Block: Auto creation of block hosting volumes enabled")
+
+		// From limits.go
+		CreateBlockHostingVolumes = true
+	}
+	if a.conf.BlockHostingVolumeSize != 0 {
+		logger.Info("Block: Block hosting volume size %v GB", a.conf.BlockHostingVolumeSize)
+
+		// From limits.go
+		BlockHostingVolumeSize = a.conf.BlockHostingVolumeSize
 	}
 }
 
@@ -289,6 +365,16 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "DELETE",
 			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}",
 			HandlerFunc: a.ClusterDelete},
+		rest.Route{
+			Name:        "ClusterSetTags",
+			Method:      "POST",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/tags",
+			HandlerFunc: a.ClusterSetTags},
+		rest.Route{
+			Name:        "ClusterSetBrickLimits",
+			Method:      "POST",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/brick-limits",
+			HandlerFunc: a.ClusterSetBrickLimits},
 
 		// Node
 		rest.Route{
@@ -311,6 +397,16 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/state",
 			HandlerFunc: a.NodeSetState},
+		rest.Route{
+			Name:        "NodeSetHostnames",
+			Method:      "POST",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/hostnames",
+			HandlerFunc: a.NodeSetHostnames},
+		rest.Route{
+			Name:        "NodeSetTags",
+			Method:      "POST",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/tags",
+			HandlerFunc: a.NodeSetTags},
 
 		// Devices
 		rest.Route{
@@ -338,6 +434,11 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "GET",
 			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/resync",
 			HandlerFunc: a.DeviceResync},
+		rest.Route{
+			Name:        "DeviceSetTags",
+			Method:      "POST",
+			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/tags",
+			HandlerFunc: a.DeviceSetTags},
 
 		// Volume
 		rest.Route{
@@ -345,6 +446,16 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/volumes",
 			HandlerFunc: a.VolumeCreate},
+		rest.Route{
+			Name:        "VolumePlan",
+			Method:      "POST",
+			Pattern:     "/volumes/plan",
+			HandlerFunc: a.VolumePlan},
+		rest.Route{
+			Name:        "VolumeImport",
+			Method:      "POST",
+			Pattern:     "/volumes/import",
+			HandlerFunc: a.VolumeImport},
 		rest.Route{
 			Name:        "VolumeInfo",
 			Method:      "GET",
@@ -355,6 +466,96 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/expand",
 			HandlerFunc: a.VolumeExpand},
+		rest.Route{
+			Name:        "VolumeShrink",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/shrink",
+			HandlerFunc: a.VolumeShrink},
+		rest.Route{
+			Name:        "VolumeSetOptions",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/options",
+			HandlerFunc: a.VolumeSetOptions},
+		rest.Route{
+			Name:        "VolumeStop",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/stop",
+			HandlerFunc: a.VolumeStop},
+		rest.Route{
+			Name:        "VolumeStart",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/start",
+			HandlerFunc: a.VolumeStart},
+		rest.Route{
+			Name:        "VolumeRestore",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/restore",
+			HandlerFunc: a.VolumeRestore},
+		rest.Route{
+			Name:        "VolumeQuota",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/quota",
+			HandlerFunc: a.VolumeQuota},
+		rest.Route{
+			Name:        "VolumeQuotaSetLimits",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/quota",
+			HandlerFunc: a.VolumeQuotaSetLimits},
+		rest.Route{
+			Name:        "VolumeQuotaEnable",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/quota/enable",
+			HandlerFunc: a.VolumeQuotaEnable},
+		rest.Route{
+			Name:        "VolumeQuotaDisable",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/quota/disable",
+			HandlerFunc: a.VolumeQuotaDisable},
+		rest.Route{
+			Name:        "VolumeBitrotStatus",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/bitrot",
+			HandlerFunc: a.VolumeBitrotStatus},
+		rest.Route{
+			Name:        "VolumeBitrotScrub",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/bitrot",
+			HandlerFunc: a.VolumeBitrotScrub},
+		rest.Route{
+			Name:        "VolumeBitrotEnable",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/bitrot/enable",
+			HandlerFunc: a.VolumeBitrotEnable},
+		rest.Route{
+			Name:        "VolumeBitrotDisable",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/bitrot/disable",
+			HandlerFunc: a.VolumeBitrotDisable},
+		rest.Route{
+			Name:        "VolumeHealInfo",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/heal",
+			HandlerFunc: a.VolumeHealInfo},
+		rest.Route{
+			Name:        "VolumeHeal",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/heal",
+			HandlerFunc: a.VolumeHeal},
+		rest.Route{
+			Name:        "VolumeSmbShare",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/smb",
+			HandlerFunc: a.VolumeSmbShare},
+		rest.Route{
+			Name:        "VolumeSmbUnshare",
+			Method:      "DELETE",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/smb",
+			HandlerFunc: a.VolumeSmbUnshare},
+		rest.Route{
+			Name:        "VolumeSetTags",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/tags",
+			HandlerFunc: a.VolumeSetTags},
 		rest.Route{
 			Name:        "VolumeDelete",
 			Method:      "DELETE",
@@ -365,6 +566,61 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "GET",
 			Pattern:     "/volumes",
 			HandlerFunc: a.VolumeList},
+
+		// Snapshot
+		rest.Route{
+			Name:        "SnapshotCreate",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/snapshots",
+			HandlerFunc: a.SnapshotCreate},
+		rest.Route{
+			Name:        "SnapshotList",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/snapshots",
+			HandlerFunc: a.SnapshotList},
+		rest.Route{
+			Name:        "SnapshotInfo",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/snapshots/{snapshot:[A-Fa-f0-9]+}",
+			HandlerFunc: a.SnapshotInfo},
+		rest.Route{
+			Name:        "SnapshotDelete",
+			Method:      "DELETE",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/snapshots/{snapshot:[A-Fa-f0-9]+}",
+			HandlerFunc: a.SnapshotDelete},
+		rest.Route{
+			Name:        "SnapshotRestore",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/snapshots/{snapshot:[A-Fa-f0-9]+}/restore",
+			HandlerFunc: a.SnapshotRestore},
+		rest.Route{
+			Name:        "SnapshotClone",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/snapshots/{snapshot:[A-Fa-f0-9]+}/clone",
+			HandlerFunc: a.SnapshotClone},
+
+		// Block Volume
+		rest.Route{
+			Name:        "BlockVolumeCreate",
+			Method:      "POST",
+			Pattern:     "/blockvolumes",
+			HandlerFunc: a.BlockVolumeCreate},
+		rest.Route{
+			Name:        "BlockVolumeInfo",
+			Method:      "GET",
+			Pattern:     "/blockvolumes/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.BlockVolumeInfo},
+		rest.Route{
+			Name:        "BlockVolumeDelete",
+			Method:      "DELETE",
+			Pattern:     "/blockvolumes/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.BlockVolumeDelete},
+		rest.Route{
+			Name:        "BlockVolumeList",
+			Method:      "GET",
+			Pattern:     "/blockvolumes",
+			HandlerFunc: a.BlockVolumeList},
+
 		// Geo-replication
 		rest.Route{
 			Name:        "GeoReplicationStatus",
@@ -381,6 +637,14 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/georeplication",
 			HandlerFunc: a.GeoReplicationPostHandler},
+
+		// Thin pools
+		rest.Route{
+			Name:        "ThinPoolWarnings",
+			Method:      "GET",
+			Pattern:     "/thinpools/warnings",
+			HandlerFunc: a.ThinPoolWarnings},
+
 		// Backup
 		rest.Route{
 			Name:        "Backup",
@@ -409,6 +673,10 @@ func (a *App) SetRoutes(router *mux.Router) error {
 
 func (a *App) Close() {
 
+	// Stop the background tasks
+	a.thinPoolMonitor.Stop()
+	a.volumeReaper.Stop()
+
 	// Close the DB
 	a.db.Close()
 	logger.Info("Closed")
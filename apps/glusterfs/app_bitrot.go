@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+var (
+	bitrotScrubFrequencies = map[string]bool{
+		"hourly":   true,
+		"daily":    true,
+		"weekly":   true,
+		"biweekly": true,
+		"monthly":  true,
+	}
+	bitrotScrubThrottles = map[string]bool{
+		"lazy":       true,
+		"normal":     true,
+		"aggressive": true,
+	}
+)
+
+func (a *App) VolumeBitrotStatus(w http.ResponseWriter, r *http.Request) {
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	status, err := volume.BitrotStatus(a.db, a.executor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.LogError("Failed to get scrub status of volume %v: %v", volume.Info.Id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumeBitrotEnable(w http.ResponseWriter, r *http.Request) {
+	a.volumeSetBitrot(w, r, true)
+}
+
+func (a *App) VolumeBitrotDisable(w http.ResponseWriter, r *http.Request) {
+	a.volumeSetBitrot(w, r, false)
+}
+
+func (a *App) volumeSetBitrot(w http.ResponseWriter, r *http.Request, enable bool) {
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	if volume.Info.Stopped {
+		http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+		return
+	}
+	if volume.Info.Bitrot.Enabled == enable {
+		if enable {
+			err = fmt.Errorf("Bitrot is already enabled on volume %v", volume.Info.Id)
+		} else {
+			err = fmt.Errorf("Bitrot is already disabled on volume %v", volume.Info.Id)
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		var err error
+		if enable {
+			logger.Info("Enabling bitrot on volume %v", volume.Info.Id)
+			err = volume.BitrotEnable(a.db, a.executor)
+		} else {
+			logger.Info("Disabling bitrot on volume %v", volume.Info.Id)
+			err = volume.BitrotDisable(a.db, a.executor)
+		}
+		if err != nil {
+			logger.LogError("Failed to change bitrot of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
+
+func (a *App) VolumeBitrotScrub(w http.ResponseWriter, r *http.Request) {
+
+	var msg api.VolumeBitrotScrubRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	if msg.Frequency == "" && msg.Throttle == "" {
+		http.Error(w, "No scrub frequency or throttle provided", http.StatusBadRequest)
+		return
+	}
+	if msg.Frequency != "" && !bitrotScrubFrequencies[msg.Frequency] {
+		http.Error(w, "Invalid scrub frequency "+msg.Frequency, http.StatusBadRequest)
+		logger.LogError("Invalid scrub frequency %v", msg.Frequency)
+		return
+	}
+	if msg.Throttle != "" && !bitrotScrubThrottles[msg.Throttle] {
+		http.Error(w, "Invalid scrub throttle "+msg.Throttle, http.StatusBadRequest)
+		logger.LogError("Invalid scrub throttle %v", msg.Throttle)
+		return
+	}
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	if volume.Info.Stopped {
+		http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+		return
+	}
+	if !volume.Info.Bitrot.Enabled {
+		http.Error(w, ErrBitrotDisabled.Error(), http.StatusConflict)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Setting scrub of volume %v", volume.Info.Id)
+		err := volume.SetBitrotScrub(a.db, a.executor, msg.Frequency, msg.Throttle)
+		if err != nil {
+			logger.LogError("Failed to set scrub of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Set scrub of volume %v", volume.Info.Id)
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeBitrot(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	url := ts.URL + "/volumes/" + v.Info.Id + "/bitrot"
+
+	post := func(url, request string) *http.Response {
+		r, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(request)))
+		tests.Assert(t, err == nil)
+		return r
+	}
+
+	// Unknown volume
+	r, err := http.Get(ts.URL + "/volumes/12345/bitrot")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	// Status without bitrot
+	r, err = http.Get(url)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var status api.VolumeBitrotStatusResponse
+	err = utils.GetJsonFromResponse(r, &status)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, !status.Enabled)
+
+	// Scrub cannot be set before bitrot is enabled
+	r = post(url, `{"frequency" : "daily"}`)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Already disabled
+	r = post(url+"/disable", "")
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	info := asyncTestVolume(t, asyncTestRequest(t, "POST", url+"/enable", "", nil))
+	tests.Assert(t, info.Bitrot.Enabled)
+
+	r = post(url+"/enable", "")
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Bad requests
+	r = post(url, `{}`)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r = post(url, `{"frequency" : "yearly"}`)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r = post(url, `{"throttle" : "fast"}`)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+
+	info = asyncTestVolume(t, asyncTestRequest(t, "POST", url,
+		`{"frequency" : "weekly", "throttle" : "lazy"}`, nil))
+	tests.Assert(t, info.Bitrot.ScrubFrequency == "weekly")
+	tests.Assert(t, info.Bitrot.ScrubThrottle == "lazy")
+
+	// Scrub status
+	app.xo.MockVolumeBitrotScrubStatus = func(host string, volume string) (*executors.BitrotScrubStatus, error) {
+		tests.Assert(t, volume == v.Info.Name)
+		return &executors.BitrotScrubStatus{
+			State:     "Active (Idle)",
+			Frequency: "weekly",
+			Throttle:  "lazy",
+			Nodes: []executors.BitrotScrubNodeStatus{
+				executors.BitrotScrubNodeStatus{
+					Node:             "node1",
+					ScrubbedFiles:    10,
+					ErrorCount:       1,
+					CorruptedObjects: []string{"3c5f1b0d-3bd5-4d3e-8e4b-4b8c0e2f7f7a"},
+				},
+			},
+		}, nil
+	}
+	r, err = http.Get(url)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &status)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, status.Enabled)
+	tests.Assert(t, status.State == "Active (Idle)")
+	tests.Assert(t, len(status.Nodes) == 1)
+	tests.Assert(t, status.Nodes[0].ErrorCount == 1)
+	tests.Assert(t, len(status.Nodes[0].CorruptedObjects) == 1)
+
+	info = asyncTestVolume(t, asyncTestRequest(t, "POST", url+"/disable", "", nil))
+	tests.Assert(t, !info.Bitrot.Enabled)
+	tests.Assert(t, info.Bitrot.ScrubFrequency == "weekly")
+}
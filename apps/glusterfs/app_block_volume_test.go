@@ -0,0 +1,228 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestBlockVolumeCreateBadRequests(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Bad JSON
+	request := []byte(`{
+        "asdfasd  0
+    }`)
+	r, err := http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == 422)
+
+	// Bad size
+	request = []byte(`{
+        "size" : 0
+    }`)
+	r, err = http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Bad hacount
+	request = []byte(`{
+        "size" : 10,
+        "hacount" : -1
+    }`)
+	r, err = http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Bad name
+	request = []byte(`{
+        "size" : 10,
+        "name" : "bad/name"
+    }`)
+	r, err = http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// No clusters
+	request = []byte(`{
+        "size" : 10
+    }`)
+	r, err = http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Unknown cluster
+	err = setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+	request = []byte(`{
+        "size" : 10,
+        "clusters" : ["abc"]
+    }`)
+	r, err = http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+}
+
+func TestBlockVolumeCreateInfoListDelete(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	defer tests.Patch(&CreateBlockHostingVolumes, true).Restore()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a block volume
+	request := []byte(`{
+        "size" : 10,
+        "name" : "myblock",
+        "hacount" : 2
+    }`)
+	r, err := http.Post(ts.URL+"/blockvolumes",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	// Query queue until finished
+	var info api.BlockVolumeInfoResponse
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+	tests.Assert(t, info.Name == "myblock")
+	tests.Assert(t, info.Size == 10)
+	tests.Assert(t, info.Hacount == 2)
+	tests.Assert(t, info.Cluster != "")
+	tests.Assert(t, info.BlockHostingVolume != "")
+	tests.Assert(t, info.BlockVolume.Iqn != "")
+	tests.Assert(t, len(info.BlockVolume.Hosts) == 2)
+
+	// The block hosting volume shows the block volume
+	var volume api.VolumeInfoResponse
+	r, err = http.Get(ts.URL + "/volumes/" + info.BlockHostingVolume)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &volume)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, volume.Block)
+	tests.Assert(t, volume.BlockInfo.FreeSize == volume.Size-10)
+	tests.Assert(t, len(volume.BlockInfo.BlockVolumes) == 1)
+	tests.Assert(t, volume.BlockInfo.BlockVolumes[0] == info.Id)
+
+	// The block hosting volume cannot be deleted
+	req, err := http.NewRequest("DELETE", ts.URL+"/volumes/"+volume.Id, nil)
+	tests.Assert(t, err == nil)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict)
+
+	// List block volumes
+	var list api.BlockVolumeListResponse
+	r, err = http.Get(ts.URL + "/blockvolumes")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.BlockVolumes) == 1)
+	tests.Assert(t, list.BlockVolumes[0] == info.Id)
+
+	// Delete the block volume
+	req, err = http.NewRequest("DELETE", ts.URL+"/blockvolumes/"+info.Id, nil)
+	tests.Assert(t, err == nil)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err = r.Location()
+	tests.Assert(t, err == nil)
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusNoContent)
+			break
+		}
+	}
+
+	// Check it is not there
+	r, err = http.Get(ts.URL + "/blockvolumes/" + info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, volume.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.BlockInfo.FreeSize == entry.Info.Size)
+		tests.Assert(t, len(entry.Info.BlockInfo.BlockVolumes) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
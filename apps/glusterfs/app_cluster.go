@@ -16,15 +16,41 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
 	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
 )
 
 func (a *App) ClusterCreate(w http.ResponseWriter, r *http.Request) {
 
+	// The request body is optional
+	var msg api.ClusterCreateRequest
+	if r.ContentLength != 0 {
+		err := utils.GetJsonFromRequest(r, &msg)
+		if err != nil {
+			http.Error(w, "request unable to be parsed", 422)
+			return
+		}
+	}
+
+	err := validateTags(msg.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.LogError(err.Error())
+		return
+	}
+	err = validateBrickLimits(msg.BrickLimits, a.brickLimits)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.LogError(err.Error())
+		return
+	}
+
 	// Create a new ClusterInfo
 	entry := NewClusterEntryFromRequest()
+	entry.Info.Tags = copyTags(msg.Tags)
+	entry.Info.BrickLimits = msg.BrickLimits
 
 	// Add cluster to db
-	err := a.db.Update(func(tx *bolt.Tx) error {
+	err = a.db.Update(func(tx *bolt.Tx) error {
 		err := entry.Save(tx)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -49,16 +75,29 @@ func (a *App) ClusterCreate(w http.ResponseWriter, r *http.Request) {
 func (a *App) ClusterList(w http.ResponseWriter, r *http.Request) {
 
 	var list api.ClusterListResponse
+	filters := tagFiltersFromRequest(r)
 
 	// Get all the cluster ids from the DB
 	err := a.db.View(func(tx *bolt.Tx) error {
-		var err error
-
-		list.Clusters, err = ClusterList(tx)
+		clusters, err := ClusterList(tx)
 		if err != nil {
 			return err
 		}
 
+		list.Clusters = make([]string, 0, len(clusters))
+		for _, id := range clusters {
+			if len(filters) > 0 {
+				entry, err := NewClusterEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				if !tagsMatch(entry.Info.Tags, filters) {
+					continue
+				}
+			}
+			list.Clusters = append(list.Clusters, id)
+		}
+
 		return nil
 	})
 
@@ -102,6 +141,14 @@ func (a *App) ClusterInfo(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 
+		if !includeTrash(r) {
+			info.Volumes, err = withoutTrashedVolumes(tx, info.Volumes)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -161,3 +208,63 @@ func (a *App) ClusterDelete(w http.ResponseWriter, r *http.Request) {
 	// Write msg
 	w.WriteHeader(http.StatusOK)
 }
+
+func (a *App) ClusterSetBrickLimits(w http.ResponseWriter, r *http.Request) {
+
+	// Get the id from the URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.BrickLimits
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	err = validateBrickLimits(msg, a.brickLimits)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.LogError(err.Error())
+		return
+	}
+
+	// Replace the limits of the cluster.  Existing volumes keep
+	// their bricks, the new limits apply when they are allocated.
+	var info *api.ClusterInfoResponse
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewClusterEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		entry.Info.BrickLimits = msg
+		err = entry.Save(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		info, err = entry.NewClusterInfoResponse(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	logger.Info("Set brick limits of cluster %v", id)
+
+	// Write msg
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
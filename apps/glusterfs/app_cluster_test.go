@@ -379,3 +379,77 @@ func TestClusterDelete(t *testing.T) {
 	tests.Assert(t, err == nil, err)
 
 }
+
+func TestClusterSetBrickLimits(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Limits are checked on create
+	request := []byte(`{
+        "brick_limits" : { "min_size_gb" : 10, "max_size_gb" : 5 }
+    }`)
+	r, err := http.Post(ts.URL+"/clusters", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// The minimum cannot be above the maximum of the server
+	request = []byte(`{
+        "brick_limits" : { "min_size_gb" : 5000 }
+    }`)
+	r, err = http.Post(ts.URL+"/clusters", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	request = []byte(`{
+        "brick_limits" : { "min_size_gb" : 10 }
+    }`)
+	r, err = http.Post(ts.URL+"/clusters", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusCreated)
+	var msg api.ClusterInfoResponse
+	err = utils.GetJsonFromResponse(r, &msg)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, msg.BrickLimits.MinSize == 10)
+
+	// Unknown cluster
+	request = []byte(`{ "max_num" : 64 }`)
+	r, err = http.Post(ts.URL+"/clusters/12345/brick-limits", "application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	// Negative limit
+	r, err = http.Post(ts.URL+"/clusters/"+msg.Id+"/brick-limits", "application/json",
+		bytes.NewBuffer([]byte(`{ "max_num" : -1 }`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Replace the limits
+	r, err = http.Post(ts.URL+"/clusters/"+msg.Id+"/brick-limits", "application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var info api.ClusterInfoResponse
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.BrickLimits.MinSize == 0)
+	tests.Assert(t, info.BrickLimits.MaxNum == 64)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewClusterEntryFromId(tx, msg.Id)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, entry.Info.BrickLimits.MaxNum == 64)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
@@ -30,6 +30,21 @@ type GlusterFSConfig struct {
 	BrickMaxSize int `json:"brick_max_size_gb"`
 	BrickMinSize int `json:"brick_min_size_gb"`
 	BrickMaxNum  int `json:"max_bricks_per_volume"`
+
+	ArbiterBrickRatio float32 `json:"arbiter_brick_ratio"`
+
+	// block settings
+	CreateBlockHostingVolumes bool `json:"auto_create_block_hosting_volume"`
+	BlockHostingVolumeSize    int  `json:"block_hosting_volume_size"`
+
+	// thin pool monitor settings, the interval is in seconds
+	ThinPoolMonitorInterval int     `json:"thin_pool_monitor_interval"`
+	ThinPoolWarningPercent  float64 `json:"thin_pool_warning_percent"`
+	ThinPoolCriticalPercent float64 `json:"thin_pool_critical_percent"`
+
+	// hours deleted volumes are kept in the trash before being
+	// destroyed, zero destroys them right away
+	VolumeTrashRetention int `json:"volume_trash_retention_hours"`
 }
 
 type ConfigFile struct {
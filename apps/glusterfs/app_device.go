@@ -34,6 +34,19 @@ func (a *App) DeviceAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg.Class != "" && !deviceClassRegex.MatchString(msg.Class) {
+		http.Error(w, "Invalid device class", http.StatusBadRequest)
+		logger.LogError("Invalid device class %v", msg.Class)
+		return
+	}
+
+	err = validateTags(msg.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.LogError(err.Error())
+		return
+	}
+
 	// Create device entry
 	device := NewDeviceEntryFromRequest(&msg)
 
@@ -184,6 +197,16 @@ func (a *App) DeviceInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ask the node how much space the bricks of the device use
+	if r.URL.Query().Get("usage") == "true" {
+		err = setDeviceUsage(a.db, a.executor, info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			logger.LogError("Failed to get usage of device %v: %v", id, err)
+			return
+		}
+	}
+
 	// Write msg
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
@@ -551,7 +551,7 @@ func TestDeviceState(t *testing.T) {
 	defer ts.Close()
 
 	// Create mock allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create a client
@@ -0,0 +1,102 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// Loads the volume of the request and checks that it can be healed,
+// answering the request on errors
+func (a *App) healVolumeFromRequest(w http.ResponseWriter,
+	r *http.Request) (*VolumeEntry, error) {
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only volumes with redundancy have anything to heal
+	if volume.Info.Durability.Type == api.DurabilityDistributeOnly {
+		err := fmt.Errorf("Heal is not supported for volume durability type %v",
+			volume.Info.Durability.Type)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+	if volume.Info.Stopped {
+		http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+		return nil, ErrVolumeStopped
+	}
+
+	return volume, nil
+}
+
+func (a *App) VolumeHealInfo(w http.ResponseWriter, r *http.Request) {
+
+	volume, err := a.healVolumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	info, err := volume.HealInfo(a.db, a.executor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.LogError("Failed to get heal info of volume %v: %v", volume.Info.Id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumeHeal(w http.ResponseWriter, r *http.Request) {
+
+	var msg api.VolumeHealRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	switch msg.Type {
+	case "", api.HealIndex, api.HealFull:
+	default:
+		http.Error(w, "Unknown heal type "+string(msg.Type), http.StatusBadRequest)
+		logger.LogError("Unknown heal type %v", msg.Type)
+		return
+	}
+
+	volume, err := a.healVolumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	// Once started, the heal status can be followed at the redirect
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Starting heal of volume %v", volume.Info.Id)
+		err := volume.Heal(a.db, a.executor, msg.Type == api.HealFull)
+		if err != nil {
+			logger.LogError("Failed to start heal of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		return "/volumes/" + volume.Info.Id + "/heal", nil
+	})
+}
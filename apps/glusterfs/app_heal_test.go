@@ -0,0 +1,164 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeHeal(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	url := ts.URL + "/volumes/" + v.Info.Id + "/heal"
+
+	names := healTestBrickNames(t, app, v)
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		var bricks executors.HealInfoBricks
+		for _, id := range v.Bricks {
+			bricks.BrickList = append(bricks.BrickList,
+				executors.BrickHealStatus{Name: names[id], NumberOfEntries: "3"})
+		}
+		return &executors.HealInfo{Bricks: bricks}, nil
+	}
+	var healed, full bool
+	app.xo.MockVolumeHeal = func(host string, volume string, f bool) error {
+		healed = true
+		full = f
+		return nil
+	}
+
+	// Unknown volume
+	r, err := http.Get(ts.URL + "/volumes/12345/heal")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Heal info
+	r, err = http.Get(url)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK, r.StatusCode)
+	var info api.VolumeHealInfoResponse
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.PendingEntries == 9)
+	tests.Assert(t, len(info.Bricks) == 3)
+	for i, brick := range info.Bricks {
+		tests.Assert(t, brick.Id == v.Bricks[i])
+		tests.Assert(t, brick.PendingEntries == 3)
+	}
+
+	// Unknown heal type
+	r, err = http.Post(url, "application/json",
+		bytes.NewBuffer([]byte(`{"type" : "partial"}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	tests.Assert(t, !healed)
+
+	// Start a full heal, which redirects to the heal info
+	r, err = http.Post(url, "application/json",
+		bytes.NewBuffer([]byte(`{"type" : "full"}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted, r.StatusCode)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+	for {
+		r, err := http.Get(location.String())
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusOK)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+			continue
+		} else {
+			info = api.VolumeHealInfoResponse{}
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+	tests.Assert(t, healed && full)
+	tests.Assert(t, info.PendingEntries == 9)
+
+	// Stopped volumes cannot be healed
+	err = v.Stop(app.db, app.executor)
+	tests.Assert(t, err == nil)
+	r, err = http.Get(url)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+}
+
+func TestVolumeHealDistributeOnly(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityDistributeOnly
+	v := NewVolumeEntryFromRequest(req)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	r, err := http.Get(ts.URL + "/volumes/" + v.Info.Id + "/heal")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/heal", "application/json",
+		bytes.NewBuffer([]byte(`{}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+}
@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func (a *App) VolumeImport(w http.ResponseWriter, r *http.Request) {
+
+	var msg api.VolumeImportRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	if msg.Name == "" {
+		http.Error(w, "Volume name missing", http.StatusBadRequest)
+		logger.LogError("Volume name missing")
+		return
+	}
+
+	// Look for the volume in the requested cluster, or in all of them
+	var clusters []string
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		clusters, err = ClusterList(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if msg.Cluster != "" {
+			if !utils.SortedStringHas(clusters, msg.Cluster) {
+				http.Error(w, fmt.Sprintf("Cluster id %v not found", msg.Cluster),
+					http.StatusBadRequest)
+				logger.LogError("Cluster id %v not found", msg.Cluster)
+				return ErrNotFound
+			}
+			clusters = []string{msg.Cluster}
+		}
+
+		// Volumes heketi already manages cannot be imported again
+		for _, clusterId := range clusters {
+			cluster, err := NewClusterEntryFromId(tx, clusterId)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			for _, volumeId := range cluster.Info.Volumes {
+				volume, err := NewVolumeEntryFromId(tx, volumeId)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return err
+				}
+				if volume.Info.Name == msg.Name {
+					err := fmt.Errorf("Volume %v is already managed as %v",
+						msg.Name, volume.Info.Id)
+					http.Error(w, err.Error(), http.StatusConflict)
+					return logger.Err(err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		volume, err := ImportVolume(a.db, a.executor, msg.Name, clusters)
+		if err != nil {
+			logger.LogError("Failed to import volume %v: %v", msg.Name, err)
+			return "", err
+		}
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
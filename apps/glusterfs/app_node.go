@@ -29,12 +29,9 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check information in JSON request
-	if len(msg.Hostnames.Manage) == 0 {
-		http.Error(w, "Manage hostname missing", http.StatusBadRequest)
-		return
-	}
-	if len(msg.Hostnames.Storage) == 0 {
-		http.Error(w, "Storage hostname missing", http.StatusBadRequest)
+	err = validateHostnames(msg.Hostnames)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -46,12 +43,11 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check for correct values
-	for _, name := range append(msg.Hostnames.Manage, msg.Hostnames.Storage...) {
-		if name == "" {
-			http.Error(w, "Hostname cannot be an empty string", http.StatusBadRequest)
-			return
-		}
+	err = validateTags(msg.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.LogError(err.Error())
+		return
 	}
 
 	// Create a node entry
@@ -369,3 +365,70 @@ func (a *App) NodeSetState(w http.ResponseWriter, r *http.Request) {
 	})
 
 }
+
+func (a *App) NodeSetHostnames(w http.ResponseWriter, r *http.Request) {
+	// Get the id from the URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	// Unmarshal JSON
+	var msg api.NodeHostnamesRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+
+	err = validateHostnames(msg.Hostnames)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var node *NodeEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		node, err = NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		_, err = node.hostnamesInUse(tx, msg.Hostnames)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// Heketi manages the node through its new hostname from now on
+	err = a.executor.GlusterdCheck(msg.Hostnames.Manage[0])
+	if err != nil {
+		logger.Err(err)
+		err := logger.LogError("Node doesn't have glusterd running on %v",
+			msg.Hostnames.Manage[0])
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Set hostnames
+	logger.Info("Changing hostnames of node %v to %v", node.Info.Id, msg.Hostnames)
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		err := node.SetHostnames(a.db, a.executor, msg.Hostnames)
+		if err != nil {
+			logger.LogError("Failed to change hostnames of node %v: %v", node.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Changed hostnames of node %v", node.Info.Id)
+		return "/nodes/" + node.Info.Id, nil
+	})
+}
@@ -1040,7 +1040,7 @@ func TestNodeState(t *testing.T) {
 	defer ts.Close()
 
 	// Create mock allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create a client
@@ -1365,7 +1365,7 @@ func TestNodeInfoAfterDelete(t *testing.T) {
 	defer ts.Close()
 
 	// Create mock allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create a client
@@ -0,0 +1,167 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// The path is passed in quotes to a gluster command run by a remote
+// shell, so only plain path characters are allowed
+var quotaPathRegex = regexp.MustCompile("^/[A-Za-z0-9._/-]*$")
+
+// Checks that the path is a directory of the volume which can be
+// passed to gluster
+func validQuotaPath(path string) bool {
+	return quotaPathRegex.MatchString(path)
+}
+
+func (a *App) VolumeQuota(w http.ResponseWriter, r *http.Request) {
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	usage, err := volume.QuotaUsage(a.db, a.executor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.LogError("Failed to get quota of volume %v: %v", volume.Info.Id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumeQuotaEnable(w http.ResponseWriter, r *http.Request) {
+	a.volumeSetQuota(w, r, true)
+}
+
+func (a *App) VolumeQuotaDisable(w http.ResponseWriter, r *http.Request) {
+	a.volumeSetQuota(w, r, false)
+}
+
+func (a *App) volumeSetQuota(w http.ResponseWriter, r *http.Request, enable bool) {
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	if volume.Info.Stopped {
+		http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+		return
+	}
+	if volume.Info.Quota.Enabled == enable {
+		if enable {
+			err = fmt.Errorf("Quota is already enabled on volume %v", volume.Info.Id)
+		} else {
+			err = fmt.Errorf("Quota is already disabled on volume %v", volume.Info.Id)
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		var err error
+		if enable {
+			logger.Info("Enabling quota on volume %v", volume.Info.Id)
+			err = volume.QuotaEnable(a.db, a.executor)
+		} else {
+			logger.Info("Disabling quota on volume %v", volume.Info.Id)
+			err = volume.QuotaDisable(a.db, a.executor)
+		}
+		if err != nil {
+			logger.LogError("Failed to change quota of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
+
+func (a *App) VolumeQuotaSetLimits(w http.ResponseWriter, r *http.Request) {
+
+	var msg api.VolumeQuotaRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	if len(msg.Set) == 0 && len(msg.Remove) == 0 {
+		http.Error(w, "No quota limits provided", http.StatusBadRequest)
+		return
+	}
+	for _, limit := range msg.Set {
+		if !validQuotaPath(limit.Path) {
+			http.Error(w, "Invalid quota path "+limit.Path, http.StatusBadRequest)
+			logger.LogError("Invalid quota path %v", limit.Path)
+			return
+		}
+		if limit.HardLimit == 0 {
+			http.Error(w, "Invalid hard limit for "+limit.Path, http.StatusBadRequest)
+			logger.LogError("Invalid hard limit for %v", limit.Path)
+			return
+		}
+		if limit.SoftLimit < 0 || limit.SoftLimit > 100 {
+			http.Error(w, "Invalid soft limit for "+limit.Path, http.StatusBadRequest)
+			logger.LogError("Invalid soft limit %v for %v", limit.SoftLimit, limit.Path)
+			return
+		}
+	}
+	for _, path := range msg.Remove {
+		if !validQuotaPath(path) {
+			http.Error(w, "Invalid quota path "+path, http.StatusBadRequest)
+			logger.LogError("Invalid quota path %v", path)
+			return
+		}
+	}
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	if volume.Info.Stopped {
+		http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+		return
+	}
+	if !volume.Info.Quota.Enabled {
+		http.Error(w, ErrQuotaDisabled.Error(), http.StatusConflict)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Setting quota limits of volume %v", volume.Info.Id)
+		err := volume.SetQuotaLimits(a.db, a.executor, msg.Set, msg.Remove)
+		if err != nil {
+			logger.LogError("Failed to set quota limits of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Set quota limits of volume %v", volume.Info.Id)
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
@@ -0,0 +1,112 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeQuota(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	url := ts.URL + "/volumes/" + v.Info.Id + "/quota"
+
+	// Unknown volume
+	r, err := http.Get(ts.URL + "/volumes/12345/quota")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Limits need quota to be enabled
+	request := `{"set" : [{"path" : "/data", "hard_limit" : 1073741824}]}`
+	r, err = http.Post(url, "application/json", bytes.NewBuffer([]byte(request)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	info := asyncTestVolume(t, asyncTestRequest(t, "POST", url+"/enable", "", nil))
+	tests.Assert(t, info.Quota.Enabled)
+
+	// Enabling it again is a conflict
+	r, err = http.Post(url+"/enable", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Bad requests
+	for _, request := range []string{
+		`{}`,
+		`{"set" : [{"path" : "data", "hard_limit" : 1024}]}`,
+		`{"set" : [{"path" : "/data"}]}`,
+		`{"set" : [{"path" : "/data", "hard_limit" : 1024, "soft_limit" : 101}]}`,
+		`{"remove" : ["/da\"ta"]}`,
+		`{"remove" : ["/$(reboot)"]}`,
+		`{"remove" : ["/data` + "`reboot`" + `"]}`,
+		`{"remove" : ["/data\\"]}`,
+		`{"remove" : ["/data; reboot"]}`,
+		`{"set" : [{"path" : "/$HOME", "hard_limit" : 1024}]}`,
+		`{"set" : [{"path" : "/data\nreboot", "hard_limit" : 1024}]}`,
+	} {
+		r, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(request)))
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusBadRequest, request)
+	}
+
+	info = asyncTestVolume(t, asyncTestRequest(t, "POST", url, request, nil))
+	tests.Assert(t, len(info.Quota.Limits) == 1)
+	tests.Assert(t, info.Quota.Limits[0].Path == "/data")
+	tests.Assert(t, info.Quota.Limits[0].HardLimit == 1073741824)
+
+	// List the limits
+	r, err = http.Get(url)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK, r.StatusCode)
+	var usage api.VolumeQuotaResponse
+	err = utils.GetJsonFromResponse(r, &usage)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, usage.Enabled)
+	tests.Assert(t, len(usage.Limits) == 1)
+	tests.Assert(t, usage.Limits[0].Path == "/data")
+
+	info = asyncTestVolume(t, asyncTestRequest(t, "POST", url,
+		`{"remove" : ["/data"]}`, nil))
+	tests.Assert(t, len(info.Quota.Limits) == 0)
+
+	info = asyncTestVolume(t, asyncTestRequest(t, "POST", url+"/disable", "", nil))
+	tests.Assert(t, !info.Quota.Enabled)
+}
@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// Share names end up in the Samba configuration and file names
+var smbShareNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func (a *App) VolumeSmbShare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.VolumeSmbShareRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	if len(msg.Nodes) == 0 {
+		http.Error(w, "No nodes to share the volume from", http.StatusBadRequest)
+		logger.LogError("No nodes to share the volume from")
+		return
+	}
+	if msg.ShareName != "" && !smbShareNameRegex.MatchString(msg.ShareName) {
+		http.Error(w, "Invalid share name "+msg.ShareName, http.StatusBadRequest)
+		logger.LogError("Invalid share name %v", msg.ShareName)
+		return
+	}
+
+	var volume *VolumeEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		if volume.Info.Mount.Smb.ShareName != "" {
+			err := fmt.Errorf("Volume %v is already shared as %v",
+				volume.Info.Id, volume.Info.Mount.Smb.ShareName)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		// Every node of the cluster may share volumes, so share
+		// names must be unique in the cluster
+		err = volume.smbShareNameInUse(tx, volume.smbShareName(msg.ShareName))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		// The nodes must be able to reach the volume
+		seen := make(map[string]bool)
+		for _, nodeId := range msg.Nodes {
+			if seen[nodeId] {
+				err := fmt.Errorf("Node %v given more than once", nodeId)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return logger.Err(err)
+			}
+			seen[nodeId] = true
+
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err == ErrNotFound {
+				err := fmt.Errorf("Node id %v not found", nodeId)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return logger.Err(err)
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if node.Info.ClusterId != volume.Info.Cluster {
+				err := fmt.Errorf("Node %v is not in cluster %v of volume %v",
+					nodeId, volume.Info.Cluster, volume.Info.Id)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return logger.Err(err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		err := volume.SmbShare(a.db, a.executor, msg.Nodes, msg.ShareName)
+		if err != nil {
+			logger.LogError("Failed to share volume %v over SMB: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
+
+func (a *App) VolumeSmbUnshare(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if volume.Info.Mount.Smb.ShareName == "" {
+		http.Error(w, fmt.Sprintf("Volume %v is not shared over SMB", id),
+			http.StatusConflict)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		err := volume.SmbUnshare(a.db, a.executor)
+		if err != nil {
+			logger.LogError("Failed to remove SMB share of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
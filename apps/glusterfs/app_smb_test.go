@@ -0,0 +1,148 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeSmbShare(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		2,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	nodes := smbTestNodes(t, app, v)
+	url := ts.URL + "/volumes/" + v.Info.Id + "/smb"
+
+	share := func(req *api.VolumeSmbShareRequest) *http.Response {
+		body, err := json.Marshal(req)
+		tests.Assert(t, err == nil)
+		r, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+		tests.Assert(t, err == nil)
+		return r
+	}
+
+	// Unknown volume
+	r, err := http.Post(ts.URL+"/volumes/12345/smb", "application/json",
+		bytes.NewBuffer([]byte(`{"nodes" : ["abc"]}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Not shared yet
+	req, err := http.NewRequest("DELETE", url, nil)
+	tests.Assert(t, err == nil)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Bad requests
+	r = share(&api.VolumeSmbShareRequest{})
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r = share(&api.VolumeSmbShareRequest{Nodes: nodes, ShareName: "my share"})
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r = share(&api.VolumeSmbShareRequest{Nodes: []string{nodes[0], nodes[0]}})
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r = share(&api.VolumeSmbShareRequest{Nodes: []string{"123"}})
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+
+	// Nodes of another cluster cannot reach the volume
+	var other string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		clusters, err := ClusterList(tx)
+		tests.Assert(t, err == nil, err)
+		for _, id := range clusters {
+			if id == v.Info.Cluster {
+				continue
+			}
+			cluster, err := NewClusterEntryFromId(tx, id)
+			tests.Assert(t, err == nil, err)
+			other = cluster.Info.Nodes[0]
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	r = share(&api.VolumeSmbShareRequest{Nodes: []string{other}})
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+
+	// Share the volume
+	r = share(&api.VolumeSmbShareRequest{Nodes: nodes[:2], ShareName: "projects"})
+	tests.Assert(t, r.StatusCode == http.StatusAccepted, r.StatusCode)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+	var info api.VolumeInfoResponse
+	for {
+		r, err := http.Get(location.String())
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusOK)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+			continue
+		} else {
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+	tests.Assert(t, info.Mount.Smb.ShareName == "projects")
+	tests.Assert(t, len(info.Mount.Smb.Hosts) == 2)
+
+	// Already shared
+	r = share(&api.VolumeSmbShareRequest{Nodes: nodes})
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Share names are unique in the cluster
+	v2 := createSampleReplicaVolumeEntry(100, 3)
+	v2.Info.Clusters = []string{v.Info.Cluster}
+	err = v2.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	r, err = http.Post(ts.URL+"/volumes/"+v2.Info.Id+"/smb", "application/json",
+		bytes.NewBufferString(`{"nodes" : ["`+nodes[0]+`"], "share_name" : "projects"}`))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+	err = v2.SmbShare(app.db, app.executor, nodes[:1], "projects")
+	tests.Assert(t, err != nil)
+
+	// Remove the share
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted, r.StatusCode)
+}
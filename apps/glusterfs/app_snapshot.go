@@ -0,0 +1,309 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+const (
+	// Limits set by gluster
+	SNAPSHOT_MAX_NAME_LENGTH        = 255
+	SNAPSHOT_MAX_DESCRIPTION_LENGTH = 1024
+)
+
+var snapshotNameRegex = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+func (a *App) SnapshotCreate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.SnapshotCreateRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+
+	if msg.Name != "" {
+		if len(msg.Name) > SNAPSHOT_MAX_NAME_LENGTH || !snapshotNameRegex.MatchString(msg.Name) {
+			http.Error(w, "Invalid snapshot name", http.StatusBadRequest)
+			logger.LogError("Invalid snapshot name %v", msg.Name)
+			return
+		}
+	}
+
+	// The description is passed to the gluster command line in quotes
+	if len(msg.Description) > SNAPSHOT_MAX_DESCRIPTION_LENGTH ||
+		strings.ContainsAny(msg.Description, "\"`$\\") {
+		http.Error(w, "Invalid snapshot description", http.StatusBadRequest)
+		logger.LogError("Invalid snapshot description")
+		return
+	}
+
+	err = a.db.View(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		// Snapshots would keep the volume from being destroyed
+		if volume.Info.Trash != nil {
+			http.Error(w, ErrVolumeTrashed.Error(), http.StatusConflict)
+			return ErrVolumeTrashed
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// A snapshot takes space in the thin pools of the bricks
+	if a.thinPoolMonitor.VolumeCritical(id) {
+		http.Error(w, ErrThinPoolCritical.Error(), http.StatusConflict)
+		logger.LogError("Unable to snapshot volume %v: %v", id, ErrThinPoolCritical)
+		return
+	}
+
+	snapshot := NewSnapshotEntryFromRequest(&msg, id)
+
+	// Create snapshot in an asynchronous function
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Creating snapshot %v of volume %v", snapshot.Info.Name, id)
+		err := snapshot.Create(a.db, a.executor)
+		if err != nil {
+			logger.LogError("Failed to create snapshot: %v", err)
+			return "", err
+		}
+
+		logger.Info("Created snapshot %v", snapshot.Info.Id)
+
+		return "/volumes/" + id + "/snapshots/" + snapshot.Info.Id, nil
+	})
+}
+
+func (a *App) SnapshotList(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var list api.SnapshotListResponse
+	err := a.db.View(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		list.Snapshots = volume.Snapshots
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// Send list back
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) SnapshotInfo(w http.ResponseWriter, r *http.Request) {
+
+	var info *api.SnapshotInfoResponse
+	err := a.db.View(func(tx *bolt.Tx) error {
+		snapshot, err := a.snapshotFromRequest(w, r, tx)
+		if err != nil {
+			return err
+		}
+
+		info, err = snapshot.NewInfoResponse(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) SnapshotDelete(w http.ResponseWriter, r *http.Request) {
+
+	var snapshot *SnapshotEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		snapshot, err = a.snapshotFromRequest(w, r, tx)
+		return err
+	})
+	if err != nil {
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		err := snapshot.Destroy(a.db, a.executor)
+		if err != nil {
+			logger.LogError("Failed to delete snapshot %v: %v", snapshot.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Deleted snapshot [%s]", snapshot.Info.Id)
+		return "", nil
+	})
+}
+
+func (a *App) SnapshotRestore(w http.ResponseWriter, r *http.Request) {
+
+	var snapshot *SnapshotEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		snapshot, err = a.snapshotFromRequest(w, r, tx)
+		if err != nil {
+			return err
+		}
+
+		// Restoring starts the volume again
+		volume, err := NewVolumeEntryFromId(tx, snapshot.Info.VolumeId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		err = volume.checkRestorable()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Restoring volume %v from snapshot %v",
+			snapshot.Info.VolumeId, snapshot.Info.Id)
+		err := snapshot.Restore(a.db, a.executor)
+		if err != nil {
+			logger.LogError("Failed to restore snapshot %v: %v", snapshot.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Restored volume %v", snapshot.Info.VolumeId)
+
+		return "/volumes/" + snapshot.Info.VolumeId, nil
+	})
+}
+
+func (a *App) SnapshotClone(w http.ResponseWriter, r *http.Request) {
+
+	var msg api.SnapshotCloneRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+
+	if msg.Name != "" && !snapshotNameRegex.MatchString(msg.Name) {
+		http.Error(w, "Invalid volume name", http.StatusBadRequest)
+		logger.LogError("Invalid volume name %v", msg.Name)
+		return
+	}
+
+	var snapshot *SnapshotEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		snapshot, err = a.snapshotFromRequest(w, r, tx)
+		return err
+	})
+	if err != nil {
+		return
+	}
+
+	// The clone is made in the thin pools of the bricks of the volume
+	if a.thinPoolMonitor.VolumeCritical(snapshot.Info.VolumeId) {
+		http.Error(w, ErrThinPoolCritical.Error(), http.StatusConflict)
+		logger.LogError("Unable to clone snapshot %v: %v",
+			snapshot.Info.Id, ErrThinPoolCritical)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Cloning snapshot %v", snapshot.Info.Id)
+		clone, err := snapshot.Clone(a.db, a.executor, msg.Name)
+		if err != nil {
+			logger.LogError("Failed to clone snapshot %v: %v", snapshot.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Cloned snapshot %v into volume %v", snapshot.Info.Id, clone.Info.Id)
+
+		return "/volumes/" + clone.Info.Id, nil
+	})
+}
+
+// Load the snapshot in the request and make sure it belongs
+// to the volume in the request.  Replies to the client on error.
+func (a *App) snapshotFromRequest(w http.ResponseWriter,
+	r *http.Request,
+	tx *bolt.Tx) (*SnapshotEntry, error) {
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+	snapshotId := vars["snapshot"]
+
+	snapshot, err := NewSnapshotEntryFromId(tx, snapshotId)
+	if err == ErrNotFound {
+		http.Error(w, "Id not found", http.StatusNotFound)
+		return nil, err
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+
+	if snapshot.Info.VolumeId != id {
+		err := fmt.Errorf("Snapshot %v does not belong to volume %v", snapshotId, id)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, err
+	}
+
+	return snapshot, nil
+}
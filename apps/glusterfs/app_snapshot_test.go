@@ -0,0 +1,576 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSnapshotCreateBadRequests(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Bad JSON
+	request := []byte(`{
+        "asdfasd  0
+    }`)
+	r, err := http.Post(ts.URL+"/volumes/123/snapshots",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == 422)
+
+	// Bad name
+	request = []byte(`{
+        "name" : "bad name;"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/123/snapshots",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Bad description
+	request = []byte(`{
+        "description" : "$(reboot)"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/123/snapshots",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Volume not found
+	request = []byte(`{
+        "name" : "mysnap"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/123/snapshots",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+}
+
+func TestSnapshotCreateInfoListDelete(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a volume
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	var snapreq *executors.SnapshotRequest
+	app.xo.MockSnapshotCreate = func(host string,
+		snapshot *executors.SnapshotRequest) (*executors.Snapshot, error) {
+		snapreq = snapshot
+		return &executors.Snapshot{Name: snapshot.Snapshot}, nil
+	}
+
+	// Create a snapshot
+	request := []byte(`{
+        "name" : "mysnap",
+        "description" : "my snapshot"
+    }`)
+	r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/snapshots",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	// Query queue until finished
+	var info api.SnapshotInfoResponse
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+	tests.Assert(t, info.Name == "mysnap")
+	tests.Assert(t, info.Description == "my snapshot")
+	tests.Assert(t, info.VolumeId == v.Info.Id)
+	tests.Assert(t, info.Created != 0)
+	tests.Assert(t, snapreq.Volume == v.Info.Name)
+	tests.Assert(t, snapreq.Snapshot == "mysnap")
+	tests.Assert(t, snapreq.Description == "my snapshot")
+
+	// The same name cannot be used again
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/snapshots",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err = r.Location()
+	tests.Assert(t, err == nil)
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusInternalServerError)
+			break
+		}
+	}
+
+	// List snapshots
+	var list api.SnapshotListResponse
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id + "/snapshots")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Snapshots) == 1)
+	tests.Assert(t, list.Snapshots[0] == info.Id)
+
+	// Snapshot does not belong to another volume
+	r, err = http.Get(ts.URL + "/volumes/abc/snapshots/" + info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	// Volume cannot be deleted while it has snapshots
+	err = app.db.View(func(tx *bolt.Tx) error {
+		v, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(v.Snapshots) == 1)
+	err = v.Destroy(app.db, app.executor)
+	tests.Assert(t, err != nil)
+
+	// Delete the snapshot
+	deleted := ""
+	app.xo.MockSnapshotList = func(host string, volume string) (*executors.SnapList, error) {
+		return &executors.SnapList{
+			Count:        1,
+			SnapshotList: []string{"mysnap"},
+		}, nil
+	}
+	app.xo.MockSnapshotDelete = func(host string, snapshot string) error {
+		deleted = snapshot
+		return nil
+	}
+	req, err := http.NewRequest("DELETE",
+		ts.URL+"/volumes/"+v.Info.Id+"/snapshots/"+info.Id, nil)
+	tests.Assert(t, err == nil)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err = r.Location()
+	tests.Assert(t, err == nil)
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusNoContent)
+			break
+		}
+	}
+	tests.Assert(t, deleted == "mysnap")
+
+	// Check it is not there
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id + "/snapshots/" + info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, len(entry.Snapshots) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestSnapshotDeleteMissingFromGluster(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a volume and a snapshot
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	s := NewSnapshotEntryFromRequest(&api.SnapshotCreateRequest{}, v.Info.Id)
+	tests.Assert(t, s.Info.Name == "snap_"+s.Info.Id)
+	err = s.Create(app.db, app.executor)
+	tests.Assert(t, err == nil)
+
+	// Gluster no longer knows about the snapshot
+	app.xo.MockSnapshotDelete = func(host string, snapshot string) error {
+		tests.Assert(t, false, "Should not be called")
+		return nil
+	}
+	err = s.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewSnapshotEntryFromId(tx, s.Info.Id)
+		tests.Assert(t, err == ErrNotFound)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a volume and a snapshot
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	s := NewSnapshotEntryFromRequest(&api.SnapshotCreateRequest{Name: "mysnap"}, v.Info.Id)
+	err = s.Create(app.db, app.executor)
+	tests.Assert(t, err == nil)
+
+	// Gluster moves the bricks of the restored volume
+	var before, after []executors.Brick
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.Bricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+			tests.Assert(t, err == nil)
+
+			host := node.Info.Hostnames.Storage[0]
+			before = append(before, executors.Brick{
+				Name: host + ":" + brick.Info.Path})
+			after = append(after, executors.Brick{
+				Name: host + ":/run/gluster/snaps/" + brick.Info.Id + "/brick"})
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	restored := false
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		vinfo := &executors.Volume{}
+		if restored {
+			vinfo.Bricks.BrickList = after
+		} else {
+			vinfo.Bricks.BrickList = before
+		}
+		return vinfo, nil
+	}
+	app.xo.MockSnapshotRestore = func(host string, volume string, snapshot string) error {
+		tests.Assert(t, volume == v.Info.Name)
+		tests.Assert(t, snapshot == "mysnap")
+		restored = true
+		return nil
+	}
+
+	// A stopped volume is not restored, it would be started again
+	err = v.setStopped(app.db, app.executor, true)
+	tests.Assert(t, err == nil)
+	r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/snapshots/"+s.Info.Id+"/restore",
+		"application/json", bytes.NewBuffer([]byte(`{}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+	err = s.Restore(app.db, app.executor)
+	tests.Assert(t, err == ErrVolumeStopped, err)
+	tests.Assert(t, !restored)
+	err = v.setStopped(app.db, app.executor, false)
+	tests.Assert(t, err == nil)
+
+	// Restore the volume
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/snapshots/"+s.Info.Id+"/restore",
+		"application/json", bytes.NewBuffer([]byte(`{}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	var info api.VolumeInfoResponse
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+	tests.Assert(t, restored)
+	tests.Assert(t, info.Id == v.Info.Id)
+	for _, brick := range info.Bricks {
+		tests.Assert(t, brick.Path == "/run/gluster/snaps/"+brick.Id+"/brick", brick.Path)
+	}
+
+	// The snapshot is consumed by the restore
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewSnapshotEntryFromId(tx, s.Info.Id)
+		tests.Assert(t, err == ErrNotFound)
+
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, len(entry.Snapshots) == 0)
+		v = entry
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// Deleting the restored volume acts on the moved bricks
+	checked, destroyed := 0, 0
+	app.xo.MockBrickDestroyCheck = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, brick.Restored)
+		tests.Assert(t, brick.Path == "/run/gluster/snaps/"+brick.Name+"/brick", brick.Path)
+		checked++
+		return nil
+	}
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, brick.Restored)
+		tests.Assert(t, brick.Path == "/run/gluster/snaps/"+brick.Name+"/brick", brick.Path)
+		destroyed++
+		return nil
+	}
+	err = v.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, checked == 3, checked)
+	tests.Assert(t, destroyed == 3, destroyed)
+}
+
+func TestSnapshotClone(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a volume and a snapshot
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	s := NewSnapshotEntryFromRequest(&api.SnapshotCreateRequest{Name: "mysnap"}, v.Info.Id)
+	err = s.Create(app.db, app.executor)
+	tests.Assert(t, err == nil)
+
+	// Gluster brick names of the volume and the clone
+	var origin, cloned []executors.Brick
+	used := make(map[string]uint64)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.Bricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+			tests.Assert(t, err == nil)
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			tests.Assert(t, err == nil)
+			used[device.Info.Id] = device.Info.Storage.Used
+
+			host := node.Info.Hostnames.Storage[0]
+			origin = append(origin, executors.Brick{
+				Name: host + ":" + brick.Info.Path})
+			cloned = append(cloned, executors.Brick{
+				Name: host + ":/run/gluster/snaps/clone/" + brick.Info.Id + "/brick"})
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		tests.Assert(t, volume == v.Info.Name)
+		vinfo := &executors.Volume{}
+		vinfo.Bricks.BrickList = origin
+		return vinfo, nil
+	}
+	app.xo.MockSnapshotClone = func(host string,
+		clone *executors.SnapshotCloneRequest) (*executors.Volume, error) {
+		tests.Assert(t, clone.Snapshot == "mysnap")
+		tests.Assert(t, clone.Volume == "myclone")
+		vinfo := &executors.Volume{}
+		vinfo.Bricks.BrickList = cloned
+		return vinfo, nil
+	}
+
+	// Clone the snapshot
+	r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/snapshots/"+s.Info.Id+"/clone",
+		"application/json", bytes.NewBuffer([]byte(`{"name" : "myclone"}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	var info api.VolumeInfoResponse
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+		} else {
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+	tests.Assert(t, info.Id != v.Info.Id)
+	tests.Assert(t, info.Name == "myclone")
+	tests.Assert(t, info.Cluster == v.Info.Cluster)
+	tests.Assert(t, info.Size == v.Info.Size)
+	tests.Assert(t, info.Mount.GlusterFS.MountPoint != "")
+	tests.Assert(t, len(info.Bricks) == len(v.Bricks))
+
+	// The bricks of the clone live in the thin pools of the volume
+	var clone *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		clone, err = NewVolumeEntryFromId(tx, info.Id)
+		tests.Assert(t, err == nil)
+
+		cluster, err := NewClusterEntryFromId(tx, clone.Info.Cluster)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, len(cluster.Info.Volumes) == 2)
+
+		for _, id := range clone.Bricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, brick.IsClone())
+			tests.Assert(t, brick.TotalSize() == 0)
+			tests.Assert(t, brick.Info.Path == "/run/gluster/snaps/clone/"+brick.OriginBrickId+"/brick")
+
+			origin, err := NewBrickEntryFromId(tx, brick.OriginBrickId)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, origin.Info.DeviceId == brick.Info.DeviceId)
+			tests.Assert(t, origin.Info.VolumeId == v.Info.Id)
+
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, utils.SortedStringHas(device.Bricks, brick.Info.Id))
+			tests.Assert(t, device.Info.Storage.Used == used[device.Info.Id])
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// The same name cannot be used again
+	_, err = s.Clone(app.db, app.executor, "myclone")
+	tests.Assert(t, err != nil)
+
+	// Delete the clone, only the cloned LVs are removed
+	destroyed := 0
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, brick.Path != "")
+		destroyed++
+		return nil
+	}
+	err = clone.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, destroyed == len(v.Bricks))
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for id, u := range used {
+			device, err := NewDeviceEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, device.Info.Storage.Used == u)
+			tests.Assert(t, len(device.Bricks) == 1)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
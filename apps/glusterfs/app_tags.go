@@ -0,0 +1,150 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func (a *App) ClusterSetTags(w http.ResponseWriter, r *http.Request) {
+	a.setTags(w, r,
+		func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+			return NewClusterEntryFromId(tx, id)
+		},
+		func(tx *bolt.Tx, entry TaggedEntry) (interface{}, error) {
+			return entry.(*ClusterEntry).NewClusterInfoResponse(tx)
+		})
+}
+
+func (a *App) NodeSetTags(w http.ResponseWriter, r *http.Request) {
+	a.setTags(w, r,
+		func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+			return NewNodeEntryFromId(tx, id)
+		},
+		func(tx *bolt.Tx, entry TaggedEntry) (interface{}, error) {
+			return entry.(*NodeEntry).NewInfoReponse(tx)
+		})
+}
+
+func (a *App) DeviceSetTags(w http.ResponseWriter, r *http.Request) {
+	a.setTags(w, r,
+		func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+			return NewDeviceEntryFromId(tx, id)
+		},
+		func(tx *bolt.Tx, entry TaggedEntry) (interface{}, error) {
+			return entry.(*DeviceEntry).NewInfoResponse(tx)
+		})
+}
+
+func (a *App) VolumeSetTags(w http.ResponseWriter, r *http.Request) {
+	a.setTags(w, r,
+		func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+			return NewVolumeEntryFromId(tx, id)
+		},
+		func(tx *bolt.Tx, entry TaggedEntry) (interface{}, error) {
+			return entry.(*VolumeEntry).NewInfoResponse(tx)
+		})
+}
+
+// Changes the tags of the entry with the id in the URL and
+// sends back its information
+func (a *App) setTags(w http.ResponseWriter,
+	r *http.Request,
+	load func(tx *bolt.Tx, id string) (TaggedEntry, error),
+	response func(tx *bolt.Tx, entry TaggedEntry) (interface{}, error)) {
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.TagsChangeRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+
+	switch msg.Change {
+	case api.SetTags:
+	case api.UpdateTags:
+	case api.DeleteTags:
+	case "":
+		msg.Change = api.UpdateTags
+	default:
+		http.Error(w, "Unknown tags change type", http.StatusBadRequest)
+		logger.LogError("Unknown tags change type %v", msg.Change)
+		return
+	}
+	if msg.Change != api.SetTags && len(msg.Tags) == 0 {
+		http.Error(w, "No tags provided", http.StatusBadRequest)
+		return
+	}
+
+	var info interface{}
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := load(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		err = ApplyTags(entry, &msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return err
+		}
+
+		err = entry.Save(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		info, err = response(tx, entry)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	logger.Info("Changed tags of %v", id)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+// Returns the tag filters in the "tag" query parameters of a list request
+func tagFiltersFromRequest(r *http.Request) []string {
+	filters := make([]string, 0)
+	for _, filter := range r.URL.Query()["tag"] {
+		if strings.TrimSpace(filter) != "" {
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters
+}
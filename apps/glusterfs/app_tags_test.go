@@ -0,0 +1,297 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestClusterCreateWithTags(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Bad tag key
+	request := []byte(`{
+        "tags" : { "bad key" : "value" }
+    }`)
+	r, err := http.Post(ts.URL+"/clusters", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// No body at all
+	r, err = http.Post(ts.URL+"/clusters", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusCreated)
+
+	request = []byte(`{
+        "tags" : { "owner" : "storage-team", "cost-center" : "4711" }
+    }`)
+	r, err = http.Post(ts.URL+"/clusters", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusCreated)
+
+	var msg api.ClusterInfoResponse
+	err = utils.GetJsonFromResponse(r, &msg)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(msg.Tags) == 2)
+	tests.Assert(t, msg.Tags["owner"] == "storage-team")
+
+	// List with filters
+	var list api.ClusterListResponse
+	r, err = http.Get(ts.URL + "/clusters")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Clusters) == 2)
+
+	r, err = http.Get(ts.URL + "/clusters?tag=owner")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Clusters) == 1)
+	tests.Assert(t, list.Clusters[0] == msg.Id)
+
+	r, err = http.Get(ts.URL + "/clusters?tag=owner:storage-team&tag=cost-center:4711")
+	tests.Assert(t, err == nil)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Clusters) == 1)
+
+	r, err = http.Get(ts.URL + "/clusters?tag=owner:someone-else")
+	tests.Assert(t, err == nil)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Clusters) == 0)
+}
+
+func TestSetTags(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	var clusterId, nodeId, deviceId string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		clusterId = v.Info.Cluster
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return err
+		}
+		nodeId = cluster.Info.Nodes[0]
+		node, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return err
+		}
+		deviceId = node.Devices[0]
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// Unknown id
+	request := []byte(`{
+        "tags" : { "owner" : "alice" }
+    }`)
+	r, err := http.Post(ts.URL+"/volumes/12345/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	// Bad JSON
+	request = []byte(`{
+        "tags" : {
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == 422)
+
+	// Unknown change type
+	request = []byte(`{
+        "tags" : { "owner" : "alice" },
+        "change_type" : "replace"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// No tags to update
+	request = []byte(`{
+        "change_type" : "update"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Bad key
+	request = []byte(`{
+        "tags" : { "owner:name" : "alice" }
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	// Tag the volume, the default change type is update
+	request = []byte(`{
+        "tags" : { "owner" : "alice", "cost-center" : "4711" }
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(volume.Tags) == 2)
+	tests.Assert(t, volume.Tags["owner"] == "alice")
+
+	// Remove one of them
+	request = []byte(`{
+        "tags" : { "cost-center" : "" },
+        "change_type" : "delete"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+
+	// Info shows the tags
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	volume = api.VolumeInfoResponse{}
+	err = utils.GetJsonFromResponse(r, &volume)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(volume.Tags) == 1)
+	tests.Assert(t, volume.Tags["owner"] == "alice")
+
+	// Filter volumes
+	var list api.VolumeListResponse
+	r, err = http.Get(ts.URL + "/volumes?tag=owner:alice")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Volumes) == 1)
+	tests.Assert(t, list.Volumes[0] == v.Info.Id)
+
+	r, err = http.Get(ts.URL + "/volumes?tag=cost-center")
+	tests.Assert(t, err == nil)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Volumes) == 0)
+
+	// Cluster
+	request = []byte(`{
+        "tags" : { "site" : "east" },
+        "change_type" : "set"
+    }`)
+	r, err = http.Post(ts.URL+"/clusters/"+clusterId+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var cluster api.ClusterInfoResponse
+	err = utils.GetJsonFromResponse(r, &cluster)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, cluster.Id == clusterId)
+	tests.Assert(t, cluster.Tags["site"] == "east")
+
+	// Node
+	request = []byte(`{
+        "tags" : { "rack" : "r12" }
+    }`)
+	r, err = http.Post(ts.URL+"/nodes/"+nodeId+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	r, err = http.Get(ts.URL + "/nodes/" + nodeId)
+	tests.Assert(t, err == nil)
+	var node api.NodeInfoResponse
+	err = utils.GetJsonFromResponse(r, &node)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, node.Tags["rack"] == "r12")
+
+	// Device
+	request = []byte(`{
+        "tags" : { "class" : "ssd" }
+    }`)
+	r, err = http.Post(ts.URL+"/devices/"+deviceId+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var device api.DeviceInfoResponse
+	err = utils.GetJsonFromResponse(r, &device)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, device.Id == deviceId)
+	tests.Assert(t, device.Tags["class"] == "ssd")
+
+	// Clearing all the tags
+	request = []byte(`{
+        "change_type" : "set"
+    }`)
+	r, err = http.Post(ts.URL+"/devices/"+deviceId+"/tags",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	err = utils.GetJsonFromResponse(r, &device)
+	tests.Assert(t, err == nil)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewDeviceEntryFromId(tx, deviceId)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Tags != nil)
+		tests.Assert(t, len(entry.Info.Tags) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
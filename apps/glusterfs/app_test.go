@@ -11,18 +11,66 @@ package glusterfs
 
 import (
 	"bytes"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
 	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
 	"github.com/heketi/tests"
 )
 
+// Sends the request and, if it was accepted, queries the queue until
+// it finished, returning the final response
+func asyncTestRequest(t *testing.T,
+	method, url, body string,
+	header http.Header) *http.Response {
+
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	tests.Assert(t, err == nil)
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	r, err := http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil)
+	if r.StatusCode != http.StatusAccepted {
+		return r
+	}
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	// Query queue until finished
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") != "true" {
+			return r
+		}
+		tests.Assert(t, r.StatusCode == http.StatusOK, r.StatusCode)
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// Returns the volume an asynchronous request was redirected to
+func asyncTestVolume(t *testing.T, r *http.Response) api.VolumeInfoResponse {
+	tests.Assert(t, r.StatusCode == http.StatusOK, r.StatusCode)
+
+	var info api.VolumeInfoResponse
+	err := utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+
+	return info
+}
+
 func TestAppBadConfigData(t *testing.T) {
 	data := []byte(`{ bad json }`)
 	app := NewApp(bytes.NewBuffer(data))
@@ -87,18 +135,17 @@ func TestAppAdvsettings(t *testing.T) {
 		}
 	}`)
 
-	bmax, bmin, bnum := BrickMaxSize, BrickMinSize, BrickMaxNum
-	defer func() {
-		BrickMaxSize, BrickMinSize, BrickMaxNum = bmax, bmin, bnum
-	}()
-
 	app := NewApp(bytes.NewReader(data))
 	defer app.Close()
 	tests.Assert(t, app != nil)
 	tests.Assert(t, app.conf.Executor == "mock")
-	tests.Assert(t, BrickMaxNum == 33)
-	tests.Assert(t, BrickMaxSize == 1*TB)
-	tests.Assert(t, BrickMinSize == 4*GB)
+	tests.Assert(t, app.brickLimits.MaxNum == 33)
+	tests.Assert(t, app.brickLimits.MaxSize == 1*TB)
+	tests.Assert(t, app.brickLimits.MinSize == 4*GB)
+	tests.Assert(t, app.allocator.BrickLimits() == app.brickLimits)
+
+	// The defaults of the package are left alone
+	tests.Assert(t, DefaultBrickLimits().MaxNum == BrickMaxNum)
 }
 
 func TestAppLogLevel(t *testing.T) {
@@ -14,6 +14,9 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
@@ -26,6 +29,10 @@ const (
 	VOLUME_CREATE_MAX_SNAPSHOT_FACTOR = 100
 )
 
+// Octal permission mode of the volume root, with optional
+// setuid, setgid and sticky bits
+var volumeModeRegex = regexp.MustCompile(`^0?[0-7]{3,4}$`)
+
 func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 
 	var msg api.VolumeCreateRequest
@@ -35,47 +42,99 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A retried request gets the volume of the first one
+	if a.replayIdempotentRequest(w, r, idempotentVolumeCreate, "") {
+		return
+	}
+
+	vol, err := a.newVolumeEntryFromCreateRequest(w, &msg)
+	if err != nil {
+		return
+	}
+
+	// Add device in an asynchronous function
+	a.asyncIdempotentRequest(w, r, idempotentVolumeCreate, vol.Info.Id, func() (string, error) {
+
+		logger.Info("Creating volume %v", vol.Info.Id)
+		err := vol.Create(a.db, a.executor, a.allocator)
+		if err != nil {
+			logger.LogError("Failed to create volume: %v", err)
+			return "", err
+		}
+
+		logger.Info("Created volume %v", vol.Info.Id)
+
+		// Done
+		return "/volumes/" + vol.Info.Id, nil
+	})
+
+}
+
+// Checks the volume create request and returns the entry of the new
+// volume. On error the response has already been sent.
+func (a *App) newVolumeEntryFromCreateRequest(w http.ResponseWriter,
+	msg *api.VolumeCreateRequest) (*VolumeEntry, error) {
+
 	switch {
 	case msg.Gid < 0:
 		http.Error(w, "Bad group id less than zero", http.StatusBadRequest)
-		logger.LogError("Bad group id less than zero")
-		return
+		return nil, logger.LogError("Bad group id less than zero")
 	case msg.Gid >= math.MaxInt32:
 		http.Error(w, "Bad group id equal or greater than 2**32", http.StatusBadRequest)
-		logger.LogError("Bad group id equal or greater than 2**32")
-		return
+		return nil, logger.LogError("Bad group id equal or greater than 2**32")
+	case msg.Uid < 0:
+		http.Error(w, "Bad user id less than zero", http.StatusBadRequest)
+		return nil, logger.LogError("Bad user id less than zero")
+	case msg.Uid >= math.MaxInt32:
+		http.Error(w, "Bad user id equal or greater than 2**32", http.StatusBadRequest)
+		return nil, logger.LogError("Bad user id equal or greater than 2**32")
+	case msg.Mode != "" && !volumeModeRegex.MatchString(msg.Mode):
+		http.Error(w, "Bad mode "+msg.Mode+", must be octal like 0770", http.StatusBadRequest)
+		return nil, logger.LogError("Bad mode %v", msg.Mode)
 	}
 
 	switch msg.Durability.Type {
 	case api.DurabilityEC:
 	case api.DurabilityReplicate:
+	case api.DurabilityArbiter:
 	case api.DurabilityDistributeOnly:
 	case "":
 		msg.Durability.Type = api.DurabilityDistributeOnly
 	default:
 		http.Error(w, "Unknown durability type", http.StatusBadRequest)
-		logger.LogError("Unknown durability type")
-		return
+		return nil, logger.LogError("Unknown durability type")
 	}
 
 	if msg.Size < 1 {
 		http.Error(w, "Invalid volume size", http.StatusBadRequest)
-		logger.LogError("Invalid volume size")
-		return
+		return nil, logger.LogError("Invalid volume size")
+	}
+	if msg.DeviceClass != "" && !deviceClassRegex.MatchString(msg.DeviceClass) {
+		http.Error(w, "Invalid device class", http.StatusBadRequest)
+		return nil, logger.LogError("Invalid device class %v", msg.DeviceClass)
+	}
+	if err := validateTags(msg.Tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, logger.LogError(err.Error())
 	}
 	if msg.Snapshot.Enable {
 		if msg.Snapshot.Factor < 1 || msg.Snapshot.Factor > VOLUME_CREATE_MAX_SNAPSHOT_FACTOR {
 			http.Error(w, "Invalid snapshot factor", http.StatusBadRequest)
-			logger.LogError("Invalid snapshot factor")
-			return
+			return nil, logger.LogError("Invalid snapshot factor")
 		}
 	}
 
 	if msg.Durability.Type == api.DurabilityReplicate {
 		if msg.Durability.Replicate.Replica > 3 {
 			http.Error(w, "Invalid replica value", http.StatusBadRequest)
-			logger.LogError("Invalid replica value")
-			return
+			return nil, logger.LogError("Invalid replica value")
+		}
+	}
+
+	if msg.Durability.Type == api.DurabilityArbiter {
+		if msg.Durability.Arbiter.Ratio < 0 || msg.Durability.Arbiter.Ratio > 1 {
+			http.Error(w, "Invalid arbiter brick ratio", http.StatusBadRequest)
+			return nil, logger.LogError("Invalid arbiter brick ratio")
 		}
 	}
 
@@ -91,13 +150,20 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 			http.Error(w,
 				fmt.Sprintf("Invalid dispersion combination: %v+%v", d.Data, d.Redundancy),
 				http.StatusBadRequest)
-			logger.LogError(fmt.Sprintf("Invalid dispersion combination: %v+%v", d.Data, d.Redundancy))
-			return
+			return nil, logger.LogError(fmt.Sprintf("Invalid dispersion combination: %v+%v", d.Data, d.Redundancy))
 		}
 	}
 
+	if err := validateBrickLimits(msg.BrickLimits, a.brickLimits); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, logger.LogError(err.Error())
+	}
+
+	vol := NewVolumeEntryFromRequest(msg)
+
 	// Check that the clusters requested are available
-	err = a.db.View(func(tx *bolt.Tx) error {
+	var minVolumeSize uint64
+	err := a.db.View(func(tx *bolt.Tx) error {
 
 		// :TODO: All we need to do is check for one instead of gathering all keys
 		clusters, err := ClusterList(tx)
@@ -120,57 +186,140 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 				return err
 			}
 		}
+		if len(msg.Clusters) != 0 {
+			clusters = msg.Clusters
+		}
+
+		// The volume must be large enough for the brick limits
+		// of at least one of the clusters, which the limits of
+		// the request must not contradict
+		var limitsErr error
+		valid := false
+		for _, clusterid := range clusters {
+			cluster, err := NewClusterEntryFromId(tx, clusterid)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			limits := vol.brickLimits(cluster, a.brickLimits)
+			if err := limits.validate(); err != nil {
+				limitsErr = err
+				continue
+			}
+			valid = true
+			size := vol.Durability.MinVolumeSize(limits)
+			if minVolumeSize == 0 || size < minVolumeSize {
+				minVolumeSize = size
+			}
+		}
+		if !valid {
+			http.Error(w, limitsErr.Error(), http.StatusBadRequest)
+			return logger.LogError(limitsErr.Error())
+		}
+
+		// Check there are devices of the requested class
+		if msg.DeviceClass != "" {
+			found, err := DeviceClassInClusters(tx, msg.DeviceClass, clusters)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if !found {
+				http.Error(w, fmt.Sprintf("No devices of class %v", msg.DeviceClass),
+					http.StatusBadRequest)
+				logger.LogError("No devices of class %v", msg.DeviceClass)
+				return ErrNotFound
+			}
+		}
+
+		// Check the placement constraints name known nodes and devices
+		if err := validatePlacement(tx, &msg.Placement); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			logger.LogError(err.Error())
+			return err
+		}
 
 		return nil
 	})
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	vol := NewVolumeEntryFromRequest(&msg)
-
-	if uint64(msg.Size)*GB < vol.Durability.MinVolumeSize() {
+	if uint64(msg.Size)*GB < minVolumeSize {
 		http.Error(w, fmt.Sprintf("Requested volume size (%v GB) is "+
 			"smaller than the minimum supported volume size (%v)",
-			msg.Size, vol.Durability.MinVolumeSize()),
+			msg.Size, minVolumeSize),
 			http.StatusBadRequest)
-		logger.LogError(fmt.Sprintf("Requested volume size (%v GB) is "+
+		return nil, logger.LogError(fmt.Sprintf("Requested volume size (%v GB) is "+
 			"smaller than the minimum supported volume size (%v)",
-			msg.Size, vol.Durability.MinVolumeSize()))
-		return
+			msg.Size, minVolumeSize))
 	}
 
-	// Add device in an asynchronous function
-	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+	return vol, nil
+}
 
-		logger.Info("Creating volume %v", vol.Info.Id)
-		err := vol.Create(a.db, a.executor, a.allocator)
-		if err != nil {
-			logger.LogError("Failed to create volume: %v", err)
-			return "", err
-		}
+func (a *App) VolumePlan(w http.ResponseWriter, r *http.Request) {
 
-		logger.Info("Created volume %v", vol.Info.Id)
+	var msg api.VolumeCreateRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
 
-		// Done
-		return "/volumes/" + vol.Info.Id, nil
-	})
+	vol, err := a.newVolumeEntryFromCreateRequest(w, &msg)
+	if err != nil {
+		return
+	}
+
+	plan, err := vol.Plan(a.db, a.allocator)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.LogError("Failed to plan volume: %v", err)
+		return
+	}
+
+	sendVolumePlan(w, plan)
+}
 
+func sendVolumePlan(w http.ResponseWriter, plan *api.VolumePlanResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		panic(err)
+	}
 }
 
 func (a *App) VolumeList(w http.ResponseWriter, r *http.Request) {
 
 	var list api.VolumeListResponse
+	filters := tagFiltersFromRequest(r)
+	trash := includeTrash(r)
 
 	// Get all the cluster ids from the DB
 	err := a.db.View(func(tx *bolt.Tx) error {
-		var err error
-
-		list.Volumes, err = VolumeList(tx)
+		volumes, err := VolumeList(tx)
 		if err != nil {
 			return err
 		}
 
+		list.Volumes = make([]string, 0, len(volumes))
+		for _, id := range volumes {
+			if len(filters) > 0 || !trash {
+				entry, err := NewVolumeEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				if entry.Info.Trash != nil && !trash {
+					continue
+				}
+				if len(filters) > 0 && !tagsMatch(entry.Info.Tags, filters) {
+					continue
+				}
+			}
+			list.Volumes = append(list.Volumes, id)
+		}
+
 		return nil
 	})
 
@@ -214,6 +363,16 @@ func (a *App) VolumeInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ask the nodes how much space the volume uses
+	if r.URL.Query().Get("usage") == "true" {
+		err = setVolumeUsage(a.db, a.executor, info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			logger.LogError("Failed to get usage of volume %v: %v", id, err)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(info); err != nil {
@@ -226,8 +385,19 @@ func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	// A retried request must not fail because the volume is gone
+	if a.replayIdempotentRequest(w, r, idempotentVolumeDelete, id) {
+		return
+	}
+
+	// Deleting a volume in the trash, or asking to purge it,
+	// destroys it right away
+	retention := time.Duration(a.conf.VolumeTrashRetention) * time.Hour
+	purge := r.URL.Query().Get("purge") == "true"
+
 	var volume *VolumeEntry
-	err := a.db.View(func(tx *bolt.Tx) error {
+	var destroy bool
+	err := a.db.Update(func(tx *bolt.Tx) error {
 
 		var err error
 		volume, err = NewVolumeEntryFromId(tx, id)
@@ -245,6 +415,28 @@ func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 
+		if len(volume.Info.BlockInfo.BlockVolumes) > 0 {
+			err := fmt.Errorf("Cannot delete volume containing block volumes")
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		if volume.Destroying {
+			http.Error(w, ErrVolumeDestroying.Error(), http.StatusConflict)
+			return ErrVolumeDestroying
+		}
+
+		// Keep the reaper and other requests from destroying
+		// the volume at the same time
+		destroy = retention <= 0 || volume.Info.Trash != nil || purge
+		if destroy {
+			err = volume.claim(tx)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+		}
+
 		return nil
 
 	})
@@ -252,17 +444,34 @@ func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+	if !destroy {
+		a.asyncIdempotentRequest(w, r, idempotentVolumeDelete, id, func() (string, error) {
+			err := volume.MoveToTrash(a.db, a.executor, retention)
+			if err != nil {
+				logger.LogError("Failed to move volume %v to the trash: %v", volume.Info.Id, err)
+				return "", err
+			}
+
+			logger.Info("Moved volume %v to the trash for %v", id, retention)
+			return "", nil
+		})
+		return
+	}
+
+	started := a.asyncIdempotentRequest(w, r, idempotentVolumeDelete, id, func() (string, error) {
 
 		// Actually destroy the Volume here
 		err := volume.Destroy(a.db, a.executor)
 
-		// If it fails for some reason, we will need to add to the DB again
-		// or hold state on the entry "DELETING"
+		// If it fails for some reason, release the volume so that
+		// it can be deleted again
 
 		// Show that the key has been deleted
 		if err != nil {
 			logger.LogError("Failed to delete volume %v: %v", volume.Info.Id, err)
+			if err := releaseClaim(a.db, id); err != nil {
+				logger.LogError("Unable to release volume %v: %v", id, err)
+			}
 			return "", err
 		}
 
@@ -270,6 +479,11 @@ func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
 		return "", nil
 
 	})
+	if !started {
+		if err := releaseClaim(a.db, id); err != nil {
+			logger.LogError("Unable to release volume %v: %v", id, err)
+		}
+	}
 
 }
 
@@ -287,6 +501,12 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 	}
 	logger.Debug("Msg: %v", msg)
 
+	// A retried request must not add the space again
+	if r.URL.Query().Get("dry_run") != "true" &&
+		a.replayIdempotentRequest(w, r, idempotentVolumeExpand, id) {
+		return
+	}
+
 	if msg.Size < 1 {
 		http.Error(w, "Invalid volume size", http.StatusBadRequest)
 		return
@@ -306,6 +526,11 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 
+		if volume.Info.Stopped {
+			http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+			return ErrVolumeStopped
+		}
+
 		return nil
 
 	})
@@ -313,11 +538,34 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only show where the new bricks would go, or how the bricks
+	// would be grown
+	if r.URL.Query().Get("dry_run") == "true" {
+		var plan *api.VolumePlanResponse
+		if msg.InPlace {
+			plan, err = volume.PlanExpandInPlace(a.db, a.allocator, msg.Size)
+		} else {
+			plan, err = volume.PlanExpand(a.db, a.allocator, msg.Size)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			logger.LogError("Failed to plan expansion of volume %v: %v", volume.Info.Id, err)
+			return
+		}
+		sendVolumePlan(w, plan)
+		return
+	}
+
 	// Expand volume in an asynchronous function
-	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+	a.asyncIdempotentRequest(w, r, idempotentVolumeExpand, volume.Info.Id, func() (string, error) {
 
 		logger.Info("Expanding volume %v", volume.Info.Id)
-		err := volume.Expand(a.db, a.executor, a.allocator, msg.Size)
+		var err error
+		if msg.InPlace {
+			err = volume.ExpandInPlace(a.db, a.executor, a.allocator, msg.Size)
+		} else {
+			err = volume.Expand(a.db, a.executor, a.allocator, msg.Size)
+		}
 		if err != nil {
 			logger.LogError("Failed to expand volume %v", volume.Info.Id)
 			return "", err
@@ -329,3 +577,285 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 	})
 
 }
+
+func (a *App) VolumeSetOptions(w http.ResponseWriter, r *http.Request) {
+	logger.Debug("In VolumeSetOptions")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.VolumeOptionsRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	if len(msg.Set) == 0 && len(msg.Reset) == 0 {
+		http.Error(w, "No volume options provided", http.StatusBadRequest)
+		return
+	}
+	for _, option := range msg.Set {
+		if len(strings.Fields(option)) < 2 {
+			http.Error(w, "Invalid volume option "+option, http.StatusBadRequest)
+			logger.LogError("Invalid volume option %v", option)
+			return
+		}
+	}
+	for _, key := range msg.Reset {
+		if len(strings.Fields(key)) != 1 {
+			http.Error(w, "Invalid volume option key "+key, http.StatusBadRequest)
+			logger.LogError("Invalid volume option key %v", key)
+			return
+		}
+	}
+
+	var volume *VolumeEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+
+	})
+	if err != nil {
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Setting options of volume %v", volume.Info.Id)
+		err := volume.SetOptions(a.db, a.executor, msg.Set, msg.Reset)
+		if err != nil {
+			logger.LogError("Failed to set options of volume %v", volume.Info.Id)
+			return "", err
+		}
+
+		logger.Info("Set options of volume %v", volume.Info.Id)
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+
+}
+
+func (a *App) VolumeStop(w http.ResponseWriter, r *http.Request) {
+	logger.Debug("In VolumeStop")
+	a.volumeSetStopped(w, r, true)
+}
+
+func (a *App) VolumeStart(w http.ResponseWriter, r *http.Request) {
+	logger.Debug("In VolumeStart")
+	a.volumeSetStopped(w, r, false)
+}
+
+func (a *App) volumeSetStopped(w http.ResponseWriter, r *http.Request, stop bool) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		if volume.Info.Trash != nil {
+			http.Error(w, ErrVolumeTrashed.Error(), http.StatusConflict)
+			return ErrVolumeTrashed
+		}
+
+		if volume.Info.Stopped == stop {
+			if stop {
+				err = fmt.Errorf("Volume %v is already stopped", id)
+			} else {
+				err = fmt.Errorf("Volume %v is already started", id)
+			}
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		if stop && volume.Info.Name == db.HeketiStorageVolumeName {
+			err := fmt.Errorf("Cannot stop volume containing the Heketi database")
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		if stop && len(volume.Info.BlockInfo.BlockVolumes) > 0 {
+			err := fmt.Errorf("Cannot stop volume containing block volumes")
+			http.Error(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		return nil
+
+	})
+	if err != nil {
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		var err error
+		if stop {
+			logger.Info("Stopping volume %v", volume.Info.Id)
+			err = volume.Stop(a.db, a.executor)
+		} else {
+			logger.Info("Starting volume %v", volume.Info.Id)
+			err = volume.Start(a.db, a.executor)
+		}
+		if err != nil {
+			logger.LogError("Failed to change state of volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Changed state of volume %v", volume.Info.Id)
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+
+}
+
+func (a *App) VolumeShrink(w http.ResponseWriter, r *http.Request) {
+	logger.Debug("In VolumeShrink")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.VolumeShrinkRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		http.Error(w, "request unable to be parsed", 422)
+		return
+	}
+	logger.Debug("Msg: %v", msg)
+
+	if msg.Size < 1 {
+		http.Error(w, "Invalid volume size", http.StatusBadRequest)
+		return
+	}
+
+	var volume *VolumeEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		if msg.Size >= volume.Info.Size {
+			err := logger.LogError("Unable to shrink volume %v of %v GB by %v GB",
+				id, volume.Info.Size, msg.Size)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return err
+		}
+
+		if volume.Info.Stopped {
+			http.Error(w, ErrVolumeStopped.Error(), http.StatusConflict)
+			return ErrVolumeStopped
+		}
+
+		return nil
+
+	})
+	if err != nil {
+		return
+	}
+
+	// Shrink volume in an asynchronous function.  The request stays
+	// pending while the data is migrated off the bricks.
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Shrinking volume %v", volume.Info.Id)
+		err := volume.Shrink(a.db, a.executor, msg.Size)
+		if err != nil {
+			logger.LogError("Failed to shrink volume %v", volume.Info.Id)
+			return "", err
+		}
+
+		logger.Info("Shrunk volume %v", volume.Info.Id)
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+
+}
+
+func (a *App) VolumeRestore(w http.ResponseWriter, r *http.Request) {
+
+	volume, err := a.volumeFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	if volume.Info.Trash == nil {
+		http.Error(w, ErrVolumeNotTrashed.Error(), http.StatusConflict)
+		return
+	}
+	if volume.Destroying {
+		http.Error(w, ErrVolumeDestroying.Error(), http.StatusConflict)
+		return
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+
+		logger.Info("Restoring volume %v from the trash", volume.Info.Id)
+		err := volume.Restore(a.db, a.executor)
+		if err != nil {
+			logger.LogError("Failed to restore volume %v: %v", volume.Info.Id, err)
+			return "", err
+		}
+
+		logger.Info("Restored volume %v", volume.Info.Id)
+
+		return "/volumes/" + volume.Info.Id, nil
+	})
+}
+
+// Volumes in the trash are only listed when the request asks for them
+func includeTrash(r *http.Request) bool {
+	return r.URL.Query().Get("include_trash") == "true"
+}
+
+// Loads the volume of the request, answering the request on errors
+func (a *App) volumeFromRequest(w http.ResponseWriter,
+	r *http.Request) (*VolumeEntry, error) {
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+	})
+
+	return volume, err
+}
@@ -86,6 +86,53 @@ func TestVolumeCreateBadGid(t *testing.T) {
 
 }
 
+func TestVolumeCreateBadUidAndMode(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// VolumeCreate JSON Request
+	request := []byte(`{
+        "size" : 100,
+        "uid" : -1
+    }`)
+
+	// Send request
+	r, err := http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	body, err := utils.GetStringFromResponse(r)
+	tests.Assert(t, err == nil)
+	tests.Assert(t,
+		strings.Contains(body, "Bad user id less than zero"))
+
+	// Mode is not octal
+	request = []byte(`{
+        "size" : 100,
+        "mode" : "u+rwx"
+    }`)
+
+	// Send request
+	r, err = http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	body, err = utils.GetStringFromResponse(r)
+	tests.Assert(t, err == nil)
+	tests.Assert(t,
+		strings.Contains(body, "Bad mode u+rwx"))
+}
+
 func TestVolumeCreateBadJson(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -179,11 +226,6 @@ func TestVolumeCreateSmallSize(t *testing.T) {
 		}
 	}`)
 
-	bmin := BrickMinSize
-	defer func() {
-		BrickMinSize = bmin
-	}()
-
 	app := NewApp(bytes.NewReader(data))
 	defer app.Close()
 
@@ -219,6 +261,54 @@ func TestVolumeCreateSmallSize(t *testing.T) {
 		"is smaller than the minimum supported volume size"), body)
 }
 
+func TestVolumeCreateBrickLimitsConflict(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		clusters, err := ClusterList(tx)
+		tests.Assert(t, err == nil)
+		cluster, err := NewClusterEntryFromId(tx, clusters[0])
+		tests.Assert(t, err == nil)
+		cluster.Info.BrickLimits.MaxSize = 25
+		return cluster.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	// A minimum above the maximum of the server or of the
+	// cluster leaves no brick size to allocate
+	for _, request := range []string{
+		`{ "size" : 100, "brick_limits" : { "min_size_gb" : 5000 } }`,
+		`{ "size" : 100, "brick_limits" : { "min_size_gb" : 30 } }`,
+	} {
+		r, err := http.Post(ts.URL+"/volumes", "application/json",
+			bytes.NewBuffer([]byte(request)))
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+		body, err := utils.GetStringFromResponse(r)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, strings.Contains(body, "is larger than the maximum brick size"), body)
+	}
+}
+
 func TestVolumeHeketiDbStorage(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -368,6 +458,41 @@ func TestVolumeCreateBadReplicaValues(t *testing.T) {
 	tests.Assert(t, strings.Contains(string(body), "Invalid replica value"))
 }
 
+func TestVolumeCreateBadArbiterRatio(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// VolumeCreate JSON Request
+	request := []byte(`{
+        "size" : 100,
+        "durability": {
+        	"type": "arbiter",
+        	"arbiter": {
+            	"ratio": 1.5
+        	}
+    	}
+    }`)
+
+	// Send request
+	r, err := http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	tests.Assert(t, err == nil)
+	r.Body.Close()
+	tests.Assert(t, strings.Contains(string(body), "Invalid arbiter brick ratio"))
+}
+
 func TestVolumeCreateBadDispersionValues(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -467,6 +592,106 @@ func TestVolumeCreateBadClusters(t *testing.T) {
 	tests.Assert(t, strings.Contains(string(body), "Cluster id bad not found"))
 }
 
+func TestVolumeCreateBadDeviceClass(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Invalid class name
+	request := []byte(`{
+        "size" : 10,
+        "device_class" : "fast disks"
+    }`)
+	r, err := http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	tests.Assert(t, err == nil)
+	r.Body.Close()
+	tests.Assert(t, strings.Contains(string(body), "Invalid device class"))
+
+	// None of the devices have a class
+	request = []byte(`{
+        "size" : 10,
+        "device_class" : "ssd"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+	body, err = ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	tests.Assert(t, err == nil)
+	r.Body.Close()
+	tests.Assert(t, strings.Contains(string(body), "No devices of class ssd"))
+}
+
+func TestVolumeCreateBadPlacement(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Unknown node
+	request := []byte(`{
+        "size" : 10,
+        "placement" : { "nodes" : ["123"] }
+    }`)
+	r, err := http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	tests.Assert(t, err == nil)
+	r.Body.Close()
+	tests.Assert(t, strings.Contains(string(body), "Node id 123 in placement constraints not found"))
+
+	// Zone both included and excluded
+	request = []byte(`{
+        "size" : 10,
+        "placement" : { "zones" : [1], "exclude_zones" : [1] }
+    }`)
+	r, err = http.Post(ts.URL+"/volumes", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+	body, err = ioutil.ReadAll(io.LimitReader(r.Body, r.ContentLength))
+	tests.Assert(t, err == nil)
+	r.Body.Close()
+	tests.Assert(t, strings.Contains(string(body), "Zone 1 is both included and excluded"))
+}
+
 func TestVolumeCreateBadSnapshotFactor(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -598,6 +823,133 @@ func TestVolumeCreate(t *testing.T) {
 	tests.Assert(t, info.Durability.Type == api.DurabilityDistributeOnly)
 }
 
+func TestVolumePlan(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Setup database
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// The request is checked as for a volume create
+	request := []byte(`{
+        "size" : 0
+    }`)
+	r, err := http.Post(ts.URL+"/volumes/plan", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest)
+
+	request = []byte(`{
+        "size" : 100,
+        "durability": {
+            "type": "replicate",
+            "replicate": {
+                "replica": 3
+            }
+        }
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/plan", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var plan api.VolumePlanResponse
+	err = utils.GetJsonFromResponse(r, &plan)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, plan.Feasible)
+	tests.Assert(t, len(plan.BrickSets) == 1)
+	tests.Assert(t, len(plan.BrickSets[0]) == 3)
+
+	// The volume was not created
+	var list api.VolumeListResponse
+	r, err = http.Get(ts.URL + "/volumes")
+	tests.Assert(t, err == nil)
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Volumes) == 0)
+
+	// Does not fit
+	request = []byte(`{
+        "size" : 2000,
+        "durability": {
+            "type": "replicate",
+            "replicate": {
+                "replica": 3
+            }
+        }
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/plan", "application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	plan = api.VolumePlanResponse{}
+	err = utils.GetJsonFromResponse(r, &plan)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, !plan.Feasible)
+	tests.Assert(t, plan.Reason != "")
+
+	// Dry run of an expansion
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	request = []byte(`{
+        "expand_size" : 100
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/expand?dry_run=true",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	plan = api.VolumePlanResponse{}
+	err = utils.GetJsonFromResponse(r, &plan)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, plan.Feasible)
+	tests.Assert(t, plan.Cluster == v.Info.Cluster)
+	tests.Assert(t, !plan.InPlace)
+
+	// Dry run of an expansion growing the bricks
+	_, oldBricks, oldDevices := growTestBricks(t, app, v.Info.Id)
+	request = []byte(`{
+        "expand_size" : 100,
+        "in_place" : true
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/expand?dry_run=true",
+		"application/json", bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	plan = api.VolumePlanResponse{}
+	err = utils.GetJsonFromResponse(r, &plan)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, plan.Feasible)
+	tests.Assert(t, plan.InPlace)
+	tests.Assert(t, len(plan.BrickSets) == 1, plan.BrickSets)
+	for _, b := range plan.BrickSets[0] {
+		tests.Assert(t, b.Size == 2*oldBricks[v.Bricks[0]].Info.Size, b.Size)
+	}
+
+	entry, bricks, devices := growTestBricks(t, app, v.Info.Id)
+	tests.Assert(t, entry.Info.Size == 100)
+	tests.Assert(t, len(bricks) == len(v.Bricks))
+	for id, brick := range bricks {
+		tests.Assert(t, brick.Info.Size == oldBricks[id].Info.Size)
+	}
+	for id, device := range devices {
+		tests.Assert(t, device.Info.Storage.Free == oldDevices[id].Info.Storage.Free)
+	}
+}
+
 func TestVolumeInfoIdNotFound(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -1063,6 +1415,274 @@ func TestVolumeExpand(t *testing.T) {
 	tests.Assert(t, len(vc.Bricks) < len(info.Bricks))
 }
 
+func TestVolumeSetOptions(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Create a cluster
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a volume
+	v := createSampleReplicaVolumeEntry(100, 2)
+	v.GlusterVolumeOptions = []string{"nfs.disable on"}
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Bad requests
+	for _, request := range []string{
+		`{}`,
+		`{"set" : ["performance.cache-size"]}`,
+		`{"reset" : ["performance.cache-size 1GB"]}`,
+	} {
+		r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/options",
+			"application/json",
+			bytes.NewBuffer([]byte(request)))
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusBadRequest, request)
+	}
+
+	// Unknown volume
+	request := []byte(`{
+        "set" : ["performance.cache-size 1GB"],
+        "reset" : ["nfs.disable"]
+    }`)
+	r, err := http.Post(ts.URL+"/volumes/12345/options",
+		"application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Send request
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/options",
+		"application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	// Query queue until finished
+	var info api.VolumeInfoResponse
+	for {
+		r, err := http.Get(location.String())
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusOK)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+			continue
+		} else {
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+
+	tests.Assert(t, len(info.GlusterVolumeOptions) == 1)
+	tests.Assert(t, info.GlusterVolumeOptions[0] == "performance.cache-size 1GB")
+}
+
+func TestVolumeStopStart(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Create a cluster
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create a volume
+	v := createSampleReplicaVolumeEntry(100, 2)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Unknown volume
+	r, err := http.Post(ts.URL+"/volumes/12345/stop", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	changeState := func(action string) api.VolumeInfoResponse {
+		r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/"+action,
+			"application/json", nil)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusAccepted, r.StatusCode)
+		location, err := r.Location()
+		tests.Assert(t, err == nil)
+
+		// Query queue until finished
+		var info api.VolumeInfoResponse
+		for {
+			r, err := http.Get(location.String())
+			tests.Assert(t, err == nil)
+			tests.Assert(t, r.StatusCode == http.StatusOK)
+			if r.Header.Get("X-Pending") == "true" {
+				time.Sleep(time.Millisecond * 10)
+				continue
+			} else {
+				err = utils.GetJsonFromResponse(r, &info)
+				tests.Assert(t, err == nil)
+				break
+			}
+		}
+		return info
+	}
+
+	// Starting a running volume is a conflict
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/start", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	info := changeState("stop")
+	tests.Assert(t, info.Stopped)
+
+	// Stopping it again is a conflict
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/stop", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// A stopped volume cannot be expanded or shrunk
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/expand",
+		"application/json",
+		bytes.NewBuffer([]byte(`{"expand_size" : 100}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/shrink",
+		"application/json",
+		bytes.NewBuffer([]byte(`{"shrink_size" : 50}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	info = changeState("start")
+	tests.Assert(t, !info.Stopped)
+}
+
+func TestVolumeStopHeketiStorageVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 2)
+	v.Info.Name = db.HeketiStorageVolumeName
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/stop", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+}
+
+func TestVolumeShrink(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// Create a volume with two brick sets
+	v, _ := setupShrinkVolume(t, app)
+
+	// Shrinking by the whole volume is not allowed
+	request := []byte(`{
+        "shrink_size" : 200
+    }`)
+	r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/shrink",
+		"application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+
+	// Unknown volume
+	request = []byte(`{
+        "shrink_size" : 100
+    }`)
+	r, err = http.Post(ts.URL+"/volumes/12345/shrink",
+		"application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Send request
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/shrink",
+		"application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	// Query queue until finished
+	var info api.VolumeInfoResponse
+	for {
+		r, err := http.Get(location.String())
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.StatusCode == http.StatusOK)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+			continue
+		} else {
+			err = utils.GetJsonFromResponse(r, &info)
+			tests.Assert(t, err == nil)
+			break
+		}
+	}
+
+	tests.Assert(t, info.Size == 100)
+	tests.Assert(t, len(info.Bricks) == 2)
+}
+
 func TestVolumeClusterResizeByAddingDevices(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"time"
+)
+
+// backgroundTask runs a function in its own goroutine, right away
+// and then at every interval, until it is stopped
+type backgroundTask struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (t *backgroundTask) start(interval time.Duration, run func()) {
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			run()
+
+			select {
+			case <-ticker.C:
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Waits for the current run to return.  Long runs should check
+// stopping() to return early.
+func (t *backgroundTask) stopTask() {
+	if t.stop == nil {
+		return
+	}
+
+	close(t.stop)
+	<-t.done
+	t.stop = nil
+}
+
+func (t *backgroundTask) stopping() bool {
+	select {
+	case <-t.stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,402 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/lpabon/godbc"
+)
+
+const (
+	// Block hosting volumes keep three copies of the block files
+	BLOCK_HOSTING_VOLUME_REPLICA = 3
+)
+
+type BlockVolumeEntry struct {
+	Info api.BlockVolumeInfo
+}
+
+func BlockVolumeList(tx *bolt.Tx) ([]string, error) {
+
+	list := EntryKeys(tx, BOLTDB_BUCKET_BLOCKVOLUME)
+	if list == nil {
+		return nil, ErrAccessList
+	}
+	return list, nil
+}
+
+func NewBlockVolumeEntry() *BlockVolumeEntry {
+	return &BlockVolumeEntry{}
+}
+
+func NewBlockVolumeEntryFromRequest(req *api.BlockVolumeCreateRequest) *BlockVolumeEntry {
+	godbc.Require(req != nil)
+
+	entry := NewBlockVolumeEntry()
+	entry.Info.Id = utils.GenUUID()
+	entry.Info.Size = req.Size
+	entry.Info.Hacount = req.Hacount
+	entry.Info.Auth = req.Auth
+
+	// If it is zero, then it will be assigned during block volume creation
+	entry.Info.Clusters = req.Clusters
+
+	if req.Name == "" {
+		entry.Info.Name = "blockvol_" + entry.Info.Id
+	} else {
+		entry.Info.Name = req.Name
+	}
+
+	return entry
+}
+
+func NewBlockVolumeEntryFromId(tx *bolt.Tx, id string) (*BlockVolumeEntry, error) {
+	godbc.Require(tx != nil)
+
+	entry := NewBlockVolumeEntry()
+	err := EntryLoad(tx, entry, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (v *BlockVolumeEntry) BucketName() string {
+	return BOLTDB_BUCKET_BLOCKVOLUME
+}
+
+func (v *BlockVolumeEntry) Save(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(v.Info.Id) > 0)
+
+	return EntrySave(tx, v, v.Info.Id)
+}
+
+func (v *BlockVolumeEntry) Delete(tx *bolt.Tx) error {
+	return EntryDelete(tx, v, v.Info.Id)
+}
+
+func (v *BlockVolumeEntry) NewInfoResponse(tx *bolt.Tx) (*api.BlockVolumeInfoResponse, error) {
+	godbc.Require(tx != nil)
+
+	info := &api.BlockVolumeInfoResponse{}
+	info.BlockVolumeInfo = v.Info
+
+	return info, nil
+}
+
+func (v *BlockVolumeEntry) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(*v)
+
+	return buffer.Bytes(), err
+}
+
+func (v *BlockVolumeEntry) Unmarshal(buffer []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(buffer))
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *BlockVolumeEntry) Create(db *bolt.DB,
+	executor executors.Executor,
+	allocator Allocator) (e error) {
+
+	// Reserve space in a block hosting volume, making
+	// room for the block volume if none has enough
+	hosting, err := v.reserveBlockHostingVolume(db)
+	if err == ErrNoSpace && CreateBlockHostingVolumes {
+		err = v.addBlockHostingSpace(db, executor, allocator)
+		if err != nil {
+			return err
+		}
+		hosting, err = v.reserveBlockHostingVolume(db)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Return the space on any error
+	defer func() {
+		if e != nil {
+			db.Update(func(tx *bolt.Tx) error {
+				return v.releaseBlockHostingVolume(tx)
+			})
+		}
+	}()
+
+	// Export the block volume from the nodes of the hosting volume
+	hosts := hosting.Info.Mount.GlusterFS.Hosts
+	if v.Info.Hacount == 0 {
+		v.Info.Hacount = len(hosts)
+	}
+	if v.Info.Hacount > len(hosts) {
+		return fmt.Errorf("Hacount %v is larger than the %v nodes of block hosting volume %v",
+			v.Info.Hacount, len(hosts), hosting.Info.Id)
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	br := &executors.BlockVolumeRequest{
+		Name:              v.Info.Name,
+		Size:              v.Info.Size,
+		GlusterVolumeName: hosting.Info.Name,
+		Hacount:           v.Info.Hacount,
+		BlockHosts:        hosts[:v.Info.Hacount],
+		Auth:              v.Info.Auth,
+	}
+	info, err := executor.BlockVolumeCreate(host, br)
+	if err != nil {
+		return err
+	}
+
+	v.Info.BlockVolume.Hosts = info.BlockHosts
+	v.Info.BlockVolume.Iqn = info.Iqn
+	v.Info.BlockVolume.Username = info.Username
+	v.Info.BlockVolume.Password = info.Password
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+	if err != nil {
+		logger.LogError("Unable to save block volume %v, removing it from volume %v",
+			v.Info.Name, hosting.Info.Name)
+		executor.BlockVolumeDestroy(host, hosting.Info.Name, v.Info.Name)
+		return err
+	}
+
+	return nil
+}
+
+func (v *BlockVolumeEntry) Destroy(db *bolt.DB, executor executors.Executor) error {
+	logger.Info("Destroying block volume %v", v.Info.Id)
+
+	var hosting *VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		hosting, err = NewVolumeEntryFromId(tx, v.Info.BlockHostingVolume)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	err = executor.BlockVolumeDestroy(host, hosting.Info.Name, v.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		err := v.releaseBlockHostingVolume(tx)
+		if err != nil {
+			return err
+		}
+
+		return v.Delete(tx)
+	})
+}
+
+// Returns the clusters the block volume may be created in
+func (v *BlockVolumeEntry) possibleClusters(tx *bolt.Tx) ([]string, error) {
+	if len(v.Info.Clusters) != 0 {
+		return v.Info.Clusters, nil
+	}
+	return ClusterList(tx)
+}
+
+// Returns the block hosting volumes in the clusters
+// the block volume may be created in
+func (v *BlockVolumeEntry) blockHostingVolumes(tx *bolt.Tx) ([]*VolumeEntry, error) {
+	clusters, err := v.possibleClusters(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []*VolumeEntry
+	for _, clusterId := range clusters {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, volumeId := range cluster.Info.Volumes {
+			volume, err := NewVolumeEntryFromId(tx, volumeId)
+			if err != nil {
+				return nil, err
+			}
+			if volume.Info.Block {
+				volumes = append(volumes, volume)
+			}
+		}
+	}
+
+	return volumes, nil
+}
+
+// Takes the space for the block volume from the first running block
+// hosting volume with enough free space and no block volume with the
+// same name
+func (v *BlockVolumeEntry) reserveBlockHostingVolume(db *bolt.DB) (*VolumeEntry, error) {
+	var hosting *VolumeEntry
+	err := db.Update(func(tx *bolt.Tx) error {
+		volumes, err := v.blockHostingVolumes(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, volume := range volumes {
+			if volume.Info.Stopped ||
+				volume.Info.BlockInfo.FreeSize < v.Info.Size {
+				continue
+			}
+
+			inuse, err := v.nameInUse(tx, volume)
+			if err != nil {
+				return err
+			}
+			if inuse {
+				logger.Debug("Block volume name %v already in use in volume %v",
+					v.Info.Name, volume.Info.Id)
+				continue
+			}
+
+			volume.Info.BlockInfo.FreeSize -= v.Info.Size
+			volume.BlockVolumeAdd(v.Info.Id)
+			err = volume.Save(tx)
+			if err != nil {
+				return err
+			}
+
+			v.Info.Cluster = volume.Info.Cluster
+			v.Info.BlockHostingVolume = volume.Info.Id
+			hosting = volume
+			return nil
+		}
+
+		return ErrNoSpace
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Block volume %v to be created on volume %v",
+		v.Info.Id, hosting.Info.Id)
+	return hosting, nil
+}
+
+// Gives back the space taken by the block volume
+func (v *BlockVolumeEntry) releaseBlockHostingVolume(tx *bolt.Tx) error {
+	hosting, err := NewVolumeEntryFromId(tx, v.Info.BlockHostingVolume)
+	if err != nil {
+		return err
+	}
+
+	hosting.Info.BlockInfo.FreeSize += v.Info.Size
+	hosting.BlockVolumeDelete(v.Info.Id)
+	return hosting.Save(tx)
+}
+
+// Block volume names are only unique inside their block hosting volume
+func (v *BlockVolumeEntry) nameInUse(tx *bolt.Tx, hosting *VolumeEntry) (bool, error) {
+	for _, id := range hosting.Info.BlockInfo.BlockVolumes {
+		blockVolume, err := NewBlockVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			// Space reserved for a block volume still being created
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if blockVolume.Info.Name == v.Info.Name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Grows an existing block hosting volume so that the block volume
+// fits in it, or creates a new block hosting volume if none can grow
+func (v *BlockVolumeEntry) addBlockHostingSpace(db *bolt.DB,
+	executor executors.Executor,
+	allocator Allocator) error {
+
+	var volumes []*VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		volumes, err = v.blockHostingVolumes(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Grow in steps of the block hosting volume size
+	for _, volume := range volumes {
+		sizeGB := v.Info.Size - volume.Info.BlockInfo.FreeSize
+		if sizeGB < BlockHostingVolumeSize {
+			sizeGB = BlockHostingVolumeSize
+		}
+
+		logger.Info("Expanding block hosting volume %v by %v GB",
+			volume.Info.Id, sizeGB)
+		err := volume.Expand(db, executor, allocator, sizeGB)
+		if err == nil {
+			return nil
+		}
+		logger.Warning("Unable to expand block hosting volume %v: %v",
+			volume.Info.Id, err)
+	}
+
+	sizeGB := BlockHostingVolumeSize
+	if v.Info.Size > sizeGB {
+		sizeGB = v.Info.Size
+	}
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = sizeGB
+	req.Clusters = v.Info.Clusters
+	req.Block = true
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = BLOCK_HOSTING_VOLUME_REPLICA
+
+	volume := NewVolumeEntryFromRequest(req)
+	logger.Info("Creating block hosting volume %v of %v GB", volume.Info.Id, sizeGB)
+	err = volume.Create(db, executor, allocator)
+	if err != nil {
+		return logger.LogError("Unable to create block hosting volume: %v", err)
+	}
+
+	return nil
+}
+
+func BlockVolumeEntryUpgrade(tx *bolt.Tx) error {
+	return nil
+}
@@ -0,0 +1,340 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+// Returns the block hosting volumes in the db
+func blockHostingVolumes(t *testing.T, db *bolt.DB) []*VolumeEntry {
+	var volumes []*VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		list, err := VolumeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range list {
+			volume, err := NewVolumeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if volume.Info.Block {
+				volumes = append(volumes, volume)
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	return volumes
+}
+
+func TestNewBlockVolumeEntryFromRequest(t *testing.T) {
+	req := &api.BlockVolumeCreateRequest{}
+	req.Size = 10
+	req.Hacount = 2
+	req.Auth = true
+	req.Clusters = []string{"abc"}
+
+	v := NewBlockVolumeEntryFromRequest(req)
+	tests.Assert(t, v.Info.Id != "")
+	tests.Assert(t, v.Info.Name == "blockvol_"+v.Info.Id)
+	tests.Assert(t, v.Info.Size == 10)
+	tests.Assert(t, v.Info.Hacount == 2)
+	tests.Assert(t, v.Info.Auth)
+	tests.Assert(t, len(v.Info.Clusters) == 1)
+
+	req.Name = "myblock"
+	v = NewBlockVolumeEntryFromRequest(req)
+	tests.Assert(t, v.Info.Name == "myblock")
+}
+
+func TestNewVolumeEntryFromRequestBlock(t *testing.T) {
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Block = true
+	req.GlusterVolumeOptions = []string{"performance.readdir-ahead on"}
+
+	v := NewVolumeEntryFromRequest(req)
+	tests.Assert(t, v.Info.Block)
+	tests.Assert(t, v.Info.BlockInfo.FreeSize == 100)
+	tests.Assert(t, len(v.GlusterVolumeOptions) == 2)
+	tests.Assert(t, v.GlusterVolumeOptions[0] == "group gluster-block")
+	tests.Assert(t, len(req.GlusterVolumeOptions) == 1)
+}
+
+func TestBlockVolumeEntryCreateNoBlockHostingVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// A volume which cannot host block volumes
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	b := NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{Size: 10})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+	tests.Assert(t, len(blockHostingVolumes(t, app.db)) == 0)
+}
+
+func TestBlockVolumeEntryCreateDestroy(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Block = true
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	hosting := NewVolumeEntryFromRequest(req)
+	err = hosting.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	var blockreq *executors.BlockVolumeRequest
+	app.xo.MockBlockVolumeCreate = func(host string,
+		blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
+		blockreq = blockVolume
+		return &executors.BlockVolumeInfo{
+			BlockHosts: blockVolume.BlockHosts,
+			Iqn:        "iqn.2016-12.org.gluster-block:abc",
+			Username:   "abc",
+			Password:   "secret",
+		}, nil
+	}
+
+	b := NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{
+		Size:    10,
+		Name:    "myblock",
+		Hacount: 2,
+		Auth:    true,
+	})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, blockreq.Name == "myblock")
+	tests.Assert(t, blockreq.Size == 10)
+	tests.Assert(t, blockreq.GlusterVolumeName == hosting.Info.Name)
+	tests.Assert(t, blockreq.Hacount == 2)
+	tests.Assert(t, blockreq.Auth)
+	tests.Assert(t, len(blockreq.BlockHosts) == 2)
+	tests.Assert(t, b.Info.Cluster == hosting.Info.Cluster)
+	tests.Assert(t, b.Info.BlockHostingVolume == hosting.Info.Id)
+	tests.Assert(t, b.Info.BlockVolume.Iqn == "iqn.2016-12.org.gluster-block:abc")
+	tests.Assert(t, b.Info.BlockVolume.Username == "abc")
+	tests.Assert(t, b.Info.BlockVolume.Password == "secret")
+	tests.Assert(t, len(b.Info.BlockVolume.Hosts) == 2)
+
+	// The name is already used in the only block hosting volume
+	dup := NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{
+		Size: 10,
+		Name: "myblock",
+	})
+	err = dup.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+
+	// Space is taken from the block hosting volume
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewBlockVolumeEntryFromId(tx, b.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Name == "myblock")
+
+		hosting, err = NewVolumeEntryFromId(tx, hosting.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, hosting.Info.BlockInfo.FreeSize == 90)
+		tests.Assert(t, len(hosting.Info.BlockInfo.BlockVolumes) == 1)
+		tests.Assert(t, hosting.Info.BlockInfo.BlockVolumes[0] == b.Info.Id)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// The block hosting volume cannot be deleted
+	err = hosting.Destroy(app.db, app.executor)
+	tests.Assert(t, err != nil)
+
+	// Destroy the block volume
+	var destroyed string
+	app.xo.MockBlockVolumeDestroy = func(host string,
+		blockHostingVolumeName string, blockVolumeName string) error {
+		tests.Assert(t, blockHostingVolumeName == hosting.Info.Name)
+		destroyed = blockVolumeName
+		return nil
+	}
+	err = b.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, destroyed == "myblock")
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewBlockVolumeEntryFromId(tx, b.Info.Id)
+		tests.Assert(t, err == ErrNotFound)
+
+		hosting, err = NewVolumeEntryFromId(tx, hosting.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, hosting.Info.BlockInfo.FreeSize == 100)
+		tests.Assert(t, len(hosting.Info.BlockInfo.BlockVolumes) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	err = hosting.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+}
+
+func TestBlockVolumeEntryCreateFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Block = true
+	hosting := NewVolumeEntryFromRequest(req)
+	err = hosting.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	app.xo.MockBlockVolumeCreate = func(host string,
+		blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
+		return nil, errors.New("TEST")
+	}
+
+	b := NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{Size: 10})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err != nil)
+
+	// More nodes requested than the volume has
+	b = NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{
+		Size:    10,
+		Hacount: 4,
+	})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err != nil)
+
+	// The space was given back
+	err = app.db.View(func(tx *bolt.Tx) error {
+		list, err := BlockVolumeList(tx)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, len(list) == 0)
+
+		hosting, err = NewVolumeEntryFromId(tx, hosting.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, hosting.Info.BlockInfo.FreeSize == 100)
+		tests.Assert(t, len(hosting.Info.BlockInfo.BlockVolumes) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestBlockVolumeEntryCreateBlockHostingVolumes(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	defer tests.Patch(&CreateBlockHostingVolumes, true).Restore()
+	defer tests.Patch(&BlockHostingVolumeSize, 100).Restore()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// A block hosting volume is created
+	b := NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{Size: 10})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	volumes := blockHostingVolumes(t, app.db)
+	tests.Assert(t, len(volumes) == 1)
+	hosting := volumes[0]
+	tests.Assert(t, hosting.Info.Size == 100)
+	tests.Assert(t, hosting.Info.Durability.Type == api.DurabilityReplicate)
+	tests.Assert(t, hosting.Info.Durability.Replicate.Replica == 3)
+	tests.Assert(t, hosting.GlusterVolumeOptions[0] == "group gluster-block")
+	tests.Assert(t, hosting.Info.BlockInfo.FreeSize == 90)
+	tests.Assert(t, b.Info.BlockHostingVolume == hosting.Info.Id)
+	tests.Assert(t, b.Info.Hacount == 3)
+
+	// The block hosting volume is grown
+	b = NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{Size: 95})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	volumes = blockHostingVolumes(t, app.db)
+	tests.Assert(t, len(volumes) == 1)
+	hosting = volumes[0]
+	tests.Assert(t, hosting.Info.Size == 200)
+	tests.Assert(t, hosting.Info.BlockInfo.FreeSize == 95)
+	tests.Assert(t, len(hosting.Info.BlockInfo.BlockVolumes) == 2)
+
+	// A new block hosting volume is created when the
+	// existing one cannot grow
+	app.xo.MockVolumeExpand = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		return nil, errors.New("TEST")
+	}
+	b = NewBlockVolumeEntryFromRequest(&api.BlockVolumeCreateRequest{Size: 150})
+	err = b.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	volumes = blockHostingVolumes(t, app.db)
+	tests.Assert(t, len(volumes) == 2)
+	for _, volume := range volumes {
+		if volume.Info.Id == hosting.Info.Id {
+			tests.Assert(t, volume.Info.Size == 200)
+			tests.Assert(t, volume.Info.BlockInfo.FreeSize == 95)
+		} else {
+			tests.Assert(t, volume.Info.Size == 150)
+			tests.Assert(t, volume.Info.BlockInfo.FreeSize == 0)
+			tests.Assert(t, b.Info.BlockHostingVolume == volume.Info.Id)
+		}
+	}
+}
@@ -25,6 +25,21 @@ type BrickEntry struct {
 	TpSize           uint64
 	PoolMetadataSize uint64
 	gidRequested     int64
+	uidRequested     int64
+	modeRequested    string
+
+	// Set when the brick is a thin snapshot of another brick
+	// and lives in the thin pool of that brick
+	OriginBrickId string
+
+	// Set when the brick was created outside of heketi and only
+	// adopted when its volume was imported
+	Imported bool
+
+	// Set when restoring a snapshot moved the brick to the logical
+	// volume gluster created for the snapshot, so the path of the
+	// brick is no longer the one heketi created
+	Restored bool
 }
 
 func BrickList(tx *bolt.Tx) ([]string, error) {
@@ -63,6 +78,58 @@ func NewBrickEntry(size, tpsize, poolMetadataSize uint64,
 	return entry
 }
 
+// Creates an entry for the clone of a brick which gluster has
+// created at path
+func NewBrickEntryFromClone(origin *BrickEntry, path, volumeid string) *BrickEntry {
+	godbc.Require(origin != nil)
+	godbc.Require(path != "")
+
+	entry := &BrickEntry{}
+	entry.TpSize = origin.TpSize
+	entry.OriginBrickId = origin.Info.Id
+	entry.Info.Id = utils.GenUUID()
+	entry.Info.Path = path
+	entry.Info.Size = origin.Info.Size
+	entry.Info.NodeId = origin.Info.NodeId
+	entry.Info.DeviceId = origin.Info.DeviceId
+	entry.Info.VolumeId = volumeid
+
+	godbc.Ensure(entry.Info.Id != "")
+	godbc.Ensure(entry.IsClone())
+
+	return entry
+}
+
+// Creates an entry for a brick created outside of heketi at path,
+// on the logical volume described by lv
+func NewBrickEntryFromImport(lv *executors.BrickLvInfo,
+	path, nodeid, volumeid string) *BrickEntry {
+
+	godbc.Require(lv != nil)
+	godbc.Require(lv.Size > 0)
+	godbc.Require(lv.VgId != "")
+	godbc.Require(path != "")
+
+	entry := &BrickEntry{}
+	entry.Imported = true
+	entry.TpSize = lv.PoolSize
+	entry.PoolMetadataSize = lv.PoolMetadataSize
+	if lv.PoolName == "" {
+		entry.TpSize = lv.Size
+	}
+	entry.Info.Id = utils.GenUUID()
+	entry.Info.Path = path
+	entry.Info.Size = lv.Size
+	entry.Info.NodeId = nodeid
+	entry.Info.DeviceId = lv.VgId
+	entry.Info.VolumeId = volumeid
+
+	godbc.Ensure(entry.Info.Id != "")
+	godbc.Ensure(entry.TpSize > 0)
+
+	return entry
+}
+
 func NewBrickEntryFromId(tx *bolt.Tx, id string) (*BrickEntry, error) {
 	godbc.Require(tx != nil)
 
@@ -147,6 +214,8 @@ func (b *BrickEntry) Create(db *bolt.DB, executor executors.Executor) error {
 	// Create request
 	req := &executors.BrickRequest{}
 	req.Gid = b.gidRequested
+	req.Uid = b.uidRequested
+	req.Mode = b.modeRequested
 	req.Name = b.Info.Id
 	req.Size = b.Info.Size
 	req.TpSize = b.TpSize
@@ -194,6 +263,11 @@ func (b *BrickEntry) Destroy(db *bolt.DB, executor executors.Executor) error {
 	req.Size = b.Info.Size
 	req.TpSize = b.TpSize
 	req.VgId = b.Info.DeviceId
+	if b.IsClone() || b.Imported || b.Restored {
+		req.Path = b.Info.Path
+	}
+	req.Imported = b.Imported
+	req.Restored = b.Restored
 
 	// Delete brick on node
 	logger.Info("Deleting brick %v", b.Info.Id)
@@ -232,16 +306,67 @@ func (b *BrickEntry) DestroyCheck(db *bolt.DB, executor executors.Executor) erro
 	req.Size = b.Info.Size
 	req.TpSize = b.TpSize
 	req.VgId = b.Info.DeviceId
+	if b.IsClone() || b.Imported || b.Restored {
+		req.Path = b.Info.Path
+	}
+	req.Imported = b.Imported
+	req.Restored = b.Restored
 
 	// Check brick on node
 	return executor.BrickDestroyCheck(host, req)
 }
 
+// Asks the node of the brick how much space the brick and its
+// thin pool use
+func (b *BrickEntry) Usage(db *bolt.DB,
+	executor executors.Executor) (*executors.BrickUsage, error) {
+
+	godbc.Require(db != nil)
+
+	// Get node hostname
+	var host string
+	err := db.View(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+		if err != nil {
+			return err
+		}
+
+		host = node.ManageHostName()
+		godbc.Check(host != "")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create request
+	req := &executors.BrickRequest{}
+	req.Name = b.Info.Id
+	req.Size = b.Info.Size
+	req.TpSize = b.TpSize
+	req.VgId = b.Info.DeviceId
+	if b.IsClone() || b.Imported || b.Restored {
+		req.Path = b.Info.Path
+	}
+	req.Imported = b.Imported
+	req.Restored = b.Restored
+
+	return executor.BrickUsage(host, req)
+}
+
 // Size consumed on device
 func (b *BrickEntry) TotalSize() uint64 {
+	// Clones use the thin pool of the original brick
+	if b.IsClone() {
+		return 0
+	}
 	return b.TpSize + b.PoolMetadataSize
 }
 
+func (b *BrickEntry) IsClone() bool {
+	return b.OriginBrickId != ""
+}
+
 func BrickEntryUpgrade(tx *bolt.Tx) error {
 	err := addVolumeIdInBrickEntry(tx)
 	if err != nil {
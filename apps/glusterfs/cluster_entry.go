@@ -23,6 +23,10 @@ import (
 
 type ClusterEntry struct {
 	Info api.ClusterInfoResponse
+
+	// Last NFS-Ganesha export id handed out in the cluster, so that
+	// volumes exported at the same time never get the same one
+	NfsLastExportId int
 }
 
 func ClusterList(tx *bolt.Tx) ([]string, error) {
@@ -45,6 +49,7 @@ func NewClusterEntry() *ClusterEntry {
 func NewClusterEntryFromRequest() *ClusterEntry {
 	entry := NewClusterEntry()
 	entry.Info.Id = utils.GenUUID()
+	entry.Info.Tags = make(map[string]string)
 
 	return entry
 }
@@ -148,6 +153,28 @@ func (c *ClusterEntry) NodeDelete(id string) {
 	c.Info.Nodes = utils.SortedStringsDelete(c.Info.Nodes, id)
 }
 
+func (c *ClusterEntry) AllTags() map[string]string {
+	return c.Info.Tags
+}
+
+func (c *ClusterEntry) SetTags(tags map[string]string) {
+	c.Info.Tags = tags
+}
+
 func ClusterEntryUpgrade(tx *bolt.Tx) error {
+	err := addTagsInClusterEntry(tx)
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+func addTagsInClusterEntry(tx *bolt.Tx) error {
+	clusters, err := ClusterList(tx)
+	if err != nil {
+		return err
+	}
+	return addTagsInEntries(tx, clusters, func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+		return NewClusterEntryFromId(tx, id)
+	})
+}
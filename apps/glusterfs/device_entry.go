@@ -13,6 +13,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"regexp"
 	"sort"
 
 	"github.com/boltdb/bolt"
@@ -26,6 +27,10 @@ const (
 	maxPoolMetadataSizeMb = 16 * GB
 )
 
+var (
+	deviceClassRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
 type DeviceEntry struct {
 	Entry
 
@@ -44,6 +49,35 @@ func DeviceList(tx *bolt.Tx) ([]string, error) {
 	return list, nil
 }
 
+// Checks if any device in the clusters is of the class
+func DeviceClassInClusters(tx *bolt.Tx, class string, clusters []string) (bool, error) {
+	for _, clusterId := range clusters {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return false, err
+		}
+
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return false, err
+			}
+
+			for _, deviceId := range node.Devices {
+				device, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return false, err
+				}
+				if device.Info.Class == class {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func NewDeviceEntry() *DeviceEntry {
 	entry := &DeviceEntry{}
 	entry.Bricks = make(sort.StringSlice, 0)
@@ -61,6 +95,8 @@ func NewDeviceEntryFromRequest(req *api.DeviceAddRequest) *DeviceEntry {
 	device := NewDeviceEntry()
 	device.Info.Id = utils.GenUUID()
 	device.Info.Name = req.Name
+	device.Info.Tags = copyTags(req.Tags)
+	device.Info.Class = req.Class
 	device.NodeId = req.NodeId
 
 	return device
@@ -301,6 +337,8 @@ func (d *DeviceEntry) NewInfoResponse(tx *bolt.Tx) (*api.DeviceInfoResponse, err
 	info := &api.DeviceInfoResponse{}
 	info.Id = d.Info.Id
 	info.Name = d.Info.Name
+	info.Tags = d.Info.Tags
+	info.Class = d.Info.Class
 	info.Storage = d.Info.Storage
 	info.State = d.State
 	info.Bricks = make([]api.BrickInfo, 0)
@@ -387,6 +425,35 @@ func (d *DeviceEntry) NewBrickEntry(amount uint64, snapFactor float64, gid int64
 
 	// :TODO: This needs unit test
 
+	tpsize, metadataSize := d.thinPoolSizes(amount, snapFactor)
+
+	// Total required size
+	total := tpsize + metadataSize
+
+	logger.Debug("device %v[%v] > required size [%v] ?",
+		d.Id(),
+		d.Info.Storage.Free, total)
+	if !d.StorageCheck(total) {
+		return nil
+	}
+
+	// Allocate amount from disk
+	d.StorageAllocate(total)
+
+	// Create brick
+	return NewBrickEntry(amount, tpsize, metadataSize, d.Info.Id, d.NodeId, gid, volumeid)
+}
+
+// Checks the device has room for a brick of the given size
+func (d *DeviceEntry) brickFits(amount uint64, snapFactor float64) bool {
+	tpsize, metadataSize := d.thinPoolSizes(amount, snapFactor)
+	return d.StorageCheck(tpsize + metadataSize)
+}
+
+// Returns the sizes, aligned to the extent size, of the thin pool and
+// its metadata for a brick of the given size
+func (d *DeviceEntry) thinPoolSizes(amount uint64, snapFactor float64) (uint64, uint64) {
+
 	// Calculate thinpool size
 	tpsize := uint64(float64(amount) * snapFactor)
 
@@ -405,21 +472,7 @@ func (d *DeviceEntry) NewBrickEntry(amount uint64, snapFactor float64, gid int64
 		metadataSize += d.ExtentSize - alignment
 	}
 
-	// Total required size
-	total := tpsize + metadataSize
-
-	logger.Debug("device %v[%v] > required size [%v] ?",
-		d.Id(),
-		d.Info.Storage.Free, total)
-	if !d.StorageCheck(total) {
-		return nil
-	}
-
-	// Allocate amount from disk
-	d.StorageAllocate(total)
-
-	// Create brick
-	return NewBrickEntry(amount, tpsize, metadataSize, d.Info.Id, d.NodeId, gid, volumeid)
+	return tpsize, metadataSize
 }
 
 // Return poolmetadatasize in KB
@@ -501,6 +554,28 @@ func (d *DeviceEntry) Remove(db *bolt.DB,
 	return nil
 }
 
+func (d *DeviceEntry) AllTags() map[string]string {
+	return d.Info.Tags
+}
+
+func (d *DeviceEntry) SetTags(tags map[string]string) {
+	d.Info.Tags = tags
+}
+
 func DeviceEntryUpgrade(tx *bolt.Tx) error {
+	err := addTagsInDeviceEntry(tx)
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+func addTagsInDeviceEntry(tx *bolt.Tx) error {
+	devices, err := DeviceList(tx)
+	if err != nil {
+		return err
+	}
+	return addTagsInEntries(tx, devices, func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+		return NewDeviceEntryFromId(tx, id)
+	})
+}
@@ -598,7 +598,7 @@ func TestDeviceSetStateFailed(t *testing.T) {
 	defer app.Close()
 
 	// Create allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create cluster entry
@@ -681,7 +681,7 @@ func TestDeviceSetStateOfflineOnline(t *testing.T) {
 	defer app.Close()
 
 	// Create allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create cluster entry
@@ -23,4 +23,13 @@ var (
 	ErrAccessList       = errors.New("Unable to access list")
 	ErrKeyExists        = errors.New("Key already exists in the database")
 	ErrNoReplacement    = errors.New("No Replacement was found for resource requested to be removed")
+	ErrVolumeStopped    = errors.New("Volume is stopped")
+	ErrQuotaDisabled    = errors.New("Quota is not enabled on the volume")
+	ErrBitrotDisabled   = errors.New("Bitrot detection is not enabled on the volume")
+	ErrPlacement        = errors.New("Not enough space on the zones, nodes and devices allowed by the placement constraints of the volume")
+	ErrThinPoolCritical = errors.New("A thin pool of the volume is critically full")
+	ErrVolumeTrashed    = errors.New("Volume is in the trash")
+	ErrVolumeNotTrashed = errors.New("Volume is not in the trash")
+	ErrVolumeDestroying = errors.New("Volume is being destroyed")
+	ErrIdempotencyKey   = errors.New("Idempotency key was already used for another request")
 )
@@ -0,0 +1,308 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/lpabon/godbc"
+)
+
+const (
+	// Keys of finished requests are forgotten after this time
+	IdempotencyKeyRetention = 24 * time.Hour
+	IdempotencyKeyMaxLength = 255
+
+	idempotentVolumeCreate = "volume create"
+	idempotentVolumeExpand = "volume expand"
+	idempotentVolumeDelete = "volume delete"
+)
+
+// IdempotencyEntry keeps the key of a request, the entity it acted
+// on and, once it finished, its result
+type IdempotencyEntry struct {
+	Key       string
+	Operation string
+	EntityId  string
+	Result    string
+	Done      bool
+	Created   int64
+}
+
+// A running request with an idempotency key, which replays wait for
+type idempotentRequest struct {
+	done   chan struct{}
+	result string
+	err    error
+}
+
+func NewIdempotencyEntry(key, operation, entityId string) *IdempotencyEntry {
+	return &IdempotencyEntry{
+		Key:       key,
+		Operation: operation,
+		EntityId:  entityId,
+		Created:   time.Now().Unix(),
+	}
+}
+
+func NewIdempotencyEntryFromKey(tx *bolt.Tx, key string) (*IdempotencyEntry, error) {
+	godbc.Require(tx != nil)
+
+	entry := &IdempotencyEntry{}
+	err := EntryLoad(tx, entry, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (i *IdempotencyEntry) BucketName() string {
+	return BOLTDB_BUCKET_IDEMPOTENCY
+}
+
+// Register saves the entry unless its key is already in use,
+// returning ErrKeyExists in that case
+func (i *IdempotencyEntry) Register(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(i.Key) > 0)
+
+	buffer, err := i.Marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = EntryRegister(tx, i, i.Key, buffer)
+	return err
+}
+
+func (i *IdempotencyEntry) Save(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(i.Key) > 0)
+
+	return EntrySave(tx, i, i.Key)
+}
+
+func (i *IdempotencyEntry) Delete(tx *bolt.Tx) error {
+	return EntryDelete(tx, i, i.Key)
+}
+
+func (i *IdempotencyEntry) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(*i)
+
+	return buffer.Bytes(), err
+}
+
+func (i *IdempotencyEntry) Unmarshal(buffer []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(buffer))
+	err := dec.Decode(i)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// replayIdempotentRequest answers a request whose idempotency key
+// was already used with the result of the first request, waiting for
+// it if it is still running.  Returns false if the request has not
+// been answered and must run.
+func (a *App) replayIdempotentRequest(w http.ResponseWriter,
+	r *http.Request,
+	operation string,
+	entityId string) bool {
+
+	key := r.Header.Get(api.IdempotencyKeyHeader)
+	if key == "" {
+		return false
+	}
+	if len(key) > IdempotencyKeyMaxLength {
+		http.Error(w, "Idempotency key is too long", http.StatusBadRequest)
+		return true
+	}
+
+	a.idempotencyLock.Lock()
+	defer a.idempotencyLock.Unlock()
+
+	return a.replayIdempotentRequestLocked(w, r, key, operation, entityId)
+}
+
+// replayIdempotentRequestLocked is replayIdempotentRequest for callers
+// holding idempotencyLock
+func (a *App) replayIdempotentRequestLocked(w http.ResponseWriter,
+	r *http.Request,
+	key string,
+	operation string,
+	entityId string) bool {
+
+	var entry *IdempotencyEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewIdempotencyEntryFromKey(tx, key)
+		return err
+	})
+	if err == ErrNotFound {
+		return false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	if entry.Operation != operation ||
+		(entityId != "" && entry.EntityId != entityId) {
+		http.Error(w, ErrIdempotencyKey.Error(), http.StatusConflict)
+		return true
+	}
+
+	if running, ok := a.idempotentRequests[key]; ok {
+		logger.Info("Waiting for request with idempotency key %v", key)
+		a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+			<-running.done
+			return running.result, running.err
+		})
+		return true
+	}
+
+	if entry.Done {
+		logger.Info("Replaying request with idempotency key %v", key)
+		a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+			return entry.Result, nil
+		})
+		return true
+	}
+
+	// The first request never finished, most likely because the
+	// server restarted, so run it again
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		return entry.Delete(tx)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	return false
+}
+
+// asyncIdempotentRequest runs the request in the background like
+// AsyncHttpRedirectFunc.  When the request has an idempotency key,
+// the key is saved so replays get the result of this request.  Keys
+// of failed requests are released so the request can be tried again.
+// A request whose key was saved meanwhile, by a request which was
+// validated at the same time, is answered like a replay.  Returns
+// false if the request was answered without running it.
+func (a *App) asyncIdempotentRequest(w http.ResponseWriter,
+	r *http.Request,
+	operation string,
+	entityId string,
+	handlerFunc func() (string, error)) bool {
+
+	key := r.Header.Get(api.IdempotencyKeyHeader)
+	if key == "" {
+		a.asyncManager.AsyncHttpRedirectFunc(w, r, handlerFunc)
+		return true
+	}
+
+	entry := NewIdempotencyEntry(key, operation, entityId)
+	running := &idempotentRequest{
+		done: make(chan struct{}),
+	}
+
+	// Look up and register the key under one lock hold, so that
+	// the key cannot be registered in between
+	a.idempotencyLock.Lock()
+	if a.replayIdempotentRequestLocked(w, r, key, operation, entityId) {
+		a.idempotencyLock.Unlock()
+		return false
+	}
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		err := a.forgetExpiredIdempotencyKeys(tx)
+		if err != nil {
+			return err
+		}
+		return entry.Register(tx)
+	})
+	if err == nil {
+		a.idempotentRequests[key] = running
+	}
+	a.idempotencyLock.Unlock()
+
+	if err == ErrKeyExists {
+		http.Error(w, ErrIdempotencyKey.Error(), http.StatusConflict)
+		return false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		result, err := handlerFunc()
+
+		a.idempotencyLock.Lock()
+		dberr := a.db.Update(func(tx *bolt.Tx) error {
+			if err != nil {
+				return entry.Delete(tx)
+			}
+			entry.Result = result
+			entry.Done = true
+			return entry.Save(tx)
+		})
+		if dberr != nil {
+			logger.LogError("Unable to save result of request with idempotency key %v: %v",
+				key, dberr)
+		}
+		delete(a.idempotentRequests, key)
+		a.idempotencyLock.Unlock()
+
+		running.result = result
+		running.err = err
+		close(running.done)
+
+		return result, err
+	})
+	return true
+}
+
+// Removes the keys of the requests which finished, or never did,
+// longer than the retention time ago
+func (a *App) forgetExpiredIdempotencyKeys(tx *bolt.Tx) error {
+	expired := time.Now().Add(-IdempotencyKeyRetention).Unix()
+
+	keys := EntryKeys(tx, BOLTDB_BUCKET_IDEMPOTENCY)
+	if keys == nil {
+		return ErrAccessList
+	}
+
+	for _, key := range keys {
+		if _, ok := a.idempotentRequests[key]; ok {
+			continue
+		}
+
+		entry, err := NewIdempotencyEntryFromKey(tx, key)
+		if err != nil {
+			return err
+		}
+		if entry.Created < expired {
+			err = entry.Delete(tx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
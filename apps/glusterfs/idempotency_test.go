@@ -0,0 +1,316 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+// Header sending the idempotency key with a request
+func idempotencyTestKey(key string) http.Header {
+	return http.Header{api.IdempotencyKeyHeader: []string{key}}
+}
+
+func TestVolumeIdempotencyKey(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	created := 0
+	app.xo.MockVolumeCreate = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		created++
+		return &executors.Volume{}, nil
+	}
+
+	// Create the volume once
+	request := `{"size": 10, "durability": {"type": "replicate"}}`
+	info := asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create-1")))
+	volume := asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create-1")))
+	tests.Assert(t, volume.Id == info.Id, volume.Id, info.Id)
+	tests.Assert(t, created == 1, created)
+	tests.Assert(t, trashTestBrickCount(t, app.db) == 2)
+
+	// Requests without a key, or with another, run again
+	asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes", request, nil))
+	tests.Assert(t, created == 2, created)
+	volume = asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create-2")))
+	tests.Assert(t, volume.Id != info.Id)
+	tests.Assert(t, created == 3, created)
+
+	// A key cannot be used for another kind of request
+	r := asyncTestRequest(t, "POST", ts.URL+"/volumes/"+info.Id+"/expand",
+		`{"expand_size": 10}`, idempotencyTestKey("create-1"))
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Expand the volume once
+	volume = asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes/"+info.Id+"/expand",
+			`{"expand_size": 10}`, idempotencyTestKey("expand-1")))
+	tests.Assert(t, volume.Size == 20, volume.Size)
+	volume = asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes/"+info.Id+"/expand",
+			`{"expand_size": 10}`, idempotencyTestKey("expand-1")))
+	tests.Assert(t, volume.Size == 20, volume.Size)
+
+	// Nor for the same kind of request on another volume
+	r = asyncTestRequest(t, "POST", ts.URL+"/volumes/"+volume.Id+"0/expand",
+		`{"expand_size": 10}`, idempotencyTestKey("expand-1"))
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Deleting again succeeds even though the volume is gone
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+info.Id, "",
+		idempotencyTestKey("delete-1"))
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+info.Id, "",
+		idempotencyTestKey("delete-1"))
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+info.Id, "",
+		idempotencyTestKey("delete-2"))
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Keys are checked for their length
+	key := string(bytes.Repeat([]byte("k"), IdempotencyKeyMaxLength+1))
+	r = asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey(key))
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+}
+
+func TestVolumeIdempotencyKeyFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	app.xo.MockVolumeCreate = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		return nil, errors.New("TEST")
+	}
+
+	// A failed request releases its key
+	request := `{"size": 10, "durability": {"type": "replicate"}}`
+	r := asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create"))
+	tests.Assert(t, r.StatusCode == http.StatusInternalServerError, r.StatusCode)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewIdempotencyEntryFromKey(tx, "create")
+		return err
+	})
+	tests.Assert(t, err == ErrNotFound, err)
+
+	// Replays of a running request wait for its result
+	release := make(chan struct{})
+	app.xo.MockVolumeCreate = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		<-release
+		return &executors.Volume{}, nil
+	}
+
+	first := make(chan api.VolumeInfoResponse)
+	go func() {
+		first <- asyncTestVolume(t,
+			asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create")))
+	}()
+	for {
+		app.idempotencyLock.Lock()
+		_, running := app.idempotentRequests["create"]
+		app.idempotencyLock.Unlock()
+		if running {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	second := make(chan api.VolumeInfoResponse)
+	go func() {
+		second <- asyncTestVolume(t,
+			asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create")))
+	}()
+	time.Sleep(time.Millisecond * 100)
+	close(release)
+
+	info := <-first
+	volume := <-second
+	tests.Assert(t, volume.Id == info.Id, volume.Id, info.Id)
+	tests.Assert(t, trashTestBrickCount(t, app.db) == 2)
+}
+
+func TestVolumeIdempotencyKeyStale(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Requests which never finished, like when the server restarted,
+	// run again
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return NewIdempotencyEntry("create", idempotentVolumeCreate, "123").Register(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	request := `{"size": 10, "durability": {"type": "replicate"}}`
+	info := asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("create")))
+	tests.Assert(t, info.Id != "123")
+
+	var entry *IdempotencyEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewIdempotencyEntryFromKey(tx, "create")
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, entry.Done)
+	tests.Assert(t, entry.EntityId == info.Id)
+	tests.Assert(t, entry.Result == "/volumes/"+info.Id, entry.Result)
+
+	// Old keys are forgotten when new requests come in
+	entry.Created = time.Now().Add(-IdempotencyKeyRetention - time.Minute).Unix()
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return entry.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes", request, idempotencyTestKey("other")))
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewIdempotencyEntryFromKey(tx, "create")
+		return err
+	})
+	tests.Assert(t, err == ErrNotFound, err)
+}
+
+func TestIdempotencyKeyRegisteredDuringValidation(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	// The first request keeps running
+	release := make(chan struct{})
+	first := httptest.NewRequest("POST", "/volumes", nil)
+	first.Header = idempotencyTestKey("create-1")
+	w := httptest.NewRecorder()
+	started := app.asyncIdempotentRequest(w, first, idempotentVolumeCreate, "vol1",
+		func() (string, error) {
+			<-release
+			return "/volumes/vol1", nil
+		})
+	tests.Assert(t, started)
+	tests.Assert(t, w.Code == http.StatusAccepted, w.Code)
+
+	// A retry validated at the same time as the first request waits
+	// for its result instead of failing
+	retry := httptest.NewRequest("POST", "/volumes", nil)
+	retry.Header = idempotencyTestKey("create-1")
+	w = httptest.NewRecorder()
+	started = app.asyncIdempotentRequest(w, retry, idempotentVolumeCreate, "vol1",
+		func() (string, error) {
+			tests.Assert(t, false, "request run twice")
+			return "", nil
+		})
+	tests.Assert(t, !started)
+	tests.Assert(t, w.Code == http.StatusAccepted, w.Code)
+
+	// Unless it is for another volume
+	other := httptest.NewRequest("POST", "/volumes", nil)
+	other.Header = idempotencyTestKey("create-1")
+	w = httptest.NewRecorder()
+	started = app.asyncIdempotentRequest(w, other, idempotentVolumeCreate, "vol2",
+		func() (string, error) {
+			tests.Assert(t, false, "request run with a used key")
+			return "", nil
+		})
+	tests.Assert(t, !started)
+	tests.Assert(t, w.Code == http.StatusConflict, w.Code)
+
+	// Wait for the result to be saved
+	close(release)
+	for i := 0; ; i++ {
+		tests.Assert(t, i < 1000, "request did not finish")
+		var entry *IdempotencyEntry
+		err := app.db.View(func(tx *bolt.Tx) error {
+			var err error
+			entry, err = NewIdempotencyEntryFromKey(tx, "create-1")
+			return err
+		})
+		tests.Assert(t, err == nil, err)
+		if entry.Done {
+			tests.Assert(t, entry.Result == "/volumes/vol1", entry.Result)
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
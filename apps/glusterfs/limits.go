@@ -9,9 +9,84 @@
 
 package glusterfs
 
-var (
-	// Default limits
+import (
+	"fmt"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// Default limits, unless the configuration sets others
 	BrickMinSize = uint64(1 * GB)
 	BrickMaxSize = uint64(4 * TB)
 	BrickMaxNum  = 32
 )
+
+var (
+	// Size of arbiter bricks relative to the data bricks
+	ArbiterBrickRatio = float32(0.05)
+
+	// Block hosting volumes are created and grown on demand
+	// when enabled.  Size in GB.
+	CreateBlockHostingVolumes = false
+	BlockHostingVolumeSize    = 1024
+)
+
+// Limits on the size and number of the bricks of a volume
+type BrickLimits struct {
+	MinSize uint64
+	MaxSize uint64
+	MaxNum  int
+}
+
+// Returns the limits used when the server configuration sets none
+func DefaultBrickLimits() BrickLimits {
+	return BrickLimits{
+		MinSize: BrickMinSize,
+		MaxSize: BrickMaxSize,
+		MaxNum:  BrickMaxNum,
+	}
+}
+
+// Returns the limits with those set in the request replacing them
+func (l BrickLimits) Override(req api.BrickLimits) BrickLimits {
+	if req.MinSize != 0 {
+		l.MinSize = uint64(req.MinSize) * GB
+	}
+	if req.MaxSize != 0 {
+		l.MaxSize = uint64(req.MaxSize) * GB
+	}
+	if req.MaxNum != 0 {
+		l.MaxNum = req.MaxNum
+	}
+	return l
+}
+
+// Checks the limits leave a brick size to allocate
+func (l BrickLimits) validate() error {
+	if l.MinSize > l.MaxSize {
+		return fmt.Errorf("Minimum brick size %v GB is larger than "+
+			"the maximum brick size %v GB", l.MinSize/GB, l.MaxSize/GB)
+	}
+	return nil
+}
+
+// Returns the limits on the bricks of the volume in the cluster.
+// Limits set on the volume win over those set on the cluster,
+// which win over the defaults of the server.
+func (v *VolumeEntry) brickLimits(cluster *ClusterEntry, defaults BrickLimits) BrickLimits {
+	limits := defaults
+	if cluster != nil {
+		limits = limits.Override(cluster.Info.BrickLimits)
+	}
+	return limits.Override(v.Info.BrickLimits)
+}
+
+// Checks the limits of a request are positive and consistent with
+// the limits they override
+func validateBrickLimits(l api.BrickLimits, defaults BrickLimits) error {
+	if l.MinSize < 0 || l.MaxSize < 0 || l.MaxNum < 0 {
+		return fmt.Errorf("Brick limits must not be negative")
+	}
+	return defaults.Override(l).validate()
+}
@@ -45,6 +45,7 @@ func NewNodeEntryFromRequest(req *api.NodeAddRequest) *NodeEntry {
 	node.Info.ClusterId = req.ClusterId
 	node.Info.Hostnames = req.Hostnames
 	node.Info.Zone = req.Zone
+	node.Info.Tags = copyTags(req.Tags)
 
 	return node
 }
@@ -415,6 +416,7 @@ func (n *NodeEntry) NewInfoReponse(tx *bolt.Tx) (*api.NodeInfoResponse, error) {
 	info.Hostnames = n.Info.Hostnames
 	info.Id = n.Info.Id
 	info.Zone = n.Info.Zone
+	info.Tags = n.Info.Tags
 	info.State = n.State
 	info.DevicesInfo = make([]api.DeviceInfoResponse, 0)
 
@@ -469,6 +471,28 @@ func (n *NodeEntry) DeviceDelete(id string) {
 	n.Devices = utils.SortedStringsDelete(n.Devices, id)
 }
 
+func (n *NodeEntry) AllTags() map[string]string {
+	return n.Info.Tags
+}
+
+func (n *NodeEntry) SetTags(tags map[string]string) {
+	n.Info.Tags = tags
+}
+
 func NodeEntryUpgrade(tx *bolt.Tx) error {
+	err := addTagsInNodeEntry(tx)
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+func addTagsInNodeEntry(tx *bolt.Tx) error {
+	nodes := EntryKeys(tx, BOLTDB_BUCKET_NODE)
+	if nodes == nil {
+		return ErrAccessList
+	}
+	return addTagsInEntries(tx, nodes, func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+		return NewNodeEntryFromId(tx, id)
+	})
+}
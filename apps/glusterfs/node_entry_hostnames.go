@@ -0,0 +1,265 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// Checks the hostnames of a node request are all set
+func validateHostnames(hostnames api.HostAddresses) error {
+	if len(hostnames.Manage) == 0 {
+		return fmt.Errorf("Manage hostname missing")
+	}
+	if len(hostnames.Storage) == 0 {
+		return fmt.Errorf("Storage hostname missing")
+	}
+
+	for _, name := range append(hostnames.Manage, hostnames.Storage...) {
+		if name == "" {
+			return fmt.Errorf("Hostname cannot be an empty string")
+		}
+	}
+
+	return nil
+}
+
+// Returns an error if any of the hostnames is registered by another
+// node, along with the registration keys left by nodes which are gone
+func (n *NodeEntry) hostnamesInUse(tx *bolt.Tx,
+	hostnames api.HostAddresses) ([]string, error) {
+
+	b := tx.Bucket([]byte(n.BucketName()))
+	if b == nil {
+		return nil, ErrDbAccess
+	}
+
+	keys := make(map[string]string)
+	for _, h := range hostnames.Manage {
+		keys[n.registerManageKey(h)] = h
+	}
+	for _, h := range hostnames.Storage {
+		keys[n.registerStorageKey(h)] = h
+	}
+
+	var stale []string
+	for key, h := range keys {
+		val := b.Get([]byte(key))
+		if val == nil || string(val) == n.Info.Id {
+			continue
+		}
+
+		conflictId := string(val)
+		_, err := NewNodeEntryFromId(tx, conflictId)
+		if err == ErrNotFound {
+			stale = append(stale, key)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("Hostname %v already used by node with id %v",
+			h, conflictId)
+	}
+
+	return stale, nil
+}
+
+// Returns the manage hostname of another node of the cluster with
+// glusterd running, or an empty string if the node is alone in it
+func (n *NodeEntry) peerManageHostname(db *bolt.DB,
+	executor executors.Executor) (string, error) {
+
+	var peers []*NodeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, n.Info.ClusterId)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range cluster.Info.Nodes {
+			if id == n.Info.Id {
+				continue
+			}
+			peer, err := NewNodeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			peers = append(peers, peer)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(peers) == 0 {
+		return "", nil
+	}
+
+	for _, peer := range peers {
+		if !peer.isOnline() {
+			continue
+		}
+		err := executor.GlusterdCheck(peer.ManageHostName())
+		if err != nil {
+			logger.Info("Glusterd not running in %v", peer.ManageHostName())
+			continue
+		}
+		return peer.ManageHostName(), nil
+	}
+
+	return "", logger.LogError("None of the other nodes in cluster %v has glusterd running",
+		n.Info.ClusterId)
+}
+
+// Replaces host by newHost in the list of hosts
+func replaceHostname(hosts []string, host, newHost string) {
+	for i, h := range hosts {
+		if h == host {
+			hosts[i] = newHost
+		}
+	}
+}
+
+// Replaces the storage hostname of the node in the NFS and SMB mount
+// information of the volume and in the hosts of its block volumes
+func (v *VolumeEntry) replaceStorageHostname(tx *bolt.Tx,
+	host, newHost string) error {
+
+	nfs := &v.Info.Mount.Nfs
+	replaceHostname(nfs.Hosts, host, newHost)
+	if strings.HasPrefix(nfs.MountPoint, host+":") {
+		nfs.MountPoint = newHost + strings.TrimPrefix(nfs.MountPoint, host)
+	}
+	replaceHostname(v.Info.Mount.Smb.Hosts, host, newHost)
+
+	for _, id := range v.Info.BlockInfo.BlockVolumes {
+		block, err := NewBlockVolumeEntryFromId(tx, id)
+		if err != nil {
+			return err
+		}
+		replaceHostname(block.Info.BlockVolume.Hosts, host, newHost)
+		err = block.Save(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetHostnames changes the hostnames of the node.  New storage
+// hostnames are probed from another node so the trusted storage pool
+// knows them, then the registration of the hostnames is moved and the
+// mount information of the volumes of the cluster, along with the
+// hosts of their block volumes, is refreshed, all in a single
+// transaction.
+func (n *NodeEntry) SetHostnames(db *bolt.DB,
+	executor executors.Executor,
+	hostnames api.HostAddresses) error {
+
+	known := make(map[string]bool)
+	for _, h := range n.Info.Hostnames.Storage {
+		known[h] = true
+	}
+	var probe []string
+	for _, h := range hostnames.Storage {
+		if !known[h] {
+			probe = append(probe, h)
+		}
+	}
+
+	if len(probe) > 0 {
+		peer, err := n.peerManageHostname(db, executor)
+		if err != nil {
+			return err
+		}
+
+		// A node alone in its cluster has no peers to tell
+		if peer != "" {
+			for _, h := range probe {
+				err := executor.PeerProbe(peer, h)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, n.Info.Id)
+		if err != nil {
+			return err
+		}
+
+		stale, err := node.hostnamesInUse(tx, hostnames)
+		if err != nil {
+			return err
+		}
+		for _, key := range stale {
+			err = EntryDelete(tx, node, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = node.Deregister(tx)
+		if err != nil {
+			return err
+		}
+		oldStorage := node.StorageHostName()
+		node.Info.Hostnames = hostnames
+		err = node.Register(tx)
+		if err != nil {
+			return err
+		}
+		err = node.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		// Clients mount the volumes through the storage hostnames
+		// of all the nodes of the cluster
+		cluster, err := NewClusterEntryFromId(tx, node.Info.ClusterId)
+		if err != nil {
+			return err
+		}
+		for _, id := range cluster.Info.Volumes {
+			volume, err := NewVolumeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			err = volume.updateMountInfo(tx)
+			if err != nil {
+				return err
+			}
+			if oldStorage != node.StorageHostName() {
+				err = volume.replaceStorageHostname(tx,
+					oldStorage, node.StorageHostName())
+				if err != nil {
+					return err
+				}
+			}
+			err = volume.Save(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		*n = *node
+		return nil
+	})
+}
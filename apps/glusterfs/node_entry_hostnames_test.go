@@ -0,0 +1,284 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestNodeSetHostnames(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Register the hostnames of the nodes, like adding them does
+	var nodes []*NodeEntry
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		for _, id := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			err = node.Register(tx)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+	node := nodes[0]
+	oldStorage := node.StorageHostName()
+	tests.Assert(t, strings.Contains(v.Info.Mount.GlusterFS.Options["backup-volfile-servers"]+
+		v.Info.Mount.GlusterFS.MountPoint, oldStorage))
+
+	// The volume is exported over NFS and SMB through the node and
+	// hosts a block volume reached through it
+	block := NewBlockVolumeEntry()
+	block.Info.Id = "block"
+	block.Info.BlockVolume.Hosts = []string{oldStorage, "other"}
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		err := block.Save(tx)
+		if err != nil {
+			return err
+		}
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		entry.Info.Mount.Nfs.Hosts = []string{oldStorage, "other"}
+		entry.Info.Mount.Nfs.MountPoint = oldStorage + ":/" + entry.Info.Name
+		entry.Info.Mount.Smb.Hosts = []string{"other", oldStorage}
+		entry.Info.BlockInfo.BlockVolumes = []string{block.Info.Id}
+		return entry.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	var checked []string
+	app.xo.MockGlusterdCheck = func(host string) error {
+		checked = append(checked, host)
+		if host == "manage-down" {
+			return errors.New("TEST")
+		}
+		return nil
+	}
+	var probed []string
+	app.xo.MockPeerProbe = func(exec_host, newnode string) error {
+		tests.Assert(t, exec_host != node.ManageHostName())
+		probed = append(probed, newnode)
+		return nil
+	}
+
+	// Requests must name both hostnames
+	url := ts.URL + "/nodes/" + node.Info.Id + "/hostnames"
+	r := asyncTestRequest(t, "POST", url,
+		`{"hostnames": {"manage": ["manage-new"]}}`, nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	r = asyncTestRequest(t, "POST", url, `{"hostnames": `, nil)
+	tests.Assert(t, r.StatusCode == 422, r.StatusCode)
+	r = asyncTestRequest(t, "POST", ts.URL+"/nodes/123/hostnames",
+		`{"hostnames": {"manage": ["manage-new"], "storage": ["storage-new"]}}`, nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound, r.StatusCode)
+
+	// Hostnames of other nodes cannot be taken
+	r = asyncTestRequest(t, "POST", url, `{"hostnames": {"manage": ["manage-new"], "storage": ["`+
+		nodes[1].StorageHostName()+`"]}}`, nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Glusterd must run on the new manage hostname
+	r = asyncTestRequest(t, "POST", url,
+		`{"hostnames": {"manage": ["manage-down"], "storage": ["storage-new"]}}`, nil)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest, r.StatusCode)
+	tests.Assert(t, len(probed) == 0)
+
+	// Change both hostnames
+	checked = nil
+	r = asyncTestRequest(t, "POST", url,
+		`{"hostnames": {"manage": ["manage-new"], "storage": ["storage-new"]}}`, nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK, r.StatusCode)
+	var info api.NodeInfoResponse
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.Id == node.Info.Id)
+	tests.Assert(t, info.Hostnames.Manage[0] == "manage-new", info.Hostnames)
+	tests.Assert(t, info.Hostnames.Storage[0] == "storage-new", info.Hostnames)
+	tests.Assert(t, len(checked) > 0 && checked[0] == "manage-new", checked)
+	tests.Assert(t, len(probed) == 1 && probed[0] == "storage-new", probed)
+
+	// The registration of the hostnames moved to the new ones
+	err = app.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BOLTDB_BUCKET_NODE))
+		tests.Assert(t, b.Get([]byte(node.registerManageKey(node.ManageHostName()))) == nil)
+		tests.Assert(t, b.Get([]byte(node.registerStorageKey(oldStorage))) == nil)
+		tests.Assert(t, string(b.Get([]byte(node.registerManageKey("manage-new")))) == node.Info.Id)
+		tests.Assert(t, string(b.Get([]byte(node.registerStorageKey("storage-new")))) == node.Info.Id)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// Volumes are mounted through the new storage hostname
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	tests.Assert(t, err == nil)
+	mount := volume.Mount.GlusterFS
+	tests.Assert(t, len(mount.Hosts) == 3, mount.Hosts)
+	servers := append(strings.Split(mount.Options["backup-volfile-servers"], ","),
+		strings.Split(mount.MountPoint, ":")[0])
+	tests.Assert(t, len(servers) == 3, servers)
+	for _, hosts := range [][]string{mount.Hosts, servers} {
+		found := false
+		for _, host := range hosts {
+			tests.Assert(t, host != oldStorage, hosts)
+			if host == "storage-new" {
+				found = true
+			}
+		}
+		tests.Assert(t, found, hosts)
+	}
+
+	// And exported and shared through it
+	tests.Assert(t, volume.Mount.Nfs.MountPoint == "storage-new:/"+v.Info.Name,
+		volume.Mount.Nfs.MountPoint)
+	tests.Assert(t, volume.Mount.Nfs.Hosts[0] == "storage-new", volume.Mount.Nfs.Hosts)
+	tests.Assert(t, volume.Mount.Nfs.Hosts[1] == "other", volume.Mount.Nfs.Hosts)
+	tests.Assert(t, volume.Mount.Smb.Hosts[1] == "storage-new", volume.Mount.Smb.Hosts)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewBlockVolumeEntryFromId(tx, block.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.BlockVolume.Hosts[0] == "storage-new",
+			entry.Info.BlockVolume.Hosts)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// The old hostnames are free for other nodes
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := nodes[1].hostnamesInUse(tx, api.HostAddresses{
+			Storage: []string{oldStorage},
+		})
+		return err
+	})
+	tests.Assert(t, err == nil, err)
+
+	// Changing only the manage hostname probes nothing
+	probed = nil
+	r = asyncTestRequest(t, "POST", url,
+		`{"hostnames": {"manage": ["manage-other"], "storage": ["storage-new"]}}`, nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK, r.StatusCode)
+	info = api.NodeInfoResponse{}
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.Hostnames.Manage[0] == "manage-other", info.Hostnames)
+	tests.Assert(t, len(probed) == 0, probed)
+}
+
+func TestNodeSetHostnamesStale(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		1,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	var node *NodeEntry
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		clusters, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		cluster, err := NewClusterEntryFromId(tx, clusters[0])
+		if err != nil {
+			return err
+		}
+		node, err = NewNodeEntryFromId(tx, cluster.Info.Nodes[0])
+		if err != nil {
+			return err
+		}
+
+		// Registration left by a node which is gone
+		gone := createSampleNodeEntry()
+		gone.Info.Hostnames.Manage = []string{"manage-new"}
+		return gone.Register(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	// A node alone in its cluster has no peers to probe from
+	app.xo.MockPeerProbe = func(exec_host, newnode string) error {
+		return errors.New("TEST")
+	}
+
+	hostnames := api.HostAddresses{
+		Manage:  []string{"manage-new"},
+		Storage: []string{"storage-new", "storage-new2"},
+	}
+	err = node.SetHostnames(app.db, app.executor, hostnames)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, node.ManageHostName() == "manage-new")
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BOLTDB_BUCKET_NODE))
+		for _, key := range []string{
+			node.registerManageKey("manage-new"),
+			node.registerStorageKey("storage-new"),
+			node.registerStorageKey("storage-new2"),
+		} {
+			tests.Assert(t, string(b.Get([]byte(key))) == node.Info.Id, key)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
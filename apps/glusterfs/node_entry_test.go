@@ -490,7 +490,7 @@ func TestNodeSetStateFailed(t *testing.T) {
 	defer app.Close()
 
 	// Create allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create cluster entry
@@ -575,7 +575,7 @@ func TestNodeSetStateOfflineOnline(t *testing.T) {
 	defer app.Close()
 
 	// Create allocator
-	mockAllocator := NewMockAllocator(app.db)
+	mockAllocator := NewMockAllocator(app.db, app.brickLimits)
 	app.allocator = mockAllocator
 
 	// Create cluster entry
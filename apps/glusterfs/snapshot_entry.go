@@ -0,0 +1,515 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/lpabon/godbc"
+)
+
+type SnapshotEntry struct {
+	Info api.SnapshotInfo
+}
+
+func SnapshotList(tx *bolt.Tx) ([]string, error) {
+
+	list := EntryKeys(tx, BOLTDB_BUCKET_SNAPSHOT)
+	if list == nil {
+		return nil, ErrAccessList
+	}
+	return list, nil
+}
+
+func NewSnapshotEntry() *SnapshotEntry {
+	return &SnapshotEntry{}
+}
+
+func NewSnapshotEntryFromRequest(req *api.SnapshotCreateRequest,
+	volumeId string) *SnapshotEntry {
+
+	godbc.Require(req != nil)
+	godbc.Require(volumeId != "")
+
+	entry := NewSnapshotEntry()
+	entry.Info.Id = utils.GenUUID()
+	entry.Info.VolumeId = volumeId
+	entry.Info.Description = req.Description
+	if req.Name == "" {
+		entry.Info.Name = "snap_" + entry.Info.Id
+	} else {
+		entry.Info.Name = req.Name
+	}
+
+	return entry
+}
+
+func NewSnapshotEntryFromId(tx *bolt.Tx, id string) (*SnapshotEntry, error) {
+	godbc.Require(tx != nil)
+
+	entry := NewSnapshotEntry()
+	err := EntryLoad(tx, entry, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (s *SnapshotEntry) BucketName() string {
+	return BOLTDB_BUCKET_SNAPSHOT
+}
+
+func (s *SnapshotEntry) Save(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(s.Info.Id) > 0)
+
+	return EntrySave(tx, s, s.Info.Id)
+}
+
+func (s *SnapshotEntry) Delete(tx *bolt.Tx) error {
+	return EntryDelete(tx, s, s.Info.Id)
+}
+
+func (s *SnapshotEntry) NewInfoResponse(tx *bolt.Tx) (*api.SnapshotInfoResponse, error) {
+	godbc.Require(tx != nil)
+
+	info := &api.SnapshotInfoResponse{}
+	info.SnapshotInfo = s.Info
+
+	return info, nil
+}
+
+func (s *SnapshotEntry) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(*s)
+
+	return buffer.Bytes(), err
+}
+
+func (s *SnapshotEntry) Unmarshal(buffer []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(buffer))
+	err := dec.Decode(s)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Snapshot names are unique in the whole trusted storage pool, not only
+// in the volume, so check all the volumes of the cluster
+func (s *SnapshotEntry) nameInUse(tx *bolt.Tx, clusterId string) (bool, error) {
+	cluster, err := NewClusterEntryFromId(tx, clusterId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, volumeId := range cluster.Info.Volumes {
+		volume, err := NewVolumeEntryFromId(tx, volumeId)
+		if err != nil {
+			return false, err
+		}
+		for _, snapshotId := range volume.Snapshots {
+			snapshot, err := NewSnapshotEntryFromId(tx, snapshotId)
+			if err != nil {
+				return false, err
+			}
+			if snapshot.Info.Name == s.Info.Name {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (s *SnapshotEntry) Create(db *bolt.DB, executor executors.Executor) error {
+
+	var volume *VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, s.Info.VolumeId)
+		if err != nil {
+			return err
+		}
+
+		inuse, err := s.nameInUse(tx, volume.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		if inuse {
+			return fmt.Errorf("Snapshot name %v already in use", s.Info.Name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, volume.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	sr := &executors.SnapshotRequest{
+		Volume:      volume.Info.Name,
+		Snapshot:    s.Info.Name,
+		Description: s.Info.Description,
+	}
+	_, err = executor.SnapshotCreate(host, sr)
+	if err != nil {
+		return err
+	}
+	s.Info.Created = time.Now().Unix()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		// Reload the volume, it may have changed while
+		// the snapshot was being taken
+		volume, err := NewVolumeEntryFromId(tx, s.Info.VolumeId)
+		if err != nil {
+			return err
+		}
+
+		volume.SnapshotAdd(s.Info.Id)
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		return s.Save(tx)
+	})
+	if err != nil {
+		logger.LogError("Unable to save snapshot %v, removing it from volume %v",
+			s.Info.Name, volume.Info.Name)
+		executor.SnapshotDelete(host, s.Info.Name)
+		return err
+	}
+
+	return nil
+}
+
+func (s *SnapshotEntry) Destroy(db *bolt.DB, executor executors.Executor) error {
+	logger.Info("Destroying snapshot %v", s.Info.Id)
+
+	var volume *VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, s.Info.VolumeId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, volume.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	// The snapshot may have already been removed from outside of Heketi,
+	// in which case there is nothing left to do but to update the db
+	snaplist, err := executor.SnapshotList(host, volume.Info.Name)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, name := range snaplist.SnapshotList {
+		if name == s.Info.Name {
+			found = true
+			break
+		}
+	}
+	if found {
+		err = executor.SnapshotDelete(host, s.Info.Name)
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.Warning("Snapshot %v not found in volume %v",
+			s.Info.Name, volume.Info.Name)
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return s.removeFromDb(tx)
+	})
+}
+
+// Restore replaces the contents of the volume with the contents of the
+// snapshot.  Gluster consumes the snapshot when it is restored.
+// The volume has to be running, it is stopped for the restore and
+// started again afterwards.
+func (s *SnapshotEntry) Restore(db *bolt.DB, executor executors.Executor) error {
+	logger.Info("Restoring volume %v from snapshot %v", s.Info.VolumeId, s.Info.Id)
+
+	var volume *VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, s.Info.VolumeId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = volume.checkRestorable()
+	if err != nil {
+		return err
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, volume.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	// Save the brick order so that the bricks can be matched
+	// to their new paths after the restore
+	before, err := executor.VolumeInfo(host, volume.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	err = executor.SnapshotRestore(host, volume.Info.Name, s.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	after, err := executor.VolumeInfo(host, volume.Info.Name)
+	if err != nil {
+		logger.LogError("Unable to get brick information of restored volume %v: %v",
+			volume.Info.Name, err)
+		after = nil
+	}
+
+	var brick_entries []*BrickEntry
+	if after != nil &&
+		len(before.Bricks.BrickList) == len(after.Bricks.BrickList) {
+		for index, brick := range before.Bricks.BrickList {
+			entry, err := volume.getBrickEntryfromBrickName(db, brick.Name)
+			if err != nil {
+				logger.Warning("Unable to find brick %v in volume %v",
+					brick.Name, volume.Info.Name)
+				continue
+			}
+
+			newname := after.Bricks.BrickList[index].Name
+			entry.Info.Path = newname[strings.LastIndex(newname, ":")+1:]
+
+			// Clones and imported bricks are already found from
+			// their path
+			if !entry.IsClone() && !entry.Imported {
+				entry.Restored = true
+			}
+			brick_entries = append(brick_entries, entry)
+		}
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, brick := range brick_entries {
+			err := brick.Save(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.removeFromDb(tx)
+	})
+}
+
+// Clone creates a new volume from the snapshot.  The bricks of the new
+// volume are thin snapshots living in the thin pools of the bricks of
+// the original volume, so the original volume cannot be deleted while
+// the clone exists.
+func (s *SnapshotEntry) Clone(db *bolt.DB,
+	executor executors.Executor,
+	name string) (*VolumeEntry, error) {
+
+	logger.Info("Cloning snapshot %v", s.Info.Id)
+
+	var origin *VolumeEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		origin, err = NewVolumeEntryFromId(tx, s.Info.VolumeId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req := &api.VolumeCreateRequest{
+		Size:                 origin.Info.Size,
+		Name:                 name,
+		Durability:           origin.Info.Durability,
+		Gid:                  origin.Info.Gid,
+		Uid:                  origin.Info.Uid,
+		Mode:                 origin.Info.Mode,
+		GlusterVolumeOptions: origin.GlusterVolumeOptions,
+		Snapshot:             origin.Info.Snapshot,
+	}
+	clone := NewVolumeEntryFromRequest(req)
+	clone.Info.Cluster = origin.Info.Cluster
+
+	// Check the cluster does not have a volume with the name
+	err = db.View(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, clone.Info.Cluster)
+		if err != nil {
+			return err
+		}
+
+		for _, volumeId := range cluster.Info.Volumes {
+			volume, err := NewVolumeEntryFromId(tx, volumeId)
+			if err != nil {
+				return err
+			}
+			if clone.Info.Name == volume.Info.Name {
+				return fmt.Errorf("Name %v already in use in cluster %v",
+					clone.Info.Name, clone.Info.Cluster)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, origin.Info.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gluster keeps the order of the bricks in the clone, use it
+	// to find the brick each cloned brick was created from
+	vinfo, err := executor.VolumeInfo(host, origin.Info.Name)
+	if err != nil {
+		return nil, err
+	}
+	var origin_bricks []*BrickEntry
+	for _, brick := range vinfo.Bricks.BrickList {
+		entry, err := origin.getBrickEntryfromBrickName(db, brick.Name)
+		if err != nil {
+			return nil, logger.LogError("Unable to find brick %v of volume %v: %v",
+				brick.Name, origin.Info.Name, err)
+		}
+		origin_bricks = append(origin_bricks, entry)
+	}
+
+	cr := &executors.SnapshotCloneRequest{
+		Snapshot: s.Info.Name,
+		Volume:   clone.Info.Name,
+	}
+	cinfo, err := executor.SnapshotClone(host, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	var brick_entries []*BrickEntry
+	err = func() error {
+		if len(cinfo.Bricks.BrickList) != len(origin_bricks) {
+			return fmt.Errorf("Clone %v has %v bricks, expected %v",
+				clone.Info.Name, len(cinfo.Bricks.BrickList), len(origin_bricks))
+		}
+		for index, brick := range cinfo.Bricks.BrickList {
+			path := brick.Name[strings.LastIndex(brick.Name, ":")+1:]
+			entry := NewBrickEntryFromClone(origin_bricks[index], path, clone.Info.Id)
+			brick_entries = append(brick_entries, entry)
+			clone.BrickAdd(entry.Info.Id)
+		}
+
+		err := clone.setupMountInfo(db)
+		if err != nil {
+			return err
+		}
+
+		return db.Update(func(tx *bolt.Tx) error {
+			for _, brick := range brick_entries {
+				err := brick.Save(tx)
+				if err != nil {
+					return err
+				}
+
+				// Add the brick to the device so that the device
+				// cannot be removed while the clone exists
+				device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+				if err != nil {
+					return err
+				}
+				device.BrickAdd(brick.Info.Id)
+				err = device.Save(tx)
+				if err != nil {
+					return err
+				}
+			}
+
+			err := clone.Save(tx)
+			if err != nil {
+				return err
+			}
+
+			cluster, err := NewClusterEntryFromId(tx, clone.Info.Cluster)
+			if err != nil {
+				return err
+			}
+			cluster.VolumeAdd(clone.Info.Id)
+			return cluster.Save(tx)
+		})
+	}()
+	if err != nil {
+		logger.LogError("Unable to register clone %v, removing it: %v",
+			clone.Info.Name, err)
+		if err := executor.VolumeDestroy(host, clone.Info.Name); err != nil {
+			logger.Err(err)
+		}
+		DestroyBricks(db, executor, brick_entries)
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+// A snapshot can only be restored on a running volume, as the volume
+// is started again after the restore, or on failure
+func (v *VolumeEntry) checkRestorable() error {
+	if v.Info.Trash != nil {
+		return ErrVolumeTrashed
+	}
+	if v.Info.Stopped {
+		return ErrVolumeStopped
+	}
+	return nil
+}
+
+func (s *SnapshotEntry) removeFromDb(tx *bolt.Tx) error {
+	volume, err := NewVolumeEntryFromId(tx, s.Info.VolumeId)
+	if err != nil {
+		return err
+	}
+
+	volume.SnapshotDelete(s.Info.Id)
+	err = volume.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	return s.Delete(tx)
+}
+
+func SnapshotEntryUpgrade(tx *bolt.Tx) error {
+	return nil
+}
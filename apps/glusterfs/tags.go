@@ -0,0 +1,134 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	TAG_KEY_MAX_LENGTH   = 128
+	TAG_VALUE_MAX_LENGTH = 512
+	TAGS_MAX             = 32
+)
+
+var (
+	// Keys cannot contain ':' which separates key and value in filters
+	tagKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_./-]+$`)
+)
+
+// Entries which can carry user defined key/value tags
+type TaggedEntry interface {
+	DbEntry
+	Save(tx *bolt.Tx) error
+	AllTags() map[string]string
+	SetTags(tags map[string]string)
+}
+
+func validateTags(tags map[string]string) error {
+	if len(tags) > TAGS_MAX {
+		return fmt.Errorf("Too many tags, at most %v are allowed", TAGS_MAX)
+	}
+	for key, value := range tags {
+		if len(key) > TAG_KEY_MAX_LENGTH || !tagKeyRegex.MatchString(key) {
+			return fmt.Errorf("Invalid tag key %v", key)
+		}
+		if len(value) > TAG_VALUE_MAX_LENGTH {
+			return fmt.Errorf("Value of tag %v is longer than %v characters",
+				key, TAG_VALUE_MAX_LENGTH)
+		}
+	}
+
+	return nil
+}
+
+// Returns a copy of the tags, never nil
+func copyTags(tags map[string]string) map[string]string {
+	c := make(map[string]string, len(tags))
+	for key, value := range tags {
+		c[key] = value
+	}
+
+	return c
+}
+
+// Changes the tags of the entry as requested. The caller
+// is responsible for saving the entry.
+func ApplyTags(entry TaggedEntry, req *api.TagsChangeRequest) error {
+	tags := copyTags(entry.AllTags())
+
+	switch req.Change {
+	case api.SetTags:
+		tags = copyTags(req.Tags)
+	case api.UpdateTags:
+		for key, value := range req.Tags {
+			tags[key] = value
+		}
+	case api.DeleteTags:
+		for key := range req.Tags {
+			delete(tags, key)
+		}
+	default:
+		return fmt.Errorf("Unknown tags change type: %v", req.Change)
+	}
+
+	if err := validateTags(tags); err != nil {
+		return err
+	}
+	entry.SetTags(tags)
+
+	return nil
+}
+
+// Filters are in "key" or "key:value" form. An entry matches
+// when it has every key and, if given, the same value.
+func tagsMatch(tags map[string]string, filters []string) bool {
+	for _, filter := range filters {
+		kv := strings.SplitN(filter, ":", 2)
+		value, ok := tags[kv[0]]
+		if !ok {
+			return false
+		}
+		if len(kv) == 2 && value != kv[1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Sets empty tags on the entries stored before tags existed
+func addTagsInEntries(tx *bolt.Tx,
+	ids []string,
+	load func(tx *bolt.Tx, id string) (TaggedEntry, error)) error {
+
+	for _, id := range ids {
+		entry, err := load(tx, id)
+		if err != nil {
+			return err
+		}
+		if entry.AllTags() != nil {
+			continue
+		}
+
+		entry.SetTags(map[string]string{})
+		err = entry.Save(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
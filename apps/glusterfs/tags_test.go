@@ -0,0 +1,210 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests.Assert(t, validateTags(nil) == nil)
+	tests.Assert(t, validateTags(map[string]string{
+		"owner":             "storage-team",
+		"example.com/class": "ssd",
+		"empty":             "",
+	}) == nil)
+
+	// Keys cannot contain the filter separator
+	tests.Assert(t, validateTags(map[string]string{"a:b": "c"}) != nil)
+	tests.Assert(t, validateTags(map[string]string{"": "c"}) != nil)
+	tests.Assert(t, validateTags(map[string]string{"a b": "c"}) != nil)
+	tests.Assert(t, validateTags(map[string]string{
+		strings.Repeat("k", TAG_KEY_MAX_LENGTH+1): "c"}) != nil)
+	tests.Assert(t, validateTags(map[string]string{
+		"k": strings.Repeat("v", TAG_VALUE_MAX_LENGTH+1)}) != nil)
+
+	many := make(map[string]string)
+	for i := 0; i <= TAGS_MAX; i++ {
+		many[strings.Repeat("k", i+1)] = "v"
+	}
+	tests.Assert(t, validateTags(many) != nil)
+}
+
+func TestApplyTags(t *testing.T) {
+	c := NewClusterEntryFromRequest()
+	tests.Assert(t, c.Info.Tags != nil)
+	tests.Assert(t, len(c.Info.Tags) == 0)
+
+	// Update adds tags
+	err := ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"owner": "alice", "cost": "42"},
+		Change: api.UpdateTags,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(c.Info.Tags) == 2)
+	tests.Assert(t, c.Info.Tags["owner"] == "alice")
+
+	// Update overwrites existing keys only
+	err = ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"owner": "bob"},
+		Change: api.UpdateTags,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(c.Info.Tags) == 2)
+	tests.Assert(t, c.Info.Tags["owner"] == "bob")
+	tests.Assert(t, c.Info.Tags["cost"] == "42")
+
+	// Delete ignores the values
+	err = ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"cost": "", "missing": ""},
+		Change: api.DeleteTags,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(c.Info.Tags) == 1)
+	tests.Assert(t, c.Info.Tags["owner"] == "bob")
+
+	// Set replaces all the tags
+	err = ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"class": "ssd"},
+		Change: api.SetTags,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(c.Info.Tags) == 1)
+	tests.Assert(t, c.Info.Tags["class"] == "ssd")
+
+	// Invalid tags leave the entry unchanged
+	err = ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"bad:key": "x"},
+		Change: api.UpdateTags,
+	})
+	tests.Assert(t, err != nil)
+	tests.Assert(t, len(c.Info.Tags) == 1)
+
+	err = ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"a": "b"},
+		Change: "replace",
+	})
+	tests.Assert(t, err != nil)
+	tests.Assert(t, len(c.Info.Tags) == 1)
+
+	// Entries stored before tags existed have none
+	c.Info.Tags = nil
+	err = ApplyTags(c, &api.TagsChangeRequest{
+		Tags:   map[string]string{"a": "b"},
+		Change: api.UpdateTags,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, c.Info.Tags["a"] == "b")
+}
+
+func TestTagsMatch(t *testing.T) {
+	tags := map[string]string{
+		"owner": "alice",
+		"class": "ssd",
+		"empty": "",
+	}
+
+	tests.Assert(t, tagsMatch(tags, []string{}))
+	tests.Assert(t, tagsMatch(nil, []string{}))
+	tests.Assert(t, tagsMatch(tags, []string{"owner"}))
+	tests.Assert(t, tagsMatch(tags, []string{"owner:alice"}))
+	tests.Assert(t, tagsMatch(tags, []string{"owner:alice", "class:ssd"}))
+	tests.Assert(t, tagsMatch(tags, []string{"empty:"}))
+	tests.Assert(t, !tagsMatch(tags, []string{"owner:bob"}))
+	tests.Assert(t, !tagsMatch(tags, []string{"owner:alice", "class:hdd"}))
+	tests.Assert(t, !tagsMatch(tags, []string{"cost"}))
+	tests.Assert(t, !tagsMatch(nil, []string{"owner"}))
+}
+
+func TestTagsEntryUpgrade(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		2,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 2)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	// Remove the tags as if the entries were stored by an older version
+	var ids map[string][]string
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		ids = map[string][]string{
+			BOLTDB_BUCKET_CLUSTER: EntryKeys(tx, BOLTDB_BUCKET_CLUSTER),
+			BOLTDB_BUCKET_NODE:    EntryKeys(tx, BOLTDB_BUCKET_NODE),
+			BOLTDB_BUCKET_DEVICE:  EntryKeys(tx, BOLTDB_BUCKET_DEVICE),
+			BOLTDB_BUCKET_VOLUME:  EntryKeys(tx, BOLTDB_BUCKET_VOLUME),
+		}
+		for bucket, list := range ids {
+			for _, id := range list {
+				entry, err := loadTaggedEntry(tx, bucket, id)
+				if err != nil {
+					return err
+				}
+				entry.SetTags(nil)
+				err = entry.Save(tx)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(ids[BOLTDB_BUCKET_NODE]) == 2)
+	tests.Assert(t, len(ids[BOLTDB_BUCKET_VOLUME]) == 1)
+
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return app.Upgrade(tx)
+	})
+	tests.Assert(t, err == nil, err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for bucket, list := range ids {
+			for _, id := range list {
+				entry, err := loadTaggedEntry(tx, bucket, id)
+				tests.Assert(t, err == nil, err)
+				tests.Assert(t, entry.AllTags() != nil, bucket, id)
+				tests.Assert(t, len(entry.AllTags()) == 0)
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func loadTaggedEntry(tx *bolt.Tx, bucket, id string) (TaggedEntry, error) {
+	switch bucket {
+	case BOLTDB_BUCKET_CLUSTER:
+		return NewClusterEntryFromId(tx, id)
+	case BOLTDB_BUCKET_NODE:
+		return NewNodeEntryFromId(tx, id)
+	case BOLTDB_BUCKET_DEVICE:
+		return NewDeviceEntryFromId(tx, id)
+	default:
+		return NewVolumeEntryFromId(tx, id)
+	}
+}
@@ -0,0 +1,256 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// Defaults of the thin pool monitor.  Thresholds are
+	// percentages of the data or metadata of a thin pool.
+	ThinPoolWarningPercent  = 80.0
+	ThinPoolCriticalPercent = 95.0
+	ThinPoolMonitorInterval = 300
+)
+
+// ThinPoolMonitor periodically samples how full the thin pools of the
+// bricks are.  Snapshots are thin volumes in the pool of the brick, so
+// a pool can fill up even though heketi sized it for the brick, and a
+// full pool corrupts the file system of the brick.  Volumes with a
+// critically full pool cannot take new snapshots or clones.
+type ThinPoolMonitor struct {
+	db       *bolt.DB
+	executor executors.Executor
+	interval time.Duration
+	warning  float64
+	critical float64
+
+	// Last sample of each brick
+	lock  sync.RWMutex
+	pools map[string]api.ThinPoolStatus
+
+	task backgroundTask
+}
+
+func NewThinPoolMonitor(db *bolt.DB,
+	executor executors.Executor,
+	conf *GlusterFSConfig) *ThinPoolMonitor {
+
+	m := &ThinPoolMonitor{
+		db:       db,
+		executor: executor,
+		interval: ThinPoolMonitorInterval * time.Second,
+		warning:  ThinPoolWarningPercent,
+		critical: ThinPoolCriticalPercent,
+		pools:    make(map[string]api.ThinPoolStatus),
+	}
+
+	if conf.ThinPoolMonitorInterval != 0 {
+		logger.Info("Adv: Thin pool monitor interval %v seconds", conf.ThinPoolMonitorInterval)
+		m.interval = time.Duration(conf.ThinPoolMonitorInterval) * time.Second
+	}
+	if conf.ThinPoolWarningPercent != 0 {
+		logger.Info("Adv: Thin pool warning at %v%%", conf.ThinPoolWarningPercent)
+		m.warning = conf.ThinPoolWarningPercent
+	}
+	if conf.ThinPoolCriticalPercent != 0 {
+		logger.Info("Adv: Thin pool critical at %v%%", conf.ThinPoolCriticalPercent)
+		m.critical = conf.ThinPoolCriticalPercent
+	}
+	if m.warning > m.critical {
+		logger.LogError("Thin pool warning threshold %v%% is above "+
+			"the critical threshold %v%%", m.warning, m.critical)
+		return nil
+	}
+
+	return m
+}
+
+// Start samples the thin pools now and then at every interval, until
+// Stop is called.  Nothing is sampled when the interval is negative.
+func (m *ThinPoolMonitor) Start() {
+	if m.interval <= 0 {
+		logger.Info("Thin pool monitor disabled")
+		return
+	}
+
+	m.task.start(m.interval, m.check)
+}
+
+func (m *ThinPoolMonitor) Stop() {
+	m.task.stopTask()
+}
+
+func (m *ThinPoolMonitor) level(usage *executors.BrickUsage) api.ThinPoolLevel {
+	switch {
+	case usage.PoolDataPercent >= m.critical ||
+		usage.PoolMetadataPercent >= m.critical:
+		return api.ThinPoolLevelCritical
+	case usage.PoolDataPercent >= m.warning ||
+		usage.PoolMetadataPercent >= m.warning:
+		return api.ThinPoolLevelWarning
+	default:
+		return api.ThinPoolLevelOk
+	}
+}
+
+// Samples the thin pool of every brick
+func (m *ThinPoolMonitor) check() {
+	var bricks []*BrickEntry
+	err := m.db.View(func(tx *bolt.Tx) error {
+		ids, err := BrickList(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			brick, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			bricks = append(bricks, brick)
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.LogError("Unable to load the bricks to monitor: %v", err)
+		return
+	}
+
+	pools := make(map[string]api.ThinPoolStatus)
+	for _, brick := range bricks {
+		if m.task.stopping() {
+			return
+		}
+
+		last, found := m.pool(brick.Info.Id)
+		usage, err := brick.Usage(m.db, m.executor)
+		if err != nil {
+			// Keep what was last seen of bricks whose node cannot
+			// be reached, so that their volumes stay blocked
+			logger.Warning("Unable to sample the thin pool of brick %v: %v",
+				brick.Info.Id, err)
+			if found {
+				pools[brick.Info.Id] = last
+			}
+			continue
+		}
+
+		status := api.ThinPoolStatus{
+			BrickId:         brick.Info.Id,
+			VolumeId:        brick.Info.VolumeId,
+			NodeId:          brick.Info.NodeId,
+			DeviceId:        brick.Info.DeviceId,
+			DataPercent:     usage.PoolDataPercent,
+			MetadataPercent: usage.PoolMetadataPercent,
+			Level:           m.level(usage),
+		}
+		if !found {
+			last.Level = api.ThinPoolLevelOk
+		}
+		if status.Level != last.Level {
+			m.logLevelChange(&status)
+		}
+		pools[brick.Info.Id] = status
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.pools = pools
+}
+
+// Only changes are logged, so that a full pool is not
+// reported again at every sample
+func (m *ThinPoolMonitor) logLevelChange(status *api.ThinPoolStatus) {
+	switch status.Level {
+	case api.ThinPoolLevelCritical:
+		logger.Critical("Thin pool of brick %v of volume %v is critically full "+
+			"(data %.1f%%, metadata %.1f%%). New snapshots and clones of the "+
+			"volume are blocked", status.BrickId, status.VolumeId,
+			status.DataPercent, status.MetadataPercent)
+	case api.ThinPoolLevelWarning:
+		logger.Warning("Thin pool of brick %v of volume %v is filling up "+
+			"(data %.1f%%, metadata %.1f%%)", status.BrickId, status.VolumeId,
+			status.DataPercent, status.MetadataPercent)
+	default:
+		logger.Info("Thin pool of brick %v of volume %v is back under %v%%",
+			status.BrickId, status.VolumeId, m.warning)
+	}
+}
+
+func (m *ThinPoolMonitor) pool(brickId string) (api.ThinPoolStatus, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	status, found := m.pools[brickId]
+	return status, found
+}
+
+// VolumeCritical returns true when the thin pool of a brick of
+// the volume is over the critical threshold
+func (m *ThinPoolMonitor) VolumeCritical(volumeId string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	for _, status := range m.pools {
+		if status.VolumeId == volumeId &&
+			status.Level == api.ThinPoolLevelCritical {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Warnings returns the thin pools over the warning threshold,
+// ordered by volume and brick
+func (m *ThinPoolMonitor) Warnings() *api.ThinPoolWarningsResponse {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	pools := make(thinPoolsByVolume, 0)
+	for _, status := range m.pools {
+		if status.Level != api.ThinPoolLevelOk {
+			pools = append(pools, status)
+		}
+	}
+	sort.Sort(pools)
+
+	return &api.ThinPoolWarningsResponse{
+		WarningPercent:  m.warning,
+		CriticalPercent: m.critical,
+		Pools:           pools,
+	}
+}
+
+type thinPoolsByVolume []api.ThinPoolStatus
+
+func (p thinPoolsByVolume) Len() int {
+	return len(p)
+}
+
+func (p thinPoolsByVolume) Less(i, j int) bool {
+	if p[i].VolumeId != p[j].VolumeId {
+		return p[i].VolumeId < p[j].VolumeId
+	}
+	return p[i].BrickId < p[j].BrickId
+}
+
+func (p thinPoolsByVolume) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
@@ -0,0 +1,274 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestNewThinPoolMonitor(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	m := NewThinPoolMonitor(app.db, app.executor, &GlusterFSConfig{})
+	tests.Assert(t, m != nil)
+	tests.Assert(t, m.interval == ThinPoolMonitorInterval*time.Second)
+	tests.Assert(t, m.warning == ThinPoolWarningPercent)
+	tests.Assert(t, m.critical == ThinPoolCriticalPercent)
+
+	m = NewThinPoolMonitor(app.db, app.executor, &GlusterFSConfig{
+		ThinPoolMonitorInterval: 60,
+		ThinPoolWarningPercent:  50,
+		ThinPoolCriticalPercent: 75,
+	})
+	tests.Assert(t, m != nil)
+	tests.Assert(t, m.interval == time.Minute)
+	tests.Assert(t, m.warning == 50)
+	tests.Assert(t, m.critical == 75)
+
+	// Warning must come before critical
+	m = NewThinPoolMonitor(app.db, app.executor, &GlusterFSConfig{
+		ThinPoolWarningPercent: 99,
+	})
+	tests.Assert(t, m == nil)
+
+	// A negative interval disables the monitor
+	m = NewThinPoolMonitor(app.db, app.executor, &GlusterFSConfig{
+		ThinPoolMonitorInterval: -1,
+	})
+	tests.Assert(t, m != nil)
+	m.Start()
+	tests.Assert(t, m.task.stop == nil)
+	m.Stop()
+}
+
+func TestThinPoolMonitorCheck(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v1 := createSampleReplicaVolumeEntry(100, 3)
+	err = v1.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	v2 := createSampleReplicaVolumeEntry(100, 3)
+	err = v2.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Fill of the thin pools by brick id
+	data := make(map[string]float64)
+	metadata := make(map[string]float64)
+	var down error
+	app.xo.MockBrickUsage = func(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+		if down != nil {
+			return nil, down
+		}
+		return &executors.BrickUsage{
+			PoolDataPercent:     data[brick.Name],
+			PoolMetadataPercent: metadata[brick.Name],
+		}, nil
+	}
+
+	m := NewThinPoolMonitor(app.db, app.executor, &GlusterFSConfig{
+		ThinPoolWarningPercent:  70,
+		ThinPoolCriticalPercent: 90,
+	})
+	tests.Assert(t, m != nil)
+
+	// Nothing to report
+	m.check()
+	tests.Assert(t, len(m.pools) == len(v1.Bricks)+len(v2.Bricks))
+	warnings := m.Warnings()
+	tests.Assert(t, warnings.WarningPercent == 70)
+	tests.Assert(t, warnings.CriticalPercent == 90)
+	tests.Assert(t, len(warnings.Pools) == 0)
+	tests.Assert(t, !m.VolumeCritical(v1.Info.Id))
+
+	// Data of one pool of v1 and metadata of one pool of v2
+	data[v1.Bricks[0]] = 75.0
+	metadata[v2.Bricks[0]] = 95.5
+	m.check()
+	warnings = m.Warnings()
+	tests.Assert(t, len(warnings.Pools) == 2, warnings.Pools)
+	for _, pool := range warnings.Pools {
+		switch pool.BrickId {
+		case v1.Bricks[0]:
+			tests.Assert(t, pool.VolumeId == v1.Info.Id)
+			tests.Assert(t, pool.Level == api.ThinPoolLevelWarning)
+			tests.Assert(t, pool.DataPercent == 75.0)
+			tests.Assert(t, pool.NodeId != "" && pool.DeviceId != "")
+		case v2.Bricks[0]:
+			tests.Assert(t, pool.VolumeId == v2.Info.Id)
+			tests.Assert(t, pool.Level == api.ThinPoolLevelCritical)
+			tests.Assert(t, pool.MetadataPercent == 95.5)
+		default:
+			t.Fatalf("Unexpected pool %v", pool.BrickId)
+		}
+	}
+	tests.Assert(t, !m.VolumeCritical(v1.Info.Id))
+	tests.Assert(t, m.VolumeCritical(v2.Info.Id))
+
+	// Unreachable nodes keep the last sample
+	down = errors.New("node is down")
+	m.check()
+	tests.Assert(t, len(m.Warnings().Pools) == 2)
+	tests.Assert(t, m.VolumeCritical(v2.Info.Id))
+
+	// Deleted volumes are no longer reported
+	down = nil
+	err = v1.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil)
+	m.check()
+	tests.Assert(t, len(m.pools) == len(v2.Bricks))
+	warnings = m.Warnings()
+	tests.Assert(t, len(warnings.Pools) == 1)
+	tests.Assert(t, warnings.Pools[0].BrickId == v2.Bricks[0])
+
+	// Back to normal
+	metadata[v2.Bricks[0]] = 10.0
+	m.check()
+	tests.Assert(t, len(m.Warnings().Pools) == 0)
+	tests.Assert(t, !m.VolumeCritical(v2.Info.Id))
+}
+
+func TestThinPoolMonitorStartStop(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	app.xo.MockBrickUsage = func(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+		return &executors.BrickUsage{PoolDataPercent: 99.0}, nil
+	}
+
+	// The pools are sampled as soon as the monitor starts
+	m := NewThinPoolMonitor(app.db, app.executor, &GlusterFSConfig{})
+	m.Start()
+	for i := 0; i < 100 && !m.VolumeCritical(v.Info.Id); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.Stop()
+	tests.Assert(t, m.VolumeCritical(v.Info.Id))
+
+	// Stopping twice does nothing
+	m.Stop()
+}
+
+func TestThinPoolCriticalBlocksSnapshots(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	s := NewSnapshotEntryFromRequest(&api.SnapshotCreateRequest{Name: "mysnap"}, v.Info.Id)
+	err = s.Create(app.db, app.executor)
+	tests.Assert(t, err == nil)
+
+	percent := 99.0
+	app.xo.MockBrickUsage = func(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+		return &executors.BrickUsage{PoolDataPercent: percent}, nil
+	}
+	app.thinPoolMonitor.check()
+
+	// Warnings are listed
+	r, err := http.Get(ts.URL + "/thinpools/warnings")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var warnings api.ThinPoolWarningsResponse
+	err = utils.GetJsonFromResponse(r, &warnings)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(warnings.Pools) == len(v.Bricks))
+	tests.Assert(t, warnings.Pools[0].Level == api.ThinPoolLevelCritical)
+
+	// Snapshots and clones are blocked
+	url := ts.URL + "/volumes/" + v.Info.Id + "/snapshots"
+	r, err = http.Post(url, "application/json",
+		bytes.NewBuffer([]byte(`{"name" : "another"}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	r, err = http.Post(url+"/"+s.Info.Id+"/clone", "application/json",
+		bytes.NewBuffer([]byte(`{"name" : "myclone"}`)))
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Allowed again once the pools are emptied
+	percent = 20.0
+	app.thinPoolMonitor.check()
+
+	r, err = http.Get(ts.URL + "/thinpools/warnings")
+	tests.Assert(t, err == nil)
+	warnings = api.ThinPoolWarningsResponse{}
+	err = utils.GetJsonFromResponse(r, &warnings)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(warnings.Pools) == 0)
+
+	asyncTestVolume(t, asyncTestRequest(t, "POST", url, `{"name" : "another"}`, nil))
+}
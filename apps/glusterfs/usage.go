@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// The space in use on the bricks is only known by the nodes, so
+// it is collected when requested instead of being saved in the db
+
+// Sets the usage of each brick in the list, as reported by its node,
+// and returns their sum along with the provisioned size in KB of the
+// bricks that reported it.  Bricks whose node cannot be reached are
+// left out so that one node does not hide the usage of the others.
+func setBricksUsage(db *bolt.DB,
+	executor executors.Executor,
+	bricks []api.BrickInfo) (*api.StorageUsage, uint64, error) {
+
+	entries := make([]*BrickEntry, len(bricks))
+	err := db.View(func(tx *bolt.Tx) error {
+		for i, brick := range bricks {
+			entry, err := NewBrickEntryFromId(tx, brick.Id)
+			if err != nil {
+				return err
+			}
+			entries[i] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total *api.StorageUsage
+	var provisioned uint64
+	for i, entry := range entries {
+		usage, err := entry.Usage(db, executor)
+		if err != nil {
+			logger.LogError("Unable to get the usage of brick %v: %v",
+				entry.Info.Id, err)
+			continue
+		}
+
+		bricks[i].Usage = &api.StorageUsage{
+			Size:                usage.Size,
+			Used:                usage.Used,
+			PoolDataPercent:     usage.PoolDataPercent,
+			PoolMetadataPercent: usage.PoolMetadataPercent,
+		}
+
+		if total == nil {
+			total = &api.StorageUsage{}
+		}
+		total.Size += usage.Size
+		total.Used += usage.Used
+		if usage.PoolDataPercent > total.PoolDataPercent {
+			total.PoolDataPercent = usage.PoolDataPercent
+		}
+		if usage.PoolMetadataPercent > total.PoolMetadataPercent {
+			total.PoolMetadataPercent = usage.PoolMetadataPercent
+		}
+		provisioned += entry.Info.Size
+	}
+
+	return total, provisioned, nil
+}
+
+// Sets the usage of the volume and of its bricks
+func setVolumeUsage(db *bolt.DB,
+	executor executors.Executor,
+	info *api.VolumeInfoResponse) error {
+
+	usage, provisioned, err := setBricksUsage(db, executor, info.Bricks)
+	if err != nil || usage == nil || provisioned == 0 {
+		return err
+	}
+
+	// The bricks hold the data of the volume along with its replicas,
+	// arbiter metadata or parity.  Scaling by the share of the brick
+	// space that the volume provides accounts for all of them.
+	ratio := float64(uint64(info.Size)*GB) / float64(provisioned)
+	usage.Size = uint64(float64(usage.Size) * ratio)
+	usage.Used = uint64(float64(usage.Used) * ratio)
+	info.Usage = usage
+
+	return nil
+}
+
+// Sets the usage of the device and of its bricks
+func setDeviceUsage(db *bolt.DB,
+	executor executors.Executor,
+	info *api.DeviceInfoResponse) error {
+
+	usage, _, err := setBricksUsage(db, executor, info.Bricks)
+	if err != nil {
+		return err
+	}
+	info.Usage = usage
+
+	return nil
+}
@@ -0,0 +1,150 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeAndDeviceUsage(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// A quarter of every brick is used
+	failedHost := ""
+	app.xo.MockBrickUsage = func(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+		if host == failedHost {
+			return nil, errors.New("node is down")
+		}
+		return &executors.BrickUsage{
+			Size:                brick.Size * 1024,
+			Used:                brick.Size * 1024 / 4,
+			PoolDataPercent:     25.0,
+			PoolMetadataPercent: 1.5,
+		}, nil
+	}
+
+	near := func(value, expected uint64) bool {
+		return value+1024 > expected && value < expected+1024
+	}
+
+	// Usage is only collected when asked for
+	r, err := http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var info api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.Usage == nil)
+	for _, brick := range info.Bricks {
+		tests.Assert(t, brick.Usage == nil)
+	}
+
+	// The replicas are not counted in the usage of the volume
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id + "?usage=true")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	info = api.VolumeInfoResponse{}
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.Usage != nil)
+	tests.Assert(t, near(info.Usage.Size, 100*GB*1024), info.Usage.Size)
+	tests.Assert(t, near(info.Usage.Used, 25*GB*1024), info.Usage.Used)
+	tests.Assert(t, info.Usage.PoolDataPercent == 25.0)
+	tests.Assert(t, info.Usage.PoolMetadataPercent == 1.5)
+	for _, brick := range info.Bricks {
+		tests.Assert(t, brick.Usage != nil)
+		tests.Assert(t, brick.Usage.Used == brick.Size*1024/4)
+	}
+
+	// Bricks on a node which is down are left out
+	var node *NodeEntry
+	app.db.View(func(tx *bolt.Tx) error {
+		node, err = NewNodeEntryFromId(tx, info.Bricks[0].NodeId)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	failedHost = node.ManageHostName()
+
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id + "?usage=true")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	info = api.VolumeInfoResponse{}
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, near(info.Usage.Used, 25*GB*1024), info.Usage.Used)
+	missing := 0
+	for _, brick := range info.Bricks {
+		if brick.Usage == nil {
+			tests.Assert(t, brick.NodeId == node.Info.Id)
+			missing++
+		}
+	}
+	tests.Assert(t, missing > 0)
+
+	// Devices report the sum of their bricks
+	failedHost = ""
+	deviceId := info.Bricks[0].DeviceId
+	r, err = http.Get(ts.URL + "/devices/" + deviceId + "?usage=true")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var device api.DeviceInfoResponse
+	err = utils.GetJsonFromResponse(r, &device)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, device.Usage != nil)
+	var used uint64
+	for _, brick := range device.Bricks {
+		tests.Assert(t, brick.Usage != nil)
+		used += brick.Usage.Used
+	}
+	tests.Assert(t, used > 0)
+	tests.Assert(t, device.Usage.Used == used)
+	tests.Assert(t, device.Usage.PoolDataPercent == 25.0)
+
+	r, err = http.Get(ts.URL + "/devices/" + deviceId)
+	tests.Assert(t, err == nil)
+	device = api.DeviceInfoResponse{}
+	err = utils.GetJsonFromResponse(r, &device)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, device.Usage == nil)
+}
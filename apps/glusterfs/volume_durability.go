@@ -14,10 +14,17 @@ import (
 )
 
 type VolumeDurability interface {
-	BrickSizeGenerator(size uint64) func() (int, uint64, error)
-	MinVolumeSize() uint64
+	BrickSizeGenerator(size uint64, limits BrickLimits) func() (int, uint64, error)
+	MinVolumeSize(limits BrickLimits) uint64
 	BricksInSet() int
 	SetDurability()
 	SetExecutorVolumeRequest(v *executors.VolumeRequest)
 	QuorumBrickCount() int
+
+	// Usable space provided by one brick set made of bricks of brick_size
+	SetSize(brick_size uint64) uint64
+
+	// Size of the brick at the given position in a set made of
+	// bricks of brick_size
+	BrickSizeInSet(position int, brick_size uint64, limits BrickLimits) uint64
 }
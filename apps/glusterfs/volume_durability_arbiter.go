@@ -0,0 +1,63 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// Gluster always places the arbiter last in the set
+	ARBITER_BRICK_POSITION = 2
+)
+
+// Replica 3 volume where the third brick of each set only
+// keeps the metadata of the files
+type VolumeArbiterDurability struct {
+	VolumeReplicaDurability
+	api.ArbiterDurability
+}
+
+func NewVolumeArbiterDurability(a *api.ArbiterDurability) *VolumeArbiterDurability {
+	v := &VolumeArbiterDurability{}
+	v.Replica = 3
+	v.Ratio = a.Ratio
+
+	return v
+}
+
+func (a *VolumeArbiterDurability) SetDurability() {
+	a.Replica = 3
+	if a.Ratio == 0 {
+		a.Ratio = ArbiterBrickRatio
+	}
+}
+
+func (a *VolumeArbiterDurability) BrickSizeInSet(position int, brick_size uint64, limits BrickLimits) uint64 {
+	if position != ARBITER_BRICK_POSITION {
+		return brick_size
+	}
+
+	size := uint64(float64(brick_size) * float64(a.Ratio))
+	if size < limits.MinSize {
+		size = limits.MinSize
+	}
+	if size > brick_size {
+		size = brick_size
+	}
+
+	return size
+}
+
+func (a *VolumeArbiterDurability) SetExecutorVolumeRequest(v *executors.VolumeRequest) {
+	v.Type = executors.DurabilityArbiter
+	v.Replica = a.Replica
+}
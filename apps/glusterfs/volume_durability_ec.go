@@ -35,7 +35,7 @@ func (d *VolumeDisperseDurability) SetDurability() {
 	}
 }
 
-func (d *VolumeDisperseDurability) BrickSizeGenerator(size uint64) func() (int, uint64, error) {
+func (d *VolumeDisperseDurability) BrickSizeGenerator(size uint64, limits BrickLimits) func() (int, uint64, error) {
 
 	sets := 1
 	return func() (int, uint64, error) {
@@ -52,9 +52,9 @@ func (d *VolumeDisperseDurability) BrickSizeGenerator(size uint64) func() (int,
 			// number of data drives in the disperse request
 			brick_size /= uint64(d.Data)
 
-			if brick_size < BrickMinSize {
+			if brick_size < limits.MinSize {
 				return 0, 0, ErrMinimumBrickSize
-			} else if brick_size <= BrickMaxSize {
+			} else if brick_size <= limits.MaxSize {
 				break
 			}
 		}
@@ -63,8 +63,8 @@ func (d *VolumeDisperseDurability) BrickSizeGenerator(size uint64) func() (int,
 	}
 }
 
-func (d *VolumeDisperseDurability) MinVolumeSize() uint64 {
-	return BrickMinSize * uint64(d.Data)
+func (d *VolumeDisperseDurability) MinVolumeSize(limits BrickLimits) uint64 {
+	return limits.MinSize * uint64(d.Data)
 }
 
 func (d *VolumeDisperseDurability) BricksInSet() int {
@@ -75,6 +75,14 @@ func (d *VolumeDisperseDurability) QuorumBrickCount() int {
 	return d.Data
 }
 
+func (d *VolumeDisperseDurability) SetSize(brick_size uint64) uint64 {
+	return brick_size * uint64(d.Data)
+}
+
+func (d *VolumeDisperseDurability) BrickSizeInSet(position int, brick_size uint64, limits BrickLimits) uint64 {
+	return brick_size
+}
+
 func (d *VolumeDisperseDurability) SetExecutorVolumeRequest(v *executors.VolumeRequest) {
 	v.Type = executors.DurabilityDispersion
 	v.Data = d.Data
@@ -36,6 +36,14 @@ func (n *NoneDurability) QuorumBrickCount() int {
 	return n.BricksInSet()
 }
 
+func (n *NoneDurability) SetSize(brick_size uint64) uint64 {
+	return brick_size
+}
+
+func (n *NoneDurability) BrickSizeInSet(position int, brick_size uint64, limits BrickLimits) uint64 {
+	return brick_size
+}
+
 func (n *NoneDurability) SetExecutorVolumeRequest(v *executors.VolumeRequest) {
 	v.Type = executors.DurabilityNone
 	v.Replica = n.Replica
@@ -31,7 +31,7 @@ func (r *VolumeReplicaDurability) SetDurability() {
 	}
 }
 
-func (r *VolumeReplicaDurability) BrickSizeGenerator(size uint64) func() (int, uint64, error) {
+func (r *VolumeReplicaDurability) BrickSizeGenerator(size uint64, limits BrickLimits) func() (int, uint64, error) {
 
 	sets := 1
 	return func() (int, uint64, error) {
@@ -44,9 +44,9 @@ func (r *VolumeReplicaDurability) BrickSizeGenerator(size uint64) func() (int, u
 			sets *= 2
 			brick_size = size / uint64(num_sets)
 
-			if brick_size < BrickMinSize {
+			if brick_size < limits.MinSize {
 				return 0, 0, ErrMinimumBrickSize
-			} else if brick_size <= BrickMaxSize {
+			} else if brick_size <= limits.MaxSize {
 				break
 			}
 		}
@@ -55,8 +55,8 @@ func (r *VolumeReplicaDurability) BrickSizeGenerator(size uint64) func() (int, u
 	}
 }
 
-func (r *VolumeReplicaDurability) MinVolumeSize() uint64 {
-	return BrickMinSize
+func (r *VolumeReplicaDurability) MinVolumeSize(limits BrickLimits) uint64 {
+	return limits.MinSize
 }
 
 func (r *VolumeReplicaDurability) BricksInSet() int {
@@ -67,6 +67,14 @@ func (r *VolumeReplicaDurability) QuorumBrickCount() int {
 	return r.BricksInSet()/2 + 1
 }
 
+func (r *VolumeReplicaDurability) SetSize(brick_size uint64) uint64 {
+	return brick_size
+}
+
+func (r *VolumeReplicaDurability) BrickSizeInSet(position int, brick_size uint64, limits BrickLimits) uint64 {
+	return brick_size
+}
+
 func (r *VolumeReplicaDurability) SetExecutorVolumeRequest(v *executors.VolumeRequest) {
 	v.Type = executors.DurabilityReplica
 	v.Replica = r.Replica
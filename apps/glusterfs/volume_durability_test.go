@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/tests"
 )
 
@@ -42,6 +43,17 @@ func TestReplicaDurabilityDefaults(t *testing.T) {
 	tests.Assert(t, r.Replica == DEFAULT_REPLICA)
 }
 
+func TestArbiterDurabilityDefaults(t *testing.T) {
+	r := NewVolumeArbiterDurability(&api.ArbiterDurability{})
+	tests.Assert(t, r.Ratio == 0)
+
+	r.SetDurability()
+	tests.Assert(t, r.Replica == 3)
+	tests.Assert(t, r.Ratio == ArbiterBrickRatio)
+	tests.Assert(t, r.BricksInSet() == 3)
+	tests.Assert(t, r.QuorumBrickCount() == 2)
+}
+
 func TestNoneDurabilitySetExecutorRequest(t *testing.T) {
 	r := &NoneDurability{}
 	r.SetDurability()
@@ -73,11 +85,21 @@ func TestReplicaDurabilitySetExecutorRequest(t *testing.T) {
 	tests.Assert(t, v.Type == executors.DurabilityReplica)
 }
 
+func TestArbiterDurabilitySetExecutorRequest(t *testing.T) {
+	r := &VolumeArbiterDurability{}
+	r.SetDurability()
+
+	v := &executors.VolumeRequest{}
+	r.SetExecutorVolumeRequest(v)
+	tests.Assert(t, v.Replica == 3)
+	tests.Assert(t, v.Type == executors.DurabilityArbiter)
+}
+
 func TestNoneDurability(t *testing.T) {
 	r := &NoneDurability{}
 	r.SetDurability()
 
-	gen := r.BrickSizeGenerator(100 * GB)
+	gen := r.BrickSizeGenerator(100*GB, DefaultBrickLimits())
 
 	// Gen 1
 	sets, brick_size, err := gen()
@@ -150,7 +172,7 @@ func TestDisperseDurability(t *testing.T) {
 	r.Data = 8
 	r.Redundancy = 3
 
-	gen := r.BrickSizeGenerator(200 * GB)
+	gen := r.BrickSizeGenerator(200*GB, DefaultBrickLimits())
 
 	// Gen 1
 	sets, brick_size, err := gen()
@@ -204,7 +226,7 @@ func TestDisperseDurabilityLargeBrickGenerator(t *testing.T) {
 	r.Data = 8
 	r.Redundancy = 3
 
-	gen := r.BrickSizeGenerator(800 * TB)
+	gen := r.BrickSizeGenerator(800*TB, DefaultBrickLimits())
 
 	// Gen 1
 	sets, brick_size, err := gen()
@@ -219,7 +241,7 @@ func TestReplicaDurabilityGenerator(t *testing.T) {
 	r := &VolumeReplicaDurability{}
 	r.Replica = 2
 
-	gen := r.BrickSizeGenerator(100 * GB)
+	gen := r.BrickSizeGenerator(100*GB, DefaultBrickLimits())
 
 	// Gen 1
 	sets, brick_size, err := gen()
@@ -290,7 +312,7 @@ func TestReplicaDurabilityLargeBrickGenerator(t *testing.T) {
 	r := &VolumeReplicaDurability{}
 	r.Replica = 2
 
-	gen := r.BrickSizeGenerator(100 * TB)
+	gen := r.BrickSizeGenerator(100*TB, DefaultBrickLimits())
 
 	// Gen 1
 	sets, brick_size, err := gen()
@@ -305,7 +327,7 @@ func TestReplicaDurabilityQuorumBrickCount3(t *testing.T) {
 	r := &VolumeReplicaDurability{}
 	r.Replica = 3
 
-	gen := r.BrickSizeGenerator(100 * TB)
+	gen := r.BrickSizeGenerator(100*TB, DefaultBrickLimits())
 
 	// Gen 1
 	sets, brick_size, err := gen()
@@ -320,7 +342,7 @@ func TestNoneDurabilityMinVolumeSize(t *testing.T) {
 	r := &NoneDurability{}
 	r.SetDurability()
 
-	minvolsize := r.MinVolumeSize()
+	minvolsize := r.MinVolumeSize(DefaultBrickLimits())
 
 	tests.Assert(t, minvolsize == BrickMinSize)
 }
@@ -329,7 +351,7 @@ func TestReplicaDurabilityMinVolumeSize(t *testing.T) {
 	r := &VolumeReplicaDurability{}
 	r.Replica = 3
 
-	minvolsize := r.MinVolumeSize()
+	minvolsize := r.MinVolumeSize(DefaultBrickLimits())
 
 	tests.Assert(t, minvolsize == BrickMinSize)
 }
@@ -339,7 +361,54 @@ func TestDisperseDurabilityMinVolumeSize(t *testing.T) {
 	r.Data = 8
 	r.Redundancy = 3
 
-	minvolsize := r.MinVolumeSize()
+	minvolsize := r.MinVolumeSize(DefaultBrickLimits())
 
 	tests.Assert(t, minvolsize == BrickMinSize*8)
 }
+
+func TestNoneDurabilitySetSize(t *testing.T) {
+	r := &NoneDurability{}
+	r.SetDurability()
+
+	tests.Assert(t, r.SetSize(10*GB) == 10*GB)
+}
+
+func TestReplicaDurabilitySetSize(t *testing.T) {
+	r := &VolumeReplicaDurability{}
+	r.Replica = 3
+
+	tests.Assert(t, r.SetSize(10*GB) == 10*GB)
+}
+
+func TestDisperseDurabilitySetSize(t *testing.T) {
+	r := &VolumeDisperseDurability{}
+	r.Data = 8
+	r.Redundancy = 3
+
+	tests.Assert(t, r.SetSize(10*GB) == 80*GB)
+}
+
+func TestArbiterDurabilityBrickSizeInSet(t *testing.T) {
+	r := NewVolumeArbiterDurability(&api.ArbiterDurability{Ratio: 0.1})
+	r.SetDurability()
+
+	// Data bricks
+	tests.Assert(t, r.BrickSizeInSet(0, 100*GB, DefaultBrickLimits()) == 100*GB)
+	tests.Assert(t, r.BrickSizeInSet(1, 100*GB, DefaultBrickLimits()) == 100*GB)
+
+	// Arbiter brick
+	tests.Assert(t, r.BrickSizeInSet(2, 100*GB, DefaultBrickLimits()) == 10*GB)
+
+	// Never smaller than the minimum brick size
+	tests.Assert(t, r.BrickSizeInSet(2, 2*GB, DefaultBrickLimits()) == BrickMinSize)
+	tests.Assert(t, r.SetSize(100*GB) == 100*GB)
+}
+
+func TestReplicaDurabilityBrickSizeInSet(t *testing.T) {
+	r := &VolumeReplicaDurability{}
+	r.Replica = 3
+
+	for i := 0; i < r.BricksInSet(); i++ {
+		tests.Assert(t, r.BrickSizeInSet(i, 10*GB, DefaultBrickLimits()) == 10*GB)
+	}
+}
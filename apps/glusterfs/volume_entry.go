@@ -14,6 +14,7 @@ import (
 	"encoding/gob"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/boltdb/bolt"
 	"github.com/heketi/heketi/executors"
@@ -42,6 +43,25 @@ type VolumeEntry struct {
 	Bricks               sort.StringSlice
 	Durability           VolumeDurability
 	GlusterVolumeOptions []string
+	Snapshots            sort.StringSlice
+
+	// Id of the NFS-Ganesha export, zero when not exported
+	NfsExportId int
+
+	// Nodes sharing the volume over SMB
+	SmbNodes []string
+
+	// Set when the volume was running when it was moved to the
+	// trash, so that it is started again when restored
+	StartOnRestore bool
+
+	// Set when the reaper or a delete request claimed the volume to
+	// destroy it, so that it can no longer be restored nor deleted again
+	Destroying bool
+
+	// Bricks removed from the volume by a shrink whose logical
+	// volumes could not be destroyed yet
+	RemovedBricks sort.StringSlice
 }
 
 func VolumeList(tx *bolt.Tx) ([]string, error) {
@@ -56,10 +76,12 @@ func VolumeList(tx *bolt.Tx) ([]string, error) {
 func NewVolumeEntry() *VolumeEntry {
 	entry := &VolumeEntry{}
 	entry.Bricks = make(sort.StringSlice, 0)
+	entry.Snapshots = make(sort.StringSlice, 0)
 
 	gob.Register(&NoneDurability{})
 	gob.Register(&VolumeReplicaDurability{})
 	gob.Register(&VolumeDisperseDurability{})
+	gob.Register(&VolumeArbiterDurability{})
 
 	return entry
 }
@@ -69,6 +91,8 @@ func NewVolumeEntryFromRequest(req *api.VolumeCreateRequest) *VolumeEntry {
 
 	vol := NewVolumeEntry()
 	vol.Info.Gid = req.Gid
+	vol.Info.Uid = req.Uid
+	vol.Info.Mode = req.Mode
 	vol.Info.Id = utils.GenUUID()
 	vol.Info.Durability = req.Durability
 	vol.Info.Snapshot = req.Snapshot
@@ -91,6 +115,12 @@ func NewVolumeEntryFromRequest(req *api.VolumeCreateRequest) *VolumeEntry {
 			vol.Info.Durability.Disperse.Redundancy)
 		vol.Durability = NewVolumeDisperseDurability(&vol.Info.Durability.Disperse)
 
+	case durability == api.DurabilityArbiter:
+		logger.Debug("[%v] Arbiter ratio %v",
+			vol.Info.Id,
+			vol.Info.Durability.Arbiter.Ratio)
+		vol.Durability = NewVolumeArbiterDurability(&vol.Info.Durability.Arbiter)
+
 	case durability == api.DurabilityDistributeOnly || durability == "":
 		logger.Debug("[%v] Distributed", vol.Info.Id)
 		vol.Durability = NewNoneDurability()
@@ -119,9 +149,27 @@ func NewVolumeEntryFromRequest(req *api.VolumeCreateRequest) *VolumeEntry {
 	// If it is zero, then no volume options are set.
 	vol.GlusterVolumeOptions = req.GlusterVolumeOptions
 
+	// Block hosting volumes start empty and need the
+	// options recommended by gluster-block
+	vol.Info.Block = req.Block
+	if vol.Info.Block {
+		vol.Info.BlockInfo.FreeSize = vol.Info.Size
+		vol.GlusterVolumeOptions = append([]string{"group gluster-block"},
+			req.GlusterVolumeOptions...)
+	}
+
 	// If it is zero, then it will be assigned during volume creation
 	vol.Info.Clusters = req.Clusters
 
+	vol.Info.Tags = copyTags(req.Tags)
+
+	vol.Info.NfsExport = req.NfsExport
+	vol.Info.Placement = req.Placement
+	vol.Info.BrickLimits = req.BrickLimits
+
+	// If it is empty, then bricks may be placed on any device
+	vol.Info.DeviceClass = req.DeviceClass
+
 	return vol
 }
 
@@ -164,6 +212,20 @@ func (v *VolumeEntry) NewInfoResponse(tx *bolt.Tx) (*api.VolumeInfoResponse, err
 	info.Durability = v.Info.Durability
 	info.Name = v.Info.Name
 	info.GlusterVolumeOptions = v.GlusterVolumeOptions
+	info.Block = v.Info.Block
+	info.BlockInfo = v.Info.BlockInfo
+	info.Tags = v.Info.Tags
+	info.DeviceClass = v.Info.DeviceClass
+	info.Stopped = v.Info.Stopped
+	info.Quota = v.Info.Quota
+	info.Bitrot = v.Info.Bitrot
+	info.Trash = v.Info.Trash
+	info.NfsExport = v.Info.NfsExport
+	info.Placement = v.Info.Placement
+	info.BrickLimits = v.Info.BrickLimits
+	info.Gid = v.Info.Gid
+	info.Uid = v.Info.Uid
+	info.Mode = v.Info.Mode
 
 	for _, brickid := range v.BricksIds() {
 		brick, err := NewBrickEntryFromId(tx, brickid)
@@ -200,6 +262,9 @@ func (v *VolumeEntry) Unmarshal(buffer []byte) error {
 	if v.Bricks == nil {
 		v.Bricks = make(sort.StringSlice, 0)
 	}
+	if v.Snapshots == nil {
+		v.Snapshots = make(sort.StringSlice, 0)
+	}
 
 	return nil
 }
@@ -215,6 +280,28 @@ func (v *VolumeEntry) BrickDelete(id string) {
 	v.Bricks = utils.SortedStringsDelete(v.Bricks, id)
 }
 
+func (v *VolumeEntry) SnapshotAdd(id string) {
+	godbc.Require(!utils.SortedStringHas(v.Snapshots, id))
+
+	v.Snapshots = append(v.Snapshots, id)
+	v.Snapshots.Sort()
+}
+
+func (v *VolumeEntry) SnapshotDelete(id string) {
+	v.Snapshots = utils.SortedStringsDelete(v.Snapshots, id)
+}
+
+func (v *VolumeEntry) BlockVolumeAdd(id string) {
+	godbc.Require(!utils.SortedStringHas(v.Info.BlockInfo.BlockVolumes, id))
+
+	v.Info.BlockInfo.BlockVolumes = append(v.Info.BlockInfo.BlockVolumes, id)
+	sort.Strings(v.Info.BlockInfo.BlockVolumes)
+}
+
+func (v *VolumeEntry) BlockVolumeDelete(id string) {
+	v.Info.BlockInfo.BlockVolumes = utils.SortedStringsDelete(v.Info.BlockInfo.BlockVolumes, id)
+}
+
 func (v *VolumeEntry) Create(db *bolt.DB,
 	executor executors.Executor,
 	allocator Allocator) (e error) {
@@ -230,94 +317,15 @@ func (v *VolumeEntry) Create(db *bolt.DB,
 		}
 	}()
 
-	// Get list of clusters
-	var possibleClusters []string
-	if len(v.Info.Clusters) == 0 {
-		err := db.View(func(tx *bolt.Tx) error {
-			var err error
-			possibleClusters, err = ClusterList(tx)
-			return err
-		})
-		if err != nil {
-			return err
-		}
-	} else {
-		possibleClusters = v.Info.Clusters
-	}
-
-	// Check we have clusters
-	if len(possibleClusters) == 0 {
-		logger.LogError("Volume being ask to be created, but there are no clusters configured")
-		return ErrNoSpace
-	}
-	logger.Debug("Using the following clusters: %+v", possibleClusters)
-
-	// Check for volume name conflict on any cluster
-	var clusters []string
-	for _, cluster := range possibleClusters {
-		var err error
-
-		// Check this cluster does not have a volume with the name
-		err = db.View(func(tx *bolt.Tx) error {
-			ce, err := NewClusterEntryFromId(tx, cluster)
-			if err != nil {
-				return err
-			}
-
-			for _, volumeId := range ce.Info.Volumes {
-				volume, err := NewVolumeEntryFromId(tx, volumeId)
-				if err != nil {
-					return err
-				}
-				if v.Info.Name == volume.Info.Name {
-					return fmt.Errorf("Name %v already in use in cluster %v",
-						v.Info.Name, cluster)
-				}
-			}
-
-			return nil
-
-		})
-		if err != nil {
-			logger.Warning("%v", err.Error())
-		} else {
-			clusters = append(clusters, cluster)
-		}
-	}
-	if len(clusters) == 0 {
-		return fmt.Errorf("Name %v is already in use in all available clusters", v.Info.Name)
+	clusters, err := v.availableClusters(db)
+	if err != nil {
+		return err
 	}
 
 	// For each cluster look for storage space for this volume
-	var brick_entries []*BrickEntry
-	var err error
-	for _, cluster := range clusters {
-
-		// Check this cluster for space
-		brick_entries, err = v.allocBricksInCluster(db, allocator, cluster, v.Info.Size)
-
-		if err == nil {
-			v.Info.Cluster = cluster
-			logger.Debug("Volume to be created on cluster %v", cluster)
-			break
-		} else if err == ErrNoSpace ||
-			err == ErrMaxBricks ||
-			err == ErrMinimumBrickSize {
-			logger.Debug("Cluster %v can not accommodate volume "+
-				"(%v), trying next cluster", cluster, err)
-			continue
-		} else {
-			// A genuine error occurred - bail out
-			return logger.LogError("Error calling v.allocBricksInCluster: %v", err)
-		}
-	}
-
-	if err != nil || brick_entries == nil {
-		// Map all 'valid' errors to NoSpace here:
-		// Only the last such error could get propagated down,
-		// so it does not make sense to hand the granularity on.
-		// But for other callers (Expand), we keep it.
-		return ErrNoSpace
+	brick_entries, err := v.allocBricksInClusters(db, allocator, clusters)
+	if err != nil {
+		return err
 	}
 
 	// Make sure to clean up bricks on error
@@ -358,6 +366,14 @@ func (v *VolumeEntry) Create(db *bolt.DB,
 		}
 	}()
 
+	// Export the volume over NFS
+	if v.Info.NfsExport {
+		err = v.nfsExport(db, executor)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Save information on db
 	err = db.Update(func(tx *bolt.Tx) error {
 
@@ -394,6 +410,16 @@ func (v *VolumeEntry) Create(db *bolt.DB,
 func (v *VolumeEntry) Destroy(db *bolt.DB, executor executors.Executor) error {
 	logger.Info("Destroying volume %v", v.Info.Id)
 
+	if len(v.Snapshots) > 0 {
+		return logger.LogError("Unable to delete volume %v because it contains %v snapshots",
+			v.Info.Id, len(v.Snapshots))
+	}
+
+	if len(v.Info.BlockInfo.BlockVolumes) > 0 {
+		return logger.LogError("Unable to delete volume %v because it contains %v block volumes",
+			v.Info.Id, len(v.Info.BlockInfo.BlockVolumes))
+	}
+
 	// Get the entries from the database
 	brick_entries := make([]*BrickEntry, len(v.Bricks))
 	var sshhost string
@@ -416,6 +442,16 @@ func (v *VolumeEntry) Destroy(db *bolt.DB, executor executors.Executor) error {
 				sshhost = node.ManageHostName()
 			}
 		}
+
+		// Bricks left over from shrinking the volume go with it
+		for _, id := range v.RemovedBricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				logger.LogError("Brick %v not found in db: %v", id, err)
+				continue
+			}
+			brick_entries = append(brick_entries, brick)
+		}
 		return nil
 	})
 
@@ -433,6 +469,17 @@ func (v *VolumeEntry) Destroy(db *bolt.DB, executor executors.Executor) error {
 		return err
 	}
 
+	// Ganesha and Samba have to let go of the volume before it is removed
+	if v.NfsExportId != 0 {
+		v.nfsUnexport(db, executor)
+	}
+	if len(v.SmbNodes) > 0 {
+		err := v.smbUnshare(db, executor)
+		if err != nil {
+			logger.Err(err)
+		}
+	}
+
 	// :TODO: What if the host is no longer available, we may need to try others
 	// Stop volume
 	err = executor.VolumeDestroy(sshhost, v.Info.Name)
@@ -487,6 +534,10 @@ func (v *VolumeEntry) Expand(db *bolt.DB,
 	allocator Allocator,
 	sizeGB int) (e error) {
 
+	if v.Info.Stopped {
+		return ErrVolumeStopped
+	}
+
 	// Allocate new bricks in the cluster
 	brick_entries, err := v.allocBricksInCluster(db, allocator, v.Info.Cluster, sizeGB)
 	if err != nil {
@@ -498,14 +549,12 @@ func (v *VolumeEntry) Expand(db *bolt.DB,
 		if e != nil {
 			logger.Debug("Error detected, cleaning up")
 
-			// Remove from db
+			// Remove from db, the new bricks were never
+			// saved on the volume entry
 			db.Update(func(tx *bolt.Tx) error {
 				for _, brick := range brick_entries {
 					v.removeBrickFromDb(tx, brick)
 				}
-				err := v.Save(tx)
-				godbc.Check(err == nil)
-
 				return nil
 			})
 		}
@@ -538,9 +587,6 @@ func (v *VolumeEntry) Expand(db *bolt.DB,
 		return err
 	}
 
-	// Increase the recorded volume size
-	v.Info.Size += sizeGB
-
 	// Save volume entry
 	err = db.Update(func(tx *bolt.Tx) error {
 
@@ -552,13 +598,121 @@ func (v *VolumeEntry) Expand(db *bolt.DB,
 			}
 		}
 
-		return v.Save(tx)
+		// Reload the volume, it may have changed while the bricks
+		// were created, and only add the new bricks and size to it
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		for _, brick := range brick_entries {
+			volume.BrickAdd(brick.Id())
+		}
+		volume.Info.Size += sizeGB
+		if volume.Info.Block {
+			volume.Info.BlockInfo.FreeSize += sizeGB
+		}
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
 	})
 
 	return err
 
 }
 
+// SetOptions resets the option keys in reset and then sets the
+// "key value" options in set on the running volume
+func (v *VolumeEntry) SetOptions(db *bolt.DB,
+	executor executors.Executor,
+	set, reset []string) error {
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	if len(reset) > 0 {
+		err = executor.VolumeResetOptions(host, v.Info.Name, reset)
+		if err != nil {
+			return err
+		}
+	}
+	if len(set) > 0 {
+		err = executor.VolumeSetOptions(host, v.Info.Name, set)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replace the recorded values of the changed keys
+	changed := utils.NewStringSet()
+	for _, key := range reset {
+		changed.Add(key)
+	}
+	for _, option := range set {
+		changed.Add(volumeOptionKey(option))
+	}
+	options := []string{}
+	for _, option := range v.GlusterVolumeOptions {
+		if !utils.SortedStringHas(changed.Set, volumeOptionKey(option)) {
+			options = append(options, option)
+		}
+	}
+	v.GlusterVolumeOptions = append(options, set...)
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// Stop stops the volume, keeping its bricks and data, until it is
+// started again
+func (v *VolumeEntry) Stop(db *bolt.DB, executor executors.Executor) error {
+	return v.setStopped(db, executor, true)
+}
+
+// Start starts the stopped volume
+func (v *VolumeEntry) Start(db *bolt.DB, executor executors.Executor) error {
+	return v.setStopped(db, executor, false)
+}
+
+func (v *VolumeEntry) setStopped(db *bolt.DB,
+	executor executors.Executor,
+	stopped bool) error {
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	if stopped {
+		err = executor.VolumeStop(host, v.Info.Name)
+	} else {
+		err = executor.VolumeStart(host, v.Info.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	v.Info.Stopped = stopped
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// Returns the key of an option in "key value" form
+func volumeOptionKey(option string) string {
+	fields := strings.Fields(option)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
 func (v *VolumeEntry) BricksIds() sort.StringSlice {
 	ids := make(sort.StringSlice, len(v.Bricks))
 	copy(ids, v.Bricks)
@@ -589,6 +743,28 @@ func (v *VolumeEntry) checkBricksCanBeDestroyed(db *bolt.DB,
 	return err
 }
 
+func (v *VolumeEntry) AllTags() map[string]string {
+	return v.Info.Tags
+}
+
+func (v *VolumeEntry) SetTags(tags map[string]string) {
+	v.Info.Tags = tags
+}
+
 func VolumeEntryUpgrade(tx *bolt.Tx) error {
+	err := addTagsInVolumeEntry(tx)
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+func addTagsInVolumeEntry(tx *bolt.Tx) error {
+	volumes, err := VolumeList(tx)
+	if err != nil {
+		return err
+	}
+	return addTagsInEntries(tx, volumes, func(tx *bolt.Tx, id string) (TaggedEntry, error) {
+		return NewVolumeEntryFromId(tx, id)
+	})
+}
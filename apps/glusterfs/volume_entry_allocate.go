@@ -18,23 +18,165 @@ import (
 	"github.com/heketi/heketi/pkg/utils"
 )
 
-func (v *VolumeEntry) allocBricksInCluster(db *bolt.DB,
+// Database access used while allocating bricks. It is satisfied by
+// *bolt.DB and by the single transaction of a volume plan.
+type allocDb interface {
+	Update(fn func(*bolt.Tx) error) error
+	View(fn func(*bolt.Tx) error) error
+}
+
+// Returns the clusters the volume may be created on, which are the
+// requested ones, or all of them, without a volume of the same name
+func (v *VolumeEntry) availableClusters(db allocDb) ([]string, error) {
+
+	// Get list of clusters
+	var possibleClusters []string
+	if len(v.Info.Clusters) == 0 {
+		err := db.View(func(tx *bolt.Tx) error {
+			var err error
+			possibleClusters, err = ClusterList(tx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		possibleClusters = v.Info.Clusters
+	}
+
+	// Check we have clusters
+	if len(possibleClusters) == 0 {
+		logger.LogError("Volume being ask to be created, but there are no clusters configured")
+		return nil, ErrNoSpace
+	}
+	logger.Debug("Using the following clusters: %+v", possibleClusters)
+
+	// Check for volume name conflict on any cluster
+	var clusters []string
+	for _, cluster := range possibleClusters {
+		var err error
+
+		// Check this cluster does not have a volume with the name
+		err = db.View(func(tx *bolt.Tx) error {
+			ce, err := NewClusterEntryFromId(tx, cluster)
+			if err != nil {
+				return err
+			}
+
+			for _, volumeId := range ce.Info.Volumes {
+				volume, err := NewVolumeEntryFromId(tx, volumeId)
+				if err != nil {
+					return err
+				}
+				if v.Info.Name == volume.Info.Name {
+					return fmt.Errorf("Name %v already in use in cluster %v",
+						v.Info.Name, cluster)
+				}
+			}
+
+			return nil
+
+		})
+		if err != nil {
+			logger.Warning("%v", err.Error())
+		} else {
+			clusters = append(clusters, cluster)
+		}
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("Name %v is already in use in all available clusters", v.Info.Name)
+	}
+
+	return clusters, nil
+}
+
+// Allocates the bricks of the volume in the first of the clusters
+// with enough space and sets it as the cluster of the volume
+func (v *VolumeEntry) allocBricksInClusters(db allocDb,
+	allocator Allocator,
+	clusters []string) ([]*BrickEntry, error) {
+
+	// For each cluster look for storage space for this volume
+	var brick_entries []*BrickEntry
+	var err error
+	placementFailed := false
+	for _, cluster := range clusters {
+
+		// Check this cluster for space
+		brick_entries, err = v.allocBricksInCluster(db, allocator, cluster, v.Info.Size)
+		if err == ErrPlacement {
+			placementFailed = true
+		}
+
+		if err == nil {
+			v.Info.Cluster = cluster
+			logger.Debug("Volume to be created on cluster %v", cluster)
+			break
+		} else if err == ErrNoSpace ||
+			err == ErrMaxBricks ||
+			err == ErrMinimumBrickSize ||
+			err == ErrPlacement {
+			logger.Debug("Cluster %v can not accommodate volume "+
+				"(%v), trying next cluster", cluster, err)
+			continue
+		} else {
+			// A genuine error occurred - bail out
+			return nil, logger.LogError("Error calling v.allocBricksInCluster: %v", err)
+		}
+	}
+
+	if err != nil || brick_entries == nil {
+		// Map all 'valid' errors to NoSpace here:
+		// Only the last such error could get propagated down,
+		// so it does not make sense to hand the granularity on.
+		// But for other callers (Expand), we keep it.
+		// Keep reporting when the constraints kept the volume
+		// out of any of the clusters.
+		if placementFailed {
+			return nil, ErrPlacement
+		}
+		return nil, ErrNoSpace
+	}
+
+	return brick_entries, nil
+}
+
+func (v *VolumeEntry) allocBricksInCluster(db allocDb,
 	allocator Allocator,
 	cluster string,
 	gbsize int) ([]*BrickEntry, error) {
 
 	size := uint64(gbsize) * GB
 
+	var limits BrickLimits
+	err := db.View(func(tx *bolt.Tx) error {
+		ce, err := NewClusterEntryFromId(tx, cluster)
+		if err != nil {
+			return err
+		}
+		limits = v.brickLimits(ce, allocator.BrickLimits())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Setup a brick size generator
 	// Note: subsequent calls to gen need to return decreasing
 	//       brick sizes in order for the following code to work!
-	gen := v.Durability.BrickSizeGenerator(size)
+	gen := v.Durability.BrickSizeGenerator(size, limits)
 
-	// Try decreasing possible brick sizes until space is found
+	// Try decreasing possible brick sizes until space is found.
+	// When the placement constraints kept the last bricks off
+	// devices with enough space, they are the reason of the failure.
+	placementFailed := false
 	for {
 		// Determine next possible brick size
 		sets, brick_size, err := gen()
 		if err != nil {
+			if err == ErrMinimumBrickSize && placementFailed {
+				err = ErrPlacement
+			}
 			logger.Err(err)
 			return nil, err
 		}
@@ -46,15 +188,19 @@ func (v *VolumeEntry) allocBricksInCluster(db *bolt.DB,
 		logger.Debug("num_bricks = %v", num_bricks)
 
 		// Check that the volume would not have too many bricks
-		if (num_bricks + len(v.Bricks)) > BrickMaxNum {
+		if (num_bricks + len(v.Bricks)) > limits.MaxNum {
 			logger.Debug("Maximum number of bricks reached")
+			if placementFailed {
+				return nil, ErrPlacement
+			}
 			return nil, ErrMaxBricks
 		}
 
 		// Allocate bricks in the cluster
-		brick_entries, err := v.allocBricks(db, allocator, cluster, sets, brick_size)
-		if err == ErrNoSpace {
+		brick_entries, err := v.allocBricks(db, allocator, cluster, sets, brick_size, limits)
+		if err == ErrNoSpace || err == ErrPlacement {
 			logger.Debug("No space, re-trying with smaller brick size")
+			placementFailed = err == ErrPlacement
 			continue
 		}
 		if err != nil {
@@ -106,10 +252,14 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 	var oldBrickNodeEntry *NodeEntry
 	var newBrickNodeEntry *NodeEntry
 	var newBrickEntry *BrickEntry
+	var limits BrickLimits
 
 	if api.DurabilityDistributeOnly == v.Info.Durability.Type {
 		return fmt.Errorf("replace brick is not supported for volume durability type %v", v.Info.Durability.Type)
 	}
+	if v.Info.Stopped {
+		return ErrVolumeStopped
+	}
 
 	err := db.View(func(tx *bolt.Tx) error {
 		var err error
@@ -126,6 +276,11 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 		if err != nil {
 			return err
 		}
+		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		limits = v.brickLimits(cluster, allocator.BrickLimits())
 		return nil
 	})
 	if err != nil {
@@ -145,7 +300,7 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 	vinfo, err := executor.VolumeInfo(node, v.Info.Name)
 	var slicestartindex int
 	var foundbrickset bool
-	var brick executors.Brick
+	var position int
 	setlist := make([]*BrickEntry, 0)
 	var onlinePeerBrickCount = 0
 	// BrickList in volume info is a slice of all bricks in volume
@@ -153,7 +308,7 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 	// If brick to be replaced is found in an iteration, other bricks in that slice form the setlist
 	for slicestartindex = 0; slicestartindex <= len(vinfo.Bricks.BrickList)-v.Durability.BricksInSet(); slicestartindex = slicestartindex + v.Durability.BricksInSet() {
 		setlist = make([]*BrickEntry, 0)
-		for _, brick = range vinfo.Bricks.BrickList[slicestartindex : slicestartindex+v.Durability.BricksInSet()] {
+		for i, brick := range vinfo.Bricks.BrickList[slicestartindex : slicestartindex+v.Durability.BricksInSet()] {
 			brickentry, err := v.getBrickEntryfromBrickName(db, brick.Name)
 			if err != nil {
 				logger.LogError("Unable to create brick entry using brick name:%v, error: %v", brick.Name, err)
@@ -161,6 +316,7 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 			}
 			if brickentry.Id() == oldBrickId {
 				foundbrickset = true
+				position = i
 			} else {
 				setlist = append(setlist, brickentry)
 			}
@@ -211,11 +367,22 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 			v.Durability.QuorumBrickCount())
 	}
 
+	// The new brick takes the position of the old brick in the set.
+	// Size it from a peer, which is always a data brick, so that
+	// arbiter bricks are replaced by arbiter sized bricks.
+	newBrickSize := v.Durability.BrickSizeInSet(position, setlist[0].Info.Size, limits)
+	if v.Info.Durability.Type == api.DurabilityArbiter &&
+		position == ARBITER_BRICK_POSITION {
+		logger.Info("Replacing arbiter brick %v", oldBrickEntry.Id())
+	}
+
 	//Create an Id for new brick
 	newBrickId := utils.GenUUID()
 
-	// Check the ring for devices to place the brick
-	deviceCh, done, errc := allocator.GetNodes(v.Info.Cluster, newBrickId)
+	// Check the ring for devices to place the brick. The new brick
+	// must be of the same device class as the rest of the volume.
+	deviceCh, done, errc := allocator.GetNodes(v.Info.Cluster, newBrickId,
+		v.placementFilter())
 	defer func() {
 		close(done)
 	}()
@@ -223,17 +390,24 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 	for deviceId := range deviceCh {
 
 		// Get device entry
+		allowed := false
 		err = db.View(func(tx *bolt.Tx) error {
 			newDeviceEntry, err = NewDeviceEntryFromId(tx, deviceId)
 			if err != nil {
 				return err
 			}
-			return nil
+			allowed, err = v.placementAllows(tx, newDeviceEntry)
+			return err
 		})
 		if err != nil {
 			return err
 		}
 
+		// Skip devices the volume may not be placed on
+		if !allowed {
+			continue
+		}
+
 		// Skip same device
 		if oldDeviceEntry.Info.Id == newDeviceEntry.Info.Id {
 			continue
@@ -261,9 +435,7 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 			if err != nil {
 				return err
 			}
-			newBrickEntry = newDeviceEntry.NewBrickEntry(oldBrickEntry.Info.Size,
-				float64(v.Info.Snapshot.Factor),
-				v.Info.Gid, v.Info.Id)
+			newBrickEntry = v.newBrickEntry(newDeviceEntry, newBrickSize)
 			err = newDeviceEntry.Save(tx)
 			if err != nil {
 				return err
@@ -389,11 +561,12 @@ func (v *VolumeEntry) replaceBrickInVolume(db *bolt.DB, executor executors.Execu
 }
 
 func (v *VolumeEntry) allocBricks(
-	db *bolt.DB,
+	db allocDb,
 	allocator Allocator,
 	cluster string,
 	bricksets int,
-	brick_size uint64) (brick_entries []*BrickEntry, e error) {
+	brick_size uint64,
+	limits BrickLimits) (brick_entries []*BrickEntry, e error) {
 
 	// Setup garbage collector function in case of error
 	defer func() {
@@ -426,7 +599,7 @@ func (v *VolumeEntry) allocBricks(
 
 		// Get allocator generator
 		// The same generator should be used for the brick and its replicas
-		deviceCh, done, errc := allocator.GetNodes(cluster, brickId)
+		deviceCh, done, errc := allocator.GetNodes(cluster, brickId, v.placementFilter())
 		defer func() {
 			close(done)
 		}()
@@ -438,6 +611,8 @@ func (v *VolumeEntry) allocBricks(
 			// Do the work in the database context so that the cluster
 			// data does not change while determining brick location
 			err := db.Update(func(tx *bolt.Tx) error {
+				brickSize := v.Durability.BrickSizeInSet(i, brick_size, limits)
+				excluded := false
 
 				// Check the ring for devices to place the brick
 				for deviceId := range deviceCh {
@@ -448,6 +623,18 @@ func (v *VolumeEntry) allocBricks(
 						return err
 					}
 
+					// Skip devices the volume may not be placed on
+					allowed, err := v.placementAllows(tx, device)
+					if err != nil {
+						return err
+					}
+					if !allowed {
+						if device.brickFits(brickSize, float64(v.Info.Snapshot.Factor)) {
+							excluded = true
+						}
+						continue
+					}
+
 					// Do not allow a device from the same node to be
 					// in the set
 					deviceOk := true
@@ -462,9 +649,7 @@ func (v *VolumeEntry) allocBricks(
 					}
 
 					// Try to allocate a brick on this device
-					brick := device.NewBrickEntry(brick_size,
-						float64(v.Info.Snapshot.Factor),
-						v.Info.Gid, v.Info.Id)
+					brick := v.newBrickEntry(device, brickSize)
 
 					// Determine if it was successful
 					if brick != nil {
@@ -506,7 +691,11 @@ func (v *VolumeEntry) allocBricks(
 					return err
 				}
 
-				// No devices found
+				// No devices found, only because of the placement
+				// constraints if an excluded device had the space
+				if excluded {
+					return ErrPlacement
+				}
 				return ErrNoSpace
 
 			})
@@ -520,6 +709,15 @@ func (v *VolumeEntry) allocBricks(
 
 }
 
+// Returns the filter restricting the devices the bricks
+// of the volume may be placed on
+func (v *VolumeEntry) placementFilter() *PlacementFilter {
+	if v.Info.DeviceClass == "" {
+		return nil
+	}
+	return &PlacementFilter{DeviceClass: v.Info.DeviceClass}
+}
+
 func (v *VolumeEntry) removeBrickFromDb(tx *bolt.Tx, brick *BrickEntry) error {
 
 	// Access device
@@ -558,3 +756,17 @@ func (v *VolumeEntry) removeBrickFromDb(tx *bolt.Tx, brick *BrickEntry) error {
 
 	return nil
 }
+
+// Allocates a brick of the volume on the device, see
+// DeviceEntry.NewBrickEntry, whose root gets the owner and
+// mode requested for the volume
+func (v *VolumeEntry) newBrickEntry(device *DeviceEntry, size uint64) *BrickEntry {
+	brick := device.NewBrickEntry(size,
+		float64(v.Info.Snapshot.Factor),
+		v.Info.Gid, v.Info.Id)
+	if brick != nil {
+		brick.uidRequested = v.Info.Uid
+		brick.modeRequested = v.Info.Mode
+	}
+	return brick
+}
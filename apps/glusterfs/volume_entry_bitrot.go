@@ -0,0 +1,136 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// BitrotEnable starts the bitrot daemon signing the files of the
+// volume and the scrubber checking them
+func (v *VolumeEntry) BitrotEnable(db *bolt.DB, executor executors.Executor) error {
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	err = executor.VolumeBitrotEnable(host, v.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	v.Info.Bitrot.Enabled = true
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// BitrotDisable stops bitrot detection on the volume. The scrub
+// settings stay with the volume and apply when it is enabled again.
+func (v *VolumeEntry) BitrotDisable(db *bolt.DB, executor executors.Executor) error {
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	err = executor.VolumeBitrotDisable(host, v.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	v.Info.Bitrot.Enabled = false
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// SetBitrotScrub changes how often and how fast the scrubber checks
+// the volume. Empty values are left unchanged.
+func (v *VolumeEntry) SetBitrotScrub(db *bolt.DB,
+	executor executors.Executor,
+	frequency, throttle string) error {
+
+	if !v.Info.Bitrot.Enabled {
+		return ErrBitrotDisabled
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	err = executor.VolumeBitrotScrub(host, v.Info.Name, &executors.BitrotScrubRequest{
+		Frequency: frequency,
+		Throttle:  throttle,
+	})
+	if err != nil {
+		return err
+	}
+
+	if frequency != "" {
+		v.Info.Bitrot.ScrubFrequency = frequency
+	}
+	if throttle != "" {
+		v.Info.Bitrot.ScrubThrottle = throttle
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// BitrotStatus returns the scrub status of each node of the volume.
+// Gluster has no scrub status for volumes without bitrot detection
+// or which are stopped.
+func (v *VolumeEntry) BitrotStatus(db *bolt.DB,
+	executor executors.Executor) (*api.VolumeBitrotStatusResponse, error) {
+
+	status := &api.VolumeBitrotStatusResponse{
+		Enabled:   v.Info.Bitrot.Enabled,
+		Frequency: v.Info.Bitrot.ScrubFrequency,
+		Throttle:  v.Info.Bitrot.ScrubThrottle,
+		Nodes:     []api.BitrotScrubNodeStatus{},
+	}
+	if !v.Info.Bitrot.Enabled || v.Info.Stopped {
+		return status, nil
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	scrub, err := executor.VolumeBitrotScrubStatus(host, v.Info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	status.State = scrub.State
+	if scrub.Frequency != "" {
+		status.Frequency = scrub.Frequency
+	}
+	if scrub.Throttle != "" {
+		status.Throttle = scrub.Throttle
+	}
+	for _, n := range scrub.Nodes {
+		status.Nodes = append(status.Nodes, api.BitrotScrubNodeStatus{
+			Node:              n.Node,
+			ScrubbedFiles:     n.ScrubbedFiles,
+			SkippedFiles:      n.SkippedFiles,
+			LastScrubTime:     n.LastScrubTime,
+			LastScrubDuration: n.LastScrubDuration,
+			ErrorCount:        n.ErrorCount,
+			CorruptedObjects:  n.CorruptedObjects,
+		})
+	}
+
+	return status, nil
+}
@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeEntryBitrot(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	// Scrub needs bitrot
+	err = v.SetBitrotScrub(app.db, app.executor, "daily", "")
+	tests.Assert(t, err == ErrBitrotDisabled, err)
+
+	// Disabled volumes have no status to ask gluster for
+	app.xo.MockVolumeBitrotScrubStatus = func(host string, volume string) (*executors.BitrotScrubStatus, error) {
+		t.Fatal("scrub status of a volume without bitrot")
+		return nil, nil
+	}
+	status, err := v.BitrotStatus(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !status.Enabled)
+	tests.Assert(t, len(status.Nodes) == 0)
+
+	// Failures leave the volume unchanged
+	app.xo.MockVolumeBitrotEnable = func(host string, volume string) error {
+		return errors.New("bitd failed")
+	}
+	err = v.BitrotEnable(app.db, app.executor)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, !v.Info.Bitrot.Enabled)
+
+	app.xo.MockVolumeBitrotEnable = func(host string, volume string) error {
+		tests.Assert(t, volume == v.Info.Name)
+		return nil
+	}
+	err = v.BitrotEnable(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+
+	var scrub *executors.BitrotScrubRequest
+	app.xo.MockVolumeBitrotScrub = func(host string, volume string, s *executors.BitrotScrubRequest) error {
+		scrub = s
+		return nil
+	}
+	err = v.SetBitrotScrub(app.db, app.executor, "daily", "")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, scrub.Frequency == "daily" && scrub.Throttle == "")
+	err = v.SetBitrotScrub(app.db, app.executor, "", "aggressive")
+	tests.Assert(t, err == nil, err)
+
+	// Saved with the volume
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, entry.Info.Bitrot.Enabled)
+		tests.Assert(t, entry.Info.Bitrot.ScrubFrequency == "daily")
+		tests.Assert(t, entry.Info.Bitrot.ScrubThrottle == "aggressive")
+
+		info, err := entry.NewInfoResponse(tx)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, info.Bitrot.Enabled)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	app.xo.MockVolumeBitrotScrubStatus = func(host string, volume string) (*executors.BitrotScrubStatus, error) {
+		return &executors.BitrotScrubStatus{
+			State: "Active (In Progress)",
+			Nodes: []executors.BitrotScrubNodeStatus{
+				executors.BitrotScrubNodeStatus{Node: "node1", ScrubbedFiles: 5},
+				executors.BitrotScrubNodeStatus{Node: "node2", SkippedFiles: 1},
+			},
+		}, nil
+	}
+	status, err = v.BitrotStatus(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, status.Enabled)
+	tests.Assert(t, status.State == "Active (In Progress)")
+	tests.Assert(t, status.Frequency == "daily")
+	tests.Assert(t, len(status.Nodes) == 2)
+	tests.Assert(t, status.Nodes[0].ScrubbedFiles == 5)
+
+	err = v.BitrotDisable(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !v.Info.Bitrot.Enabled)
+}
@@ -39,29 +39,38 @@ func (v *VolumeEntry) createVolume(db *bolt.DB,
 		return err
 	}
 
+	return v.setupMountInfo(db)
+}
+
+// Set the information clients need to mount the volume
+func (v *VolumeEntry) setupMountInfo(db *bolt.DB) error {
+	return db.View(func(tx *bolt.Tx) error {
+		return v.updateMountInfo(tx)
+	})
+}
+
+func (v *VolumeEntry) updateMountInfo(tx *bolt.Tx) error {
+
 	// Get all brick hosts
 	stringset := utils.NewStringSet()
-	err = db.View(func(tx *bolt.Tx) error {
-		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+	cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+	for _, nodeId := range cluster.Info.Nodes {
+		node, err := NewNodeEntryFromId(tx, nodeId)
 		if err != nil {
 			return err
 		}
-		for _, nodeId := range cluster.Info.Nodes {
-			node, err := NewNodeEntryFromId(tx, nodeId)
-			if err != nil {
-				return err
-			}
-			stringset.Add(node.StorageHostName())
-		}
-		return err
-	})
+		stringset.Add(node.StorageHostName())
+	}
 
 	hosts := stringset.Strings()
 	v.Info.Mount.GlusterFS.Hosts = hosts
 
 	// Save volume information
 	v.Info.Mount.GlusterFS.MountPoint = fmt.Sprintf("%v:%v",
-		hosts[0], vr.Name)
+		hosts[0], v.Info.Name)
 
 	// Set glusterfs mount volfile-servers options
 	v.Info.Mount.GlusterFS.Options = make(map[string]string)
@@ -0,0 +1,261 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+)
+
+var (
+	// Returned when the bricks of a volume cannot be grown in place
+	errNoInPlaceGrowth = errors.New("Bricks cannot be grown in place")
+)
+
+// New sizes of a brick grown in place
+type brickGrowth struct {
+	brick *BrickEntry
+	host  string
+
+	// Sizes in KB
+	size         uint64
+	tpSize       uint64
+	metadataSize uint64
+
+	// Space taken from the device
+	allocated uint64
+}
+
+// ExpandInPlace adds sizeGB to the volume by growing its existing bricks
+// when their devices have room for it, without adding bricks to the
+// volume. Otherwise the volume is expanded with new brick sets.
+func (v *VolumeEntry) ExpandInPlace(db *bolt.DB,
+	executor executors.Executor,
+	allocator Allocator,
+	sizeGB int) error {
+
+	if v.Info.Stopped {
+		return ErrVolumeStopped
+	}
+
+	err := v.growBricks(db, executor, allocator.BrickLimits(), sizeGB)
+	if err == errNoInPlaceGrowth {
+		logger.Info("Adding bricks to volume %v instead", v.Info.Id)
+		return v.Expand(db, executor, allocator, sizeGB)
+	}
+
+	return err
+}
+
+// Grows every brick of the volume by the same proportion, which
+// grows every brick set and so the volume by sizeGB
+func (v *VolumeEntry) growBricks(db *bolt.DB,
+	executor executors.Executor,
+	defaults BrickLimits,
+	sizeGB int) (e error) {
+
+	// Reserve the space on the devices
+	var growths []*brickGrowth
+	err := db.Update(func(tx *bolt.Tx) error {
+		var err error
+		growths, err = v.reserveBrickGrowth(tx, defaults, sizeGB)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Grow the bricks one at a time, so that only the bricks
+	// which were not grown are given back on error
+	var grown int
+	defer func() {
+		if e == nil {
+			return
+		}
+		db.Update(func(tx *bolt.Tx) error {
+			for _, g := range growths[grown:] {
+				device, err := NewDeviceEntryFromId(tx, g.brick.Info.DeviceId)
+				if err != nil {
+					return logger.Err(err)
+				}
+				device.StorageFree(g.allocated)
+				err = device.Save(tx)
+				if err != nil {
+					return logger.Err(err)
+				}
+			}
+			return saveGrownBricks(tx, growths[:grown])
+		})
+	}()
+
+	for _, g := range growths {
+		req := &executors.BrickRequest{
+			Name: g.brick.Info.Id,
+			VgId: g.brick.Info.DeviceId,
+			Size: g.size,
+		}
+		if g.tpSize > g.brick.TpSize {
+			req.TpSize = g.tpSize
+		}
+		if g.metadataSize > g.brick.PoolMetadataSize {
+			req.PoolMetadataSize = g.metadataSize
+		}
+
+		logger.Info("Growing brick %v to %v KB", g.brick.Info.Id, g.size)
+		err := executor.BrickExpand(g.host, req)
+		if err != nil {
+			return err
+		}
+		grown++
+	}
+
+	// Increase the recorded volume size.  Reload the volume, it may
+	// have changed while the bricks were grown.
+	return db.Update(func(tx *bolt.Tx) error {
+		err := saveGrownBricks(tx, growths)
+		if err != nil {
+			return err
+		}
+
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		volume.Info.Size += sizeGB
+		if volume.Info.Block {
+			volume.Info.BlockInfo.FreeSize += sizeGB
+		}
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+}
+
+// Determines the new size of each brick and takes the additional
+// space from the devices. Returns errNoInPlaceGrowth when any of the
+// bricks cannot be grown, in which case nothing is changed.
+func (v *VolumeEntry) reserveBrickGrowth(tx *bolt.Tx,
+	defaults BrickLimits,
+	sizeGB int) ([]*brickGrowth, error) {
+
+	if v.Info.Size < 1 || len(v.Bricks) == 0 {
+		return nil, errNoInPlaceGrowth
+	}
+	oldSize := uint64(v.Info.Size)
+	newSize := uint64(v.Info.Size + sizeGB)
+
+	cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	limits := v.brickLimits(cluster, defaults)
+
+	growths := make([]*brickGrowth, 0, len(v.Bricks))
+	devices := make(map[string]*DeviceEntry)
+	for _, id := range v.Bricks {
+		brick, err := NewBrickEntryFromId(tx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// The thin pool of a cloned brick belongs to another brick
+		if brick.IsClone() {
+			logger.Info("Brick %v of volume %v is a clone and cannot be grown",
+				brick.Info.Id, v.Info.Id)
+			return nil, errNoInPlaceGrowth
+		}
+
+		// Imported bricks are not named the way heketi names its bricks
+		if brick.Imported {
+			logger.Info("Brick %v of volume %v was imported and cannot be grown",
+				brick.Info.Id, v.Info.Id)
+			return nil, errNoInPlaceGrowth
+		}
+
+		// Nor are the bricks a snapshot restore moved elsewhere
+		if brick.Restored {
+			logger.Info("Brick %v of volume %v was restored from a snapshot "+
+				"and cannot be grown", brick.Info.Id, v.Info.Id)
+			return nil, errNoInPlaceGrowth
+		}
+
+		device, ok := devices[brick.Info.DeviceId]
+		if !ok {
+			device, err = NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			if err != nil {
+				return nil, err
+			}
+			devices[device.Info.Id] = device
+		}
+		node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		g := &brickGrowth{
+			brick: brick,
+			host:  node.ManageHostName(),
+			size:  (brick.Info.Size*newSize + oldSize - 1) / oldSize,
+		}
+		if g.size > limits.MaxSize {
+			logger.Info("Brick %v of volume %v would be larger than %v KB",
+				brick.Info.Id, v.Info.Id, limits.MaxSize)
+			return nil, errNoInPlaceGrowth
+		}
+
+		g.tpSize, g.metadataSize = device.thinPoolSizes(g.size,
+			float64(v.Info.Snapshot.Factor))
+		if g.tpSize < brick.TpSize {
+			g.tpSize = brick.TpSize
+		}
+		if g.metadataSize < brick.PoolMetadataSize {
+			g.metadataSize = brick.PoolMetadataSize
+		}
+		g.allocated = g.tpSize + g.metadataSize - brick.TotalSize()
+
+		if !device.StorageCheck(g.allocated) {
+			logger.Info("Device %v has no room to grow brick %v",
+				device.Info.Id, brick.Info.Id)
+			return nil, errNoInPlaceGrowth
+		}
+		device.StorageAllocate(g.allocated)
+
+		growths = append(growths, g)
+	}
+
+	for _, device := range devices {
+		err := device.Save(tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return growths, nil
+}
+
+func saveGrownBricks(tx *bolt.Tx, growths []*brickGrowth) error {
+	for _, g := range growths {
+		g.brick.Info.Size = g.size
+		g.brick.TpSize = g.tpSize
+		g.brick.PoolMetadataSize = g.metadataSize
+		err := g.brick.Save(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/tests"
+)
+
+// Returns the bricks of the volume and the devices they are on
+func growTestBricks(t *testing.T, app *App,
+	volumeId string) (*VolumeEntry, map[string]*BrickEntry, map[string]*DeviceEntry) {
+
+	var v *VolumeEntry
+	bricks := make(map[string]*BrickEntry)
+	devices := make(map[string]*DeviceEntry)
+	err := app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		v, err = NewVolumeEntryFromId(tx, volumeId)
+		if err != nil {
+			return err
+		}
+		for _, id := range v.Bricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			bricks[id] = brick
+
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			if err != nil {
+				return err
+			}
+			devices[device.Info.Id] = device
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	return v, bricks, devices
+}
+
+func TestVolumeEntryExpandInPlace(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	_, oldBricks, oldDevices := growTestBricks(t, app, v.Info.Id)
+	tests.Assert(t, len(oldBricks) == 3)
+
+	// Changes made to the volume while the bricks grow are kept
+	var grown []*executors.BrickRequest
+	app.xo.MockBrickExpand = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, host != "")
+		grown = append(grown, brick)
+		if len(grown) == 1 {
+			err := app.db.Update(func(tx *bolt.Tx) error {
+				entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+				if err != nil {
+					return err
+				}
+				entry.GlusterVolumeOptions = []string{"nfs.disable on"}
+				return entry.Save(tx)
+			})
+			tests.Assert(t, err == nil)
+		}
+		return nil
+	}
+	app.xo.MockVolumeExpand = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+		tests.Assert(t, false, "bricks added to the volume")
+		return nil, nil
+	}
+
+	err = v.ExpandInPlace(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(grown) == 3)
+	tests.Assert(t, v.Info.Size == 200)
+
+	entry, bricks, devices := growTestBricks(t, app, v.Info.Id)
+	tests.Assert(t, entry.Info.Size == 200)
+	tests.Assert(t, len(entry.GlusterVolumeOptions) == 1, entry.GlusterVolumeOptions)
+	tests.Assert(t, len(bricks) == 3)
+	for _, req := range grown {
+		old := oldBricks[req.Name]
+		brick := bricks[req.Name]
+		tests.Assert(t, old != nil && brick != nil)
+		tests.Assert(t, req.VgId == brick.Info.DeviceId)
+		tests.Assert(t, req.Size == 2*old.Info.Size)
+		tests.Assert(t, req.TpSize == brick.TpSize)
+		tests.Assert(t, brick.Info.Size == 2*old.Info.Size)
+		tests.Assert(t, brick.TpSize > old.TpSize)
+
+		// The device accounts for the larger thin pool
+		grownBy := brick.TotalSize() - old.TotalSize()
+		device := devices[brick.Info.DeviceId]
+		oldDevice := oldDevices[brick.Info.DeviceId]
+		tests.Assert(t, device.Info.Storage.Used == oldDevice.Info.Storage.Used+grownBy)
+		tests.Assert(t, device.Info.Storage.Free == oldDevice.Info.Storage.Free-grownBy)
+	}
+}
+
+func TestVolumeEntryExpandInPlaceFallback(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(400, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(v.Bricks) == 3)
+	_, oldBricks, oldDevices := growTestBricks(t, app, v.Info.Id)
+
+	app.xo.MockBrickExpand = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, false, "brick grown in place")
+		return nil
+	}
+
+	// Bricks of 700GB do not fit on the devices
+	err = v.ExpandInPlace(app.db, app.executor, app.allocator, 300)
+	tests.Assert(t, err == nil, err)
+
+	entry, bricks, _ := growTestBricks(t, app, v.Info.Id)
+	tests.Assert(t, entry.Info.Size == 700)
+	tests.Assert(t, len(bricks) == 6)
+	for id, old := range oldBricks {
+		tests.Assert(t, bricks[id].Info.Size == old.Info.Size)
+		tests.Assert(t, bricks[id].TpSize == old.TpSize)
+	}
+
+	// The devices of the old bricks are unchanged
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for id, old := range oldDevices {
+			device, err := NewDeviceEntryFromId(tx, id)
+			tests.Assert(t, err == nil, err)
+			tests.Assert(t, device.Info.Storage.Free == old.Info.Storage.Free)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestVolumeEntryExpandInPlaceFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	_, oldBricks, oldDevices := growTestBricks(t, app, v.Info.Id)
+
+	// Only the first brick can be grown
+	var grown string
+	app.xo.MockBrickExpand = func(host string, brick *executors.BrickRequest) error {
+		if grown != "" {
+			return errors.New("lvextend failed")
+		}
+		grown = brick.Name
+		return nil
+	}
+
+	err = v.ExpandInPlace(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, grown != "")
+
+	entry, bricks, devices := growTestBricks(t, app, v.Info.Id)
+	tests.Assert(t, entry.Info.Size == 100)
+	tests.Assert(t, len(bricks) == 3)
+	for id, brick := range bricks {
+		old := oldBricks[id]
+		device := devices[brick.Info.DeviceId]
+		oldDevice := oldDevices[brick.Info.DeviceId]
+		if id == grown {
+			// The brick keeps its new size and space
+			tests.Assert(t, brick.Info.Size == 2*old.Info.Size)
+			tests.Assert(t, device.Info.Storage.Free ==
+				oldDevice.Info.Storage.Free-(brick.TotalSize()-old.TotalSize()))
+		} else {
+			tests.Assert(t, brick.Info.Size == old.Info.Size)
+			tests.Assert(t, brick.TpSize == old.TpSize)
+			tests.Assert(t, device.Info.Storage.Free == oldDevice.Info.Storage.Free)
+		}
+	}
+}
+
+func TestVolumeEntryExpandInPlaceRestored(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	// A snapshot restore moved a brick away from its heketi path
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		brick, err := NewBrickEntryFromId(tx, v.Bricks[0])
+		if err != nil {
+			return err
+		}
+		brick.Info.Path = "/run/gluster/snaps/" + brick.Info.Id + "/brick"
+		brick.Restored = true
+		return brick.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := v.reserveBrickGrowth(tx, app.brickLimits, 100)
+		return err
+	})
+	tests.Assert(t, err == errNoInPlaceGrowth, err)
+
+	// The volume grows by adding bricks instead
+	app.xo.MockBrickExpand = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, false, "brick grown in place")
+		return nil
+	}
+	err = v.ExpandInPlace(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == nil, err)
+	entry, bricks, _ := growTestBricks(t, app, v.Info.Id)
+	tests.Assert(t, entry.Info.Size == 200)
+	tests.Assert(t, len(bricks) == 6)
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// HealInfo returns the number of entries pending heal on each brick of
+// the volume, as reported by gluster
+func (v *VolumeEntry) HealInfo(db *bolt.DB,
+	executor executors.Executor) (*api.VolumeHealInfoResponse, error) {
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	healinfo, err := executor.HealInfo(host, v.Info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gluster names the bricks by their storage host and path
+	brickIds := make(map[string]string)
+	err = db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.BricksIds() {
+			brick, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			brickIds[fmt.Sprintf("%v:%v", node.StorageHostName(), brick.Info.Path)] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := &api.VolumeHealInfoResponse{
+		Bricks: make([]api.BrickHealInfo, 0, len(healinfo.Bricks.BrickList)),
+	}
+	for _, status := range healinfo.Bricks.BrickList {
+		brick := api.BrickHealInfo{
+			Id:             brickIds[status.Name],
+			Name:           status.Name,
+			Status:         status.Status,
+			PendingEntries: -1,
+		}
+
+		// Bricks which are down report "-"
+		if entries, err := strconv.Atoi(status.NumberOfEntries); err == nil {
+			brick.PendingEntries = entries
+			info.PendingEntries += entries
+		}
+
+		info.Bricks = append(info.Bricks, brick)
+	}
+
+	return info, nil
+}
+
+// Heal starts healing the volume. An index heal only heals the entries
+// gluster recorded as pending, a full heal crawls the whole volume.
+func (v *VolumeEntry) Heal(db *bolt.DB,
+	executor executors.Executor,
+	full bool) error {
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	return executor.VolumeHeal(host, v.Info.Name, full)
+}
@@ -0,0 +1,105 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/tests"
+)
+
+// Returns the names gluster uses for the bricks of the volume
+func healTestBrickNames(t *testing.T, app *App, v *VolumeEntry) map[string]string {
+	names := make(map[string]string)
+	err := app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.Bricks {
+			be, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			ne, err := NewNodeEntryFromId(tx, be.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			names[id] = fmt.Sprintf("%v:%v", ne.Info.Hostnames.Storage[0], be.Info.Path)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	return names
+}
+
+func TestVolumeEntryHealInfo(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(v.Bricks) == 3)
+
+	// One brick with pending entries, one up to date and one down
+	names := healTestBrickNames(t, app, v)
+	pending, healthy := v.Bricks[0], v.Bricks[1]
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		tests.Assert(t, volume == v.Info.Name)
+		var bricks executors.HealInfoBricks
+		bricks.BrickList = []executors.BrickHealStatus{
+			{Name: names[pending], Status: "Connected", NumberOfEntries: "12"},
+			{Name: names[healthy], Status: "Connected", NumberOfEntries: "0"},
+			{
+				Name:            "information not available",
+				Status:          "Transport endpoint is not connected",
+				NumberOfEntries: "-",
+			},
+		}
+		return &executors.HealInfo{Bricks: bricks}, nil
+	}
+
+	info, err := v.HealInfo(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.PendingEntries == 12)
+	tests.Assert(t, len(info.Bricks) == 3)
+	tests.Assert(t, info.Bricks[0].Id == pending)
+	tests.Assert(t, info.Bricks[0].PendingEntries == 12)
+	tests.Assert(t, info.Bricks[0].Status == "Connected")
+	tests.Assert(t, info.Bricks[1].Id == healthy)
+	tests.Assert(t, info.Bricks[1].PendingEntries == 0)
+	tests.Assert(t, info.Bricks[2].Id == "")
+	tests.Assert(t, info.Bricks[2].PendingEntries == -1)
+
+	// Start a full heal
+	var full bool
+	app.xo.MockVolumeHeal = func(host string, volume string, f bool) error {
+		tests.Assert(t, volume == v.Info.Name)
+		full = f
+		return nil
+	}
+	err = v.Heal(app.db, app.executor, true)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, full)
+}
@@ -0,0 +1,260 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// Determines the durability of a volume created outside of heketi
+// from the brick counts gluster reports for it
+func importDurability(vinfo *executors.Volume) (api.VolumeDurabilityInfo, error) {
+	var durability api.VolumeDurabilityInfo
+
+	switch {
+	case vinfo.StripeCount > 1:
+		return durability, fmt.Errorf("Striped volume %v cannot be imported",
+			vinfo.VolumeName)
+
+	case vinfo.ArbiterCount > 0:
+		if vinfo.ReplicaCount != 3 || vinfo.ArbiterCount != 1 {
+			return durability, fmt.Errorf("Arbiter volume %v is not replica 3 "+
+				"with one arbiter and cannot be imported", vinfo.VolumeName)
+		}
+		durability.Type = api.DurabilityArbiter
+
+	case vinfo.DisperseCount > 0:
+		durability.Type = api.DurabilityEC
+		durability.Disperse.Data = vinfo.DisperseCount - vinfo.RedundancyCount
+		durability.Disperse.Redundancy = vinfo.RedundancyCount
+
+	case vinfo.ReplicaCount > 1:
+		durability.Type = api.DurabilityReplicate
+		durability.Replicate.Replica = vinfo.ReplicaCount
+
+	default:
+		durability.Type = api.DurabilityDistributeOnly
+	}
+
+	return durability, nil
+}
+
+// Finds the first of the clusters with a gluster volume called name
+func importFindVolume(db *bolt.DB,
+	executor executors.Executor,
+	name string,
+	clusters []string) (string, *executors.Volume, error) {
+
+	for _, clusterId := range clusters {
+		host, err := GetVerifiedManageHostname(db, executor, clusterId)
+		if err != nil {
+			logger.Warning("Unable to look for volume %v in cluster %v: %v",
+				name, clusterId, err)
+			continue
+		}
+
+		vinfo, err := executor.VolumeInfo(host, name)
+		if err != nil {
+			logger.Debug("Volume %v not found in cluster %v: %v",
+				name, clusterId, err)
+			continue
+		}
+
+		return clusterId, vinfo, nil
+	}
+
+	return "", nil, fmt.Errorf("Volume %v not found in clusters %v",
+		name, clusters)
+}
+
+// ImportVolume adopts the gluster volume called name, created outside of
+// heketi in one of the clusters. Every brick of the volume must be on its
+// own logical volume in the volume group of a device heketi manages. The
+// space of the bricks is taken from their devices.
+func ImportVolume(db *bolt.DB,
+	executor executors.Executor,
+	name string,
+	clusters []string) (*VolumeEntry, error) {
+
+	logger.Info("Importing volume %v", name)
+
+	clusterId, vinfo, err := importFindVolume(db, executor, name, clusters)
+	if err != nil {
+		return nil, err
+	}
+
+	durability, err := importDurability(vinfo)
+	if err != nil {
+		return nil, err
+	}
+	req := &api.VolumeCreateRequest{
+		Name:       name,
+		Durability: durability,
+	}
+	for _, option := range vinfo.Options.OptionList {
+		req.GlusterVolumeOptions = append(req.GlusterVolumeOptions,
+			option.Name+" "+option.Value)
+	}
+	v := NewVolumeEntryFromRequest(req)
+	v.Info.Cluster = clusterId
+	v.Info.Stopped = vinfo.Status != 1
+
+	setSize := v.Durability.BricksInSet()
+	if len(vinfo.Bricks.BrickList) == 0 ||
+		len(vinfo.Bricks.BrickList)%setSize != 0 {
+		return nil, fmt.Errorf("Volume %v has %v bricks, which is not a "+
+			"multiple of %v", name, len(vinfo.Bricks.BrickList), setSize)
+	}
+
+	// Gluster names bricks by the storage or manage host of their node
+	nodes := make(map[string]*NodeEntry)
+	err = db.View(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return err
+		}
+
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			for _, host := range node.Info.Hostnames.Storage {
+				nodes[host] = node
+			}
+			for _, host := range node.Info.Hostnames.Manage {
+				nodes[host] = node
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the logical volume and device of each brick
+	var brick_entries []*BrickEntry
+	pools := make(map[string]bool)
+	for _, brick := range vinfo.Bricks.BrickList {
+		sep := strings.LastIndex(brick.Name, ":")
+		host, path := brick.Name[:sep], brick.Name[sep+1:]
+
+		node, ok := nodes[host]
+		if !ok {
+			return nil, fmt.Errorf("Brick %v of volume %v is not on a node "+
+				"of cluster %v", brick.Name, name, clusterId)
+		}
+
+		lv, err := executor.BrickLvInfo(node.ManageHostName(), path)
+		if err != nil {
+			return nil, err
+		}
+		if lv.VgId == "" || !utils.SortedStringHas(node.Devices, lv.VgId) {
+			return nil, fmt.Errorf("Brick %v of volume %v is on volume group "+
+				"%v, which is not a device of node %v",
+				brick.Name, name, lv.VgName, node.Info.Id)
+		}
+
+		// Heketi accounts the thin pool to the brick, so each brick
+		// needs a thin pool of its own
+		if lv.PoolName != "" {
+			pool := node.Info.Id + ":" + lv.VgName + "/" + lv.PoolName
+			if pools[pool] {
+				return nil, fmt.Errorf("Brick %v of volume %v shares thin "+
+					"pool %v with another brick", brick.Name, name, lv.PoolName)
+			}
+			pools[pool] = true
+		}
+
+		entry := NewBrickEntryFromImport(lv, path, node.Info.Id, v.Info.Id)
+		brick_entries = append(brick_entries, entry)
+		v.BrickAdd(entry.Info.Id)
+	}
+
+	// The size of a set is given by its first brick, which holds data
+	var size uint64
+	for i := 0; i < len(brick_entries); i += setSize {
+		size += v.Durability.SetSize(brick_entries[i].Info.Size)
+	}
+	v.Info.Size = int(size / GB)
+	if arbiter, ok := v.Durability.(*VolumeArbiterDurability); ok {
+		arbiter.Ratio = float32(brick_entries[ARBITER_BRICK_POSITION].Info.Size) /
+			float32(brick_entries[0].Info.Size)
+		v.Info.Durability.Arbiter.Ratio = arbiter.Ratio
+	}
+
+	err = v.setupMountInfo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return err
+		}
+
+		for _, volumeId := range cluster.Info.Volumes {
+			volume, err := NewVolumeEntryFromId(tx, volumeId)
+			if err != nil {
+				return err
+			}
+			if v.Info.Name == volume.Info.Name {
+				return fmt.Errorf("Name %v already in use in cluster %v",
+					v.Info.Name, clusterId)
+			}
+		}
+
+		for _, brick := range brick_entries {
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			if err != nil {
+				return err
+			}
+			if !device.StorageCheck(brick.TotalSize()) {
+				return fmt.Errorf("Device %v does not have the %v KB "+
+					"used by brick %v", device.Info.Id,
+					brick.TotalSize(), brick.Info.Path)
+			}
+			device.StorageAllocate(brick.TotalSize())
+			device.BrickAdd(brick.Info.Id)
+			err = device.Save(tx)
+			if err != nil {
+				return err
+			}
+
+			err = brick.Save(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = v.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		cluster.VolumeAdd(v.Info.Id)
+		return cluster.Save(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Imported volume %v as %v", name, v.Info.Id)
+
+	return v, nil
+}
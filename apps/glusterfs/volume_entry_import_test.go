@@ -0,0 +1,219 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+// Mocks a replica 3 gluster volume called imported with a 10 GB brick
+// on the first device of each node of the first cluster. Bricks live at
+// /bricks/<device id>/brick.
+func importTestMocks(t *testing.T, app *App) string {
+	var clusterId string
+	var bricks []executors.Brick
+	err := app.db.View(func(tx *bolt.Tx) error {
+		clusters, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		clusterId = clusters[0]
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			bricks = append(bricks, executors.Brick{
+				Name: fmt.Sprintf("%v:/bricks/%v/brick",
+					node.Info.Hostnames.Storage[0], node.Devices[0]),
+			})
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		if volume != "imported" {
+			return nil, fmt.Errorf("Volume %v does not exist", volume)
+		}
+		vinfo := &executors.Volume{
+			VolumeName:   volume,
+			Status:       1,
+			ReplicaCount: 3,
+		}
+		vinfo.Bricks.BrickList = bricks
+		vinfo.Options.OptionList = []executors.Option{
+			{Name: "performance.readdir-ahead", Value: "on"},
+		}
+		return vinfo, nil
+	}
+	app.xo.MockBrickLvInfo = func(host string, path string) (*executors.BrickLvInfo, error) {
+		vgId := strings.Split(path, "/")[2]
+		return &executors.BrickLvInfo{
+			VgId:             vgId,
+			VgName:           "vg_" + vgId,
+			LvName:           "lv_imported",
+			MountPoint:       "/bricks/" + vgId,
+			PoolName:         "pool_imported",
+			Size:             10 * GB,
+			PoolSize:         10 * GB,
+			PoolMetadataSize: 52 * MB,
+		}, nil
+	}
+
+	return clusterId
+}
+
+func TestImportVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+	clusterId := importTestMocks(t, app)
+
+	// Unknown volume
+	_, err = ImportVolume(app.db, app.executor, "unknown", []string{clusterId})
+	tests.Assert(t, err != nil)
+
+	v, err := ImportVolume(app.db, app.executor, "imported", []string{clusterId})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, v.Info.Name == "imported")
+	tests.Assert(t, v.Info.Cluster == clusterId)
+	tests.Assert(t, v.Info.Size == 10)
+	tests.Assert(t, v.Info.Durability.Type == api.DurabilityReplicate)
+	tests.Assert(t, v.Info.Durability.Replicate.Replica == 3)
+	tests.Assert(t, !v.Info.Stopped)
+	tests.Assert(t, len(v.GlusterVolumeOptions) == 1)
+	tests.Assert(t, v.GlusterVolumeOptions[0] == "performance.readdir-ahead on")
+	tests.Assert(t, len(v.Bricks) == 3)
+	tests.Assert(t, len(v.Info.Mount.GlusterFS.Hosts) == 3)
+
+	// The space of the bricks is taken from their devices
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.Bricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil, err)
+			tests.Assert(t, brick.Imported)
+			tests.Assert(t, brick.Info.VolumeId == v.Info.Id)
+			tests.Assert(t, brick.Info.Path == "/bricks/"+brick.Info.DeviceId+"/brick")
+
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			tests.Assert(t, err == nil, err)
+			tests.Assert(t, device.Info.Storage.Used == 10*GB+52*MB)
+			tests.Assert(t, device.Info.Storage.Free == 1*TB-10*GB-52*MB)
+			tests.Assert(t, len(device.Bricks) == 1)
+		}
+
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, len(cluster.Info.Volumes) == 1)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	// The volume cannot be imported twice
+	_, err = ImportVolume(app.db, app.executor, "imported", []string{clusterId})
+	tests.Assert(t, err != nil)
+
+	// Imported bricks are destroyed from their path
+	destroyed := 0
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, brick.Imported)
+		tests.Assert(t, strings.HasSuffix(brick.Path, "/brick"), brick.Path)
+		destroyed++
+		return nil
+	}
+	err = v.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, destroyed == 3)
+}
+
+func TestImportVolumeUnknownDevice(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+	clusterId := importTestMocks(t, app)
+
+	// Bricks on volume groups heketi did not create
+	app.xo.MockBrickLvInfo = func(host string, path string) (*executors.BrickLvInfo, error) {
+		return &executors.BrickLvInfo{
+			VgName: "rhgs",
+			LvName: "lv_imported",
+			Size:   10 * GB,
+		}, nil
+	}
+	_, err = ImportVolume(app.db, app.executor, "imported", []string{clusterId})
+	tests.Assert(t, err != nil)
+	tests.Assert(t, strings.Contains(err.Error(), "not a device"), err)
+
+	// Nothing was recorded
+	err = app.db.View(func(tx *bolt.Tx) error {
+		volumes, err := VolumeList(tx)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, len(volumes) == 0)
+		bricks, err := BrickList(tx)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, len(bricks) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+}
+
+func TestImportDurability(t *testing.T) {
+	d, err := importDurability(&executors.Volume{ReplicaCount: 1})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, d.Type == api.DurabilityDistributeOnly)
+
+	d, err = importDurability(&executors.Volume{ReplicaCount: 3, ArbiterCount: 1})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, d.Type == api.DurabilityArbiter)
+
+	d, err = importDurability(&executors.Volume{DisperseCount: 6, RedundancyCount: 2})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, d.Type == api.DurabilityEC)
+	tests.Assert(t, d.Disperse.Data == 4)
+	tests.Assert(t, d.Disperse.Redundancy == 2)
+
+	_, err = importDurability(&executors.Volume{StripeCount: 2})
+	tests.Assert(t, err != nil)
+}
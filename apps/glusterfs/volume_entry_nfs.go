@@ -0,0 +1,169 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+)
+
+const (
+	// Ganesha reserves export id 0 for the pseudo file system root
+	// and its sample configuration exports with id 1
+	nfsFirstExportId = 2
+
+	// Ganesha export ids are 16 bit
+	nfsMaxExportId = 65535
+)
+
+// Picks an export id not used by the other volumes of the cluster,
+// as every node of the cluster exports all of them.  The id is
+// recorded in the cluster entry, so volumes being created, which the
+// cluster does not list yet, never get the same id.
+func (v *VolumeEntry) allocNfsExportId(tx *bolt.Tx) (int, error) {
+	cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+	if err != nil {
+		return 0, err
+	}
+
+	used := make(map[int]bool)
+	for _, id := range cluster.Info.Volumes {
+		volume, err := NewVolumeEntryFromId(tx, id)
+		if err != nil {
+			return 0, err
+		}
+		used[volume.NfsExportId] = true
+	}
+
+	id := cluster.NfsLastExportId
+	for tries := nfsFirstExportId; tries <= nfsMaxExportId; tries++ {
+		id++
+		if id < nfsFirstExportId || id > nfsMaxExportId {
+			id = nfsFirstExportId
+		}
+		if used[id] {
+			continue
+		}
+
+		cluster.NfsLastExportId = id
+		err = cluster.Save(tx)
+		if err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	return 0, fmt.Errorf("No NFS export ids left in cluster %v", v.Info.Cluster)
+}
+
+// Exports the volume from the NFS-Ganesha server of every online node
+// of its cluster and sets the NFS mount information
+func (v *VolumeEntry) nfsExport(db *bolt.DB, executor executors.Executor) error {
+	var manageHosts, storageHosts []string
+	err := db.Update(func(tx *bolt.Tx) error {
+		var err error
+		v.NfsExportId, err = v.allocNfsExportId(tx)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			if !node.isOnline() {
+				continue
+			}
+			manageHosts = append(manageHosts, node.ManageHostName())
+			storageHosts = append(storageHosts, node.StorageHostName())
+		}
+
+		return nil
+	})
+	if err != nil {
+		v.NfsExportId = 0
+		return err
+	}
+	if len(manageHosts) == 0 {
+		v.NfsExportId = 0
+		return fmt.Errorf("No online nodes to export volume %v over NFS",
+			v.Info.Name)
+	}
+
+	logger.Info("Exporting volume %v over NFS with export id %v",
+		v.Info.Id, v.NfsExportId)
+	req := &executors.NfsExportRequest{
+		Volume:   v.Info.Name,
+		ExportId: v.NfsExportId,
+	}
+	for i, host := range manageHosts {
+		err := executor.VolumeNfsExport(host, req)
+		if err != nil {
+			for _, exported := range manageHosts[:i] {
+				executor.VolumeNfsUnexport(exported, v.Info.Name)
+			}
+			v.NfsExportId = 0
+			return err
+		}
+	}
+
+	v.Info.Mount.Nfs.Hosts = storageHosts
+	v.Info.Mount.Nfs.MountPoint = fmt.Sprintf("%v:/%v",
+		storageHosts[0], v.Info.Name)
+
+	return nil
+}
+
+// Removes the NFS export of the volume from the nodes of its cluster.
+// Failures are only logged, a node which cannot be reached keeps a
+// stale export of a volume that no longer exists.
+func (v *VolumeEntry) nfsUnexport(db *bolt.DB, executor executors.Executor) {
+	var hosts []string
+	err := db.View(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			hosts = append(hosts, node.ManageHostName())
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.LogError("Unable to find the nodes exporting volume %v: %v",
+			v.Info.Id, err)
+		return
+	}
+
+	logger.Info("Removing NFS export of volume %v", v.Info.Id)
+	for _, host := range hosts {
+		err := executor.VolumeNfsUnexport(host, v.Info.Name)
+		if err != nil {
+			logger.LogError("Unable to remove NFS export of volume %v "+
+				"on host %v: %v", v.Info.Id, host, err)
+		}
+	}
+
+	v.NfsExportId = 0
+	v.Info.Mount.Nfs.Hosts = nil
+	v.Info.Mount.Nfs.MountPoint = ""
+}
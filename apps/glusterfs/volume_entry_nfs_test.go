@@ -0,0 +1,216 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeEntryCreateNfsExport(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	exports := make(map[string]int)
+	app.xo.MockVolumeNfsExport = func(host string, export *executors.NfsExportRequest) error {
+		exports[host+"/"+export.Volume] = export.ExportId
+		return nil
+	}
+	unexported := 0
+	app.xo.MockVolumeNfsUnexport = func(host string, volume string) error {
+		delete(exports, host+"/"+volume)
+		unexported++
+		return nil
+	}
+
+	// Volumes are not exported unless requested
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(exports) == 0)
+	tests.Assert(t, v.NfsExportId == 0)
+
+	// Every node exports the volume with the same id
+	v1 := createSampleReplicaVolumeEntry(100, 3)
+	v1.Info.NfsExport = true
+	err = v1.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, v1.NfsExportId == nfsFirstExportId)
+	tests.Assert(t, len(exports) == 3, exports)
+	for _, id := range exports {
+		tests.Assert(t, id == nfsFirstExportId)
+	}
+	tests.Assert(t, len(v1.Info.Mount.Nfs.Hosts) == 3)
+	tests.Assert(t, strings.HasSuffix(v1.Info.Mount.Nfs.MountPoint, ":/"+v1.Info.Name),
+		v1.Info.Mount.Nfs.MountPoint)
+
+	// The export is kept in the db
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v1.Info.Id)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, entry.NfsExportId == v1.NfsExportId)
+
+		info, err := entry.NewInfoResponse(tx)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, info.NfsExport)
+		tests.Assert(t, info.Mount.Nfs.MountPoint == v1.Info.Mount.Nfs.MountPoint)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	// Export ids are unique in the cluster
+	v2 := createSampleReplicaVolumeEntry(100, 3)
+	v2.Info.NfsExport = true
+	err = v2.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, v2.NfsExportId == nfsFirstExportId+1)
+	tests.Assert(t, len(exports) == 6, exports)
+
+	// Deleting the volume removes the export from every node
+	err = v1.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, unexported == 3)
+	tests.Assert(t, len(exports) == 3, exports)
+
+	// Volumes which are not exported are not unexported
+	err = v.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, unexported == 3)
+}
+
+func TestVolumeEntryNfsExportIdAlloc(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	// Volumes being created are not listed by the cluster yet, but
+	// still get their own export id
+	var ids []int
+	for i := 0; i < 2; i++ {
+		err = app.db.Update(func(tx *bolt.Tx) error {
+			id, err := v.allocNfsExportId(tx)
+			ids = append(ids, id)
+			return err
+		})
+		tests.Assert(t, err == nil, err)
+	}
+	tests.Assert(t, ids[0] == nfsFirstExportId, ids)
+	tests.Assert(t, ids[1] == nfsFirstExportId+1, ids)
+
+	// Ids used by the volumes are skipped when the ids wrap around
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		v.NfsExportId = nfsFirstExportId
+		err := v.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		cluster.NfsLastExportId = nfsMaxExportId
+		err = cluster.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		id, err := v.allocNfsExportId(tx)
+		tests.Assert(t, id == nfsFirstExportId+1, id)
+		return err
+	})
+	tests.Assert(t, err == nil, err)
+}
+
+func TestVolumeEntryCreateNfsExportFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// The second node fails to export the volume
+	exported := 0
+	app.xo.MockVolumeNfsExport = func(host string, export *executors.NfsExportRequest) error {
+		if exported == 1 {
+			return errors.New("nfs-ganesha is not running")
+		}
+		exported++
+		return nil
+	}
+	unexported := 0
+	app.xo.MockVolumeNfsUnexport = func(host string, volume string) error {
+		unexported++
+		return nil
+	}
+	destroyed := false
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		destroyed = true
+		return nil
+	}
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.NfsExport = true
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, unexported == 1, unexported)
+	tests.Assert(t, destroyed)
+
+	// Nothing is left in the db
+	err = app.db.View(func(tx *bolt.Tx) error {
+		volumes, err := VolumeList(tx)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, len(volumes) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+}
@@ -0,0 +1,120 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// Returns true when the volume restricts where its bricks are placed
+func (v *VolumeEntry) hasPlacementConstraints() bool {
+	p := &v.Info.Placement
+	return len(p.Zones) > 0 || len(p.ExcludeZones) > 0 ||
+		len(p.Nodes) > 0 || len(p.ExcludeNodes) > 0 ||
+		len(p.Devices) > 0 || len(p.ExcludeDevices) > 0
+}
+
+// Checks that id is included, or nothing is, and that it is not excluded
+func placementAllowsId(include, exclude []string, id string) bool {
+	for _, excluded := range exclude {
+		if id == excluded {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, included := range include {
+		if id == included {
+			return true
+		}
+	}
+	return false
+}
+
+func placementAllowsZone(include, exclude []int, zone int) bool {
+	for _, excluded := range exclude {
+		if zone == excluded {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, included := range include {
+		if zone == included {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that the placement constraints of the volume allow
+// a brick on the device
+func (v *VolumeEntry) placementAllows(tx *bolt.Tx, device *DeviceEntry) (bool, error) {
+	p := &v.Info.Placement
+	if !placementAllowsId(p.Devices, p.ExcludeDevices, device.Info.Id) ||
+		!placementAllowsId(p.Nodes, p.ExcludeNodes, device.NodeId) {
+		return false, nil
+	}
+
+	// Only load the node when the zone matters
+	if len(p.Zones) == 0 && len(p.ExcludeZones) == 0 {
+		return true, nil
+	}
+	node, err := NewNodeEntryFromId(tx, device.NodeId)
+	if err != nil {
+		return false, err
+	}
+
+	return placementAllowsZone(p.Zones, p.ExcludeZones, node.Info.Zone), nil
+}
+
+// Checks the placement constraints of a request only name nodes and
+// devices which exist, and do not both include and exclude them
+func validatePlacement(tx *bolt.Tx, p *api.PlacementConstraints) error {
+	for _, id := range append(p.Nodes, p.ExcludeNodes...) {
+		_, err := NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			return fmt.Errorf("Node id %v in placement constraints not found", id)
+		} else if err != nil {
+			return err
+		}
+	}
+	for _, id := range append(p.Devices, p.ExcludeDevices...) {
+		_, err := NewDeviceEntryFromId(tx, id)
+		if err == ErrNotFound {
+			return fmt.Errorf("Device id %v in placement constraints not found", id)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range p.Nodes {
+		if !placementAllowsId(nil, p.ExcludeNodes, id) {
+			return fmt.Errorf("Node %v is both included and excluded", id)
+		}
+	}
+	for _, id := range p.Devices {
+		if !placementAllowsId(nil, p.ExcludeDevices, id) {
+			return fmt.Errorf("Device %v is both included and excluded", id)
+		}
+	}
+	for _, zone := range p.Zones {
+		if !placementAllowsZone(nil, p.ExcludeZones, zone) {
+			return fmt.Errorf("Zone %v is both included and excluded", zone)
+		}
+	}
+
+	return nil
+}
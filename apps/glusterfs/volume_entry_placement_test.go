@@ -0,0 +1,197 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+)
+
+func TestVolumeEntryCreateWithPlacement(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	// Nodes alternate between zones 0 and 1
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		6,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	var zone0, zone1, devices []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range EntryKeys(tx, BOLTDB_BUCKET_NODE) {
+			node, err := NewNodeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if node.Info.Zone == 0 {
+				zone0 = append(zone0, id)
+			} else {
+				zone1 = append(zone1, id)
+			}
+		}
+		for _, id := range zone1 {
+			node, err := NewNodeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			devices = append(devices, node.Devices[0])
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(zone0) == 3)
+	tests.Assert(t, len(zone1) == 3)
+
+	brickPlacement := func(v *VolumeEntry) (nodes, devices map[string]bool) {
+		nodes = map[string]bool{}
+		devices = map[string]bool{}
+		err := app.db.View(func(tx *bolt.Tx) error {
+			for _, brickId := range v.Bricks {
+				brick, err := NewBrickEntryFromId(tx, brickId)
+				if err != nil {
+					return err
+				}
+				nodes[brick.Info.NodeId] = true
+				devices[brick.Info.DeviceId] = true
+			}
+			return nil
+		})
+		tests.Assert(t, err == nil, err)
+		return
+	}
+
+	// Only zone 0
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Placement.Zones = []int{0}
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	nodes, _ := brickPlacement(v)
+	for _, id := range zone0 {
+		tests.Assert(t, nodes[id], "brick missing on node", id)
+	}
+	tests.Assert(t, len(nodes) == 3)
+
+	// Excluding zone 0 is the same as only zone 1
+	v = createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Placement.ExcludeZones = []int{0}
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	nodes, _ = brickPlacement(v)
+	for _, id := range zone1 {
+		tests.Assert(t, nodes[id], "brick missing on node", id)
+	}
+	tests.Assert(t, len(nodes) == 3)
+
+	// Only the listed devices
+	v = createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Placement.Devices = devices
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	_, used := brickPlacement(v)
+	for _, id := range devices {
+		tests.Assert(t, used[id], "brick missing on device", id)
+	}
+	tests.Assert(t, len(used) == 3)
+
+	// Two nodes cannot hold a replica 3 set
+	v = createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Placement.Zones = []int{1}
+	v.Info.Placement.ExcludeNodes = zone1[:1]
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrPlacement, err)
+
+	// Nor can the given nodes hold the volume
+	v = createSampleReplicaVolumeEntry(2000, 3)
+	v.Info.Placement.Nodes = zone0
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrPlacement, err)
+
+	// Without constraints there is plain lack of space
+	v = createSampleReplicaVolumeEntry(5000, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+
+	// As there is when the constraints allow every device
+	v = createSampleReplicaVolumeEntry(5000, 3)
+	v.Info.Placement.Zones = []int{0, 1}
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+
+	// The brick limits are reported when the constraints did not matter
+	v = createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Placement.Zones = []int{0, 1}
+	v.Info.BrickLimits.MaxSize = 10
+	v.Info.BrickLimits.MaxNum = 3
+	var clusters []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		clusters, err = ClusterList(tx)
+		return err
+	})
+	tests.Assert(t, err == nil, err)
+	_, err = v.allocBricksInCluster(app.db, app.allocator, clusters[0], v.Info.Size)
+	tests.Assert(t, err == ErrMaxBricks, err)
+}
+
+func TestValidatePlacement(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		nodes := EntryKeys(tx, BOLTDB_BUCKET_NODE)
+		devices := EntryKeys(tx, BOLTDB_BUCKET_DEVICE)
+
+		v := NewVolumeEntry()
+		p := &v.Info.Placement
+		tests.Assert(t, validatePlacement(tx, p) == nil)
+
+		p.Nodes = nodes[:2]
+		p.ExcludeDevices = devices[:1]
+		p.Zones = []int{0, 1}
+		tests.Assert(t, validatePlacement(tx, p) == nil)
+
+		p.ExcludeNodes = []string{"123"}
+		tests.Assert(t, validatePlacement(tx, p) != nil)
+
+		p.ExcludeNodes = nodes[1:]
+		tests.Assert(t, validatePlacement(tx, p) != nil)
+
+		p.ExcludeNodes = nil
+		p.Devices = devices[:1]
+		tests.Assert(t, validatePlacement(tx, p) != nil)
+
+		p.Devices = nil
+		p.ExcludeZones = []int{1}
+		tests.Assert(t, validatePlacement(tx, p) != nil)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+}
@@ -0,0 +1,172 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+var (
+	// Returned to roll back the transaction a plan was made in
+	errPlanRollback = errors.New("Volume plan rolled back")
+)
+
+// Runs all the database accesses of a brick allocation in a single
+// transaction, so that the changes can be thrown away afterwards
+type planDb struct {
+	tx *bolt.Tx
+}
+
+func (p *planDb) Update(fn func(*bolt.Tx) error) error {
+	return fn(p.tx)
+}
+
+func (p *planDb) View(fn func(*bolt.Tx) error) error {
+	return fn(p.tx)
+}
+
+// Plan returns where the bricks of the volume would be placed if it was
+// created now. Nothing is saved, the executor is not used and the volume
+// entry is not changed.
+func (v *VolumeEntry) Plan(db *bolt.DB,
+	allocator Allocator) (*api.VolumePlanResponse, error) {
+
+	return v.plan(db, func(pdb allocDb, vol *VolumeEntry) ([]*BrickEntry, error) {
+		clusters, err := vol.availableClusters(pdb)
+		if err != nil {
+			return nil, err
+		}
+		return vol.allocBricksInClusters(pdb, allocator, clusters)
+	})
+}
+
+// PlanExpand returns where the bricks added by expanding the volume by
+// sizeGB would be placed. Like Plan, it does not change anything.
+func (v *VolumeEntry) PlanExpand(db *bolt.DB,
+	allocator Allocator,
+	sizeGB int) (*api.VolumePlanResponse, error) {
+
+	return v.plan(db, func(pdb allocDb, vol *VolumeEntry) ([]*BrickEntry, error) {
+		return vol.allocBricksInCluster(pdb, allocator, vol.Info.Cluster, sizeGB)
+	})
+}
+
+// PlanExpandInPlace returns how the bricks of the volume would be
+// grown to expand it by sizeGB, or where new bricks would be placed
+// when they cannot be grown, like ExpandInPlace does. Like Plan, it
+// does not change anything.
+func (v *VolumeEntry) PlanExpandInPlace(db *bolt.DB,
+	allocator Allocator,
+	sizeGB int) (*api.VolumePlanResponse, error) {
+
+	var plan *api.VolumePlanResponse
+	err := db.Update(func(tx *bolt.Tx) error {
+		growths, err := v.reserveBrickGrowth(tx, allocator.BrickLimits(), sizeGB)
+		if err != nil {
+			return err
+		}
+
+		brick_entries := make([]*BrickEntry, 0, len(growths))
+		for _, g := range growths {
+			g.brick.Info.Size = g.size
+			brick_entries = append(brick_entries, g.brick)
+		}
+		plan, err = v.newPlanResponse(tx, brick_entries)
+		if err != nil {
+			return err
+		}
+		plan.InPlace = true
+
+		return errPlanRollback
+	})
+	if err == errNoInPlaceGrowth {
+		return v.PlanExpand(db, allocator, sizeGB)
+	} else if err != errPlanRollback {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// Allocates bricks on a copy of the volume in a transaction which is
+// always rolled back. Errors allocating the bricks make the plan not
+// feasible, all the others are returned.
+func (v *VolumeEntry) plan(db *bolt.DB,
+	alloc func(pdb allocDb, vol *VolumeEntry) ([]*BrickEntry, error)) (*api.VolumePlanResponse, error) {
+
+	buffer, err := v.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	vol := NewVolumeEntry()
+	err = vol.Unmarshal(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *api.VolumePlanResponse
+	err = db.Update(func(tx *bolt.Tx) error {
+		brick_entries, err := alloc(&planDb{tx: tx}, vol)
+		if err != nil {
+			plan = &api.VolumePlanResponse{
+				Reason:    err.Error(),
+				BrickSets: make([][]api.BrickPlan, 0),
+			}
+			return errPlanRollback
+		}
+
+		plan, err = vol.newPlanResponse(tx, brick_entries)
+		if err != nil {
+			return err
+		}
+
+		return errPlanRollback
+	})
+	if err != errPlanRollback {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (v *VolumeEntry) newPlanResponse(tx *bolt.Tx,
+	brick_entries []*BrickEntry) (*api.VolumePlanResponse, error) {
+
+	plan := &api.VolumePlanResponse{
+		Feasible:  true,
+		Cluster:   v.Info.Cluster,
+		BrickSets: make([][]api.BrickPlan, 0),
+	}
+
+	// Bricks are allocated one set after the other
+	bricksInSet := v.Durability.BricksInSet()
+	for i, brick := range brick_entries {
+		node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+		if err != nil {
+			return nil, err
+		}
+
+		if i%bricksInSet == 0 {
+			plan.BrickSets = append(plan.BrickSets, make([]api.BrickPlan, 0, bricksInSet))
+		}
+		set := len(plan.BrickSets) - 1
+		plan.BrickSets[set] = append(plan.BrickSets[set], api.BrickPlan{
+			DeviceId: brick.Info.DeviceId,
+			NodeId:   brick.Info.NodeId,
+			Host:     node.StorageHostName(),
+			Size:     brick.Info.Size,
+		})
+	}
+
+	return plan, nil
+}
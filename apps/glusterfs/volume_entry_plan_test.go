@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+// Returns the number of bricks in the db and the free space of all devices
+func planTestDbUsage(t *testing.T, app *App) (int, uint64) {
+	var bricks int
+	var free uint64
+	err := app.db.View(func(tx *bolt.Tx) error {
+		bricks = len(EntryKeys(tx, BOLTDB_BUCKET_BRICK))
+		for _, id := range EntryKeys(tx, BOLTDB_BUCKET_DEVICE) {
+			device, err := NewDeviceEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			free += device.Info.Storage.Free
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	return bricks, free
+}
+
+func checkPlanBrickSets(t *testing.T, plan *api.VolumePlanResponse, bricksInSet int) {
+	tests.Assert(t, plan.Feasible, plan.Reason)
+	tests.Assert(t, plan.Reason == "")
+	tests.Assert(t, len(plan.BrickSets) > 0)
+	for _, set := range plan.BrickSets {
+		tests.Assert(t, len(set) == bricksInSet, set)
+
+		nodes := make(map[string]bool)
+		for _, brick := range set {
+			tests.Assert(t, brick.DeviceId != "")
+			tests.Assert(t, brick.Host != "")
+			tests.Assert(t, brick.Size > 0)
+			tests.Assert(t, !nodes[brick.NodeId], "bricks of a set on the same node")
+			nodes[brick.NodeId] = true
+		}
+	}
+}
+
+func TestVolumeEntryPlan(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		2,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	bricks, free := planTestDbUsage(t, app)
+	tests.Assert(t, bricks == 0)
+
+	v := createSampleReplicaVolumeEntry(200, 3)
+	plan, err := v.Plan(app.db, app.allocator)
+	tests.Assert(t, err == nil, err)
+	checkPlanBrickSets(t, plan, 3)
+	tests.Assert(t, plan.Cluster != "")
+
+	// Nothing changed
+	tests.Assert(t, v.Info.Cluster == "")
+	tests.Assert(t, len(v.Bricks) == 0)
+	newBricks, newFree := planTestDbUsage(t, app)
+	tests.Assert(t, newBricks == 0)
+	tests.Assert(t, newFree == free)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == ErrNotFound)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// Too large for any of the clusters
+	v = createSampleReplicaVolumeEntry(5000, 3)
+	plan, err = v.Plan(app.db, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !plan.Feasible)
+	tests.Assert(t, plan.Reason == ErrNoSpace.Error(), plan.Reason)
+	tests.Assert(t, plan.Cluster == "")
+	tests.Assert(t, len(plan.BrickSets) == 0)
+	newBricks, newFree = planTestDbUsage(t, app)
+	tests.Assert(t, newBricks == 0)
+	tests.Assert(t, newFree == free)
+}
+
+func TestVolumeEntryPlanExpand(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		2,      // clusters
+		4,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 2)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	volumeBricks := len(v.Bricks)
+
+	bricks, free := planTestDbUsage(t, app)
+	tests.Assert(t, bricks == volumeBricks)
+
+	plan, err := v.PlanExpand(app.db, app.allocator, 300)
+	tests.Assert(t, err == nil, err)
+	checkPlanBrickSets(t, plan, 2)
+
+	// The new bricks are in the cluster of the volume
+	tests.Assert(t, plan.Cluster == v.Info.Cluster)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, set := range plan.BrickSets {
+			for _, brick := range set {
+				node, err := NewNodeEntryFromId(tx, brick.NodeId)
+				tests.Assert(t, err == nil, err)
+				tests.Assert(t, node.Info.ClusterId == v.Info.Cluster)
+			}
+		}
+
+		// The volume is unchanged
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, len(entry.Bricks) == volumeBricks)
+		tests.Assert(t, entry.Info.Size == 100)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(v.Bricks) == volumeBricks)
+
+	newBricks, newFree := planTestDbUsage(t, app)
+	tests.Assert(t, newBricks == bricks)
+	tests.Assert(t, newFree == free)
+
+	// Not enough space left in the cluster of the volume
+	plan, err = v.PlanExpand(app.db, app.allocator, 5000)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !plan.Feasible)
+	tests.Assert(t, plan.Reason != "")
+}
@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// QuotaEnable enables quota on the volume, after which limits
+// can be set on its directories
+func (v *VolumeEntry) QuotaEnable(db *bolt.DB, executor executors.Executor) error {
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	err = executor.VolumeQuotaEnable(host, v.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	v.Info.Quota.Enabled = true
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// QuotaDisable disables quota on the volume. Gluster drops all the
+// limits of the volume, so they are forgotten as well.
+func (v *VolumeEntry) QuotaDisable(db *bolt.DB, executor executors.Executor) error {
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	err = executor.VolumeQuotaDisable(host, v.Info.Name)
+	if err != nil {
+		return err
+	}
+
+	v.Info.Quota.Enabled = false
+	v.Info.Quota.Limits = nil
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// SetQuotaLimits removes the limits of the paths in remove and then sets
+// the limits in set. Every limit gluster accepted is recorded, even when
+// a later one fails.
+func (v *VolumeEntry) SetQuotaLimits(db *bolt.DB,
+	executor executors.Executor,
+	set []api.QuotaLimit,
+	remove []string) (e error) {
+
+	if !v.Info.Quota.Enabled {
+		return ErrQuotaDisabled
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := db.Update(func(tx *bolt.Tx) error {
+			return v.Save(tx)
+		})
+		if e == nil {
+			e = err
+		}
+	}()
+
+	for _, path := range remove {
+		err := executor.VolumeQuotaLimitRemove(host, v.Info.Name, path)
+		if err != nil {
+			return err
+		}
+		v.forgetQuotaLimit(path)
+	}
+
+	for _, limit := range set {
+		err := executor.VolumeQuotaLimitSet(host, v.Info.Name, &executors.QuotaLimit{
+			Path:      limit.Path,
+			HardLimit: limit.HardLimit,
+			SoftLimit: limit.SoftLimit,
+		})
+		if err != nil {
+			return err
+		}
+		v.forgetQuotaLimit(limit.Path)
+		v.Info.Quota.Limits = append(v.Info.Quota.Limits, limit)
+	}
+
+	return nil
+}
+
+func (v *VolumeEntry) forgetQuotaLimit(path string) {
+	limits := []api.QuotaLimit{}
+	for _, limit := range v.Info.Quota.Limits {
+		if limit.Path != path {
+			limits = append(limits, limit)
+		}
+	}
+	v.Info.Quota.Limits = limits
+}
+
+// QuotaUsage returns the recorded limits of the volume together with the
+// space gluster reports as used under each of them. The usage of a stopped
+// volume is not known.
+func (v *VolumeEntry) QuotaUsage(db *bolt.DB,
+	executor executors.Executor) (*api.VolumeQuotaResponse, error) {
+
+	usage := &api.VolumeQuotaResponse{
+		Enabled: v.Info.Quota.Enabled,
+		Limits:  make([]api.QuotaUsage, 0, len(v.Info.Quota.Limits)),
+	}
+
+	current := make(map[string]executors.QuotaUsage)
+	if v.Info.Quota.Enabled && !v.Info.Stopped && len(v.Info.Quota.Limits) > 0 {
+		host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := executor.VolumeQuotaList(host, v.Info.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range list.Limits {
+			current[u.Path] = u
+		}
+	}
+
+	for _, limit := range v.Info.Quota.Limits {
+		u := api.QuotaUsage{QuotaLimit: limit}
+		if c, ok := current[limit.Path]; ok {
+			u.Used = c.UsedSpace
+			u.Available = c.AvailSpace
+			u.SoftLimitExceeded = c.SoftLimitExceeded == "Yes"
+			u.HardLimitExceeded = c.HardLimitExceeded == "Yes"
+		}
+		usage.Limits = append(usage.Limits, u)
+	}
+
+	return usage, nil
+}
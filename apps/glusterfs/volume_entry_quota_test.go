@@ -0,0 +1,190 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+func quotaTestVolume(t *testing.T, app *App, id string) *VolumeEntry {
+	var v *VolumeEntry
+	err := app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		v, err = NewVolumeEntryFromId(tx, id)
+		return err
+	})
+	tests.Assert(t, err == nil, err)
+
+	return v
+}
+
+func TestVolumeEntryQuota(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Limits need quota to be enabled
+	err = v.SetQuotaLimits(app.db, app.executor,
+		[]api.QuotaLimit{{Path: "/data", HardLimit: 1024}}, nil)
+	tests.Assert(t, err == ErrQuotaDisabled, err)
+
+	err = v.QuotaEnable(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, quotaTestVolume(t, app, v.Info.Id).Info.Quota.Enabled)
+
+	set := map[string]executors.QuotaLimit{}
+	app.xo.MockVolumeQuotaLimitSet = func(host string, volume string, limit *executors.QuotaLimit) error {
+		tests.Assert(t, volume == v.Info.Name)
+		set[limit.Path] = *limit
+		return nil
+	}
+	var removed []string
+	app.xo.MockVolumeQuotaLimitRemove = func(host string, volume string, path string) error {
+		tests.Assert(t, volume == v.Info.Name)
+		removed = append(removed, path)
+		return nil
+	}
+
+	err = v.SetQuotaLimits(app.db, app.executor, []api.QuotaLimit{
+		{Path: "/data", HardLimit: 1024},
+		{Path: "/logs", HardLimit: 2048, SoftLimit: 70},
+	}, nil)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(set) == 2)
+	tests.Assert(t, set["/logs"].HardLimit == 2048)
+	tests.Assert(t, set["/logs"].SoftLimit == 70)
+
+	// Replace one limit and remove the other
+	err = v.SetQuotaLimits(app.db, app.executor,
+		[]api.QuotaLimit{{Path: "/data", HardLimit: 4096}},
+		[]string{"/logs"})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(removed) == 1 && removed[0] == "/logs")
+	entry := quotaTestVolume(t, app, v.Info.Id)
+	tests.Assert(t, len(entry.Info.Quota.Limits) == 1)
+	tests.Assert(t, entry.Info.Quota.Limits[0].Path == "/data")
+	tests.Assert(t, entry.Info.Quota.Limits[0].HardLimit == 4096)
+
+	// Limits accepted before a failure are kept
+	app.xo.MockVolumeQuotaLimitSet = func(host string, volume string, limit *executors.QuotaLimit) error {
+		if limit.Path == "/bad" {
+			return errors.New("limit-usage failed")
+		}
+		return nil
+	}
+	err = v.SetQuotaLimits(app.db, app.executor, []api.QuotaLimit{
+		{Path: "/home", HardLimit: 1024},
+		{Path: "/bad", HardLimit: 1024},
+	}, nil)
+	tests.Assert(t, err != nil)
+	entry = quotaTestVolume(t, app, v.Info.Id)
+	tests.Assert(t, len(entry.Info.Quota.Limits) == 2, entry.Info.Quota.Limits)
+	tests.Assert(t, entry.Info.Quota.Limits[1].Path == "/home")
+
+	// Disabling quota drops the limits
+	err = v.QuotaDisable(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	entry = quotaTestVolume(t, app, v.Info.Id)
+	tests.Assert(t, !entry.Info.Quota.Enabled)
+	tests.Assert(t, len(entry.Info.Quota.Limits) == 0)
+}
+
+func TestVolumeEntryQuotaUsage(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	listed := 0
+	app.xo.MockVolumeQuotaList = func(host string, volume string) (*executors.QuotaList, error) {
+		listed++
+		return &executors.QuotaList{
+			Limits: []executors.QuotaUsage{
+				executors.QuotaUsage{
+					Path:              "/data",
+					HardLimit:         1024,
+					UsedSpace:         1024,
+					AvailSpace:        0,
+					SoftLimitExceeded: "Yes",
+					HardLimitExceeded: "Yes",
+				},
+			},
+		}, nil
+	}
+
+	// Nothing to ask gluster about without quota
+	usage, err := v.QuotaUsage(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !usage.Enabled)
+	tests.Assert(t, len(usage.Limits) == 0)
+	tests.Assert(t, listed == 0)
+
+	err = v.QuotaEnable(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	err = v.SetQuotaLimits(app.db, app.executor, []api.QuotaLimit{
+		{Path: "/data", HardLimit: 1024},
+		{Path: "/logs", HardLimit: 2048},
+	}, nil)
+	tests.Assert(t, err == nil, err)
+
+	usage, err = v.QuotaUsage(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, usage.Enabled)
+	tests.Assert(t, listed == 1)
+	tests.Assert(t, len(usage.Limits) == 2)
+	tests.Assert(t, usage.Limits[0].Path == "/data")
+	tests.Assert(t, usage.Limits[0].Used == 1024)
+	tests.Assert(t, usage.Limits[0].HardLimitExceeded)
+	tests.Assert(t, usage.Limits[1].Path == "/logs")
+	tests.Assert(t, usage.Limits[1].Used == 0)
+	tests.Assert(t, !usage.Limits[1].HardLimitExceeded)
+
+	app.xo.MockVolumeQuotaList = func(host string, volume string) (*executors.QuotaList, error) {
+		return nil, errors.New("quota list failed")
+	}
+	_, err = v.QuotaUsage(app.db, app.executor)
+	tests.Assert(t, err != nil)
+}
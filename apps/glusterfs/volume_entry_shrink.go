@@ -0,0 +1,292 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+var (
+	// Time to wait between checks of the data migration
+	// started by remove-brick
+	removeBrickStatusInterval = 10 * time.Second
+
+	// Time after which the data migration is given up and stopped
+	removeBrickTimeout = 12 * time.Hour
+)
+
+// Shrink removes whole brick sets from the end of the volume until
+// up to sizeGB have been removed.  The data on the bricks is migrated
+// to the remaining bricks before they are destroyed.
+func (v *VolumeEntry) Shrink(db *bolt.DB,
+	executor executors.Executor,
+	sizeGB int) (e error) {
+
+	if v.Info.Stopped {
+		return ErrVolumeStopped
+	}
+
+	// Try again to clean up bricks left over by an earlier shrink
+	if len(v.RemovedBricks) > 0 {
+		err := v.destroyRemovedBricks(db, executor)
+		if err != nil {
+			logger.Warning("Bricks removed from volume %v are left over: %v",
+				v.Info.Id, err)
+		}
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	// Gluster keeps the bricks of a set next to each other
+	// in the order in which they were added
+	vinfo, err := executor.VolumeInfo(host, v.Info.Name)
+	if err != nil {
+		return logger.Err(err)
+	}
+	brickNames := vinfo.Bricks.BrickList
+	inSet := v.Durability.BricksInSet()
+	if len(brickNames)%inSet != 0 {
+		return logger.LogError("Volume %v has %v bricks which is not a multiple of %v",
+			v.Info.Id, len(brickNames), inSet)
+	}
+
+	// Pick brick sets from the end, always leaving the first one
+	var (
+		brick_entries []*BrickEntry
+		bricks        []executors.BrickInfo
+		removedGB     int
+	)
+	for set := len(brickNames)/inSet - 1; set > 0; set-- {
+		var setEntries []*BrickEntry
+		var setBricks []executors.BrickInfo
+		for _, b := range brickNames[set*inSet : (set+1)*inSet] {
+			brick, err := v.getBrickEntryfromBrickName(db, b.Name)
+			if err != nil {
+				return logger.LogError("Unable to find brick %v of volume %v: %v",
+					b.Name, v.Info.Id, err)
+			}
+			setEntries = append(setEntries, brick)
+
+			hostPath := strings.SplitN(b.Name, ":", 2)
+			setBricks = append(setBricks, executors.BrickInfo{
+				Host: hostPath[0],
+				Path: hostPath[1],
+			})
+		}
+
+		setGB := int(v.Durability.SetSize(setEntries[0].Info.Size) / GB)
+		if removedGB+setGB > sizeGB {
+			break
+		}
+		removedGB += setGB
+		brick_entries = append(brick_entries, setEntries...)
+		bricks = append(bricks, setBricks...)
+	}
+	if len(brick_entries) == 0 {
+		return logger.LogError("Unable to shrink volume %v by %v GB without "+
+			"removing more space or the last brick set", v.Info.Id, sizeGB)
+	}
+
+	// Bricks with snapshots cannot be removed
+	err = v.checkBricksCanBeDestroyed(db, executor, brick_entries)
+	if err != nil {
+		return err
+	}
+
+	// Take the space off the block hosting volume so that no block
+	// volume is placed on it while the data is migrated
+	committed := false
+	if v.Info.Block {
+		err = v.changeBlockFreeSize(db, -removedGB)
+		if err == ErrNoSpace {
+			return logger.LogError("Unable to shrink volume %v by %v GB, block volumes "+
+				"leave only %v GB free", v.Info.Id, removedGB, v.Info.BlockInfo.FreeSize)
+		} else if err != nil {
+			return err
+		}
+
+		defer func() {
+			if e != nil && !committed {
+				err := v.changeBlockFreeSize(db, removedGB)
+				if err != nil {
+					logger.LogError("Unable to give back %v GB to block hosting "+
+						"volume %v: %v", removedGB, v.Info.Id, err)
+				}
+			}
+		}()
+	}
+
+	// Move the data off the bricks
+	logger.Info("Removing %v bricks from volume %v", len(bricks), v.Info.Id)
+	err = executor.VolumeRemoveBricks(host, v.Info.Name, bricks, "start")
+	if err != nil {
+		return err
+	}
+	err = waitForRemoveBricks(executor, host, v.Info.Name, bricks)
+	if err != nil {
+		executor.VolumeRemoveBricks(host, v.Info.Name, bricks, "stop")
+		return err
+	}
+	err = executor.VolumeRemoveBricks(host, v.Info.Name, bricks, "commit")
+	if err != nil {
+		return err
+	}
+	committed = true
+
+	// The bricks are no longer part of the volume, drop them from it
+	// right away.  Reload the volume, it may have changed during the
+	// migration.  The block hosting space was already taken off.
+	err = db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		volume.Info.Size -= removedGB
+		for _, brick := range brick_entries {
+			volume.BrickDelete(brick.Id())
+			volume.RemovedBricks = append(volume.RemovedBricks, brick.Id())
+		}
+		volume.RemovedBricks.Sort()
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+	if err != nil {
+		return logger.Err(err)
+	}
+
+	return v.destroyRemovedBricks(db, executor)
+}
+
+// Destroys the bricks removed from the volume by a shrink and gives
+// their space back to the devices.  Bricks which could not be
+// destroyed are tried again on the next shrink or when the volume
+// is destroyed.
+func (v *VolumeEntry) destroyRemovedBricks(db *bolt.DB,
+	executor executors.Executor) error {
+
+	var brick_entries []*BrickEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.RemovedBricks {
+			brick, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			brick_entries = append(brick_entries, brick)
+		}
+		return nil
+	})
+	if err != nil {
+		return logger.Err(err)
+	}
+
+	err = DestroyBricks(db, executor, brick_entries)
+	if err != nil {
+		return logger.LogError("Unable to delete bricks removed from volume %v: %v",
+			v.Info.Id, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		for _, brick := range brick_entries {
+			err := volume.removeBrickFromDb(tx, brick)
+			if err != nil {
+				return err
+			}
+			volume.RemovedBricks = utils.SortedStringsDelete(volume.RemovedBricks,
+				brick.Id())
+		}
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+	if err != nil {
+		return logger.Err(err)
+	}
+
+	return nil
+}
+
+// Changes the free space of the block hosting volume, reloaded from
+// the db, by deltaGB.  Returns ErrNoSpace if not enough space is free.
+func (v *VolumeEntry) changeBlockFreeSize(db *bolt.DB, deltaGB int) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		if volume.Info.BlockInfo.FreeSize+deltaGB < 0 {
+			*v = *volume
+			return ErrNoSpace
+		}
+
+		volume.Info.BlockInfo.FreeSize += deltaGB
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+}
+
+func waitForRemoveBricks(executor executors.Executor,
+	host, volume string,
+	bricks []executors.BrickInfo) error {
+
+	deadline := time.Now().Add(removeBrickTimeout)
+	for {
+		status, err := executor.VolumeRemoveBricksStatus(host, volume, bricks)
+		if err != nil {
+			return err
+		}
+
+		switch status.Aggregate.Status {
+		case executors.RemoveBrickCompleted:
+			logger.Info("Migrated %v files from the bricks of volume %v",
+				status.Aggregate.Files, volume)
+			return nil
+		case executors.RemoveBrickStopped, executors.RemoveBrickFailed:
+			return fmt.Errorf("Data migration from the bricks of volume %v %v "+
+				"with %v failures", volume, status.Aggregate.StatusStr,
+				status.Aggregate.Failures)
+		}
+
+		logger.Info("Migrating data from the bricks of volume %v: "+
+			"%v files, %v bytes moved", volume,
+			status.Aggregate.Files, status.Aggregate.Size)
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Data migration from the bricks of volume %v "+
+				"did not complete within %v", volume, removeBrickTimeout)
+		}
+		time.Sleep(removeBrickStatusInterval)
+	}
+}
@@ -0,0 +1,450 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+// Returns the gluster names of the given bricks
+func brickNamesFromIds(t *testing.T, db *bolt.DB, ids []string) []string {
+	var names []string
+	err := db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			brick, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			names = append(names,
+				fmt.Sprintf("%v:%v", node.Info.Hostnames.Storage[0], brick.Info.Path))
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	return names
+}
+
+// Creates a replica 2 volume with two brick sets of 100GB each and
+// makes VolumeInfo report the bricks in the order they were added.
+// Returns the ids of the bricks in the second set.
+func setupShrinkVolume(t *testing.T, app *App) (*VolumeEntry, []string) {
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Bricks are looked up by name, make them unique
+	app.xo.MockBrickCreate = func(host string,
+		brick *executors.BrickRequest) (*executors.BrickInfo, error) {
+		return &executors.BrickInfo{
+			Path: "/mockpath/" + brick.Name,
+		}, nil
+	}
+
+	v := createSampleReplicaVolumeEntry(100, 2)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	firstSet := v.BricksIds()
+	tests.Assert(t, len(firstSet) == 2)
+
+	err = v.Expand(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(v.Bricks) == 4)
+
+	var secondSet []string
+	for _, id := range v.BricksIds() {
+		if id != firstSet[0] && id != firstSet[1] {
+			secondSet = append(secondSet, id)
+		}
+	}
+
+	names := brickNamesFromIds(t, app.db, append(firstSet, secondSet...))
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		vinfo := &executors.Volume{}
+		for _, name := range names {
+			vinfo.Bricks.BrickList = append(vinfo.Bricks.BrickList,
+				executors.Brick{Name: name})
+		}
+		return vinfo, nil
+	}
+
+	return v, secondSet
+}
+
+func TestVolumeEntryShrink(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, secondSet := setupShrinkVolume(t, app)
+	removedNames := brickNamesFromIds(t, app.db, secondSet)
+
+	// Remember the devices of the bricks to be removed
+	deviceFree := make(map[string]uint64)
+	err := app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range secondSet {
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			tests.Assert(t, err == nil)
+			deviceFree[device.Info.Id] = device.Info.Storage.Free
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// Report the migration in progress once
+	var actions []string
+	app.xo.MockVolumeRemoveBricks = func(host string, volume string,
+		bricks []executors.BrickInfo, action string) error {
+
+		tests.Assert(t, volume == v.Info.Name)
+		tests.Assert(t, len(bricks) == 2)
+		for i, b := range bricks {
+			tests.Assert(t, b.Host+":"+b.Path == removedNames[i])
+		}
+		actions = append(actions, action)
+		return nil
+	}
+	checks := 0
+	app.xo.MockVolumeRemoveBricksStatus = func(host string, volume string,
+		bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+
+		checks++
+		status := &executors.RemoveBrickStatus{}
+		if checks == 1 {
+			status.Aggregate.Status = executors.RemoveBrickInProgress
+		} else {
+			status.Aggregate.Status = executors.RemoveBrickCompleted
+		}
+		return status, nil
+	}
+	destroyed := 0
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		destroyed++
+		return nil
+	}
+	defer tests.Patch(&removeBrickStatusInterval, time.Millisecond).Restore()
+
+	// Only whole brick sets can be removed
+	err = v.Shrink(app.db, app.executor, 50)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, len(actions) == 0)
+
+	err = v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, checks == 2)
+	tests.Assert(t, destroyed == 2)
+	tests.Assert(t, len(actions) == 2)
+	tests.Assert(t, actions[0] == "start")
+	tests.Assert(t, actions[1] == "commit")
+	tests.Assert(t, v.Info.Size == 100)
+	tests.Assert(t, len(v.Bricks) == 2)
+
+	// Check db
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Size == 100)
+		tests.Assert(t, len(entry.Bricks) == 2)
+
+		for _, id := range secondSet {
+			_, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == ErrNotFound)
+		}
+
+		for id, free := range deviceFree {
+			device, err := NewDeviceEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, device.Info.Storage.Free > free)
+			for _, brickId := range secondSet {
+				tests.Assert(t, !utils.SortedStringHas(device.Bricks, brickId))
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// The last brick set cannot be removed
+	err = v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+}
+
+func TestVolumeEntryShrinkBlockHostingVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, _ := setupShrinkVolume(t, app)
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		v.Info.Block = true
+		v.Info.BlockInfo.FreeSize = 150
+		return v.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	// The space is taken off before the data is migrated, and
+	// changes made meanwhile are kept
+	app.xo.MockVolumeRemoveBricksStatus = func(host string, volume string,
+		bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+
+		err := app.db.Update(func(tx *bolt.Tx) error {
+			entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, entry.Info.BlockInfo.FreeSize == 50,
+				entry.Info.BlockInfo.FreeSize)
+			entry.Info.BlockInfo.FreeSize -= 30
+			entry.BlockVolumeAdd("block1")
+			return entry.Save(tx)
+		})
+		tests.Assert(t, err == nil)
+
+		status := &executors.RemoveBrickStatus{}
+		status.Aggregate.Status = executors.RemoveBrickCompleted
+		return status, nil
+	}
+
+	err = v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err == nil, err)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Size == 100)
+		tests.Assert(t, entry.Info.BlockInfo.FreeSize == 20,
+			entry.Info.BlockInfo.FreeSize)
+		tests.Assert(t, len(entry.Info.BlockInfo.BlockVolumes) == 1)
+		tests.Assert(t, len(entry.Bricks) == 2)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestVolumeEntryShrinkBlockHostingVolumeFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, _ := setupShrinkVolume(t, app)
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		v.Info.Block = true
+		v.Info.BlockInfo.FreeSize = 150
+		return v.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+
+	app.xo.MockVolumeRemoveBricksStatus = func(host string, volume string,
+		bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+
+		status := &executors.RemoveBrickStatus{}
+		status.Aggregate.Status = executors.RemoveBrickFailed
+		return status, nil
+	}
+
+	// The space is given back when the bricks could not be removed
+	err = v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, v.Info.BlockInfo.FreeSize == 150, v.Info.BlockInfo.FreeSize)
+
+	// Space used by block volumes cannot be removed
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		v.Info.BlockInfo.FreeSize = 50
+		return v.Save(tx)
+	})
+	tests.Assert(t, err == nil)
+	err = v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, v.Info.BlockInfo.FreeSize == 50, v.Info.BlockInfo.FreeSize)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Size == 200)
+		tests.Assert(t, entry.Info.BlockInfo.FreeSize == 50)
+		tests.Assert(t, len(entry.Bricks) == 4)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestVolumeEntryShrinkMigrationFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, _ := setupShrinkVolume(t, app)
+
+	var actions []string
+	app.xo.MockVolumeRemoveBricks = func(host string, volume string,
+		bricks []executors.BrickInfo, action string) error {
+
+		actions = append(actions, action)
+		return nil
+	}
+	app.xo.MockVolumeRemoveBricksStatus = func(host string, volume string,
+		bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+
+		status := &executors.RemoveBrickStatus{}
+		status.Aggregate.Status = executors.RemoveBrickFailed
+		status.Aggregate.StatusStr = "failed"
+		return status, nil
+	}
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, false, "Bricks must not be destroyed")
+		return nil
+	}
+
+	err := v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, len(actions) == 2)
+	tests.Assert(t, actions[0] == "start")
+	tests.Assert(t, actions[1] == "stop")
+
+	// Nothing changed in the db
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Size == 200)
+		tests.Assert(t, len(entry.Bricks) == 4)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
+func TestVolumeEntryShrinkMigrationTimeout(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, _ := setupShrinkVolume(t, app)
+
+	var actions []string
+	app.xo.MockVolumeRemoveBricks = func(host string, volume string,
+		bricks []executors.BrickInfo, action string) error {
+
+		actions = append(actions, action)
+		return nil
+	}
+	app.xo.MockVolumeRemoveBricksStatus = func(host string, volume string,
+		bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+
+		status := &executors.RemoveBrickStatus{}
+		status.Aggregate.Status = executors.RemoveBrickInProgress
+		return status, nil
+	}
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		tests.Assert(t, false, "Bricks must not be destroyed")
+		return nil
+	}
+	defer tests.Patch(&removeBrickStatusInterval, time.Millisecond).Restore()
+	defer tests.Patch(&removeBrickTimeout, 10*time.Millisecond).Restore()
+
+	// A migration which never completes is stopped
+	err := v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, len(actions) == 2, actions)
+	tests.Assert(t, actions[0] == "start")
+	tests.Assert(t, actions[1] == "stop")
+}
+
+func TestVolumeEntryShrinkDestroyFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, secondSet := setupShrinkVolume(t, app)
+
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		return errors.New("TEST")
+	}
+
+	// The bricks are dropped from the volume, but the ones which
+	// could not be destroyed keep their space on the devices
+	err := v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, len(v.Bricks) == 2)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range secondSet {
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil, err)
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			tests.Assert(t, err == nil)
+			tests.Assert(t, utils.SortedStringHas(device.Bricks, id))
+		}
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, entry.Info.Size == 100)
+		tests.Assert(t, len(entry.Bricks) == 2)
+		tests.Assert(t, len(entry.RemovedBricks) == 2)
+		for _, id := range secondSet {
+			tests.Assert(t, utils.SortedStringHas(entry.RemovedBricks, id))
+			tests.Assert(t, !utils.SortedStringHas(entry.Bricks, id))
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil)
+
+	// The next shrink destroys them, even if it removes nothing else
+	destroyed := 0
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) error {
+		destroyed++
+		return nil
+	}
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		vinfo := &executors.Volume{}
+		for _, name := range brickNamesFromIds(t, app.db, v.BricksIds()) {
+			vinfo.Bricks.BrickList = append(vinfo.Bricks.BrickList,
+				executors.Brick{Name: name})
+		}
+		return vinfo, nil
+	}
+	err = v.Shrink(app.db, app.executor, 100)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, destroyed == 2, destroyed)
+	tests.Assert(t, len(v.RemovedBricks) == 0)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range secondSet {
+			_, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == ErrNotFound, err)
+		}
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, len(entry.RemovedBricks) == 0)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
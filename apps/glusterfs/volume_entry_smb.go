@@ -0,0 +1,215 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+)
+
+// Share name used when the request does not name the share
+func (v *VolumeEntry) defaultSmbShareName() string {
+	return "gluster-" + v.Info.Name
+}
+
+// Returns the share name requested, or the default one
+func (v *VolumeEntry) smbShareName(shareName string) string {
+	if shareName == "" {
+		return v.defaultSmbShareName()
+	}
+	return shareName
+}
+
+// Returns an error if another volume of the cluster is shared as
+// shareName.  Samba share names are case-insensitive.
+func (v *VolumeEntry) smbShareNameInUse(tx *bolt.Tx, shareName string) error {
+	cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range cluster.Info.Volumes {
+		if id == v.Info.Id {
+			continue
+		}
+		volume, err := NewVolumeEntryFromId(tx, id)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(volume.Info.Mount.Smb.ShareName, shareName) {
+			return fmt.Errorf("Share name %v already used by volume %v",
+				shareName, id)
+		}
+	}
+
+	return nil
+}
+
+// SmbShare shares the volume over SMB as shareName from the Samba
+// server of each of the nodes. When a node fails, the share is removed
+// from the nodes which already had it.  The share name is reserved
+// in the db before the nodes share the volume, so that concurrent
+// requests cannot use the same name.
+func (v *VolumeEntry) SmbShare(db *bolt.DB,
+	executor executors.Executor,
+	nodes []string,
+	shareName string) (e error) {
+
+	shareName = v.smbShareName(shareName)
+
+	var manageHosts, storageHosts []string
+	err := db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		if volume.Info.Mount.Smb.ShareName != "" {
+			return fmt.Errorf("Volume %v is already shared as %v",
+				volume.Info.Id, volume.Info.Mount.Smb.ShareName)
+		}
+		err = volume.smbShareNameInUse(tx, shareName)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range nodes {
+			node, err := NewNodeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			manageHosts = append(manageHosts, node.ManageHostName())
+			storageHosts = append(storageHosts, node.StorageHostName())
+		}
+
+		volume.Info.Mount.Smb.ShareName = shareName
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Release the name if the volume could not be shared
+	defer func() {
+		if e != nil {
+			err := db.Update(func(tx *bolt.Tx) error {
+				volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+				if err != nil {
+					return err
+				}
+				volume.Info.Mount.Smb.ShareName = ""
+				err = volume.Save(tx)
+				if err != nil {
+					return err
+				}
+
+				*v = *volume
+				return nil
+			})
+			if err != nil {
+				logger.LogError("Unable to release share name %v of volume %v: %v",
+					shareName, v.Info.Id, err)
+			}
+		}
+	}()
+
+	logger.Info("Sharing volume %v over SMB as %v", v.Info.Id, shareName)
+	req := &executors.SmbShareRequest{
+		Volume:    v.Info.Name,
+		ShareName: shareName,
+	}
+	for i, host := range manageHosts {
+		err := executor.VolumeSmbShare(host, req)
+		if err != nil {
+			for _, shared := range manageHosts[:i] {
+				executor.VolumeSmbUnshare(shared, shareName)
+			}
+			return err
+		}
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		volume.SmbNodes = nodes
+		volume.Info.Mount.Smb.Hosts = storageHosts
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+}
+
+// SmbUnshare removes the SMB share of the volume from every node
+// sharing it
+func (v *VolumeEntry) SmbUnshare(db *bolt.DB, executor executors.Executor) error {
+	err := v.smbUnshare(db, executor)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+}
+
+// Removes the share from each node sharing it. Every node is tried, the
+// share is only forgotten once it was removed from all of them.
+func (v *VolumeEntry) smbUnshare(db *bolt.DB, executor executors.Executor) error {
+	var hosts []string
+	err := db.View(func(tx *bolt.Tx) error {
+		for _, id := range v.SmbNodes {
+			node, err := NewNodeEntryFromId(tx, id)
+			if err == ErrNotFound {
+				logger.Warning("Node %v sharing volume %v no longer exists",
+					id, v.Info.Id)
+				continue
+			} else if err != nil {
+				return err
+			}
+			hosts = append(hosts, node.ManageHostName())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Removing SMB share %v of volume %v",
+		v.Info.Mount.Smb.ShareName, v.Info.Id)
+	var failed error
+	for _, host := range hosts {
+		err := executor.VolumeSmbUnshare(host, v.Info.Mount.Smb.ShareName)
+		if err != nil {
+			failed = err
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+
+	v.SmbNodes = nil
+	v.Info.Mount.Smb.ShareName = ""
+	v.Info.Mount.Smb.Hosts = nil
+	return nil
+}
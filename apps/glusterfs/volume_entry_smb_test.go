@@ -0,0 +1,193 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/tests"
+)
+
+// Returns the ids of the nodes of the cluster of the volume
+func smbTestNodes(t *testing.T, app *App, v *VolumeEntry) []string {
+	var nodes []string
+	err := app.db.View(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, cluster.Info.Nodes...)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	return nodes
+}
+
+func TestVolumeEntrySmbShare(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	nodes := smbTestNodes(t, app, v)
+
+	shares := make(map[string]string)
+	app.xo.MockVolumeSmbShare = func(host string, share *executors.SmbShareRequest) error {
+		tests.Assert(t, share.Volume == v.Info.Name)
+		shares[host] = share.ShareName
+		return nil
+	}
+	app.xo.MockVolumeSmbUnshare = func(host string, share string) error {
+		tests.Assert(t, shares[host] == share)
+		delete(shares, host)
+		return nil
+	}
+
+	// Share from two of the nodes with the default name
+	err = v.SmbShare(app.db, app.executor, nodes[:2], "")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(shares) == 2)
+	tests.Assert(t, v.Info.Mount.Smb.ShareName == "gluster-"+v.Info.Name)
+	tests.Assert(t, len(v.Info.Mount.Smb.Hosts) == 2)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, len(entry.SmbNodes) == 2)
+
+		info, err := entry.NewInfoResponse(tx)
+		tests.Assert(t, err == nil, err)
+		tests.Assert(t, info.Mount.Smb.ShareName == v.Info.Mount.Smb.ShareName)
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+
+	// Remove the share
+	err = v.SmbUnshare(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(shares) == 0)
+	tests.Assert(t, v.Info.Mount.Smb.ShareName == "")
+	tests.Assert(t, len(v.SmbNodes) == 0)
+
+	// Deleting the volume removes its share
+	err = v.SmbShare(app.db, app.executor, nodes, "projects")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(shares) == 3)
+	for _, name := range shares {
+		tests.Assert(t, name == "projects")
+	}
+	err = v.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(shares) == 0)
+}
+
+func TestVolumeEntrySmbShareFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	nodes := smbTestNodes(t, app, v)
+
+	// The last node fails, the others drop the share again
+	shared := 0
+	app.xo.MockVolumeSmbShare = func(host string, share *executors.SmbShareRequest) error {
+		if shared == 2 {
+			return errors.New("smbd is not running")
+		}
+		shared++
+		return nil
+	}
+	app.xo.MockVolumeSmbUnshare = func(host string, share string) error {
+		shared--
+		return nil
+	}
+
+	err = v.SmbShare(app.db, app.executor, nodes, "")
+	tests.Assert(t, err != nil)
+	tests.Assert(t, shared == 0, shared)
+	tests.Assert(t, v.Info.Mount.Smb.ShareName == "")
+	tests.Assert(t, len(v.SmbNodes) == 0)
+}
+
+func TestVolumeEntrySmbShareNameReserved(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	v2 := createSampleReplicaVolumeEntry(100, 3)
+	err = v2.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	nodes := smbTestNodes(t, app, v)
+
+	// The name is taken before the nodes share the volume, and
+	// names only differing in case are the same share
+	var concurrent error
+	app.xo.MockVolumeSmbShare = func(host string, share *executors.SmbShareRequest) error {
+		if concurrent == nil {
+			concurrent = v2.SmbShare(app.db, app.executor, nodes, "PROJECTS")
+		}
+		return nil
+	}
+	err = v.SmbShare(app.db, app.executor, nodes, "projects")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, concurrent != nil)
+	tests.Assert(t, v2.Info.Mount.Smb.ShareName == "")
+
+	// The volume cannot be shared twice
+	err = v.SmbShare(app.db, app.executor, nodes, "other")
+	tests.Assert(t, err != nil)
+	tests.Assert(t, v.Info.Mount.Smb.ShareName == "projects")
+	tests.Assert(t, len(v.SmbNodes) == 3)
+}
@@ -849,6 +849,98 @@ func TestVolumeEntryCreateMaxBrickSize(t *testing.T) {
 
 }
 
+func TestVolumeEntryCreateBrickLimits(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Limit the bricks of the cluster to 25GB
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		clusters, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		cluster, err := NewClusterEntryFromId(tx, clusters[0])
+		if err != nil {
+			return err
+		}
+		cluster.Info.BrickLimits.MaxSize = 25
+		return cluster.Save(tx)
+	})
+	tests.Assert(t, err == nil, err)
+
+	brickSizes := func(v *VolumeEntry) []uint64 {
+		var sizes []uint64
+		err := app.db.View(func(tx *bolt.Tx) error {
+			for _, id := range v.Bricks {
+				brick, err := NewBrickEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				sizes = append(sizes, brick.Info.Size)
+			}
+			return nil
+		})
+		tests.Assert(t, err == nil, err)
+		return sizes
+	}
+
+	// The cluster limit applies
+	v := createSampleReplicaVolumeEntry(100, 2)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	sizes := brickSizes(v)
+	tests.Assert(t, len(sizes) == 8, len(sizes))
+	for _, size := range sizes {
+		tests.Assert(t, size == 25*GB, size)
+	}
+
+	// The volume limit wins over the cluster limit
+	v = createSampleReplicaVolumeEntry(100, 2)
+	v.Info.BrickLimits.MaxSize = 100
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	sizes = brickSizes(v)
+	tests.Assert(t, len(sizes) == 2, len(sizes))
+	for _, size := range sizes {
+		tests.Assert(t, size == 100*GB, size)
+	}
+
+	// Too few bricks allowed
+	v = createSampleReplicaVolumeEntry(100, 2)
+	v.Info.BrickLimits.MaxNum = 4
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+
+	// Bricks would be too small
+	v = createSampleReplicaVolumeEntry(100, 2)
+	v.Info.BrickLimits.MinSize = 30
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+
+	// The limits are kept with the volume
+	v = createSampleReplicaVolumeEntry(50, 2)
+	v.Info.BrickLimits.MaxSize = 50
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	err = v.Expand(app.db, app.executor, app.allocator, 50)
+	tests.Assert(t, err == nil, err)
+	for _, size := range brickSizes(v) {
+		tests.Assert(t, size == 50*GB, size)
+	}
+}
+
 func TestVolumeEntryCreateOnClustersRequested(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -1443,6 +1535,210 @@ func TestVolumeEntryExpand(t *testing.T) {
 	tests.Assert(t, reflect.DeepEqual(entry, v))
 }
 
+func TestVolumeEntryExpandKeepsConcurrentChanges(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create block hosting volume
+	v := createSampleReplicaVolumeEntry(100, 2)
+	v.Info.Block = true
+	v.Info.BlockInfo.FreeSize = 100
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Reserve space for a block volume while the volume expands
+	app.xo.MockVolumeExpand = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+		err := app.db.Update(func(tx *bolt.Tx) error {
+			entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+			if err != nil {
+				return err
+			}
+			entry.Info.BlockInfo.FreeSize -= 30
+			entry.BlockVolumeAdd("block1")
+			return entry.Save(tx)
+		})
+		tests.Assert(t, err == nil)
+		return &executors.Volume{}, nil
+	}
+
+	err = v.Expand(app.db, app.executor, app.allocator, 50)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, v.Info.Size == 150)
+	tests.Assert(t, v.Info.BlockInfo.FreeSize == 120,
+		"expected FreeSize 120, got", v.Info.BlockInfo.FreeSize)
+	tests.Assert(t, len(v.Info.BlockInfo.BlockVolumes) == 1)
+	tests.Assert(t, len(v.Bricks) == 4)
+
+	var entry *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, reflect.DeepEqual(entry, v))
+}
+
+func TestVolumeEntrySetOptions(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Create volume
+	v := createSampleReplicaVolumeEntry(100, 2)
+	v.GlusterVolumeOptions = []string{
+		"performance.cache-size 512MB",
+		"nfs.disable on",
+		"server.allow-insecure on",
+	}
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	var set, reset []string
+	app.xo.MockVolumeSetOptions = func(host string, volume string, options []string) error {
+		tests.Assert(t, volume == v.Info.Name)
+		set = options
+		return nil
+	}
+	app.xo.MockVolumeResetOptions = func(host string, volume string, options []string) error {
+		tests.Assert(t, volume == v.Info.Name)
+		reset = options
+		return nil
+	}
+
+	err = v.SetOptions(app.db, app.executor,
+		[]string{"performance.cache-size 1GB", "performance.io-thread-count 32"},
+		[]string{"nfs.disable"})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(set) == 2)
+	tests.Assert(t, len(reset) == 1 && reset[0] == "nfs.disable")
+
+	// Check db
+	var entry *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewVolumeEntryFromId(tx, v.Info.Id)
+
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, reflect.DeepEqual(entry.GlusterVolumeOptions, []string{
+		"server.allow-insecure on",
+		"performance.cache-size 1GB",
+		"performance.io-thread-count 32",
+	}), entry.GlusterVolumeOptions)
+
+	// Nothing is recorded when gluster fails
+	app.xo.MockVolumeSetOptions = func(host string, volume string, options []string) error {
+		return errors.New("set failed")
+	}
+	err = v.SetOptions(app.db, app.executor, []string{"nfs.disable on"}, nil)
+	tests.Assert(t, err != nil)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewVolumeEntryFromId(tx, v.Info.Id)
+
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(entry.GlusterVolumeOptions) == 3)
+}
+
+func TestVolumeEntryStopStart(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 2)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	var stopped, started string
+	app.xo.MockVolumeStop = func(host string, volume string) error {
+		stopped = volume
+		return nil
+	}
+	app.xo.MockVolumeStart = func(host string, volume string) error {
+		started = volume
+		return nil
+	}
+
+	checkStopped := func(expected bool) {
+		err := app.db.View(func(tx *bolt.Tx) error {
+			entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+			tests.Assert(t, err == nil, err)
+			tests.Assert(t, entry.Info.Stopped == expected)
+			return nil
+		})
+		tests.Assert(t, err == nil)
+	}
+
+	err = v.Stop(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, stopped == v.Info.Name)
+	tests.Assert(t, v.Info.Stopped)
+	checkStopped(true)
+
+	// A stopped volume cannot be changed
+	err = v.Expand(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == ErrVolumeStopped, err)
+	err = v.ExpandInPlace(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == ErrVolumeStopped, err)
+	err = v.replaceBrickInVolume(app.db, app.executor, app.allocator, v.Bricks[0])
+	tests.Assert(t, err == ErrVolumeStopped, err)
+
+	err = v.Start(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, started == v.Info.Name)
+	tests.Assert(t, !v.Info.Stopped)
+	checkStopped(false)
+
+	// Nothing is recorded when gluster fails
+	app.xo.MockVolumeStop = func(host string, volume string) error {
+		return errors.New("stop failed")
+	}
+	err = v.Stop(app.db, app.executor)
+	tests.Assert(t, err != nil)
+	checkStopped(false)
+
+	err = v.Expand(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == nil, err)
+}
+
 func TestVolumeEntryDoNotAllowDeviceOnSameNode(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -1694,6 +1990,261 @@ func TestReplaceBrickInVolume(t *testing.T) {
 	tests.Assert(t, !oldBrickIdExists, "old Brick not deleted")
 }
 
+func TestVolumeEntryCreateWithDeviceClass(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	// Create a cluster in the database
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Make the first device of each node an ssd and
+	// the second one an hdd
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		for _, id := range EntryKeys(tx, BOLTDB_BUCKET_NODE) {
+			node, err := NewNodeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			for i, deviceId := range node.Devices {
+				device, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return err
+				}
+				device.Info.Class = []string{"ssd", "hdd"}[i]
+				err = device.Save(tx)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	app.allocator = NewSimpleAllocatorFromDb(app.db, app.brickLimits)
+
+	deviceClass := func(brickId string) string {
+		var class string
+		err := app.db.View(func(tx *bolt.Tx) error {
+			brick, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+			if err != nil {
+				return err
+			}
+			class = device.Info.Class
+			return nil
+		})
+		tests.Assert(t, err == nil, err)
+		return class
+	}
+
+	// No devices of the class
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.DeviceClass = "nvme"
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == ErrNoSpace, err)
+
+	v = createSampleReplicaVolumeEntry(100, 3)
+	v.Info.DeviceClass = "ssd"
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(v.Bricks) == 3)
+	for _, brickId := range v.Bricks {
+		tests.Assert(t, deviceClass(brickId) == "ssd")
+	}
+
+	// The replacement brick is placed on an ssd as well
+	var brickNames []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		info, err := v.NewInfoResponse(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, info.DeviceClass == "ssd")
+
+		for _, brick := range v.Bricks {
+			be, err := NewBrickEntryFromId(tx, brick)
+			if err != nil {
+				return err
+			}
+			ne, err := NewNodeEntryFromId(tx, be.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			brickNames = append(brickNames,
+				fmt.Sprintf("%v:%v", ne.Info.Hostnames.Storage[0], be.Info.Path))
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		var bricks []executors.Brick
+		for _, name := range brickNames {
+			bricks = append(bricks, executors.Brick{Name: name})
+		}
+		return &executors.Volume{
+			Bricks: executors.Bricks{BrickList: bricks},
+		}, nil
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		var bricks executors.HealInfoBricks
+		for _, name := range brickNames {
+			bricks.BrickList = append(bricks.BrickList,
+				executors.BrickHealStatus{Name: name, NumberOfEntries: "0"})
+		}
+		return &executors.HealInfo{Bricks: bricks}, nil
+	}
+
+	oldBrickId := v.Bricks[0]
+	err = v.replaceBrickInVolume(app.db, app.executor, app.allocator, oldBrickId)
+	tests.Assert(t, err == nil, err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		v, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(v.Bricks) == 3)
+	for _, brickId := range v.Bricks {
+		tests.Assert(t, brickId != oldBrickId)
+		tests.Assert(t, deviceClass(brickId) == "ssd")
+	}
+}
+
+// Creates an arbiter volume and returns the gluster names
+// of its bricks in the order they were passed to gluster
+func createSampleArbiterVolume(t *testing.T, app *App,
+	size int, ratio float32) (*VolumeEntry, []string) {
+
+	var brickNames []string
+	app.xo.MockVolumeCreate = func(host string,
+		vr *executors.VolumeRequest) (*executors.Volume, error) {
+
+		tests.Assert(t, vr.Type == executors.DurabilityArbiter)
+		tests.Assert(t, vr.Replica == 3)
+		for _, b := range vr.Bricks {
+			brickNames = append(brickNames, b.Host+":"+b.Path)
+		}
+		return &executors.Volume{}, nil
+	}
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = size
+	req.Durability.Type = api.DurabilityArbiter
+	req.Durability.Arbiter.Ratio = ratio
+	v := NewVolumeEntryFromRequest(req)
+	err := v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+
+	return v, brickNames
+}
+
+func TestVolumeEntryCreateArbiter(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v, brickNames := createSampleArbiterVolume(t, app, 100, 0.1)
+	tests.Assert(t, len(v.Bricks) == 3)
+	tests.Assert(t, len(brickNames) == 3)
+
+	// The third brick passed to gluster is the small arbiter brick
+	for i, name := range brickNames {
+		brick, err := v.getBrickEntryfromBrickName(app.db, name)
+		tests.Assert(t, err == nil, err)
+		if i == ARBITER_BRICK_POSITION {
+			tests.Assert(t, brick.Info.Size == 10*GB, brick.Info.Size)
+		} else {
+			tests.Assert(t, brick.Info.Size == 100*GB, brick.Info.Size)
+		}
+	}
+}
+
+func TestReplaceBrickInVolumeArbiter(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v, brickNames := createSampleArbiterVolume(t, app, 100, 0.1)
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		vinfo := &executors.Volume{}
+		for _, name := range brickNames {
+			vinfo.Bricks.BrickList = append(vinfo.Bricks.BrickList,
+				executors.Brick{Name: name})
+		}
+		return vinfo, nil
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		h := &executors.HealInfo{}
+		for _, name := range brickNames {
+			h.Bricks.BrickList = append(h.Bricks.BrickList,
+				executors.BrickHealStatus{Name: name, NumberOfEntries: "0"})
+		}
+		return h, nil
+	}
+
+	arbiter, err := v.getBrickEntryfromBrickName(app.db,
+		brickNames[ARBITER_BRICK_POSITION])
+	tests.Assert(t, err == nil)
+
+	err = v.replaceBrickInVolume(app.db, app.executor, app.allocator, arbiter.Id())
+	tests.Assert(t, err == nil, err)
+
+	// The arbiter brick was replaced by a brick of the same size
+	err = app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil)
+		tests.Assert(t, len(entry.Bricks) == 3)
+
+		small := 0
+		for _, id := range entry.Bricks {
+			tests.Assert(t, id != arbiter.Id())
+			brick, err := NewBrickEntryFromId(tx, id)
+			tests.Assert(t, err == nil)
+			if brick.Info.Size == 10*GB {
+				small++
+			} else {
+				tests.Assert(t, brick.Info.Size == 100*GB)
+			}
+		}
+		tests.Assert(t, small == 1)
+		return nil
+	})
+	tests.Assert(t, err == nil)
+}
+
 func TestNewVolumeEntryWithVolumeOptions(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -2084,3 +2635,84 @@ func TestReplaceBrickInVolumeSelfHealQuorumNotMet(t *testing.T) {
 	tests.Assert(t, brickOnOldNode, "brick found on oldNode")
 	tests.Assert(t, oldBrickIdExists, "old Brick not deleted")
 }
+
+func TestVolumeEntryBrickRootPermissions(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	// Every brick root is owned and accessible as requested
+	created := 0
+	app.xo.MockBrickCreate = func(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error) {
+		tests.Assert(t, brick.Uid == 1001, brick.Uid)
+		tests.Assert(t, brick.Gid == 2002, brick.Gid)
+		tests.Assert(t, brick.Mode == "0770", brick.Mode)
+		created++
+		return &executors.BrickInfo{
+			Path: "/mockpath/" + brick.Name,
+		}, nil
+	}
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Uid = 1001
+	v.Info.Gid = 2002
+	v.Info.Mode = "0770"
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, created == 3, created)
+
+	// Bricks added by expand
+	err = v.Expand(app.db, app.executor, app.allocator, 100)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, created == 6, created)
+
+	// The brick replacing another one
+	var brickNames []string
+	var be *BrickEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, brick := range v.Bricks {
+			be, err = NewBrickEntryFromId(tx, brick)
+			if err != nil {
+				return err
+			}
+			ne, err := NewNodeEntryFromId(tx, be.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			brickNames = append(brickNames,
+				fmt.Sprintf("%v:%v", ne.Info.Hostnames.Storage[0], be.Info.Path))
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, err)
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		var bricks []executors.Brick
+		for _, name := range brickNames {
+			bricks = append(bricks, executors.Brick{Name: name})
+		}
+		return &executors.Volume{
+			Bricks: executors.Bricks{BrickList: bricks},
+		}, nil
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		var bricks executors.HealInfoBricks
+		for _, name := range brickNames {
+			bricks.BrickList = append(bricks.BrickList,
+				executors.BrickHealStatus{Name: name, NumberOfEntries: "0"})
+		}
+		return &executors.HealInfo{Bricks: bricks}, nil
+	}
+	err = v.replaceBrickInVolume(app.db, app.executor, app.allocator, be.Id())
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, created == 7, created)
+}
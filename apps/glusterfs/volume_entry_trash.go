@@ -0,0 +1,232 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// MoveToTrash stops the volume and keeps it, along with its bricks,
+// until it is restored or the retention period expires.  NFS exports
+// and SMB shares are removed, a restored volume has to be exported
+// and shared again.
+func (v *VolumeEntry) MoveToTrash(db *bolt.DB,
+	executor executors.Executor,
+	retention time.Duration) error {
+
+	if v.Info.Trash != nil {
+		return ErrVolumeTrashed
+	}
+
+	// The volume could not be destroyed once it expires
+	if len(v.Snapshots) > 0 {
+		return logger.LogError("Unable to delete volume %v because it contains %v snapshots",
+			v.Info.Id, len(v.Snapshots))
+	}
+	if len(v.Info.BlockInfo.BlockVolumes) > 0 {
+		return logger.LogError("Unable to delete volume %v because it contains %v block volumes",
+			v.Info.Id, len(v.Info.BlockInfo.BlockVolumes))
+	}
+
+	// Ganesha and Samba must not keep serving a deleted volume
+	if v.NfsExportId != 0 {
+		v.nfsUnexport(db, executor)
+	}
+	if len(v.SmbNodes) > 0 {
+		err := v.smbUnshare(db, executor)
+		if err != nil {
+			return err
+		}
+	}
+
+	startOnRestore := !v.Info.Stopped
+	if startOnRestore {
+		host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+
+		err = executor.VolumeStop(host, v.Info.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reload the volume, it may have changed while it was stopped
+	return db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		if volume.Info.Trash != nil {
+			return ErrVolumeTrashed
+		}
+
+		now := time.Now()
+		volume.NfsExportId = v.NfsExportId
+		volume.Info.Mount.Nfs = v.Info.Mount.Nfs
+		volume.SmbNodes = v.SmbNodes
+		volume.Info.Mount.Smb = v.Info.Mount.Smb
+		volume.Info.Stopped = true
+		volume.StartOnRestore = startOnRestore
+		volume.Info.Trash = &api.VolumeTrashInfo{
+			DeletedAt: now.Unix(),
+			ExpiresAt: now.Add(retention).Unix(),
+		}
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+}
+
+// Restore takes the volume out of the trash, starting it again
+// if it was running when it was deleted.  The volume is taken out
+// of the trash before it is started so the reaper cannot destroy
+// it meanwhile, and put back if it fails to start.
+func (v *VolumeEntry) Restore(db *bolt.DB, executor executors.Executor) error {
+	var trash *api.VolumeTrashInfo
+	err := db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		if volume.Info.Trash == nil {
+			return ErrVolumeNotTrashed
+		}
+		if volume.Destroying {
+			return ErrVolumeDestroying
+		}
+
+		trash = volume.Info.Trash
+		volume.Info.Trash = nil
+		err = volume.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		*v = *volume
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !v.StartOnRestore {
+		return nil
+	}
+
+	host, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	if err == nil {
+		err = executor.VolumeStart(host, v.Info.Name)
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		volume, dberr := NewVolumeEntryFromId(tx, v.Info.Id)
+		if dberr != nil {
+			return dberr
+		}
+		if err != nil {
+			volume.Info.Trash = trash
+		} else {
+			volume.Info.Stopped = false
+			volume.StartOnRestore = false
+		}
+		dberr = volume.Save(tx)
+		if dberr != nil {
+			return dberr
+		}
+
+		*v = *volume
+		return err
+	})
+}
+
+// claimExpired marks the volume as being destroyed if its time in
+// the trash expired, so it can no longer be restored.  Returns the
+// reloaded entry, or nil if the volume is not to be destroyed.
+func claimExpired(db *bolt.DB, id string, now time.Time) (*VolumeEntry, error) {
+	var volume *VolumeEntry
+	err := db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !entry.trashExpired(now) || entry.Destroying {
+			return nil
+		}
+
+		err = entry.claim(tx)
+		if err != nil {
+			return err
+		}
+
+		volume = entry
+		return nil
+	})
+
+	return volume, err
+}
+
+// claim marks the volume as being destroyed, so that neither the
+// reaper nor another request destroys or restores it meanwhile
+func (v *VolumeEntry) claim(tx *bolt.Tx) error {
+	if v.Destroying {
+		return ErrVolumeDestroying
+	}
+
+	v.Destroying = true
+	return v.Save(tx)
+}
+
+// releaseClaim lets the volume be restored again after it could
+// not be destroyed
+func releaseClaim(db *bolt.DB, id string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		volume, err := NewVolumeEntryFromId(tx, id)
+		if err != nil {
+			return err
+		}
+
+		volume.Destroying = false
+		return volume.Save(tx)
+	})
+}
+
+func (v *VolumeEntry) trashExpired(now time.Time) bool {
+	return v.Info.Trash != nil && now.Unix() >= v.Info.Trash.ExpiresAt
+}
+
+// Returns the volumes of the list which are not in the trash
+func withoutTrashedVolumes(tx *bolt.Tx, ids []string) ([]string, error) {
+	volumes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		volume, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			volumes = append(volumes, id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if volume.Info.Trash == nil {
+			volumes = append(volumes, id)
+		}
+	}
+
+	return volumes, nil
+}
@@ -0,0 +1,474 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func trashTestVolumeList(t *testing.T, url string) []string {
+	r, err := http.Get(url)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+
+	var list api.VolumeListResponse
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil)
+
+	return list.Volumes
+}
+
+func trashTestBrickCount(t *testing.T, db *bolt.DB) int {
+	var bricks []string
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		bricks, err = BrickList(tx)
+		return err
+	})
+	tests.Assert(t, err == nil)
+
+	return len(bricks)
+}
+
+func TestVolumeTrash(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	app.conf.VolumeTrashRetention = 1
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	bricks := trashTestBrickCount(t, app.db)
+	tests.Assert(t, bricks == 3, bricks)
+
+	stopped := 0
+	app.xo.MockVolumeStop = func(host string, volume string) error {
+		stopped++
+		return nil
+	}
+	started := 0
+	app.xo.MockVolumeStart = func(host string, volume string) error {
+		started++
+		return nil
+	}
+	destroyed := 0
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		destroyed++
+		return nil
+	}
+
+	// Deleting keeps the stopped volume and its bricks in the trash
+	r := asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id, "", nil)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	tests.Assert(t, stopped == 1, stopped)
+	tests.Assert(t, destroyed == 0, destroyed)
+	tests.Assert(t, trashTestBrickCount(t, app.db) == bricks)
+
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusOK)
+	var info api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, info.Stopped)
+	tests.Assert(t, info.Trash != nil)
+	tests.Assert(t, info.Trash.ExpiresAt-info.Trash.DeletedAt == 3600,
+		info.Trash.ExpiresAt-info.Trash.DeletedAt)
+
+	// Volumes in the trash are only listed when asked for
+	volumes := trashTestVolumeList(t, ts.URL+"/volumes")
+	tests.Assert(t, len(volumes) == 0, volumes)
+	volumes = trashTestVolumeList(t, ts.URL+"/volumes?include_trash=true")
+	tests.Assert(t, len(volumes) == 1, volumes)
+	tests.Assert(t, volumes[0] == v.Info.Id)
+
+	var cluster api.ClusterInfoResponse
+	r, err = http.Get(ts.URL + "/clusters/" + v.Info.Cluster)
+	tests.Assert(t, err == nil)
+	err = utils.GetJsonFromResponse(r, &cluster)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(cluster.Volumes) == 0, cluster.Volumes)
+	r, err = http.Get(ts.URL + "/clusters/" + v.Info.Cluster + "?include_trash=true")
+	tests.Assert(t, err == nil)
+	err = utils.GetJsonFromResponse(r, &cluster)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(cluster.Volumes) == 1, cluster.Volumes)
+
+	// A volume in the trash cannot be started
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/start", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+	tests.Assert(t, started == 0)
+
+	// Restore brings it back running
+	info = asyncTestVolume(t,
+		asyncTestRequest(t, "POST", ts.URL+"/volumes/"+v.Info.Id+"/restore", "", nil))
+	tests.Assert(t, info.Id == v.Info.Id)
+	tests.Assert(t, info.Trash == nil)
+	tests.Assert(t, !info.Stopped)
+	tests.Assert(t, started == 1, started)
+	volumes = trashTestVolumeList(t, ts.URL+"/volumes")
+	tests.Assert(t, len(volumes) == 1, volumes)
+
+	// Only volumes in the trash can be restored
+	r, err = http.Post(ts.URL+"/volumes/"+v.Info.Id+"/restore", "application/json", nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusConflict, r.StatusCode)
+
+	// Deleting a volume in the trash again destroys it
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id, "", nil)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	tests.Assert(t, stopped == 2, stopped)
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id, "", nil)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	tests.Assert(t, destroyed == 1, destroyed)
+	tests.Assert(t, trashTestBrickCount(t, app.db) == 0)
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+
+	// Purging skips the trash
+	v = createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id+"?purge=true", "", nil)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	tests.Assert(t, destroyed == 2, destroyed)
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+}
+
+func TestVolumeTrashStoppedVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	err = v.setStopped(app.db, app.executor, true)
+	tests.Assert(t, err == nil)
+
+	calls := 0
+	app.xo.MockVolumeStop = func(host string, volume string) error {
+		calls++
+		return nil
+	}
+	app.xo.MockVolumeStart = func(host string, volume string) error {
+		calls++
+		return nil
+	}
+
+	// A stopped volume stays stopped after being restored
+	err = v.Restore(app.db, app.executor)
+	tests.Assert(t, err == ErrVolumeNotTrashed, err)
+	err = v.MoveToTrash(app.db, app.executor, time.Hour)
+	tests.Assert(t, err == nil, err)
+	err = v.MoveToTrash(app.db, app.executor, time.Hour)
+	tests.Assert(t, err == ErrVolumeTrashed, err)
+	err = v.Restore(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, calls == 0, calls)
+	tests.Assert(t, v.Info.Stopped)
+
+	var entry *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, entry.Info.Trash == nil)
+	tests.Assert(t, entry.Info.Stopped)
+}
+
+func TestVolumeTrashExportedVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.NfsExport = true
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil, err)
+	err = v.SmbShare(app.db, app.executor, smbTestNodes(t, app, v), "")
+	tests.Assert(t, err == nil, err)
+
+	unexported := 0
+	app.xo.MockVolumeNfsUnexport = func(host string, volume string) error {
+		unexported++
+		return nil
+	}
+	unshared := 0
+	app.xo.MockVolumeSmbUnshare = func(host string, share string) error {
+		unshared++
+		return nil
+	}
+
+	// Changes made while the volume is stopped are kept
+	app.xo.MockVolumeStop = func(host string, volume string) error {
+		return app.db.Update(func(tx *bolt.Tx) error {
+			entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+			if err != nil {
+				return err
+			}
+			entry.GlusterVolumeOptions = []string{"nfs.disable on"}
+			return entry.Save(tx)
+		})
+	}
+
+	// The volume is no longer exported nor shared from the trash
+	err = v.MoveToTrash(app.db, app.executor, time.Hour)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, unexported == 3, unexported)
+	tests.Assert(t, unshared == 3, unshared)
+
+	var entry *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, entry.Info.Trash != nil)
+	tests.Assert(t, entry.NfsExportId == 0)
+	tests.Assert(t, len(entry.Info.Mount.Nfs.Hosts) == 0)
+	tests.Assert(t, len(entry.SmbNodes) == 0)
+	tests.Assert(t, entry.Info.Mount.Smb.ShareName == "")
+	tests.Assert(t, len(entry.GlusterVolumeOptions) == 1, entry.GlusterVolumeOptions)
+	tests.Assert(t, reflect.DeepEqual(entry, v))
+}
+
+func TestVolumeTrashDeleteClaimsVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	app.conf.VolumeTrashRetention = 1
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	err = v.MoveToTrash(app.db, app.executor, -time.Minute)
+	tests.Assert(t, err == nil)
+
+	// The volume is claimed before it is destroyed, so neither the
+	// reaper nor another request can destroy it at the same time
+	var second *http.Response
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		second = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id, "", nil)
+		claimed, err := claimExpired(app.db, v.Info.Id, time.Now())
+		tests.Assert(t, err == nil)
+		tests.Assert(t, claimed == nil)
+		return errors.New("MOCK")
+	}
+	r := asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id, "", nil)
+	tests.Assert(t, r.StatusCode == http.StatusInternalServerError, r.StatusCode)
+	tests.Assert(t, second.StatusCode == http.StatusConflict, second.StatusCode)
+
+	// The claim is released when the volume could not be destroyed
+	var entry *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, !entry.Destroying)
+
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		return nil
+	}
+	r = asyncTestRequest(t, "DELETE", ts.URL+"/volumes/"+v.Info.Id, "", nil)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent, r.StatusCode)
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound)
+}
+
+func TestVolumeReaper(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	expired := createSampleReplicaVolumeEntry(100, 3)
+	err = expired.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	err = expired.MoveToTrash(app.db, app.executor, -time.Minute)
+	tests.Assert(t, err == nil)
+
+	kept := createSampleReplicaVolumeEntry(100, 3)
+	err = kept.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	err = kept.MoveToTrash(app.db, app.executor, time.Hour)
+	tests.Assert(t, err == nil)
+
+	running := createSampleReplicaVolumeEntry(100, 3)
+	err = running.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+
+	// Only the expired volume is destroyed
+	r := NewVolumeReaper(app.db, app.executor)
+	r.reap()
+
+	var volumes []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volumes, err = VolumeList(tx)
+		return err
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(volumes) == 2, volumes)
+	tests.Assert(t, !utils.SortedStringHas(volumes, expired.Info.Id))
+	tests.Assert(t, trashTestBrickCount(t, app.db) == 6)
+}
+
+func TestVolumeReaperRestore(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor, app.allocator)
+	tests.Assert(t, err == nil)
+	err = v.MoveToTrash(app.db, app.executor, -time.Minute)
+	tests.Assert(t, err == nil)
+
+	// A volume restored after it expired is not claimed by the reaper
+	stale := *v
+	err = v.Restore(app.db, app.executor)
+	tests.Assert(t, err == nil, err)
+	claimed, err := claimExpired(app.db, v.Info.Id, time.Now())
+	tests.Assert(t, err == nil)
+	tests.Assert(t, claimed == nil)
+
+	// Nor restored again from a stale copy
+	err = stale.Restore(app.db, app.executor)
+	tests.Assert(t, err == ErrVolumeNotTrashed, err)
+
+	// A claimed volume can no longer be restored
+	err = v.MoveToTrash(app.db, app.executor, -time.Minute)
+	tests.Assert(t, err == nil)
+	claimed, err = claimExpired(app.db, v.Info.Id, time.Now())
+	tests.Assert(t, err == nil)
+	tests.Assert(t, claimed != nil && claimed.Destroying)
+	err = v.Restore(app.db, app.executor)
+	tests.Assert(t, err == ErrVolumeDestroying, err)
+
+	err = releaseClaim(app.db, v.Info.Id)
+	tests.Assert(t, err == nil)
+
+	// The reaper releases the volumes it fails to destroy
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		return errors.New("TEST")
+	}
+	NewVolumeReaper(app.db, app.executor).reap()
+	tests.Assert(t, trashTestBrickCount(t, app.db) == 3)
+
+	// Volumes failing to start stay in the trash
+	app.xo.MockVolumeStart = func(host string, volume string) error {
+		return errors.New("TEST")
+	}
+	err = v.Restore(app.db, app.executor)
+	tests.Assert(t, err != nil)
+	tests.Assert(t, v.Info.Trash != nil)
+	tests.Assert(t, !v.Destroying)
+}
@@ -0,0 +1,100 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/heketi/executors"
+)
+
+const (
+	VolumeReaperInterval = 10 * time.Minute
+)
+
+// VolumeReaper destroys the volumes whose time in the trash expired
+type VolumeReaper struct {
+	db       *bolt.DB
+	executor executors.Executor
+	task     backgroundTask
+}
+
+func NewVolumeReaper(db *bolt.DB, executor executors.Executor) *VolumeReaper {
+	return &VolumeReaper{
+		db:       db,
+		executor: executor,
+	}
+}
+
+func (r *VolumeReaper) Start() {
+	r.task.start(VolumeReaperInterval, r.reap)
+}
+
+func (r *VolumeReaper) Stop() {
+	r.task.stopTask()
+}
+
+func (r *VolumeReaper) reap() {
+	now := time.Now()
+
+	var expired []string
+	err := r.db.View(func(tx *bolt.Tx) error {
+		volumes, err := VolumeList(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range volumes {
+			volume, err := NewVolumeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if volume.trashExpired(now) {
+				expired = append(expired, id)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.LogError("Unable to find the expired volumes in the trash: %v", err)
+		return
+	}
+
+	// Volumes which cannot be destroyed are tried again next time
+	for _, id := range expired {
+		if r.task.stopping() {
+			return
+		}
+
+		// The volume may have been restored or deleted meanwhile
+		volume, err := claimExpired(r.db, id, now)
+		if err != nil {
+			logger.LogError("Unable to claim expired volume %v: %v", id, err)
+			continue
+		}
+		if volume == nil {
+			continue
+		}
+
+		logger.Info("Time of volume %v in the trash expired", id)
+		err = volume.Destroy(r.db, r.executor)
+		if err != nil {
+			logger.LogError("Failed to destroy expired volume %v: %v", id, err)
+			err = releaseClaim(r.db, id)
+			if err != nil {
+				logger.LogError("Unable to release expired volume %v: %v", id, err)
+			}
+			continue
+		}
+		logger.Info("Deleted volume [%s]", id)
+	}
+}
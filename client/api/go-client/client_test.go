@@ -146,10 +146,14 @@ func TestTopology(t *testing.T) {
 	for n := 0; n < 4; n++ {
 		volumeReq := &api.VolumeCreateRequest{}
 		volumeReq.Size = 10
+		// Also export the last one over NFS
+		volumeReq.NfsExport = n == 3
 		volume, err := c.VolumeCreate(volumeReq)
 		tests.Assert(t, err == nil)
 		tests.Assert(t, volume.Id != "")
 		tests.Assert(t, volume.Size == volumeReq.Size)
+		tests.Assert(t, volume.NfsExport == volumeReq.NfsExport)
+		tests.Assert(t, (volume.Mount.Nfs.MountPoint != "") == volumeReq.NfsExport)
 		volumeinfos = append(volumeinfos, *volume)
 	}
 	topology, err = c.TopologyInfo()
@@ -264,6 +268,55 @@ func TestClientCluster(t *testing.T) {
 	tests.Assert(t, len(list.Clusters) == 1)
 	tests.Assert(t, list.Clusters[0] == info.Id)
 
+	// Create a tagged cluster
+	tagged, err := c.ClusterCreateWithRequest(&api.ClusterCreateRequest{
+		Tags: map[string]string{"owner": "storage-team"},
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, tagged.Tags["owner"] == "storage-team")
+
+	list, err = c.ClusterListByTags([]string{"owner:storage-team"})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Clusters) == 1)
+	tests.Assert(t, list.Clusters[0] == tagged.Id)
+
+	// Change its tags
+	tagged, err = c.ClusterSetTags(tagged.Id, &api.TagsChangeRequest{
+		Tags:   map[string]string{"site": "east"},
+		Change: api.UpdateTags,
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(tagged.Tags) == 2)
+	tests.Assert(t, tagged.Tags["site"] == "east")
+
+	_, err = c.ClusterSetTags(tagged.Id, &api.TagsChangeRequest{
+		Tags:   map[string]string{"bad key": "x"},
+		Change: api.UpdateTags,
+	})
+	tests.Assert(t, err != nil)
+
+	list, err = c.ClusterListByTags([]string{"owner", "site:west"})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Clusters) == 0)
+
+	// Set its brick limits
+	tagged, err = c.ClusterSetBrickLimits(tagged.Id, &api.BrickLimits{
+		MinSize: 10,
+		MaxNum:  64,
+	})
+	tests.Assert(t, err == nil)
+	tests.Assert(t, tagged.BrickLimits.MinSize == 10)
+	tests.Assert(t, tagged.BrickLimits.MaxNum == 64)
+
+	_, err = c.ClusterSetBrickLimits(tagged.Id, &api.BrickLimits{
+		MinSize: 10,
+		MaxSize: 5,
+	})
+	tests.Assert(t, err != nil)
+
+	err = c.ClusterDelete(tagged.Id)
+	tests.Assert(t, err == nil)
+
 	// Delete non-existent cluster
 	err = c.ClusterDelete("badid")
 	tests.Assert(t, err != nil)
@@ -341,6 +394,23 @@ func TestClientNode(t *testing.T) {
 	tests.Assert(t, info.State == api.EntryStateOnline)
 	tests.Assert(t, reflect.DeepEqual(info, node))
 
+	// Change hostnames
+	hostnamesReq := &api.NodeHostnamesRequest{}
+	_, err = c.NodeSetHostnames(node.Id, hostnamesReq)
+	tests.Assert(t, err != nil)
+	hostnamesReq.Hostnames.Manage = []string{"manage-new"}
+	hostnamesReq.Hostnames.Storage = []string{"storage-new"}
+	info, err = c.NodeSetHostnames(node.Id, hostnamesReq)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, reflect.DeepEqual(info.Hostnames, hostnamesReq.Hostnames))
+
+	// The old hostnames can be used again
+	nodeReq.Zone = 11
+	other, err := c.NodeAdd(nodeReq)
+	tests.Assert(t, err == nil)
+	err = c.NodeDelete(other.Id)
+	tests.Assert(t, err == nil)
+
 	// Delete invalid node
 	err = c.NodeDelete("badid")
 	tests.Assert(t, err != nil)
@@ -520,9 +590,19 @@ func TestClientVolume(t *testing.T) {
 	tests.Assert(t, err == nil)
 	tests.Assert(t, len(list.Volumes) == 0)
 
-	// Create a volume
+	// Plan a volume
 	volumeReq := &api.VolumeCreateRequest{}
 	volumeReq.Size = 10
+	plan, err := c.VolumePlan(volumeReq)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, plan.Feasible)
+	tests.Assert(t, len(plan.BrickSets) > 0)
+
+	list, err = c.VolumeList()
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(list.Volumes) == 0)
+
+	// Create a volume
 	volume, err := c.VolumeCreate(volumeReq)
 	tests.Assert(t, err == nil)
 	tests.Assert(t, volume.Id != "")
@@ -543,21 +623,256 @@ func TestClientVolume(t *testing.T) {
 	tests.Assert(t, err == nil)
 	tests.Assert(t, reflect.DeepEqual(info, volume))
 
+	// Get info with the space used on the nodes
+	info, err = c.VolumeInfoWithUsage(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.Usage != nil)
+	for _, brick := range info.Bricks {
+		tests.Assert(t, brick.Usage != nil)
+	}
+	deviceUsage, err := c.DeviceInfoWithUsage(info.Bricks[0].DeviceId)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, deviceUsage.Usage != nil)
+
+	// No thin pool is filling up
+	thinPools, err := c.ThinPoolWarnings()
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, thinPools.CriticalPercent > thinPools.WarningPercent)
+	tests.Assert(t, len(thinPools.Pools) == 0)
+
 	// Expand volume with a bad id
 	expandReq := &api.VolumeExpandRequest{}
 	expandReq.Size = 10
 	volumeInfo, err := c.VolumeExpand("badid", expandReq)
 	tests.Assert(t, err != nil)
 
+	// Plan the expansion
+	plan, err = c.VolumeExpandPlan(volume.Id, expandReq)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, plan.Feasible)
+	tests.Assert(t, plan.Cluster == volume.Cluster)
+
 	// Expand volume
 	volumeInfo, err = c.VolumeExpand(volume.Id, expandReq)
 	tests.Assert(t, err == nil)
 	tests.Assert(t, volumeInfo.Size == 20)
 
+	// Set volume options
+	optionsReq := &api.VolumeOptionsRequest{
+		Set: []string{"performance.cache-size 1GB"},
+	}
+	volumeInfo, err = c.VolumeSetOptions(volume.Id, optionsReq)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(volumeInfo.GlusterVolumeOptions) == 1)
+	tests.Assert(t, volumeInfo.GlusterVolumeOptions[0] == "performance.cache-size 1GB")
+
+	// Stop and start the volume
+	volumeInfo, err = c.VolumeStop(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, volumeInfo.Stopped)
+	_, err = c.VolumeExpand(volume.Id, expandReq)
+	tests.Assert(t, err != nil)
+	_, err = c.VolumeStop(volume.Id)
+	tests.Assert(t, err != nil)
+	volumeInfo, err = c.VolumeStart(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !volumeInfo.Stopped)
+
+	// Distributed volumes have nothing to heal
+	_, err = c.VolumeHealInfo(volume.Id)
+	tests.Assert(t, err != nil)
+	_, err = c.VolumeHeal(volume.Id, &api.VolumeHealRequest{Type: api.HealFull})
+	tests.Assert(t, err != nil)
+
+	// Share the volume over SMB from one node
+	volumeInfo, err = c.VolumeSmbShare(volume.Id, &api.VolumeSmbShareRequest{
+		Nodes: []string{volumeInfo.Bricks[0].NodeId},
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, volumeInfo.Mount.Smb.ShareName == "gluster-"+volume.Name)
+	tests.Assert(t, len(volumeInfo.Mount.Smb.Hosts) == 1)
+	volumeInfo, err = c.VolumeSmbUnshare(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, volumeInfo.Mount.Smb.ShareName == "")
+
+	// Volumes managed by heketi cannot be imported
+	_, err = c.VolumeImport(&api.VolumeImportRequest{Name: volume.Name})
+	tests.Assert(t, err != nil)
+
+	// Quota limits
+	_, err = c.VolumeQuotaSetLimits(volume.Id, &api.VolumeQuotaRequest{
+		Set: []api.QuotaLimit{{Path: "/data", HardLimit: 1024}},
+	})
+	tests.Assert(t, err != nil)
+	volumeInfo, err = c.VolumeQuotaEnable(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, volumeInfo.Quota.Enabled)
+	volumeInfo, err = c.VolumeQuotaSetLimits(volume.Id, &api.VolumeQuotaRequest{
+		Set: []api.QuotaLimit{{Path: "/data", HardLimit: 1024}},
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(volumeInfo.Quota.Limits) == 1)
+	quota, err := c.VolumeQuota(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, quota.Enabled)
+	tests.Assert(t, len(quota.Limits) == 1)
+	tests.Assert(t, quota.Limits[0].HardLimit == 1024)
+	volumeInfo, err = c.VolumeQuotaDisable(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !volumeInfo.Quota.Enabled)
+	tests.Assert(t, len(volumeInfo.Quota.Limits) == 0)
+
+	// Bitrot detection
+	_, err = c.VolumeBitrotScrub(volume.Id, &api.VolumeBitrotScrubRequest{
+		Frequency: "daily",
+	})
+	tests.Assert(t, err != nil)
+	volumeInfo, err = c.VolumeBitrotEnable(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, volumeInfo.Bitrot.Enabled)
+	volumeInfo, err = c.VolumeBitrotScrub(volume.Id, &api.VolumeBitrotScrubRequest{
+		Frequency: "daily",
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, volumeInfo.Bitrot.ScrubFrequency == "daily")
+	bitrot, err := c.VolumeBitrotStatus(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, bitrot.Enabled)
+	volumeInfo, err = c.VolumeBitrotDisable(volume.Id)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, !volumeInfo.Bitrot.Enabled)
+
+	// Snapshot volume with a bad id
+	snapshotReq := &api.SnapshotCreateRequest{}
+	snapshotReq.Name = "mysnap"
+	_, err = c.SnapshotCreate("badid", snapshotReq)
+	tests.Assert(t, err != nil)
+
+	// Snapshot volume
+	snapshot, err := c.SnapshotCreate(volume.Id, snapshotReq)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, snapshot.Name == "mysnap")
+	tests.Assert(t, snapshot.VolumeId == volume.Id)
+
+	// Get list of snapshots
+	snapshots, err := c.SnapshotList(volume.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(snapshots.Snapshots) == 1)
+	tests.Assert(t, snapshots.Snapshots[0] == snapshot.Id)
+
+	// Get snapshot info
+	snapshotInfo, err := c.SnapshotInfo(volume.Id, snapshot.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, reflect.DeepEqual(snapshotInfo, snapshot))
+
+	// Volume with snapshots cannot be deleted
+	err = c.VolumeDelete(volume.Id)
+	tests.Assert(t, err != nil)
+
+	// Restore volume, which consumes the snapshot
+	volumeInfo, err = c.SnapshotRestore(volume.Id, snapshot.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, volumeInfo.Id == volume.Id)
+	_, err = c.SnapshotInfo(volume.Id, snapshot.Id)
+	tests.Assert(t, err != nil)
+
+	// Delete snapshot
+	snapshot, err = c.SnapshotCreate(volume.Id, snapshotReq)
+	tests.Assert(t, err == nil)
+	err = c.SnapshotDelete(volume.Id, "badid")
+	tests.Assert(t, err != nil)
+	err = c.SnapshotDelete(volume.Id, snapshot.Id)
+	tests.Assert(t, err == nil)
+	snapshots, err = c.SnapshotList(volume.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(snapshots.Snapshots) == 0)
+
+	// Create a block hosting volume
+	hostingReq := &api.VolumeCreateRequest{}
+	hostingReq.Size = 20
+	hostingReq.Block = true
+	hosting, err := c.VolumeCreate(hostingReq)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, hosting.Block)
+	tests.Assert(t, hosting.BlockInfo.FreeSize == 20)
+
+	// Create a block volume
+	blockReq := &api.BlockVolumeCreateRequest{}
+	blockReq.Size = 5
+	blockReq.Name = "myblock"
+	blockvolume, err := c.BlockVolumeCreate(blockReq)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, blockvolume.Id != "")
+	tests.Assert(t, blockvolume.Name == "myblock")
+	tests.Assert(t, blockvolume.BlockHostingVolume == hosting.Id)
+
+	// Get list of block volumes
+	blockvolumes, err := c.BlockVolumeList()
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(blockvolumes.BlockVolumes) == 1)
+	tests.Assert(t, blockvolumes.BlockVolumes[0] == blockvolume.Id)
+
+	// Get block volume info
+	_, err = c.BlockVolumeInfo("badid")
+	tests.Assert(t, err != nil)
+	blockvolumeInfo, err := c.BlockVolumeInfo(blockvolume.Id)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, reflect.DeepEqual(blockvolumeInfo, blockvolume))
+
+	// Block hosting volume with block volumes cannot be deleted
+	err = c.VolumeDelete(hosting.Id)
+	tests.Assert(t, err != nil)
+
+	// Delete block volume
+	err = c.BlockVolumeDelete("badid")
+	tests.Assert(t, err != nil)
+	err = c.BlockVolumeDelete(blockvolume.Id)
+	tests.Assert(t, err == nil)
+	blockvolumes, err = c.BlockVolumeList()
+	tests.Assert(t, err == nil)
+	tests.Assert(t, len(blockvolumes.BlockVolumes) == 0)
+	err = c.VolumeDelete(hosting.Id)
+	tests.Assert(t, err == nil)
+
 	// Delete bad id
 	err = c.VolumeDelete("badid")
 	tests.Assert(t, err != nil)
 
+	// The server destroys deleted volumes right away by default,
+	// so there is nothing in the trash to restore
+	list, err = c.VolumeListWithTrash(nil)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, utils.SortedStringHas(list.Volumes, volume.Id))
+	_, err = c.VolumeRestore(volume.Id)
+	tests.Assert(t, err != nil)
+	err = c.VolumePurge("badid")
+	tests.Assert(t, err != nil)
+
+	// Retried requests with the same idempotency key run once
+	volumeReq = &api.VolumeCreateRequest{}
+	volumeReq.Size = 10
+	created, err := c.VolumeCreateWithKey(volumeReq, "create-key")
+	tests.Assert(t, err == nil)
+	retried, err := c.VolumeCreateWithKey(volumeReq, "create-key")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, retried.Id == created.Id)
+
+	expandReq = &api.VolumeExpandRequest{}
+	expandReq.Size = 10
+	retried, err = c.VolumeExpandWithKey(created.Id, expandReq, "expand-key")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, retried.Size == 20)
+	retried, err = c.VolumeExpandWithKey(created.Id, expandReq, "expand-key")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, retried.Size == 20)
+
+	err = c.VolumeDeleteWithKey(created.Id, "delete-key")
+	tests.Assert(t, err == nil)
+	err = c.VolumeDeleteWithKey(created.Id, "delete-key")
+	tests.Assert(t, err == nil)
+	_, err = c.VolumeCreateWithKey(volumeReq, "delete-key")
+	tests.Assert(t, err != nil)
+
 	// Delete volume
 	err = c.VolumeDelete(volume.Id)
 	tests.Assert(t, err == nil)
@@ -14,16 +14,29 @@ package client
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
+	"net/url"
 
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
 )
 
 func (c *Client) ClusterCreate() (*api.ClusterInfoResponse, error) {
+	return c.ClusterCreateWithRequest(&api.ClusterCreateRequest{})
+}
+
+func (c *Client) ClusterCreateWithRequest(request *api.ClusterCreateRequest) (
+	*api.ClusterInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a request
-	req, err := http.NewRequest("POST", c.host+"/clusters", bytes.NewBuffer([]byte(`{}`)))
+	req, err := http.NewRequest("POST", c.host+"/clusters", bytes.NewBuffer(buffer))
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +68,51 @@ func (c *Client) ClusterCreate() (*api.ClusterInfoResponse, error) {
 	return &cluster, nil
 }
 
+// ClusterSetBrickLimits replaces the limits on the bricks of the
+// volumes allocated in the cluster
+func (c *Client) ClusterSetBrickLimits(id string, request *api.BrickLimits) (
+	*api.ClusterInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST", c.host+"/clusters/"+id+"/brick-limits",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var cluster api.ClusterInfoResponse
+	err = utils.GetJsonFromResponse(r, &cluster)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cluster, nil
+}
+
 func (c *Client) ClusterInfo(id string) (*api.ClusterInfoResponse, error) {
 
 	// Create request
@@ -90,9 +148,19 @@ func (c *Client) ClusterInfo(id string) (*api.ClusterInfoResponse, error) {
 }
 
 func (c *Client) ClusterList() (*api.ClusterListResponse, error) {
+	return c.ClusterListByTags(nil)
+}
+
+// Lists the clusters with all the tags in the filters.
+// Filters are in "key" or "key:value" form.
+func (c *Client) ClusterListByTags(filters []string) (*api.ClusterListResponse, error) {
 
 	// Create request
-	req, err := http.NewRequest("GET", c.host+"/clusters", nil)
+	listUrl := c.host + "/clusters"
+	if len(filters) > 0 {
+		listUrl += "?" + url.Values{"tag": filters}.Encode()
+	}
+	req, err := http.NewRequest("GET", listUrl, nil)
 	if err != nil {
 		return nil, err
 	}
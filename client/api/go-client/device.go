@@ -64,9 +64,19 @@ func (c *Client) DeviceAdd(request *api.DeviceAddRequest) error {
 }
 
 func (c *Client) DeviceInfo(id string) (*api.DeviceInfoResponse, error) {
+	return c.deviceInfo(c.host + "/devices/" + id)
+}
+
+// DeviceInfoWithUsage also reports how much space the bricks of the
+// device use, as collected from the node
+func (c *Client) DeviceInfoWithUsage(id string) (*api.DeviceInfoResponse, error) {
+	return c.deviceInfo(c.host + "/devices/" + id + "?usage=true")
+}
+
+func (c *Client) deviceInfo(url string) (*api.DeviceInfoResponse, error) {
 
 	// Create request
-	req, err := http.NewRequest("GET", c.host+"/devices/"+id, nil)
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -183,3 +183,58 @@ func (c *Client) NodeState(id string, request *api.StateRequest) error {
 
 	return nil
 }
+
+// NodeSetHostnames changes the manage and storage hostnames of a node
+// without moving its bricks
+func (c *Client) NodeSetHostnames(id string, request *api.NodeHostnamesRequest) (
+	*api.NodeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/nodes/"+id+"/hostnames",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.waitForResponseWithTimer(r, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var node api.NodeInfoResponse
+	err = utils.GetJsonFromResponse(r, &node)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
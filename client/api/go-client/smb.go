@@ -0,0 +1,94 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), as published by the Free Software Foundation,
+// or under the Apache License, Version 2.0 <LICENSE-APACHE2 or
+// http://www.apache.org/licenses/LICENSE-2.0>.
+//
+// You may not use this file except in compliance with those terms.
+//
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// VolumeSmbShare shares the volume over SMB from the requested nodes
+func (c *Client) VolumeSmbShare(id string, request *api.VolumeSmbShareRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/volumes/"+id+"/smb",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.volumeSmbRequest(req)
+}
+
+// VolumeSmbUnshare removes the SMB share of the volume
+func (c *Client) VolumeSmbUnshare(id string) (*api.VolumeInfoResponse, error) {
+
+	// Create a request
+	req, err := http.NewRequest("DELETE", c.host+"/volumes/"+id+"/smb", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.volumeSmbRequest(req)
+}
+
+func (c *Client) volumeSmbRequest(req *http.Request) (*api.VolumeInfoResponse, error) {
+
+	// Set token
+	err := c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.waitForResponseWithTimer(r, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
@@ -0,0 +1,110 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), as published by the Free Software Foundation,
+// or under the Apache License, Version 2.0 <LICENSE-APACHE2 or
+// http://www.apache.org/licenses/LICENSE-2.0>.
+//
+// You may not use this file except in compliance with those terms.
+//
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func (c *Client) ClusterSetTags(id string, request *api.TagsChangeRequest) (
+	*api.ClusterInfoResponse, error) {
+
+	var cluster api.ClusterInfoResponse
+	err := c.setTags("/clusters/"+id+"/tags", request, &cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cluster, nil
+}
+
+func (c *Client) NodeSetTags(id string, request *api.TagsChangeRequest) (
+	*api.NodeInfoResponse, error) {
+
+	var node api.NodeInfoResponse
+	err := c.setTags("/nodes/"+id+"/tags", request, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func (c *Client) DeviceSetTags(id string, request *api.TagsChangeRequest) (
+	*api.DeviceInfoResponse, error) {
+
+	var device api.DeviceInfoResponse
+	err := c.setTags("/devices/"+id+"/tags", request, &device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func (c *Client) VolumeSetTags(id string, request *api.TagsChangeRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	var volume api.VolumeInfoResponse
+	err := c.setTags("/volumes/"+id+"/tags", request, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
+func (c *Client) setTags(path string, request *api.TagsChangeRequest, info interface{}) error {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST", c.host+path, bytes.NewBuffer(buffer))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != http.StatusOK {
+		return utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	err = utils.GetJsonFromResponse(r, info)
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -31,6 +31,7 @@ func (c *Client) TopologyInfo() (*api.TopologyInfoResponse, error) {
 		}
 		cluster := api.Cluster{
 			Id:      clusteri.Id,
+			Tags:    clusteri.Tags,
 			Volumes: make([]api.VolumeInfoResponse, 0),
 			Nodes:   make([]api.NodeInfoResponse, 0),
 		}
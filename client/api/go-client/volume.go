@@ -16,6 +16,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/heketi/heketi/pkg/glusterfs/api"
@@ -25,6 +26,20 @@ import (
 func (c *Client) VolumeCreate(request *api.VolumeCreateRequest) (
 	*api.VolumeInfoResponse, error) {
 
+	return c.volumeCreate(request, "")
+}
+
+// VolumeCreateWithKey creates the volume once, however many times
+// it is called with the same idempotency key
+func (c *Client) VolumeCreateWithKey(request *api.VolumeCreateRequest,
+	idempotencyKey string) (*api.VolumeInfoResponse, error) {
+
+	return c.volumeCreate(request, idempotencyKey)
+}
+
+func (c *Client) volumeCreate(request *api.VolumeCreateRequest,
+	idempotencyKey string) (*api.VolumeInfoResponse, error) {
+
 	// Marshal request to JSON
 	buffer, err := json.Marshal(request)
 	if err != nil {
@@ -39,6 +54,7 @@ func (c *Client) VolumeCreate(request *api.VolumeCreateRequest) (
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setIdempotencyKey(req, idempotencyKey)
 
 	// Set token
 	err = c.setToken(req)
@@ -76,9 +92,79 @@ func (c *Client) VolumeCreate(request *api.VolumeCreateRequest) (
 
 }
 
+// VolumeImport adopts a gluster volume created outside of heketi
+func (c *Client) VolumeImport(request *api.VolumeImportRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/volumes/import",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.waitForResponseWithTimer(r, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
 func (c *Client) VolumeExpand(id string, request *api.VolumeExpandRequest) (
 	*api.VolumeInfoResponse, error) {
 
+	return c.volumeExpand(id, request, "")
+}
+
+// VolumeExpandWithKey expands the volume once, however many times
+// it is called with the same idempotency key
+func (c *Client) VolumeExpandWithKey(id string,
+	request *api.VolumeExpandRequest,
+	idempotencyKey string) (*api.VolumeInfoResponse, error) {
+
+	return c.volumeExpand(id, request, idempotencyKey)
+}
+
+func (c *Client) volumeExpand(id string,
+	request *api.VolumeExpandRequest,
+	idempotencyKey string) (*api.VolumeInfoResponse, error) {
+
 	// Marshal request to JSON
 	buffer, err := json.Marshal(request)
 	if err != nil {
@@ -93,6 +179,230 @@ func (c *Client) VolumeExpand(id string, request *api.VolumeExpandRequest) (
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setIdempotencyKey(req, idempotencyKey)
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.waitForResponseWithTimer(r, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+
+}
+
+// VolumePlan returns where the bricks of the volume would be
+// placed, without creating it
+func (c *Client) VolumePlan(request *api.VolumeCreateRequest) (
+	*api.VolumePlanResponse, error) {
+
+	return c.volumePlan(c.host+"/volumes/plan", request)
+}
+
+// VolumeExpandPlan returns where the bricks added by the expansion
+// would be placed, without expanding the volume
+func (c *Client) VolumeExpandPlan(id string, request *api.VolumeExpandRequest) (
+	*api.VolumePlanResponse, error) {
+
+	return c.volumePlan(c.host+"/volumes/"+id+"/expand?dry_run=true", request)
+}
+
+func (c *Client) volumePlan(planUrl string, request interface{}) (
+	*api.VolumePlanResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST", planUrl, bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var plan api.VolumePlanResponse
+	err = utils.GetJsonFromResponse(r, &plan)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+func (c *Client) VolumeShrink(id string, request *api.VolumeShrinkRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/volumes/"+id+"/shrink",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.waitForResponseWithTimer(r, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+
+}
+
+func (c *Client) VolumeSetOptions(id string, request *api.VolumeOptionsRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/volumes/"+id+"/options",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.waitForResponseWithTimer(r, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+
+}
+
+func (c *Client) VolumeStop(id string) (*api.VolumeInfoResponse, error) {
+	return c.volumeSetState(id, "stop")
+}
+
+func (c *Client) VolumeStart(id string) (*api.VolumeInfoResponse, error) {
+	return c.volumeSetState(id, "start")
+}
+
+// VolumeRestore takes a deleted volume out of the trash
+func (c *Client) VolumeRestore(id string) (*api.VolumeInfoResponse, error) {
+	return c.volumeSetState(id, "restore")
+}
+
+func (c *Client) volumeSetState(id, action string) (*api.VolumeInfoResponse, error) {
+
+	// Create a request
+	req, err := http.NewRequest("POST", c.host+"/volumes/"+id+"/"+action, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set token
 	err = c.setToken(req)
@@ -131,9 +441,36 @@ func (c *Client) VolumeExpand(id string, request *api.VolumeExpandRequest) (
 }
 
 func (c *Client) VolumeList() (*api.VolumeListResponse, error) {
+	return c.VolumeListByTags(nil)
+}
+
+// Lists the volumes with all the tags in the filters.
+// Filters are in "key" or "key:value" form.
+func (c *Client) VolumeListByTags(filters []string) (*api.VolumeListResponse, error) {
+	return c.volumeList(filters, false)
+}
+
+// VolumeListWithTrash is like VolumeListByTags, but also lists
+// the deleted volumes kept in the trash
+func (c *Client) VolumeListWithTrash(filters []string) (*api.VolumeListResponse, error) {
+	return c.volumeList(filters, true)
+}
+
+func (c *Client) volumeList(filters []string, trash bool) (*api.VolumeListResponse, error) {
 
 	// Create request
-	req, err := http.NewRequest("GET", c.host+"/volumes", nil)
+	listUrl := c.host + "/volumes"
+	params := url.Values{}
+	if len(filters) > 0 {
+		params["tag"] = filters
+	}
+	if trash {
+		params.Set("include_trash", "true")
+	}
+	if len(params) > 0 {
+		listUrl += "?" + params.Encode()
+	}
+	req, err := http.NewRequest("GET", listUrl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -164,9 +501,19 @@ func (c *Client) VolumeList() (*api.VolumeListResponse, error) {
 }
 
 func (c *Client) VolumeInfo(id string) (*api.VolumeInfoResponse, error) {
+	return c.volumeInfo(c.host + "/volumes/" + id)
+}
+
+// VolumeInfoWithUsage also reports how much space the volume and
+// its bricks use, as collected from the nodes
+func (c *Client) VolumeInfoWithUsage(id string) (*api.VolumeInfoResponse, error) {
+	return c.volumeInfo(c.host + "/volumes/" + id + "?usage=true")
+}
+
+func (c *Client) volumeInfo(url string) (*api.VolumeInfoResponse, error) {
 
 	// Create request
-	req, err := http.NewRequest("GET", c.host+"/volumes/"+id, nil)
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -197,13 +544,32 @@ func (c *Client) VolumeInfo(id string) (*api.VolumeInfoResponse, error) {
 	return &volume, nil
 }
 
+// VolumeDelete destroys the volume, or moves it to the trash
+// when the server keeps deleted volumes
 func (c *Client) VolumeDelete(id string) error {
+	return c.volumeDelete(c.host+"/volumes/"+id, "")
+}
+
+// VolumeDeleteWithKey succeeds again when retried with the same
+// idempotency key, even though the volume is already gone
+func (c *Client) VolumeDeleteWithKey(id string, idempotencyKey string) error {
+	return c.volumeDelete(c.host+"/volumes/"+id, idempotencyKey)
+}
+
+// VolumePurge destroys the volume right away, even when the
+// server keeps deleted volumes in the trash
+func (c *Client) VolumePurge(id string) error {
+	return c.volumeDelete(c.host+"/volumes/"+id+"?purge=true", "")
+}
+
+func (c *Client) volumeDelete(url string, idempotencyKey string) error {
 
 	// Create a request
-	req, err := http.NewRequest("DELETE", c.host+"/volumes/"+id, nil)
+	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return err
 	}
+	setIdempotencyKey(req, idempotencyKey)
 
 	// Set token
 	err = c.setToken(req)
@@ -231,3 +597,10 @@ func (c *Client) VolumeDelete(id string) error {
 
 	return nil
 }
+
+// Requests without a key run every time they are sent
+func setIdempotencyKey(req *http.Request, idempotencyKey string) {
+	if idempotencyKey != "" {
+		req.Header.Set(api.IdempotencyKeyHeader, idempotencyKey)
+	}
+}
@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bitrotScrubFrequency string
+	bitrotScrubThrottle  string
+)
+
+func initVolumeBitrotCommand() {
+	volumeCommand.AddCommand(volumeBitrotCommand)
+	volumeBitrotCommand.AddCommand(
+		volumeBitrotEnableCommand,
+		volumeBitrotDisableCommand,
+		volumeBitrotScrubCommand,
+		volumeBitrotStatusCommand,
+	)
+
+	volumeBitrotScrubCommand.Flags().StringVar(&bitrotScrubFrequency, "frequency", "",
+		"\n\tOptional: How often the files are scrubbed."+
+			"\n\tOne of hourly, daily, weekly, biweekly or monthly")
+	volumeBitrotScrubCommand.Flags().StringVar(&bitrotScrubThrottle, "throttle", "",
+		"\n\tOptional: How much of the node resources the scrubber uses."+
+			"\n\tOne of lazy, normal or aggressive")
+	volumeBitrotEnableCommand.SilenceUsage = true
+	volumeBitrotDisableCommand.SilenceUsage = true
+	volumeBitrotScrubCommand.SilenceUsage = true
+	volumeBitrotStatusCommand.SilenceUsage = true
+}
+
+var volumeBitrotCommand = &cobra.Command{
+	Use:   "bitrot",
+	Short: "Volume bitrot detection Management",
+	Long:  "Heketi Volume bitrot detection Management",
+}
+
+var volumeBitrotEnableCommand = &cobra.Command{
+	Use:     "enable",
+	Short:   "Enables bitrot detection on a volume",
+	Long:    "Enables bitrot detection on a volume",
+	Example: "  $ heketi-cli volume bitrot enable 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeBitrotEnable(id)
+		})
+	},
+}
+
+var volumeBitrotDisableCommand = &cobra.Command{
+	Use:     "disable",
+	Short:   "Disables bitrot detection on a volume",
+	Long:    "Disables bitrot detection on a volume",
+	Example: "  $ heketi-cli volume bitrot disable 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeBitrotDisable(id)
+		})
+	},
+}
+
+var volumeBitrotScrubCommand = &cobra.Command{
+	Use:   "scrub",
+	Short: "Sets the scrub frequency and throttle of a volume",
+	Long:  "Sets how often and how fast the scrubber checks the files of a volume",
+	Example: `  * Scrub the volume every week without loading the nodes:
+    $ heketi-cli volume bitrot scrub 886a86a868711bef83001 \
+      --frequency=weekly --throttle=lazy
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		if bitrotScrubFrequency == "" && bitrotScrubThrottle == "" {
+			return errors.New("Missing frequency or throttle")
+		}
+
+		req := &api.VolumeBitrotScrubRequest{
+			Frequency: bitrotScrubFrequency,
+			Throttle:  bitrotScrubThrottle,
+		}
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		volume, err := heketi.VolumeBitrotScrub(volumeId, req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(volume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", volume)
+		}
+
+		return nil
+	},
+}
+
+var volumeBitrotStatusCommand = &cobra.Command{
+	Use:     "status",
+	Short:   "Shows the scrub status of a volume",
+	Long:    "Shows the scrub status of a volume and the corrupted files found on each node",
+	Example: "  $ heketi-cli volume bitrot status 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		status, err := heketi.VolumeBitrotStatus(volumeId)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(status)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", status)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,210 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	blockVolumeSize     int
+	blockVolumeName     string
+	blockVolumeClusters string
+	blockVolumeHacount  int
+	blockVolumeAuth     bool
+)
+
+func initBlockVolumeCommand() {
+	RootCmd.AddCommand(blockVolumeCommand)
+	blockVolumeCommand.AddCommand(
+		blockVolumeCreateCommand,
+		blockVolumeDeleteCommand,
+		blockVolumeInfoCommand,
+		blockVolumeListCommand,
+	)
+
+	blockVolumeCreateCommand.Flags().IntVar(&blockVolumeSize, "size", -1,
+		"\n\tSize of block volume in GB")
+	blockVolumeCreateCommand.Flags().StringVar(&blockVolumeName, "name", "",
+		"\n\tOptional: Name of block volume. Only set if really necessary")
+	blockVolumeCreateCommand.Flags().StringVar(&blockVolumeClusters, "clusters", "",
+		"\n\tOptional: Comma separated list of cluster ids where this block volume"+
+			"\n\tmust be allocated. If omitted, Heketi will allocate the block volume"+
+			"\n\ton any of the configured clusters which have the available space.")
+	blockVolumeCreateCommand.Flags().IntVar(&blockVolumeHacount, "ha", 0,
+		"\n\tOptional: Number of nodes exporting the block volume."+
+			"\n\tIf omitted, all the nodes of the block hosting volume export it.")
+	blockVolumeCreateCommand.Flags().BoolVar(&blockVolumeAuth, "auth", false,
+		"\n\tOptional: Require CHAP authentication to access the block volume")
+	blockVolumeCreateCommand.SilenceUsage = true
+	blockVolumeDeleteCommand.SilenceUsage = true
+	blockVolumeInfoCommand.SilenceUsage = true
+	blockVolumeListCommand.SilenceUsage = true
+}
+
+var blockVolumeCommand = &cobra.Command{
+	Use:   "blockvolume",
+	Short: "Heketi Block Volume Management",
+	Long:  "Heketi Block Volume Management",
+}
+
+var blockVolumeCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "Create a GlusterFS block volume",
+	Long:  "Create a GlusterFS block volume",
+	Example: `  * Create a 100GB block volume:
+      $ heketi-cli blockvolume create --size=100
+
+  * Create a 100GB block volume exported by two nodes with authentication:
+      $ heketi-cli blockvolume create --size=100 --ha=2 --auth
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check block volume size
+		if blockVolumeSize == -1 {
+			return errors.New("Missing block volume size")
+		}
+
+		// Create request blob
+		req := &api.BlockVolumeCreateRequest{}
+		req.Size = blockVolumeSize
+		req.Name = blockVolumeName
+		req.Hacount = blockVolumeHacount
+		req.Auth = blockVolumeAuth
+
+		// Check clusters
+		if blockVolumeClusters != "" {
+			req.Clusters = strings.Split(blockVolumeClusters, ",")
+		}
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// Add block volume
+		blockVolume, err := heketi.BlockVolumeCreate(req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(blockVolume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", blockVolume)
+		}
+
+		return nil
+	},
+}
+
+var blockVolumeDeleteCommand = &cobra.Command{
+	Use:     "delete",
+	Short:   "Deletes the block volume",
+	Long:    "Deletes the block volume",
+	Example: "  $ heketi-cli blockvolume delete 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Block volume id missing")
+		}
+		blockVolumeId := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		err := heketi.BlockVolumeDelete(blockVolumeId)
+		if err == nil {
+			fmt.Fprintf(stdout, "Block volume %v deleted\n", blockVolumeId)
+		}
+
+		return err
+	},
+}
+
+var blockVolumeInfoCommand = &cobra.Command{
+	Use:     "info",
+	Short:   "Retrieves information about the block volume",
+	Long:    "Retrieves information about the block volume",
+	Example: "  $ heketi-cli blockvolume info 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Block volume id missing")
+		}
+		blockVolumeId := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		info, err := heketi.BlockVolumeInfo(blockVolumeId)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", info)
+		}
+		return nil
+	},
+}
+
+var blockVolumeListCommand = &cobra.Command{
+	Use:     "list",
+	Short:   "Lists the block volumes managed by Heketi",
+	Long:    "Lists the block volumes managed by Heketi",
+	Example: "  $ heketi-cli blockvolume list",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// List block volumes
+		list, err := heketi.BlockVolumeList()
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(list)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			for _, id := range list.BlockVolumes {
+				blockVolume, err := heketi.BlockVolumeInfo(id)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(stdout, "Id:%-35v Cluster:%-35v Name:%v\n",
+					id,
+					blockVolume.Cluster,
+					blockVolume.Name)
+			}
+		}
+
+		return nil
+	},
+}
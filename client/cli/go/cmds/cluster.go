@@ -16,6 +16,7 @@ import (
 	"strings"
 
 	"github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/spf13/cobra"
 )
 
@@ -25,10 +26,32 @@ func init() {
 	clusterCommand.AddCommand(clusterDeleteCommand)
 	clusterCommand.AddCommand(clusterListCommand)
 	clusterCommand.AddCommand(clusterInfoCommand)
+	clusterCommand.AddCommand(clusterBrickLimitsCommand)
+	initTagsCommands(clusterCommand, "cluster",
+		func(heketi *client.Client, id string, req *api.TagsChangeRequest) (interface{}, error) {
+			return heketi.ClusterSetTags(id, req)
+		})
+	clusterCreateCommand.Flags().StringVar(&tags, "tags", "",
+		"\n\tOptional: Comma separated list of key:value tags of the cluster.")
+	for _, c := range []*cobra.Command{clusterCreateCommand, clusterBrickLimitsCommand} {
+		c.Flags().IntVar(&brickMinSize, "brick-min-size", 0,
+			"\n\tOptional: Minimum size in GiB of the bricks of the volumes"+
+				"\n\tof the cluster.  Defaults to the server configuration.")
+		c.Flags().IntVar(&brickMaxSize, "brick-max-size", 0,
+			"\n\tOptional: Maximum size in GiB of the bricks of the volumes"+
+				"\n\tof the cluster.  Defaults to the server configuration.")
+		c.Flags().IntVar(&brickMaxNum, "max-bricks", 0,
+			"\n\tOptional: Maximum number of bricks of the volumes of the"+
+				"\n\tcluster.  Defaults to the server configuration.")
+	}
+	clusterListCommand.Flags().StringVar(&tagFilters, "tags", "",
+		"\n\tOptional: Comma separated list of key or key:value tags."+
+			"\n\tOnly clusters with all these tags are listed.")
 	clusterCreateCommand.SilenceUsage = true
 	clusterDeleteCommand.SilenceUsage = true
 	clusterInfoCommand.SilenceUsage = true
 	clusterListCommand.SilenceUsage = true
+	clusterBrickLimitsCommand.SilenceUsage = true
 }
 
 var clusterCommand = &cobra.Command{
@@ -41,12 +64,24 @@ var clusterCreateCommand = &cobra.Command{
 	Use:     "create",
 	Short:   "Create a cluster",
 	Long:    "Create a cluster",
-	Example: "  $ heketi-cli cluster create",
+	Example: "  $ heketi-cli cluster create --tags=owner:storage-team",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		req := &api.ClusterCreateRequest{}
+		t, err := parseTags(tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = t
+		req.BrickLimits = api.BrickLimits{
+			MinSize: brickMinSize,
+			MaxSize: brickMaxSize,
+			MaxNum:  brickMaxNum,
+		}
+
 		// Create a client to talk to Heketi
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 		// Create cluster
-		cluster, err := heketi.ClusterCreate()
+		cluster, err := heketi.ClusterCreateWithRequest(req)
 		if err != nil {
 			return err
 		}
@@ -129,23 +164,83 @@ var clusterInfoCommand = &cobra.Command{
 			fmt.Fprintf(stdout, "Cluster id: %v\n", info.Id)
 			fmt.Fprintf(stdout, "Nodes:\n%v", strings.Join(info.Nodes, "\n"))
 			fmt.Fprintf(stdout, "\nVolumes:\n%v", strings.Join(info.Volumes, "\n"))
+			if len(info.Tags) > 0 {
+				fmt.Fprintf(stdout, "\nTags: %v", tagsString(info.Tags))
+			}
+			printBrickLimits(&info.BrickLimits)
 		}
 
 		return nil
 	},
 }
 
+var clusterBrickLimitsCommand = &cobra.Command{
+	Use:   "brick-limits [cluster_id]",
+	Short: "Sets the limits on the bricks of the volumes of the cluster",
+	Long: "Sets the limits on the size and number of the bricks of the " +
+		"volumes of the cluster.  Unset limits fall back to the server configuration.",
+	Example: "  $ heketi-cli cluster brick-limits 886a86a868711bef83001 " +
+		"--brick-min-size=10 --max-bricks=64",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := cmd.Flags().Args()
+		if len(s) < 1 {
+			return errors.New("Cluster id missing")
+		}
+
+		//set clusterId
+		clusterId := cmd.Flags().Arg(0)
+
+		// Create a client to talk to Heketi
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		info, err := heketi.ClusterSetBrickLimits(clusterId, &api.BrickLimits{
+			MinSize: brickMinSize,
+			MaxSize: brickMaxSize,
+			MaxNum:  brickMaxNum,
+		})
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "Cluster id: %v", info.Id)
+			printBrickLimits(&info.BrickLimits)
+			fmt.Fprintf(stdout, "\n")
+		}
+
+		return nil
+	},
+}
+
+func printBrickLimits(l *api.BrickLimits) {
+	if l.MinSize != 0 {
+		fmt.Fprintf(stdout, "\nBrick Min Size: %v GiB", l.MinSize)
+	}
+	if l.MaxSize != 0 {
+		fmt.Fprintf(stdout, "\nBrick Max Size: %v GiB", l.MaxSize)
+	}
+	if l.MaxNum != 0 {
+		fmt.Fprintf(stdout, "\nMax Bricks: %v", l.MaxNum)
+	}
+}
+
 var clusterListCommand = &cobra.Command{
 	Use:     "list",
 	Short:   "Lists the clusters managed by Heketi",
 	Long:    "Lists the clusters managed by Heketi",
-	Example: "  $ heketi-cli cluster list",
+	Example: "  $ heketi-cli cluster list --tags=owner:storage-team",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Create a client
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
 		// List clusters
-		list, err := heketi.ClusterList()
+		list, err := heketi.ClusterListByTags(parseTagFilters(tagFilters))
 		if err != nil {
 			return err
 		}
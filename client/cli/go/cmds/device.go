@@ -21,6 +21,7 @@ import (
 
 var (
 	device, nodeId string
+	deviceClass    string
 )
 
 func init() {
@@ -36,6 +37,16 @@ func init() {
 		"Name of device to add")
 	deviceAddCommand.Flags().StringVar(&nodeId, "node", "",
 		"Id of the node which has this device")
+	deviceAddCommand.Flags().StringVar(&deviceClass, "class", "",
+		"Class of the device, like ssd or hdd")
+	deviceAddCommand.Flags().StringVar(&tags, "tags", "",
+		"Comma separated list of key:value tags of the device")
+	deviceInfoCommand.Flags().BoolVar(&showUsage, "usage", false,
+		"Show how much space the bricks use, as reported by the node")
+	initTagsCommands(deviceCommand, "device",
+		func(heketi *client.Client, id string, req *api.TagsChangeRequest) (interface{}, error) {
+			return heketi.DeviceSetTags(id, req)
+		})
 	deviceAddCommand.SilenceUsage = true
 	deviceDeleteCommand.SilenceUsage = true
 	deviceRemoveCommand.SilenceUsage = true
@@ -55,7 +66,8 @@ var deviceAddCommand = &cobra.Command{
 	Long:  "Add new device to node to be managed by Heketi",
 	Example: `  $ heketi-cli device add \
       --name=/dev/sdb
-      --node=3e098cb4407d7109806bb196d9e8f095 `,
+      --node=3e098cb4407d7109806bb196d9e8f095 \
+      --class=ssd`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check arguments
 		if device == "" {
@@ -69,12 +81,18 @@ var deviceAddCommand = &cobra.Command{
 		req := &api.DeviceAddRequest{}
 		req.Name = device
 		req.NodeId = nodeId
+		req.Class = deviceClass
+		t, err := parseTags(tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = t
 
 		// Create a client
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
 		// Add node
-		err := heketi.DeviceAdd(req)
+		err = heketi.DeviceAdd(req)
 		if err != nil {
 			return err
 		} else {
@@ -165,7 +183,13 @@ var deviceInfoCommand = &cobra.Command{
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
 		// Create cluster
-		info, err := heketi.DeviceInfo(deviceId)
+		var info *api.DeviceInfoResponse
+		var err error
+		if showUsage {
+			info, err = heketi.DeviceInfoWithUsage(deviceId)
+		} else {
+			info, err = heketi.DeviceInfo(deviceId)
+		}
 		if err != nil {
 			return err
 		}
@@ -194,15 +218,34 @@ var deviceInfoCommand = &cobra.Command{
 				info.Storage.Total/(1024*1024),
 				info.Storage.Used/(1024*1024),
 				info.Storage.Free/(1024*1024))
+			if info.Class != "" {
+				fmt.Fprintf(stdout, "Class: %v\n", info.Class)
+			}
+			if len(info.Tags) > 0 {
+				fmt.Fprintf(stdout, "Tags: %v\n", tagsString(info.Tags))
+			}
+			if info.Usage != nil {
+				fmt.Fprintf(stdout, "Used on Bricks (GiB): %v\n"+
+					"Fullest Thin Pool Data: %.2f%%\n"+
+					"Fullest Thin Pool Metadata: %.2f%%\n",
+					info.Usage.Used/(1024*1024*1024),
+					info.Usage.PoolDataPercent,
+					info.Usage.PoolMetadataPercent)
+			}
 
 			fmt.Fprintf(stdout, "Bricks:\n")
 			for _, d := range info.Bricks {
 				fmt.Fprintf(stdout, "Id:%-35v"+
-					"Size (GiB):%-8v"+
-					"Path: %v\n",
+					"Size (GiB):%-8v",
 					d.Id,
-					d.Size/(1024*1024),
-					d.Path)
+					d.Size/(1024*1024))
+				if d.Usage != nil {
+					fmt.Fprintf(stdout, "Used (GiB):%-8v"+
+						"Thin Pool:%-8s",
+						d.Usage.Used/(1024*1024*1024),
+						fmt.Sprintf("%.1f%%", d.Usage.PoolDataPercent))
+				}
+				fmt.Fprintf(stdout, "Path: %v\n", d.Path)
 			}
 		}
 		return nil
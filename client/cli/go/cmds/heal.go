@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var healFull bool
+
+func initVolumeHealCommand() {
+	volumeCommand.AddCommand(volumeHealCommand)
+	volumeHealCommand.AddCommand(
+		volumeHealInfoCommand,
+		volumeHealStartCommand,
+	)
+
+	volumeHealStartCommand.Flags().BoolVar(&healFull, "full", false,
+		"\n\tOptional: Crawl the whole volume instead of only healing"+
+			"\n\tthe entries gluster recorded as pending heal")
+	volumeHealInfoCommand.SilenceUsage = true
+	volumeHealStartCommand.SilenceUsage = true
+}
+
+var volumeHealCommand = &cobra.Command{
+	Use:   "heal",
+	Short: "Volume self heal Management",
+	Long:  "Heketi Volume self heal Management",
+}
+
+var volumeHealInfoCommand = &cobra.Command{
+	Use:   "info",
+	Short: "Shows the entries pending heal on each brick of a volume",
+	Long: "Shows the entries pending heal on each brick of a volume. " +
+		"Nodes should only be taken offline when no entries are pending",
+	Example: "  $ heketi-cli volume heal info 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		info, err := heketi.VolumeHealInfo(volumeId)
+		if err != nil {
+			return err
+		}
+
+		return printHealInfo(info)
+	},
+}
+
+var volumeHealStartCommand = &cobra.Command{
+	Use:   "start",
+	Short: "Starts healing a volume",
+	Long:  "Starts healing a volume",
+	Example: `  * Heal the entries pending heal:
+    $ heketi-cli volume heal start 886a86a868711bef83001
+
+  * Crawl the whole volume:
+    $ heketi-cli volume heal start 886a86a868711bef83001 --full
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		req := &api.VolumeHealRequest{
+			Type: api.HealIndex,
+		}
+		if healFull {
+			req.Type = api.HealFull
+		}
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		info, err := heketi.VolumeHeal(volumeId, req)
+		if err != nil {
+			return err
+		}
+
+		return printHealInfo(info)
+	},
+}
+
+func printHealInfo(info *api.VolumeHealInfoResponse) error {
+	if options.Json {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, string(data))
+	} else {
+		fmt.Fprintf(stdout, "%v", info)
+	}
+
+	return nil
+}
@@ -35,15 +35,24 @@ func init() {
 	nodeCommand.AddCommand(nodeDisableCommand)
 	nodeCommand.AddCommand(nodeListCommand)
 	nodeCommand.AddCommand(nodeRemoveCommand)
+	nodeCommand.AddCommand(nodeSetHostnamesCommand)
 	nodeAddCommand.Flags().IntVar(&zone, "zone", -1, "The zone in which the node should reside")
 	nodeAddCommand.Flags().StringVar(&clusterId, "cluster", "", "The cluster in which the node should reside")
 	nodeAddCommand.Flags().StringVar(&managmentHostNames, "management-host-name", "", "Management host name")
 	nodeAddCommand.Flags().StringVar(&storageHostNames, "storage-host-name", "", "Storage host name")
+	nodeAddCommand.Flags().StringVar(&tags, "tags", "", "Comma separated list of key:value tags of the node")
+	nodeSetHostnamesCommand.Flags().StringVar(&managmentHostNames, "management-host-name", "", "New management host name")
+	nodeSetHostnamesCommand.Flags().StringVar(&storageHostNames, "storage-host-name", "", "New storage host name")
+	initTagsCommands(nodeCommand, "node",
+		func(heketi *client.Client, id string, req *api.TagsChangeRequest) (interface{}, error) {
+			return heketi.NodeSetTags(id, req)
+		})
 	nodeAddCommand.SilenceUsage = true
 	nodeDeleteCommand.SilenceUsage = true
 	nodeInfoCommand.SilenceUsage = true
 	nodeListCommand.SilenceUsage = true
 	nodeRemoveCommand.SilenceUsage = true
+	nodeSetHostnamesCommand.SilenceUsage = true
 }
 
 var nodeCommand = &cobra.Command{
@@ -83,6 +92,11 @@ var nodeAddCommand = &cobra.Command{
 		req.Hostnames.Manage = []string{managmentHostNames}
 		req.Hostnames.Storage = []string{storageHostNames}
 		req.Zone = zone
+		t, err := parseTags(tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = t
 
 		// Create a client
 		heketi := client.NewClient(options.Url, options.User, options.Key)
@@ -289,6 +303,9 @@ var nodeInfoCommand = &cobra.Command{
 				info.Zone,
 				info.Hostnames.Manage[0],
 				info.Hostnames.Storage[0])
+			if len(info.Tags) > 0 {
+				fmt.Fprintf(stdout, "Tags: %v\n", tagsString(info.Tags))
+			}
 			fmt.Fprintf(stdout, "Devices:\n")
 			for _, d := range info.DevicesInfo {
 				fmt.Fprintf(stdout, "Id:%-35v"+
@@ -340,3 +357,59 @@ var nodeRemoveCommand = &cobra.Command{
 		return err
 	},
 }
+
+var nodeSetHostnamesCommand = &cobra.Command{
+	Use:   "set-hostnames [node_id]",
+	Short: "Changes the hostnames of a node",
+	Long: "Changes the management and storage hostnames of a node, like after\n" +
+		"renumbering its network, without moving its bricks",
+	Example: `  $ heketi-cli node set-hostnames 886a86a868711bef83001 \
+      --management-host-name=node1-manage.gluster.lab.com \
+      --storage-host-name=node1-storage.gluster.lab.com
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := cmd.Flags().Args()
+
+		//ensure proper number of args
+		if len(s) < 1 {
+			return errors.New("Node id missing")
+		}
+		if managmentHostNames == "" {
+			return errors.New("Missing management hostname")
+		}
+		if storageHostNames == "" {
+			return errors.New("Missing storage hostname")
+		}
+
+		nodeId := cmd.Flags().Arg(0)
+
+		// Create request blob
+		req := &api.NodeHostnamesRequest{}
+		req.Hostnames.Manage = []string{managmentHostNames}
+		req.Hostnames.Storage = []string{storageHostNames}
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		node, err := heketi.NodeSetHostnames(nodeId, req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(node)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "Node %v hostnames changed\n"+
+				"Management Hostname %v\n"+
+				"Storage Hostname %v\n",
+				node.Id,
+				node.Hostnames.Manage[0],
+				node.Hostnames.Storage[0])
+		}
+		return nil
+	},
+}
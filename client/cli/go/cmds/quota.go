@@ -0,0 +1,229 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	quotaPath      string
+	quotaHardLimit string
+	quotaSoftLimit int
+)
+
+func initVolumeQuotaCommand() {
+	volumeCommand.AddCommand(volumeQuotaCommand)
+	volumeQuotaCommand.AddCommand(
+		volumeQuotaEnableCommand,
+		volumeQuotaDisableCommand,
+		volumeQuotaListCommand,
+		volumeQuotaSetCommand,
+		volumeQuotaRemoveCommand,
+	)
+
+	volumeQuotaSetCommand.Flags().StringVar(&quotaPath, "path", "",
+		"\n\tDirectory of the volume to limit, starting with /")
+	volumeQuotaSetCommand.Flags().StringVar(&quotaHardLimit, "hard-limit", "",
+		"\n\tHard limit in bytes, or with a K, M, G or T suffix")
+	volumeQuotaSetCommand.Flags().IntVar(&quotaSoftLimit, "soft-limit", 0,
+		"\n\tOptional: Percentage of the hard limit at which warnings"+
+			"\n\tare logged. Defaults to the gluster default")
+	volumeQuotaRemoveCommand.Flags().StringVar(&quotaPath, "path", "",
+		"\n\tDirectory of the volume whose limit is removed")
+	volumeQuotaEnableCommand.SilenceUsage = true
+	volumeQuotaDisableCommand.SilenceUsage = true
+	volumeQuotaListCommand.SilenceUsage = true
+	volumeQuotaSetCommand.SilenceUsage = true
+	volumeQuotaRemoveCommand.SilenceUsage = true
+}
+
+// Converts a size like 512M or 10G to bytes
+func parseQuotaSize(size string) (uint64, error) {
+	multiplier := uint64(1)
+	number := strings.ToUpper(size)
+	for i, suffix := range []string{"K", "M", "G", "T"} {
+		if strings.HasSuffix(number, suffix) {
+			multiplier = 1 << (10 * uint(i+1))
+			number = strings.TrimSuffix(number, suffix)
+			break
+		}
+	}
+
+	value, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("Invalid size %v", size)
+	}
+
+	return value * multiplier, nil
+}
+
+var volumeQuotaCommand = &cobra.Command{
+	Use:   "quota",
+	Short: "Volume directory quota Management",
+	Long:  "Heketi Volume directory quota Management",
+}
+
+var volumeQuotaEnableCommand = &cobra.Command{
+	Use:     "enable",
+	Short:   "Enables quota on a volume",
+	Long:    "Enables quota on a volume",
+	Example: "  $ heketi-cli volume quota enable 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeQuotaEnable(id)
+		})
+	},
+}
+
+var volumeQuotaDisableCommand = &cobra.Command{
+	Use:     "disable",
+	Short:   "Disables quota on a volume",
+	Long:    "Disables quota on a volume, removing all of its limits",
+	Example: "  $ heketi-cli volume quota disable 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeQuotaDisable(id)
+		})
+	},
+}
+
+var volumeQuotaListCommand = &cobra.Command{
+	Use:     "list",
+	Short:   "Lists the quota limits of a volume and their usage",
+	Long:    "Lists the quota limits of a volume and their usage",
+	Example: "  $ heketi-cli volume quota list 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		quota, err := heketi.VolumeQuota(volumeId)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(quota)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", quota)
+		}
+
+		return nil
+	},
+}
+
+var volumeQuotaSetCommand = &cobra.Command{
+	Use:   "set",
+	Short: "Sets the quota limit of a directory",
+	Long:  "Sets the quota limit of a directory of a volume, replacing any existing limit",
+	Example: `  * Limit a directory to 10GiB:
+    $ heketi-cli volume quota set 886a86a868711bef83001 \
+      --path=/projects/web --hard-limit=10G
+
+  * Log warnings once 70% of the limit is used:
+    $ heketi-cli volume quota set 886a86a868711bef83001 \
+      --path=/projects/web --hard-limit=10G --soft-limit=70
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		if quotaPath == "" {
+			return errors.New("Missing directory path")
+		}
+		if quotaHardLimit == "" {
+			return errors.New("Missing hard limit")
+		}
+		hardLimit, err := parseQuotaSize(quotaHardLimit)
+		if err != nil {
+			return err
+		}
+
+		req := &api.VolumeQuotaRequest{
+			Set: []api.QuotaLimit{
+				api.QuotaLimit{
+					Path:      quotaPath,
+					HardLimit: hardLimit,
+					SoftLimit: quotaSoftLimit,
+				},
+			},
+		}
+
+		return volumeSetQuotaLimits(volumeId, req)
+	},
+}
+
+var volumeQuotaRemoveCommand = &cobra.Command{
+	Use:     "remove",
+	Short:   "Removes the quota limit of a directory",
+	Long:    "Removes the quota limit of a directory of a volume",
+	Example: "  $ heketi-cli volume quota remove 886a86a868711bef83001 --path=/projects/web",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		if quotaPath == "" {
+			return errors.New("Missing directory path")
+		}
+
+		req := &api.VolumeQuotaRequest{
+			Remove: []string{quotaPath},
+		}
+
+		return volumeSetQuotaLimits(volumeId, req)
+	},
+}
+
+func volumeSetQuotaLimits(volumeId string, req *api.VolumeQuotaRequest) error {
+
+	// Create a client
+	heketi := client.NewClient(options.Url, options.User, options.Key)
+
+	volume, err := heketi.VolumeQuotaSetLimits(volumeId, req)
+	if err != nil {
+		return err
+	}
+
+	if options.Json {
+		data, err := json.Marshal(volume)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, string(data))
+	} else {
+		fmt.Fprintf(stdout, "%v", volume)
+	}
+
+	return nil
+}
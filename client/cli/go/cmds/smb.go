@@ -0,0 +1,85 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"errors"
+	"strings"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	smbNodes     string
+	smbShareName string
+)
+
+func initVolumeSmbCommand() {
+	volumeCommand.AddCommand(volumeSmbCommand)
+	volumeSmbCommand.AddCommand(
+		volumeSmbShareCommand,
+		volumeSmbUnshareCommand,
+	)
+
+	volumeSmbShareCommand.Flags().StringVar(&smbNodes, "nodes", "",
+		"\n\tComma separated list of ids of the nodes whose Samba server"+
+			"\n\tshares the volume")
+	volumeSmbShareCommand.Flags().StringVar(&smbShareName, "share-name", "",
+		"\n\tOptional: Name of the share.  Defaults to gluster-<volume name>")
+	volumeSmbShareCommand.SilenceUsage = true
+	volumeSmbUnshareCommand.SilenceUsage = true
+}
+
+var volumeSmbCommand = &cobra.Command{
+	Use:   "smb",
+	Short: "Volume SMB share Management",
+	Long:  "Heketi Volume SMB share Management",
+}
+
+var volumeSmbShareCommand = &cobra.Command{
+	Use:   "share",
+	Short: "Shares a volume over SMB",
+	Long: "Shares a volume over SMB from the Samba server of each node, " +
+		"using the vfs_glusterfs module",
+	Example: "  $ heketi-cli volume smb share 886a86a868711bef83001 " +
+		"--nodes=3e2a8d2d9bb6f3b0,b0e9b5a0bc3da2f5",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		if smbNodes == "" {
+			return errors.New("Nodes missing")
+		}
+
+		req := &api.VolumeSmbShareRequest{
+			Nodes:     strings.Split(smbNodes, ","),
+			ShareName: smbShareName,
+		}
+
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeSmbShare(id, req)
+		})
+	},
+}
+
+var volumeSmbUnshareCommand = &cobra.Command{
+	Use:     "unshare",
+	Short:   "Removes the SMB share of a volume",
+	Long:    "Removes the SMB share of a volume from every node sharing it",
+	Example: "  $ heketi-cli volume smb unshare 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeSmbUnshare(id)
+		})
+	},
+}
@@ -0,0 +1,290 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotName        string
+	snapshotDescription string
+	cloneName           string
+)
+
+func initVolumeSnapshotCommand() {
+	volumeCommand.AddCommand(volumeSnapshotCommand)
+	volumeSnapshotCommand.AddCommand(
+		volumeSnapshotCreateCommand,
+		volumeSnapshotListCommand,
+		volumeSnapshotInfoCommand,
+		volumeSnapshotDeleteCommand,
+		volumeSnapshotRestoreCommand,
+		volumeSnapshotCloneCommand,
+	)
+
+	volumeSnapshotCreateCommand.Flags().StringVar(&snapshotName, "name", "",
+		"\n\tOptional: Name of the snapshot. Must be unique in the cluster")
+	volumeSnapshotCreateCommand.Flags().StringVar(&snapshotDescription, "description", "",
+		"\n\tOptional: Description of the snapshot")
+	volumeSnapshotCloneCommand.Flags().StringVar(&cloneName, "name", "",
+		"\n\tOptional: Name of the new volume")
+	volumeSnapshotCreateCommand.SilenceUsage = true
+	volumeSnapshotListCommand.SilenceUsage = true
+	volumeSnapshotInfoCommand.SilenceUsage = true
+	volumeSnapshotDeleteCommand.SilenceUsage = true
+	volumeSnapshotRestoreCommand.SilenceUsage = true
+	volumeSnapshotCloneCommand.SilenceUsage = true
+}
+
+var volumeSnapshotCommand = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Volume snapshot Management",
+	Long:  "Heketi Volume snapshot Management",
+}
+
+var volumeSnapshotCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "Take a snapshot of a volume",
+	Long:  "Take a snapshot of a volume",
+	Example: `  * Take a snapshot of a volume:
+      $ heketi-cli volume snapshot create 886a86a868711bef83001
+
+  * Take a snapshot with a name and a description:
+      $ heketi-cli volume snapshot create 886a86a868711bef83001 \
+        --name=before_upgrade --description="Before the upgrade"
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		req := &api.SnapshotCreateRequest{
+			Name:        snapshotName,
+			Description: snapshotDescription,
+		}
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// Take snapshot
+		snapshot, err := heketi.SnapshotCreate(volumeId, req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", snapshot)
+		}
+
+		return nil
+	},
+}
+
+var volumeSnapshotListCommand = &cobra.Command{
+	Use:     "list",
+	Short:   "Lists the snapshots of a volume",
+	Long:    "Lists the snapshots of a volume",
+	Example: "  $ heketi-cli volume snapshot list 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// List snapshots
+		list, err := heketi.SnapshotList(volumeId)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(list)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			for _, id := range list.Snapshots {
+				snapshot, err := heketi.SnapshotInfo(volumeId, id)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(stdout, "Id:%-35v Name:%v\n",
+					id,
+					snapshot.Name)
+			}
+		}
+
+		return nil
+	},
+}
+
+var volumeSnapshotInfoCommand = &cobra.Command{
+	Use:     "info",
+	Short:   "Retreives information about the snapshot",
+	Long:    "Retreives information about the snapshot",
+	Example: "  $ heketi-cli volume snapshot info 886a86a868711bef83001 9f3b3e8dd6e6c2a4",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 2 {
+			return errors.New("Volume id and snapshot id are required")
+		}
+		volumeId := cmd.Flags().Arg(0)
+		snapshotId := cmd.Flags().Arg(1)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// Get snapshot information
+		snapshot, err := heketi.SnapshotInfo(volumeId, snapshotId)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", snapshot)
+		}
+
+		return nil
+	},
+}
+
+var volumeSnapshotDeleteCommand = &cobra.Command{
+	Use:     "delete",
+	Short:   "Deletes the snapshot",
+	Long:    "Deletes the snapshot",
+	Example: "  $ heketi-cli volume snapshot delete 886a86a868711bef83001 9f3b3e8dd6e6c2a4",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 2 {
+			return errors.New("Volume id and snapshot id are required")
+		}
+		volumeId := cmd.Flags().Arg(0)
+		snapshotId := cmd.Flags().Arg(1)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		err := heketi.SnapshotDelete(volumeId, snapshotId)
+		if err == nil {
+			fmt.Fprintf(stdout, "Snapshot %v deleted\n", snapshotId)
+		}
+
+		return err
+	},
+}
+
+var volumeSnapshotRestoreCommand = &cobra.Command{
+	Use:   "restore",
+	Short: "Restores the volume from the snapshot",
+	Long: "Restores the volume from the snapshot.  The volume is stopped while" +
+		" it is restored and the snapshot is removed afterwards.",
+	Example: "  $ heketi-cli volume snapshot restore 886a86a868711bef83001 9f3b3e8dd6e6c2a4",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 2 {
+			return errors.New("Volume id and snapshot id are required")
+		}
+		volumeId := cmd.Flags().Arg(0)
+		snapshotId := cmd.Flags().Arg(1)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		volume, err := heketi.SnapshotRestore(volumeId, snapshotId)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(volume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", volume)
+		}
+
+		return nil
+	},
+}
+
+var volumeSnapshotCloneCommand = &cobra.Command{
+	Use:   "clone",
+	Short: "Creates a new volume from the snapshot",
+	Long: "Creates a new writable volume from the snapshot.  The original" +
+		" volume cannot be deleted while the clone exists.",
+	Example: `  * Clone a snapshot:
+      $ heketi-cli volume snapshot clone 886a86a868711bef83001 9f3b3e8dd6e6c2a4
+
+  * Clone a snapshot into a volume with a specific name:
+      $ heketi-cli volume snapshot clone 886a86a868711bef83001 9f3b3e8dd6e6c2a4 \
+        --name=ci_copy
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 2 {
+			return errors.New("Volume id and snapshot id are required")
+		}
+		volumeId := cmd.Flags().Arg(0)
+		snapshotId := cmd.Flags().Arg(1)
+
+		req := &api.SnapshotCloneRequest{
+			Name: cloneName,
+		}
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		volume, err := heketi.SnapshotClone(volumeId, snapshotId, req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(volume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", volume)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tags       string
+	tagFilters string
+)
+
+// Parses tags in "key:value" form, either comma separated
+// in a single string or as separate arguments
+func parseTags(args ...string) (map[string]string, error) {
+	t := make(map[string]string)
+	for _, arg := range args {
+		for _, tag := range strings.Split(arg, ",") {
+			if tag == "" {
+				continue
+			}
+			kv := strings.SplitN(tag, ":", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("Invalid tag %v, expected key:value", tag)
+			}
+			t[kv[0]] = kv[1]
+		}
+	}
+
+	return t, nil
+}
+
+// Parses a comma separated list of "key" or "key:value" filters
+func parseTagFilters(s string) []string {
+	var filters []string
+	for _, filter := range strings.Split(s, ",") {
+		if filter != "" {
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters
+}
+
+func tagsString(t map[string]string) string {
+	pairs := make([]string, 0, len(t))
+	for key, value := range t {
+		pairs = append(pairs, key+":"+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+// Creates the settags and rmtags subcommands of an entity
+// whose tags are changed by setTags
+func initTagsCommands(parent *cobra.Command,
+	entity string,
+	setTags func(heketi *client.Client, id string, req *api.TagsChangeRequest) (interface{}, error)) {
+
+	var exact bool
+
+	run := func(cmd *cobra.Command, req *api.TagsChangeRequest) error {
+		id := cmd.Flags().Arg(0)
+
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		info, err := setTags(heketi, id, req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "Tags of %v %v changed\n", entity, id)
+		}
+		return nil
+	}
+
+	setTagsCommand := &cobra.Command{
+		Use:   "settags [" + entity + "_id] [key:value]...",
+		Short: "Sets tags on the " + entity,
+		Long:  "Sets tags on the " + entity,
+		Example: fmt.Sprintf("  $ heketi-cli %v settags 886a86a868711bef83001 owner:storage-team\n"+
+			"  $ heketi-cli %v settags --exact 886a86a868711bef83001 owner:storage-team",
+			entity, entity),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := cmd.Flags().Args()
+			if len(s) < 1 {
+				return fmt.Errorf("%v id missing", strings.Title(entity))
+			}
+			if len(s) < 2 && !exact {
+				return errors.New("Tags missing")
+			}
+
+			t, err := parseTags(s[1:]...)
+			if err != nil {
+				return err
+			}
+
+			req := &api.TagsChangeRequest{
+				Tags:   t,
+				Change: api.UpdateTags,
+			}
+			if exact {
+				req.Change = api.SetTags
+			}
+			return run(cmd, req)
+		},
+	}
+	setTagsCommand.Flags().BoolVar(&exact, "exact", false,
+		"\n\tOptional: Replace all the tags of the "+entity+" with the given tags.")
+	setTagsCommand.SilenceUsage = true
+
+	rmTagsCommand := &cobra.Command{
+		Use:     "rmtags [" + entity + "_id] [key]...",
+		Short:   "Removes tags from the " + entity,
+		Long:    "Removes tags from the " + entity,
+		Example: fmt.Sprintf("  $ heketi-cli %v rmtags 886a86a868711bef83001 owner", entity),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := cmd.Flags().Args()
+			if len(s) < 1 {
+				return fmt.Errorf("%v id missing", strings.Title(entity))
+			}
+			if len(s) < 2 {
+				return errors.New("Tag keys missing")
+			}
+
+			req := &api.TagsChangeRequest{
+				Tags:   make(map[string]string),
+				Change: api.DeleteTags,
+			}
+			for _, key := range s[1:] {
+				req.Tags[key] = ""
+			}
+			return run(cmd, req)
+		},
+	}
+	rmTagsCommand.SilenceUsage = true
+
+	parent.AddCommand(setTagsCommand)
+	parent.AddCommand(rmTagsCommand)
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(thinPoolCommand)
+	thinPoolCommand.AddCommand(thinPoolWarningsCommand)
+	thinPoolWarningsCommand.SilenceUsage = true
+}
+
+var thinPoolCommand = &cobra.Command{
+	Use:   "thinpool",
+	Short: "Heketi Thin Pool Monitoring",
+	Long:  "Heketi Thin Pool Monitoring",
+}
+
+var thinPoolWarningsCommand = &cobra.Command{
+	Use:   "warnings",
+	Short: "Lists the thin pools of bricks which are filling up",
+	Long: "Lists the thin pools of bricks over the warning threshold. Volumes\n" +
+		"with a thin pool over the critical threshold cannot take new\n" +
+		"snapshots or clones",
+	Example: "  $ heketi-cli thinpool warnings",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Create a client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		warnings, err := heketi.ThinPoolWarnings()
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(warnings)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+			return nil
+		}
+
+		fmt.Fprintf(stdout, "Warning at %v%%, critical at %v%%\n",
+			warnings.WarningPercent, warnings.CriticalPercent)
+		for _, pool := range warnings.Pools {
+			fmt.Fprintf(stdout, "Volume:%-35v"+
+				"Brick:%-35v"+
+				"Data:%-8s"+
+				"Metadata:%-8s"+
+				"%v\n",
+				pool.VolumeId,
+				pool.BrickId,
+				fmt.Sprintf("%.1f%%", pool.DataPercent),
+				fmt.Sprintf("%.1f%%", pool.MetadataPercent),
+				pool.Level)
+		}
+
+		return nil
+	},
+}
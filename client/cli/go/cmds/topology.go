@@ -24,6 +24,7 @@ const (
 	DURABILITY_STRING_REPLICATE       = "replicate"
 	DURABILITY_STRING_DISTRIBUTE_ONLY = "none"
 	DURABILITY_STRING_EC              = "disperse"
+	DURABILITY_STRING_ARBITER         = "arbiter"
 )
 
 var jsonConfigFile string
@@ -235,6 +236,9 @@ var topologyInfoCommand = &cobra.Command{
 			// Get the cluster list and iterate over
 			for i, _ := range topoinfo.ClusterList {
 				fmt.Fprintf(stdout, "\nCluster Id: %v\n", topoinfo.ClusterList[i].Id)
+				if len(topoinfo.ClusterList[i].Tags) > 0 {
+					fmt.Fprintf(stdout, "Tags: %v\n", tagsString(topoinfo.ClusterList[i].Tags))
+				}
 				fmt.Fprintf(stdout, "\n    %s\n", "Volumes:")
 				for k, _ := range topoinfo.ClusterList[i].Volumes {
 
@@ -264,6 +268,8 @@ var topologyInfoCommand = &cobra.Command{
 					case api.DurabilityReplicate:
 						s += fmt.Sprintf("\tReplica: %v\n",
 							v.Durability.Replicate.Replica)
+					case api.DurabilityArbiter:
+						s += "\tReplica: 3 (arbiter 1)\n"
 					}
 					if v.Snapshot.Enable {
 						s += fmt.Sprintf("\tSnapshot: Enabled\n"+
@@ -272,6 +278,12 @@ var topologyInfoCommand = &cobra.Command{
 					} else {
 						s += "\tSnapshot: Disabled\n"
 					}
+					if v.DeviceClass != "" {
+						s += fmt.Sprintf("\tDevice Class: %v\n", v.DeviceClass)
+					}
+					if len(v.Tags) > 0 {
+						s += fmt.Sprintf("\tTags: %v\n", tagsString(v.Tags))
+					}
 					s += "\n\t\tBricks:\n"
 					for _, b := range v.Bricks {
 						s += fmt.Sprintf("\t\t\tId: %v\n"+
@@ -304,6 +316,9 @@ var topologyInfoCommand = &cobra.Command{
 						info.Zone,
 						info.Hostnames.Manage[0],
 						info.Hostnames.Storage[0])
+					if len(info.Tags) > 0 {
+						fmt.Fprintf(stdout, "\tTags: %v\n", tagsString(info.Tags))
+					}
 					fmt.Fprintf(stdout, "\tDevices:\n")
 
 					// format and print the device info
@@ -320,6 +335,12 @@ var topologyInfoCommand = &cobra.Command{
 							d.Storage.Total/(1024*1024),
 							d.Storage.Used/(1024*1024),
 							d.Storage.Free/(1024*1024))
+						if d.Class != "" {
+							fmt.Fprintf(stdout, "\t\t\tClass: %v\n", d.Class)
+						}
+						if len(d.Tags) > 0 {
+							fmt.Fprintf(stdout, "\t\t\tTags: %v\n", tagsString(d.Tags))
+						}
 
 						// format and print the brick information
 						fmt.Fprintf(stdout, "\t\t\tBricks:\n")
@@ -29,15 +29,38 @@ var (
 	replica              int
 	disperseData         int
 	redundancy           int
+	arbiterRatio         float64
 	gid                  int64
+	uid                  int64
+	mode                 string
 	snapshotFactor       float64
 	clusters             string
 	expandSize           int
+	shrinkSize           int
 	id                   string
 	kubePvFile           string
 	kubePvEndpoint       string
 	kubePv               bool
 	glusterVolumeOptions string
+	resetVolumeOptions   string
+	block                bool
+	dryRun               bool
+	expandInPlace        bool
+	importCluster        string
+	nfsExport            bool
+	placeZones           []int
+	excludeZones         []int
+	placeNodes           string
+	excludeNodes         string
+	placeDevices         string
+	excludeDevices       string
+	brickMinSize         int
+	brickMaxSize         int
+	brickMaxNum          int
+	showUsage            bool
+	purge                bool
+	listTrash            bool
+	idempotencyKey       string
 )
 
 func init() {
@@ -45,21 +68,44 @@ func init() {
 	volumeCommand.AddCommand(volumeCreateCommand)
 	volumeCommand.AddCommand(volumeDeleteCommand)
 	volumeCommand.AddCommand(volumeExpandCommand)
+	volumeCommand.AddCommand(volumeShrinkCommand)
+	volumeCommand.AddCommand(volumeSetCommand)
+	volumeCommand.AddCommand(volumeStopCommand)
+	volumeCommand.AddCommand(volumeStartCommand)
+	volumeCommand.AddCommand(volumeRestoreCommand)
+	volumeCommand.AddCommand(volumeImportCommand)
 	volumeCommand.AddCommand(volumeInfoCommand)
 	volumeCommand.AddCommand(volumeListCommand)
 	initGeoRepCommand()
+	initVolumeSnapshotCommand()
+	initVolumeQuotaCommand()
+	initVolumeBitrotCommand()
+	initVolumeHealCommand()
+	initVolumeSmbCommand()
+	initBlockVolumeCommand()
+	initTagsCommands(volumeCommand, "volume",
+		func(heketi *client.Client, id string, req *api.TagsChangeRequest) (interface{}, error) {
+			return heketi.VolumeSetTags(id, req)
+		})
 
 	volumeCreateCommand.Flags().IntVar(&size, "size", -1,
 		"\n\tSize of volume in GB")
 	volumeCreateCommand.Flags().Int64Var(&gid, "gid", 0,
 		"\n\tOptional: Initialize volume with the specified group id")
+	volumeCreateCommand.Flags().Int64Var(&uid, "uid", 0,
+		"\n\tOptional: Initialize volume with the specified user id")
+	volumeCreateCommand.Flags().StringVar(&mode, "mode", "",
+		"\n\tOptional: Initialize volume with the specified octal permission"+
+			"\n\tmode, like 0770.  Defaults to 2775 when a group id is set.")
 	volumeCreateCommand.Flags().StringVar(&volname, "name", "",
 		"\n\tOptional: Name of volume. Only set if really necessary")
 	volumeCreateCommand.Flags().StringVar(&durability, "durability", "replicate",
 		"\n\tOptional: Durability type.  Values are:"+
 			"\n\t\tnone: No durability.  Distributed volume only."+
 			"\n\t\treplicate: (Default) Distributed-Replica volume."+
-			"\n\t\tdisperse: Distributed-Erasure Coded volume.")
+			"\n\t\tdisperse: Distributed-Erasure Coded volume."+
+			"\n\t\tarbiter: Distributed-Replica 3 volume where the third brick"+
+			"\n\t\t         of each set is a small metadata only brick.")
 	volumeCreateCommand.Flags().IntVar(&replica, "replica", 3,
 		"\n\tReplica value for durability type 'replicate'."+
 			"\n\tDefault is 3")
@@ -69,6 +115,9 @@ func init() {
 	volumeCreateCommand.Flags().IntVar(&redundancy, "redundancy", 2,
 		"\n\tOptional: Redundancy value for durability type 'disperse'."+
 			"\n\tDefault is 2")
+	volumeCreateCommand.Flags().Float64Var(&arbiterRatio, "arbiter-brick-ratio", 0,
+		"\n\tOptional: Size of the arbiter bricks relative to the data bricks"+
+			"\n\tfor durability type 'arbiter'.  If omitted, the server default is used.")
 	volumeCreateCommand.Flags().Float64Var(&snapshotFactor, "snapshot-factor", 1.0,
 		"\n\tOptional: Amount of storage to allocate for snapshot support."+
 			"\n\tMust be greater 1.0.  For example if a 10TiB volume requires 5TiB of"+
@@ -83,6 +132,62 @@ func init() {
 	volumeCreateCommand.Flags().StringVar(&glusterVolumeOptions, "gluster-volume-options", "",
 		"\n\tOptional: Comma separated list of volume options which can be set on the volume."+
 			"\n\tIf omitted, Heketi will set no volume option for the volume.")
+	volumeCreateCommand.Flags().BoolVar(&block, "block", false,
+		"\n\tOptional: Create a block hosting volume to store block volumes.")
+	volumeCreateCommand.Flags().StringVar(&deviceClass, "device-class", "",
+		"\n\tOptional: Only place the bricks of the volume on devices of this class.")
+	volumeCreateCommand.Flags().StringVar(&tags, "tags", "",
+		"\n\tOptional: Comma separated list of key:value tags of the volume.")
+	volumeCreateCommand.Flags().BoolVar(&nfsExport, "nfs-export", false,
+		"\n\tOptional: Export the volume over NFS with the NFS-Ganesha server"+
+			"\n\tof every node of the cluster.")
+	volumeCreateCommand.Flags().IntSliceVar(&placeZones, "zones", nil,
+		"\n\tOptional: Comma separated list of zones the bricks of the volume"+
+			"\n\tmust be placed in.")
+	volumeCreateCommand.Flags().IntSliceVar(&excludeZones, "exclude-zones", nil,
+		"\n\tOptional: Comma separated list of zones the bricks of the volume"+
+			"\n\tmust not be placed in.")
+	volumeCreateCommand.Flags().StringVar(&placeNodes, "nodes", "",
+		"\n\tOptional: Comma separated list of ids of the nodes the bricks of"+
+			"\n\tthe volume must be placed on.")
+	volumeCreateCommand.Flags().StringVar(&excludeNodes, "exclude-nodes", "",
+		"\n\tOptional: Comma separated list of ids of the nodes the bricks of"+
+			"\n\tthe volume must not be placed on.")
+	volumeCreateCommand.Flags().StringVar(&placeDevices, "devices", "",
+		"\n\tOptional: Comma separated list of ids of the devices the bricks of"+
+			"\n\tthe volume must be placed on.")
+	volumeCreateCommand.Flags().StringVar(&excludeDevices, "exclude-devices", "",
+		"\n\tOptional: Comma separated list of ids of the devices the bricks of"+
+			"\n\tthe volume must not be placed on.")
+	volumeCreateCommand.Flags().IntVar(&brickMinSize, "brick-min-size", 0,
+		"\n\tOptional: Minimum size in GiB of the bricks of the volume."+
+			"\n\tDefaults to the limit of the cluster.")
+	volumeCreateCommand.Flags().IntVar(&brickMaxSize, "brick-max-size", 0,
+		"\n\tOptional: Maximum size in GiB of the bricks of the volume."+
+			"\n\tDefaults to the limit of the cluster.")
+	volumeCreateCommand.Flags().IntVar(&brickMaxNum, "max-bricks", 0,
+		"\n\tOptional: Maximum number of bricks of the volume."+
+			"\n\tDefaults to the limit of the cluster.")
+	volumeCreateCommand.Flags().BoolVar(&dryRun, "dry-run", false,
+		"\n\tOptional: Only show where the bricks of the volume would be placed"+
+			"\n\tand whether the volume can be created, without creating it.")
+	volumeCreateCommand.Flags().StringVar(&idempotencyKey, "idempotency-key", "",
+		"\n\tOptional: Key identifying the request.  Retrying with the same key"+
+			"\n\treturns the volume created by the first try instead of creating another.")
+	volumeImportCommand.Flags().StringVar(&importCluster, "cluster", "",
+		"\n\tOptional: Id of the cluster with the volume.  If omitted, Heketi"+
+			"\n\tlooks for the volume in all the configured clusters.")
+	volumeListCommand.Flags().StringVar(&tagFilters, "tags", "",
+		"\n\tOptional: Comma separated list of key or key:value tags."+
+			"\n\tOnly volumes with all these tags are listed.")
+	volumeListCommand.Flags().BoolVar(&listTrash, "trash", false,
+		"\n\tOptional: Also list the deleted volumes kept in the trash")
+	volumeDeleteCommand.Flags().BoolVar(&purge, "purge", false,
+		"\n\tOptional: Destroy the volume right away instead of keeping it"+
+			"\n\tin the trash, when the server is configured to keep deleted volumes")
+	volumeDeleteCommand.Flags().StringVar(&idempotencyKey, "idempotency-key", "",
+		"\n\tOptional: Key identifying the request.  Retrying with the same key"+
+			"\n\tsucceeds even though the first try already deleted the volume.")
 	volumeCreateCommand.Flags().BoolVar(&kubePv, "persistent-volume", false,
 		"\n\tOptional: Output to standard out a persistent volume JSON file for OpenShift or"+
 			"\n\tKubernetes with the name provided.")
@@ -95,9 +200,34 @@ func init() {
 		"\n\tAmount in GB to add to the volume")
 	volumeExpandCommand.Flags().StringVar(&id, "volume", "",
 		"\n\tId of volume to expand")
+	volumeExpandCommand.Flags().BoolVar(&expandInPlace, "in-place", false,
+		"\n\tOptional: Grow the existing bricks of the volume when their devices"+
+			"\n\thave room for it.  Bricks are added to the volume otherwise.")
+	volumeExpandCommand.Flags().BoolVar(&dryRun, "dry-run", false,
+		"\n\tOptional: Only show where the new bricks would be placed"+
+			"\n\tand whether the volume can be expanded, without expanding it.")
+	volumeExpandCommand.Flags().StringVar(&idempotencyKey, "idempotency-key", "",
+		"\n\tOptional: Key identifying the request.  Retrying with the same key"+
+			"\n\tdoes not expand the volume again.")
+	volumeShrinkCommand.Flags().IntVar(&shrinkSize, "shrink-size", -1,
+		"\n\tAmount in GB to remove from the volume.  Only whole brick sets"+
+			"\n\tare removed, so less space than requested may be removed.")
+	volumeShrinkCommand.Flags().StringVar(&id, "volume", "",
+		"\n\tId of volume to shrink")
+	volumeSetCommand.Flags().StringVar(&glusterVolumeOptions, "gluster-volume-options", "",
+		"\n\tOptional: Comma separated list of volume options to set on the volume."+
+			"\n\tEach option is a key followed by a value, like \"performance.cache-size 1GB\".")
+	volumeInfoCommand.Flags().BoolVar(&showUsage, "usage", false,
+		"\n\tOptional: Show how much space the volume uses, as reported by the nodes")
+	volumeSetCommand.Flags().StringVar(&resetVolumeOptions, "reset", "",
+		"\n\tOptional: Comma separated list of volume option keys to reset to"+
+			"\n\ttheir default values.")
 	volumeCreateCommand.SilenceUsage = true
+	volumeSetCommand.SilenceUsage = true
 	volumeDeleteCommand.SilenceUsage = true
+	volumeRestoreCommand.SilenceUsage = true
 	volumeExpandCommand.SilenceUsage = true
+	volumeShrinkCommand.SilenceUsage = true
 	volumeInfoCommand.SilenceUsage = true
 	volumeListCommand.SilenceUsage = true
 }
@@ -125,6 +255,9 @@ var volumeCreateCommand = &cobra.Command{
   * Create a 100GB distributed volume
       $ heketi-cli volume create --size=100 --durability=none
 
+  * Create a 100GB replica 3 arbiter volume with 2% sized arbiter bricks:
+      $ heketi-cli volume create --size=100 --durability=arbiter --arbiter-brick-ratio=0.02
+
   * Create a 100GB erasure coded 4+2 volume with 25GB snapshot storage:
       $ heketi-cli volume create --size=100 --durability=disperse --snapshot-factor=1.25
 
@@ -132,6 +265,21 @@ var volumeCreateCommand = &cobra.Command{
       $ heketi-cli volume create --size=100 --durability=disperse --snapshot-factor=1.25 \
         --disperse-data=8 --redundancy=3
 
+  * Create a 500GB replica 3 volume to host block volumes:
+      $ heketi-cli volume create --size=500 --block
+
+  * Create a 100GB replica 3 volume on ssd devices only:
+      $ heketi-cli volume create --size=100 --device-class=ssd
+
+  * Create a 10GB replica 3 volume tagged with its owner and cost center:
+      $ heketi-cli volume create --size=10 --tags=owner:storage-team,cost-center:4711
+
+  * Show where the bricks of a 1TB replica 3 volume would be placed:
+      $ heketi-cli volume create --size=1024 --dry-run
+
+  * Create a 100GB replica 3 volume in zones 1, 2 and 3 only:
+      $ heketi-cli volume create --size=100 --zones=1,2,3
+
   * Create a 100GB distributed volume which supports performance related volume options.
       $ heketi-cli volume create --size=100 --durability=none --gluster-volume-options="performance.rda-cache-limit 10MB","performance.nl-cache-positive-entry no"
 `,
@@ -152,24 +300,59 @@ var volumeCreateCommand = &cobra.Command{
 		req.Size = size
 		req.Durability.Type = api.DurabilityType(durability)
 		req.Durability.Replicate.Replica = replica
+		req.Durability.Arbiter.Ratio = float32(arbiterRatio)
 		req.Durability.Disperse.Data = disperseData
 		req.Durability.Disperse.Redundancy = redundancy
+		req.Block = block
+		req.DeviceClass = deviceClass
+		req.NfsExport = nfsExport
+		req.BrickLimits.MinSize = brickMinSize
+		req.BrickLimits.MaxSize = brickMaxSize
+		req.BrickLimits.MaxNum = brickMaxNum
 
 		// Check clusters
 		if clusters != "" {
 			req.Clusters = strings.Split(clusters, ",")
 		}
 
+		// Check placement constraints
+		req.Placement.Zones = placeZones
+		req.Placement.ExcludeZones = excludeZones
+		if placeNodes != "" {
+			req.Placement.Nodes = strings.Split(placeNodes, ",")
+		}
+		if excludeNodes != "" {
+			req.Placement.ExcludeNodes = strings.Split(excludeNodes, ",")
+		}
+		if placeDevices != "" {
+			req.Placement.Devices = strings.Split(placeDevices, ",")
+		}
+		if excludeDevices != "" {
+			req.Placement.ExcludeDevices = strings.Split(excludeDevices, ",")
+		}
+
 		// Check volume options
 		if glusterVolumeOptions != "" {
 			req.GlusterVolumeOptions = strings.Split(glusterVolumeOptions, ",")
 		}
 
+		t, err := parseTags(tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = t
+
 		// Set group id if specified
 		if gid != 0 {
 			req.Gid = gid
 		}
 
+		// Set user id and mode if specified
+		if uid != 0 {
+			req.Uid = uid
+		}
+		req.Mode = mode
+
 		if volname != "" {
 			req.Name = volname
 		}
@@ -182,8 +365,16 @@ var volumeCreateCommand = &cobra.Command{
 		// Create a client
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
+		if dryRun {
+			plan, err := heketi.VolumePlan(req)
+			if err != nil {
+				return err
+			}
+			return printVolumePlan(plan)
+		}
+
 		// Add volume
-		volume, err := heketi.VolumeCreate(req)
+		volume, err := heketi.VolumeCreateWithKey(req, idempotencyKey)
 		if err != nil {
 			return err
 		}
@@ -229,6 +420,19 @@ var volumeCreateCommand = &cobra.Command{
 	},
 }
 
+func printVolumePlan(plan *api.VolumePlanResponse) error {
+	if options.Json {
+		data, err := json.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, string(data))
+	} else {
+		fmt.Fprintf(stdout, "%v", plan)
+	}
+	return nil
+}
+
 var volumeDeleteCommand = &cobra.Command{
 	Use:     "delete",
 	Short:   "Deletes the volume",
@@ -249,7 +453,12 @@ var volumeDeleteCommand = &cobra.Command{
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
 		//set url
-		err := heketi.VolumeDelete(volumeId)
+		var err error
+		if purge {
+			err = heketi.VolumePurge(volumeId)
+		} else {
+			err = heketi.VolumeDeleteWithKey(volumeId, idempotencyKey)
+		}
 		if err == nil {
 			fmt.Fprintf(stdout, "Volume %v deleted\n", volumeId)
 		}
@@ -264,6 +473,12 @@ var volumeExpandCommand = &cobra.Command{
 	Long:  "Expand a volume",
 	Example: `  * Add 10GB to a volume
     $ heketi-cli volume expand --volume=60d46d518074b13a04ce1022c8c7193c --expand-size=10
+
+  * Add 10GB to a volume by growing its bricks if possible
+    $ heketi-cli volume expand --volume=60d46d518074b13a04ce1022c8c7193c --expand-size=10 --in-place
+
+  * Show where the bricks for 100GB more would be placed
+    $ heketi-cli volume expand --volume=60d46d518074b13a04ce1022c8c7193c --expand-size=100 --dry-run
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check volume size
@@ -278,12 +493,116 @@ var volumeExpandCommand = &cobra.Command{
 		// Create request
 		req := &api.VolumeExpandRequest{}
 		req.Size = expandSize
+		req.InPlace = expandInPlace
 
 		// Create client
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
+		if dryRun {
+			plan, err := heketi.VolumeExpandPlan(id, req)
+			if err != nil {
+				return err
+			}
+			return printVolumePlan(plan)
+		}
+
 		// Expand volume
-		volume, err := heketi.VolumeExpand(id, req)
+		volume, err := heketi.VolumeExpandWithKey(id, req, idempotencyKey)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(volume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", volume)
+		}
+		return nil
+	},
+}
+
+var volumeShrinkCommand = &cobra.Command{
+	Use:   "shrink",
+	Short: "Shrink a volume",
+	Long: "Shrink a volume by removing brick sets.  The data on the bricks" +
+		" is migrated to the remaining bricks before they are deleted.",
+	Example: `  * Remove up to 10GB from a volume
+    $ heketi-cli volume shrink --volume=60d46d518074b13a04ce1022c8c7193c --shrink-size=10
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check volume size
+		if shrinkSize == -1 {
+			return errors.New("Missing volume amount to shrink")
+		}
+
+		if id == "" {
+			return errors.New("Missing volume id")
+		}
+
+		// Create request
+		req := &api.VolumeShrinkRequest{}
+		req.Size = shrinkSize
+
+		// Create client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// Shrink volume
+		volume, err := heketi.VolumeShrink(id, req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(volume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", volume)
+		}
+		return nil
+	},
+}
+
+var volumeSetCommand = &cobra.Command{
+	Use:   "set",
+	Short: "Changes the options of a volume",
+	Long:  "Changes the gluster volume options of a running volume",
+	Example: `  * Set the cache size of a volume:
+    $ heketi-cli volume set 886a86a868711bef83001 \
+      --gluster-volume-options="performance.cache-size 1GB"
+
+  * Reset an option to its default value:
+    $ heketi-cli volume set 886a86a868711bef83001 --reset=performance.cache-size
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume id missing")
+		}
+		volumeId := cmd.Flags().Arg(0)
+
+		req := &api.VolumeOptionsRequest{}
+		if glusterVolumeOptions != "" {
+			req.Set = strings.Split(glusterVolumeOptions, ",")
+		}
+		if resetVolumeOptions != "" {
+			req.Reset = strings.Split(resetVolumeOptions, ",")
+		}
+		if len(req.Set) == 0 && len(req.Reset) == 0 {
+			return errors.New("Missing volume options to set or reset")
+		}
+
+		// Create client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		// Set options
+		volume, err := heketi.VolumeSetOptions(volumeId, req)
 		if err != nil {
 			return err
 		}
@@ -301,6 +620,113 @@ var volumeExpandCommand = &cobra.Command{
 	},
 }
 
+var volumeStopCommand = &cobra.Command{
+	Use:     "stop",
+	Short:   "Stops a volume",
+	Long:    "Stops a volume for maintenance. A stopped volume cannot be mounted, expanded or have its bricks replaced",
+	Example: "  $ heketi-cli volume stop 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeStop(id)
+		})
+	},
+}
+
+var volumeStartCommand = &cobra.Command{
+	Use:     "start",
+	Short:   "Starts a stopped volume",
+	Long:    "Starts a volume which was stopped",
+	Example: "  $ heketi-cli volume start 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeStart(id)
+		})
+	},
+}
+
+var volumeRestoreCommand = &cobra.Command{
+	Use:     "restore",
+	Short:   "Restores a deleted volume from the trash",
+	Long:    "Restores a deleted volume from the trash, starting it if it was running",
+	Example: "  $ heketi-cli volume restore 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return volumeSetState(cmd, func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error) {
+			return heketi.VolumeRestore(id)
+		})
+	},
+}
+
+var volumeImportCommand = &cobra.Command{
+	Use:   "import",
+	Short: "Imports a volume created outside of Heketi",
+	Long: "Adopts a gluster volume created outside of Heketi. Each brick must " +
+		"be on its own logical volume in a device managed by Heketi",
+	Example: `  * Import volume myvol from any cluster:
+    $ heketi-cli volume import myvol
+
+  * Import volume myvol from a cluster:
+    $ heketi-cli volume import myvol --cluster=3f9c2e7d6a1b`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		if len(cmd.Flags().Args()) < 1 {
+			return errors.New("Volume name missing")
+		}
+
+		req := &api.VolumeImportRequest{
+			Name:    cmd.Flags().Arg(0),
+			Cluster: importCluster,
+		}
+
+		// Create client
+		heketi := client.NewClient(options.Url, options.User, options.Key)
+
+		volume, err := heketi.VolumeImport(req)
+		if err != nil {
+			return err
+		}
+
+		if options.Json {
+			data, err := json.Marshal(volume)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, string(data))
+		} else {
+			fmt.Fprintf(stdout, "%v", volume)
+		}
+		return nil
+	},
+}
+
+func volumeSetState(cmd *cobra.Command,
+	change func(heketi *client.Client, id string) (*api.VolumeInfoResponse, error)) error {
+
+	//ensure proper number of args
+	if len(cmd.Flags().Args()) < 1 {
+		return errors.New("Volume id missing")
+	}
+	volumeId := cmd.Flags().Arg(0)
+
+	// Create client
+	heketi := client.NewClient(options.Url, options.User, options.Key)
+
+	volume, err := change(heketi, volumeId)
+	if err != nil {
+		return err
+	}
+
+	if options.Json {
+		data, err := json.Marshal(volume)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, string(data))
+	} else {
+		fmt.Fprintf(stdout, "%v", volume)
+	}
+	return nil
+}
+
 var volumeInfoCommand = &cobra.Command{
 	Use:     "info",
 	Short:   "Retrieves information about the volume",
@@ -320,7 +746,13 @@ var volumeInfoCommand = &cobra.Command{
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
 		// Create cluster
-		info, err := heketi.VolumeInfo(volumeId)
+		var info *api.VolumeInfoResponse
+		var err error
+		if showUsage {
+			info, err = heketi.VolumeInfoWithUsage(volumeId)
+		} else {
+			info, err = heketi.VolumeInfo(volumeId)
+		}
 		if err != nil {
 			return err
 		}
@@ -343,13 +775,19 @@ var volumeListCommand = &cobra.Command{
 	Use:     "list",
 	Short:   "Lists the volumes managed by Heketi",
 	Long:    "Lists the volumes managed by Heketi",
-	Example: "  $ heketi-cli volume list",
+	Example: "  $ heketi-cli volume list --tags=owner:storage-team",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Create a client
 		heketi := client.NewClient(options.Url, options.User, options.Key)
 
 		// List volumes
-		list, err := heketi.VolumeList()
+		var list *api.VolumeListResponse
+		var err error
+		if listTrash {
+			list, err = heketi.VolumeListWithTrash(parseTagFilters(tagFilters))
+		} else {
+			list, err = heketi.VolumeListByTags(parseTagFilters(tagFilters))
+		}
 		if err != nil {
 			return err
 		}
@@ -367,10 +805,14 @@ var volumeListCommand = &cobra.Command{
 					return err
 				}
 
-				fmt.Fprintf(stdout, "Id:%-35v Cluster:%-35v Name:%v\n",
+				fmt.Fprintf(stdout, "Id:%-35v Cluster:%-35v Name:%v",
 					id,
 					volume.Cluster,
 					volume.Name)
+				if volume.Trash != nil {
+					fmt.Fprintf(stdout, " [trash]")
+				}
+				fmt.Fprintf(stdout, "\n")
 			}
 		}
 
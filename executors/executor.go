@@ -21,18 +21,49 @@ type Executor interface {
 	BrickCreate(host string, brick *BrickRequest) (*BrickInfo, error)
 	BrickDestroy(host string, brick *BrickRequest) error
 	BrickDestroyCheck(host string, brick *BrickRequest) error
+	BrickExpand(host string, brick *BrickRequest) error
+	BrickLvInfo(host string, path string) (*BrickLvInfo, error)
+	BrickUsage(host string, brick *BrickRequest) (*BrickUsage, error)
 	VolumeCreate(host string, volume *VolumeRequest) (*Volume, error)
 	VolumeDestroy(host string, volume string) error
 	VolumeDestroyCheck(host, volume string) error
 	VolumeExpand(host string, volume *VolumeRequest) (*Volume, error)
 	VolumeReplaceBrick(host string, volume string, oldBrick *BrickInfo, newBrick *BrickInfo) error
+	VolumeRemoveBricks(host string, volume string, bricks []BrickInfo, action string) error
+	VolumeRemoveBricksStatus(host string, volume string, bricks []BrickInfo) (*RemoveBrickStatus, error)
 	VolumeInfo(host string, volume string) (*Volume, error)
+	VolumeSetOptions(host string, volume string, options []string) error
+	VolumeResetOptions(host string, volume string, options []string) error
+	VolumeStop(host string, volume string) error
+	VolumeStart(host string, volume string) error
+	VolumeQuotaEnable(host string, volume string) error
+	VolumeQuotaDisable(host string, volume string) error
+	VolumeQuotaLimitSet(host string, volume string, limit *QuotaLimit) error
+	VolumeQuotaLimitRemove(host string, volume string, path string) error
+	VolumeQuotaList(host string, volume string) (*QuotaList, error)
+	VolumeBitrotEnable(host string, volume string) error
+	VolumeBitrotDisable(host string, volume string) error
+	VolumeBitrotScrub(host string, volume string, scrub *BitrotScrubRequest) error
+	VolumeBitrotScrubStatus(host string, volume string) (*BitrotScrubStatus, error)
 	GeoReplicationCreate(host, volume string, geoRep *GeoReplicationRequest) error
 	GeoReplicationConfig(host, volume string, geoRep *GeoReplicationRequest) error
 	GeoReplicationAction(host, volume, action string, geoRep *GeoReplicationRequest) error
 	GeoReplicationVolumeStatus(host, volume string) (*GeoReplicationStatus, error)
 	GeoReplicationStatus(host string) (*GeoReplicationStatus, error)
 	HealInfo(host string, volume string) (*HealInfo, error)
+	VolumeHeal(host string, volume string, full bool) error
+	VolumeNfsExport(host string, export *NfsExportRequest) error
+	VolumeNfsUnexport(host string, volume string) error
+	VolumeSmbShare(host string, share *SmbShareRequest) error
+	VolumeSmbUnshare(host string, share string) error
+	SnapshotCreate(host string, snapshot *SnapshotRequest) (*Snapshot, error)
+	SnapshotList(host string, volume string) (*SnapList, error)
+	SnapshotDelete(host string, snapshot string) error
+	SnapshotRestore(host string, volume string, snapshot string) error
+	SnapshotClone(host string, clone *SnapshotCloneRequest) (*Volume, error)
+	BlockVolumeCreate(host string, blockVolume *BlockVolumeRequest) (*BlockVolumeInfo, error)
+	BlockVolumeDestroy(host string, blockHostingVolumeName string, blockVolumeName string) error
+	BlockVolumeInfo(host string, blockHostingVolumeName string, blockVolumeName string) (*BlockVolumeInfo, error)
 	SetLogLevel(level string)
 }
 
@@ -89,6 +120,7 @@ const (
 	DurabilityNone DurabilityType = iota
 	DurabilityReplica
 	DurabilityDispersion
+	DurabilityArbiter
 )
 
 // Returns the size of the device
@@ -106,6 +138,53 @@ type BrickRequest struct {
 	Size             uint64
 	PoolMetadataSize uint64
 	Gid              int64
+	Uid              int64
+
+	// Permission mode of the brick root in octal, like 0770
+	Mode string
+
+	// Only set for bricks which were not created by BrickCreate,
+	// like the bricks of a cloned volume
+	Path string
+
+	// Set for bricks created outside of heketi, whose logical
+	// volume and thin pool are found from the mount at Path
+	Imported bool
+
+	// Set for bricks moved by a snapshot restore to the logical
+	// volume mounted at Path, which is in the thin pool of the brick
+	Restored bool
+}
+
+// Logical volume of a brick, found from the file system the brick is on
+type BrickLvInfo struct {
+	// Id of the device heketi created the volume group on, empty
+	// when the volume group was not created by heketi
+	VgId   string
+	VgName string
+	LvName string
+
+	// Mount point of the file system of the brick
+	MountPoint string
+
+	// Thin pool of the logical volume, empty for thick volumes
+	PoolName string
+
+	// Sizes in KB
+	Size             uint64
+	PoolSize         uint64
+	PoolMetadataSize uint64
+}
+
+// Space used on the file system of a brick and in its thin pool
+type BrickUsage struct {
+	// Sizes in bytes
+	Size uint64
+	Used uint64
+
+	// Fill percentages of the thin pool, zero for thick volumes
+	PoolDataPercent     float64
+	PoolMetadataPercent float64
 }
 
 // Returns information about the location of the brick
@@ -189,6 +268,84 @@ type VolInfo struct {
 	Volumes Volumes  `xml:"volumes"`
 }
 
+type SnapshotRequest struct {
+	Volume      string
+	Snapshot    string
+	Description string
+}
+
+type SnapshotCloneRequest struct {
+	Snapshot string
+
+	// Name of the new volume
+	Volume string
+}
+
+type Snapshot struct {
+	XMLName xml.Name `xml:"snapshot"`
+	UUID    string   `xml:"uuid"`
+	Name    string   `xml:"name"`
+}
+
+type SnapList struct {
+	XMLName      xml.Name `xml:"snapList"`
+	Count        int      `xml:"count"`
+	SnapshotList []string `xml:"snapshot"`
+}
+
+type BlockVolumeRequest struct {
+	Name string
+
+	// Size in GB
+	Size int
+
+	// File volume storing the block volume
+	GlusterVolumeName string
+
+	// Nodes exporting the block volume through iSCSI
+	Hacount    int
+	BlockHosts []string
+	Auth       bool
+}
+
+type BlockVolumeInfo struct {
+	Name              string
+	Size              int
+	GlusterVolumeName string
+	Gbid              string
+	Hacount           int
+	BlockHosts        []string
+	Iqn               string
+	Username          string
+	Password          string
+}
+
+// Status of the data migration started by remove-brick
+const (
+	RemoveBrickNotStarted = 0
+	RemoveBrickInProgress = 1
+	RemoveBrickStopped    = 2
+	RemoveBrickCompleted  = 3
+	RemoveBrickFailed     = 4
+)
+
+type RemoveBrickNodeStatus struct {
+	NodeName  string `xml:"nodeName"`
+	Files     int    `xml:"files"`
+	Size      uint64 `xml:"size"`
+	Lookups   int    `xml:"lookups"`
+	Failures  int    `xml:"failures"`
+	Skipped   int    `xml:"skipped"`
+	Status    int    `xml:"status"`
+	StatusStr string `xml:"statusStr"`
+}
+
+type RemoveBrickStatus struct {
+	XMLName   xml.Name                `xml:"volRemoveBrick"`
+	Nodes     []RemoveBrickNodeStatus `xml:"node"`
+	Aggregate RemoveBrickNodeStatus   `xml:"aggregate"`
+}
+
 type HealInfoBricks struct {
 	BrickList []BrickHealStatus `xml:"brick"`
 }
@@ -197,3 +354,74 @@ type HealInfo struct {
 	XMLName xml.Name       `xml:"healInfo"`
 	Bricks  HealInfoBricks `xml:"bricks"`
 }
+
+type QuotaLimit struct {
+	// Directory of the volume the limit applies to
+	Path string
+
+	// Hard limit in bytes
+	HardLimit uint64
+
+	// Percentage of the hard limit, zero for the gluster default
+	SoftLimit int
+}
+
+type QuotaUsage struct {
+	Path              string `xml:"path"`
+	HardLimit         uint64 `xml:"hard_limit"`
+	SoftLimitPercent  string `xml:"soft_limit_percent"`
+	SoftLimitValue    uint64 `xml:"soft_limit_value"`
+	UsedSpace         uint64 `xml:"used_space"`
+	AvailSpace        uint64 `xml:"avail_space"`
+	SoftLimitExceeded string `xml:"sl_exceeded"`
+	HardLimitExceeded string `xml:"hl_exceeded"`
+}
+
+type QuotaList struct {
+	XMLName xml.Name     `xml:"volQuota"`
+	Limits  []QuotaUsage `xml:"limit"`
+}
+
+// Export of a volume by the NFS-Ganesha server of a node
+type NfsExportRequest struct {
+	Volume string
+
+	// Unique among the exports of the Ganesha server
+	ExportId int
+}
+
+// Samba share of a volume through the vfs_glusterfs module
+type SmbShareRequest struct {
+	Volume    string
+	ShareName string
+}
+
+type BitrotScrubRequest struct {
+	// One of hourly, daily, weekly, biweekly or monthly.
+	// Unchanged when empty.
+	Frequency string
+
+	// One of lazy, normal or aggressive. Unchanged when empty.
+	Throttle string
+}
+
+// Scrub status of a volume, as shown by gluster volume bitrot
+// <volume> scrub status
+type BitrotScrubStatus struct {
+	State     string
+	Throttle  string
+	Frequency string
+	Nodes     []BitrotScrubNodeStatus
+}
+
+type BitrotScrubNodeStatus struct {
+	Node              string
+	ScrubbedFiles     uint64
+	SkippedFiles      uint64
+	LastScrubTime     string
+	LastScrubDuration string
+	ErrorCount        uint64
+
+	// GFIDs of the corrupted files found on the bricks of the node
+	CorruptedObjects []string
+}
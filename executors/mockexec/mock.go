@@ -23,18 +23,49 @@ type MockExecutor struct {
 	MockBrickCreate                func(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error)
 	MockBrickDestroy               func(host string, brick *executors.BrickRequest) error
 	MockBrickDestroyCheck          func(host string, brick *executors.BrickRequest) error
+	MockBrickExpand                func(host string, brick *executors.BrickRequest) error
+	MockBrickLvInfo                func(host string, path string) (*executors.BrickLvInfo, error)
+	MockBrickUsage                 func(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error)
 	MockVolumeCreate               func(host string, volume *executors.VolumeRequest) (*executors.Volume, error)
 	MockVolumeExpand               func(host string, volume *executors.VolumeRequest) (*executors.Volume, error)
 	MockVolumeDestroy              func(host string, volume string) error
 	MockVolumeDestroyCheck         func(host, volume string) error
 	MockVolumeReplaceBrick         func(host string, volume string, oldBrick *executors.BrickInfo, newBrick *executors.BrickInfo) error
 	MockVolumeInfo                 func(host string, volume string) (*executors.Volume, error)
+	MockVolumeSetOptions           func(host string, volume string, options []string) error
+	MockVolumeResetOptions         func(host string, volume string, options []string) error
+	MockVolumeStop                 func(host string, volume string) error
+	MockVolumeStart                func(host string, volume string) error
+	MockVolumeQuotaEnable          func(host string, volume string) error
+	MockVolumeQuotaDisable         func(host string, volume string) error
+	MockVolumeQuotaLimitSet        func(host string, volume string, limit *executors.QuotaLimit) error
+	MockVolumeQuotaLimitRemove     func(host string, volume string, path string) error
+	MockVolumeQuotaList            func(host string, volume string) (*executors.QuotaList, error)
+	MockVolumeBitrotEnable         func(host string, volume string) error
+	MockVolumeBitrotDisable        func(host string, volume string) error
+	MockVolumeBitrotScrub          func(host string, volume string, scrub *executors.BitrotScrubRequest) error
+	MockVolumeBitrotScrubStatus    func(host string, volume string) (*executors.BitrotScrubStatus, error)
+	MockVolumeRemoveBricks         func(host string, volume string, bricks []executors.BrickInfo, action string) error
+	MockVolumeRemoveBricksStatus   func(host string, volume string, bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error)
 	MockGeoReplicationCreate       func(host string, volume string, geoRep *executors.GeoReplicationRequest) error
 	MockGeoReplicationConfig       func(host string, volume string, geoRep *executors.GeoReplicationRequest) error
 	MockGeoReplicationAction       func(host string, volume string, action string, geoRep *executors.GeoReplicationRequest) error
 	MockGeoReplicationVolumeStatus func(host string, volume string) (*executors.GeoReplicationStatus, error)
 	MockGeoReplicationStatus       func(host string) (*executors.GeoReplicationStatus, error)
 	MockHealInfo                   func(host string, volume string) (*executors.HealInfo, error)
+	MockVolumeHeal                 func(host string, volume string, full bool) error
+	MockVolumeNfsExport            func(host string, export *executors.NfsExportRequest) error
+	MockVolumeNfsUnexport          func(host string, volume string) error
+	MockVolumeSmbShare             func(host string, share *executors.SmbShareRequest) error
+	MockVolumeSmbUnshare           func(host string, share string) error
+	MockSnapshotCreate             func(host string, snapshot *executors.SnapshotRequest) (*executors.Snapshot, error)
+	MockSnapshotList               func(host string, volume string) (*executors.SnapList, error)
+	MockSnapshotDelete             func(host string, snapshot string) error
+	MockSnapshotRestore            func(host string, volume string, snapshot string) error
+	MockSnapshotClone              func(host string, clone *executors.SnapshotCloneRequest) (*executors.Volume, error)
+	MockBlockVolumeCreate          func(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error)
+	MockBlockVolumeDestroy         func(host string, blockHostingVolumeName string, blockVolumeName string) error
+	MockBlockVolumeInfo            func(host string, blockHostingVolumeName string, blockVolumeName string) (*executors.BlockVolumeInfo, error)
 }
 
 func NewMockExecutor() (*MockExecutor, error) {
@@ -78,6 +109,18 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockBrickExpand = func(host string, brick *executors.BrickRequest) error {
+		return nil
+	}
+
+	m.MockBrickLvInfo = func(host string, path string) (*executors.BrickLvInfo, error) {
+		return &executors.BrickLvInfo{}, nil
+	}
+
+	m.MockBrickUsage = func(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+		return &executors.BrickUsage{}, nil
+	}
+
 	m.MockVolumeCreate = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
 		return &executors.Volume{}, nil
 	}
@@ -98,6 +141,71 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockVolumeRemoveBricks = func(host string, volume string, bricks []executors.BrickInfo, action string) error {
+		return nil
+	}
+
+	m.MockVolumeRemoveBricksStatus = func(host string, volume string, bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+		return &executors.RemoveBrickStatus{
+			Aggregate: executors.RemoveBrickNodeStatus{
+				Status:    executors.RemoveBrickCompleted,
+				StatusStr: "completed",
+			},
+		}, nil
+	}
+
+	m.MockVolumeSetOptions = func(host string, volume string, options []string) error {
+		return nil
+	}
+
+	m.MockVolumeResetOptions = func(host string, volume string, options []string) error {
+		return nil
+	}
+
+	m.MockVolumeStop = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeStart = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeQuotaEnable = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeQuotaDisable = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeQuotaLimitSet = func(host string, volume string, limit *executors.QuotaLimit) error {
+		return nil
+	}
+
+	m.MockVolumeQuotaLimitRemove = func(host string, volume string, path string) error {
+		return nil
+	}
+
+	m.MockVolumeQuotaList = func(host string, volume string) (*executors.QuotaList, error) {
+		return &executors.QuotaList{}, nil
+	}
+
+	m.MockVolumeBitrotEnable = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeBitrotDisable = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeBitrotScrub = func(host string, volume string, scrub *executors.BitrotScrubRequest) error {
+		return nil
+	}
+
+	m.MockVolumeBitrotScrubStatus = func(host string, volume string) (*executors.BitrotScrubStatus, error) {
+		return &executors.BitrotScrubStatus{}, nil
+	}
+
 	m.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
 		var bricks []executors.Brick
 		brick := executors.Brick{Name: host + ":/mockpath"}
@@ -119,6 +227,72 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return &executors.HealInfo{}, nil
 	}
 
+	m.MockVolumeHeal = func(host string, volume string, full bool) error {
+		return nil
+	}
+
+	m.MockVolumeNfsExport = func(host string, export *executors.NfsExportRequest) error {
+		return nil
+	}
+
+	m.MockVolumeNfsUnexport = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeSmbShare = func(host string, share *executors.SmbShareRequest) error {
+		return nil
+	}
+
+	m.MockVolumeSmbUnshare = func(host string, share string) error {
+		return nil
+	}
+
+	m.MockSnapshotCreate = func(host string, snapshot *executors.SnapshotRequest) (*executors.Snapshot, error) {
+		return &executors.Snapshot{
+			Name: snapshot.Snapshot,
+		}, nil
+	}
+
+	m.MockSnapshotList = func(host string, volume string) (*executors.SnapList, error) {
+		return &executors.SnapList{}, nil
+	}
+
+	m.MockSnapshotDelete = func(host string, snapshot string) error {
+		return nil
+	}
+
+	m.MockSnapshotRestore = func(host string, volume string, snapshot string) error {
+		return nil
+	}
+
+	m.MockSnapshotClone = func(host string, clone *executors.SnapshotCloneRequest) (*executors.Volume, error) {
+		return &executors.Volume{
+			VolumeName: clone.Volume,
+		}, nil
+	}
+
+	m.MockBlockVolumeCreate = func(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
+		return &executors.BlockVolumeInfo{
+			Name:              blockVolume.Name,
+			Size:              blockVolume.Size,
+			GlusterVolumeName: blockVolume.GlusterVolumeName,
+			Hacount:           blockVolume.Hacount,
+			BlockHosts:        blockVolume.BlockHosts,
+			Iqn:               "iqn.2016-12.org.gluster-block:mock",
+		}, nil
+	}
+
+	m.MockBlockVolumeDestroy = func(host string, blockHostingVolumeName string, blockVolumeName string) error {
+		return nil
+	}
+
+	m.MockBlockVolumeInfo = func(host string, blockHostingVolumeName string, blockVolumeName string) (*executors.BlockVolumeInfo, error) {
+		return &executors.BlockVolumeInfo{
+			Name:              blockVolumeName,
+			GlusterVolumeName: blockHostingVolumeName,
+		}, nil
+	}
+
 	m.MockGeoReplicationCreate = func(host, volume string, geoRep *executors.GeoReplicationRequest) error {
 		return nil
 	}
@@ -182,6 +356,18 @@ func (m *MockExecutor) BrickDestroyCheck(host string, brick *executors.BrickRequ
 	return m.MockBrickDestroyCheck(host, brick)
 }
 
+func (m *MockExecutor) BrickExpand(host string, brick *executors.BrickRequest) error {
+	return m.MockBrickExpand(host, brick)
+}
+
+func (m *MockExecutor) BrickLvInfo(host string, path string) (*executors.BrickLvInfo, error) {
+	return m.MockBrickLvInfo(host, path)
+}
+
+func (m *MockExecutor) BrickUsage(host string, brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+	return m.MockBrickUsage(host, brick)
+}
+
 func (m *MockExecutor) VolumeCreate(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
 	return m.MockVolumeCreate(host, volume)
 }
@@ -206,10 +392,122 @@ func (m *MockExecutor) VolumeInfo(host string, volume string) (*executors.Volume
 	return m.MockVolumeInfo(host, volume)
 }
 
+func (m *MockExecutor) VolumeRemoveBricks(host string, volume string, bricks []executors.BrickInfo, action string) error {
+	return m.MockVolumeRemoveBricks(host, volume, bricks, action)
+}
+
+func (m *MockExecutor) VolumeRemoveBricksStatus(host string, volume string, bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+	return m.MockVolumeRemoveBricksStatus(host, volume, bricks)
+}
+
+func (m *MockExecutor) VolumeSetOptions(host string, volume string, options []string) error {
+	return m.MockVolumeSetOptions(host, volume, options)
+}
+
+func (m *MockExecutor) VolumeResetOptions(host string, volume string, options []string) error {
+	return m.MockVolumeResetOptions(host, volume, options)
+}
+
+func (m *MockExecutor) VolumeStop(host string, volume string) error {
+	return m.MockVolumeStop(host, volume)
+}
+
+func (m *MockExecutor) VolumeStart(host string, volume string) error {
+	return m.MockVolumeStart(host, volume)
+}
+
+func (m *MockExecutor) VolumeQuotaEnable(host string, volume string) error {
+	return m.MockVolumeQuotaEnable(host, volume)
+}
+
+func (m *MockExecutor) VolumeQuotaDisable(host string, volume string) error {
+	return m.MockVolumeQuotaDisable(host, volume)
+}
+
+func (m *MockExecutor) VolumeQuotaLimitSet(host string, volume string, limit *executors.QuotaLimit) error {
+	return m.MockVolumeQuotaLimitSet(host, volume, limit)
+}
+
+func (m *MockExecutor) VolumeQuotaLimitRemove(host string, volume string, path string) error {
+	return m.MockVolumeQuotaLimitRemove(host, volume, path)
+}
+
+func (m *MockExecutor) VolumeQuotaList(host string, volume string) (*executors.QuotaList, error) {
+	return m.MockVolumeQuotaList(host, volume)
+}
+
+func (m *MockExecutor) VolumeBitrotEnable(host string, volume string) error {
+	return m.MockVolumeBitrotEnable(host, volume)
+}
+
+func (m *MockExecutor) VolumeBitrotDisable(host string, volume string) error {
+	return m.MockVolumeBitrotDisable(host, volume)
+}
+
+func (m *MockExecutor) VolumeBitrotScrub(host string, volume string, scrub *executors.BitrotScrubRequest) error {
+	return m.MockVolumeBitrotScrub(host, volume, scrub)
+}
+
+func (m *MockExecutor) VolumeBitrotScrubStatus(host string, volume string) (*executors.BitrotScrubStatus, error) {
+	return m.MockVolumeBitrotScrubStatus(host, volume)
+}
+
 func (m *MockExecutor) HealInfo(host string, volume string) (*executors.HealInfo, error) {
 	return m.MockHealInfo(host, volume)
 }
 
+func (m *MockExecutor) VolumeHeal(host string, volume string, full bool) error {
+	return m.MockVolumeHeal(host, volume, full)
+}
+
+func (m *MockExecutor) VolumeNfsExport(host string, export *executors.NfsExportRequest) error {
+	return m.MockVolumeNfsExport(host, export)
+}
+
+func (m *MockExecutor) VolumeNfsUnexport(host string, volume string) error {
+	return m.MockVolumeNfsUnexport(host, volume)
+}
+
+func (m *MockExecutor) VolumeSmbShare(host string, share *executors.SmbShareRequest) error {
+	return m.MockVolumeSmbShare(host, share)
+}
+
+func (m *MockExecutor) VolumeSmbUnshare(host string, share string) error {
+	return m.MockVolumeSmbUnshare(host, share)
+}
+
+func (m *MockExecutor) SnapshotCreate(host string, snapshot *executors.SnapshotRequest) (*executors.Snapshot, error) {
+	return m.MockSnapshotCreate(host, snapshot)
+}
+
+func (m *MockExecutor) SnapshotList(host string, volume string) (*executors.SnapList, error) {
+	return m.MockSnapshotList(host, volume)
+}
+
+func (m *MockExecutor) SnapshotDelete(host string, snapshot string) error {
+	return m.MockSnapshotDelete(host, snapshot)
+}
+
+func (m *MockExecutor) SnapshotRestore(host string, volume string, snapshot string) error {
+	return m.MockSnapshotRestore(host, volume, snapshot)
+}
+
+func (m *MockExecutor) SnapshotClone(host string, clone *executors.SnapshotCloneRequest) (*executors.Volume, error) {
+	return m.MockSnapshotClone(host, clone)
+}
+
+func (m *MockExecutor) BlockVolumeCreate(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
+	return m.MockBlockVolumeCreate(host, blockVolume)
+}
+
+func (m *MockExecutor) BlockVolumeDestroy(host string, blockHostingVolumeName string, blockVolumeName string) error {
+	return m.MockBlockVolumeDestroy(host, blockHostingVolumeName, blockVolumeName)
+}
+
+func (m *MockExecutor) BlockVolumeInfo(host string, blockHostingVolumeName string, blockVolumeName string) (*executors.BlockVolumeInfo, error) {
+	return m.MockBlockVolumeInfo(host, blockHostingVolumeName, blockVolumeName)
+}
+
 func (m *MockExecutor) GeoReplicationCreate(host, volume string, geoRep *executors.GeoReplicationRequest) error {
 	return m.MockGeoReplicationCreate(host, volume, geoRep)
 }
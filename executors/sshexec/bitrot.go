@@ -0,0 +1,172 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/lpabon/godbc"
+)
+
+func (s *SshExecutor) VolumeBitrotEnable(host string, volume string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume bitrot %v enable", volume),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to enable bitrot on volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeBitrotDisable(host string, volume string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume bitrot %v disable", volume),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to disable bitrot on volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+// VolumeBitrotScrub sets how often and how fast the scrubber of
+// the volume checks the files of the bricks
+func (s *SshExecutor) VolumeBitrotScrub(host string,
+	volume string,
+	scrub *executors.BitrotScrubRequest) error {
+
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+	godbc.Require(scrub != nil)
+
+	commands := []string{}
+	if scrub.Frequency != "" {
+		commands = append(commands,
+			fmt.Sprintf("gluster --mode=script volume bitrot %v scrub-frequency %v",
+				volume, scrub.Frequency))
+	}
+	if scrub.Throttle != "" {
+		commands = append(commands,
+			fmt.Sprintf("gluster --mode=script volume bitrot %v scrub-throttle %v",
+				volume, scrub.Throttle))
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to set scrub of volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeBitrotScrubStatus(host string,
+	volume string) (*executors.BitrotScrubStatus, error) {
+
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume bitrot %v scrub status", volume),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get scrub status of volume %v: %v", volume, err)
+	}
+
+	status := parseBitrotScrubStatus(output[0])
+	logger.Debug("%+v\n", status)
+
+	return status, nil
+}
+
+// Parses the scrub status gluster prints as "key: value" lines.  The
+// volume wide values come first, followed by a section for each node
+// starting with its name.  Corrupted files are listed below the error
+// count of a node as "<gfid> ==> BRICK: <path>".
+func parseBitrotScrubStatus(output string) *executors.BitrotScrubStatus {
+	status := &executors.BitrotScrubStatus{}
+
+	var node *executors.BitrotScrubNodeStatus
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.Contains(line, "==>") {
+			if node != nil {
+				gfid := strings.TrimSpace(strings.SplitN(line, "==>", 2)[0])
+				node.CorruptedObjects = append(node.CorruptedObjects, gfid)
+			}
+			continue
+		}
+
+		// The key of the duration holds its format, like (D:M:H:M:S)
+		sep := ":"
+		if strings.HasPrefix(line, "Duration of last scrub") {
+			sep = "):"
+		}
+		parts := strings.SplitN(line, sep, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "State of scrub":
+			status.State = value
+		case "Scrub impact":
+			status.Throttle = value
+		case "Scrub frequency":
+			status.Frequency = value
+		case "Node":
+			status.Nodes = append(status.Nodes, executors.BitrotScrubNodeStatus{
+				Node: value,
+			})
+			node = &status.Nodes[len(status.Nodes)-1]
+		}
+		if node == nil {
+			continue
+		}
+
+		switch key {
+		case "Number of Scrubbed files":
+			node.ScrubbedFiles, _ = strconv.ParseUint(value, 10, 64)
+		case "Number of Skipped files":
+			node.SkippedFiles, _ = strconv.ParseUint(value, 10, 64)
+		case "Last completed scrub time":
+			node.LastScrubTime = value
+		case "Error count":
+			node.ErrorCount, _ = strconv.ParseUint(value, 10, 64)
+		default:
+			if strings.HasPrefix(key, "Duration of last scrub") {
+				node.LastScrubDuration = value
+			}
+		}
+	}
+
+	return status
+}
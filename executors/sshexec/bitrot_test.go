@@ -0,0 +1,183 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecVolumeBitrot(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	var cmds []string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		cmds = commands
+
+		return nil, nil
+	}
+
+	err = s.VolumeBitrotEnable("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 1)
+	tests.Assert(t, cmds[0] == "gluster --mode=script volume bitrot myvol enable", cmds)
+
+	err = s.VolumeBitrotScrub("host", "myvol", &executors.BitrotScrubRequest{
+		Frequency: "weekly",
+		Throttle:  "lazy",
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 2)
+	tests.Assert(t, cmds[0] == "gluster --mode=script volume bitrot myvol "+
+		"scrub-frequency weekly", cmds)
+	tests.Assert(t, cmds[1] == "gluster --mode=script volume bitrot myvol "+
+		"scrub-throttle lazy", cmds)
+
+	err = s.VolumeBitrotScrub("host", "myvol", &executors.BitrotScrubRequest{
+		Throttle: "aggressive",
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 1)
+	tests.Assert(t, cmds[0] == "gluster --mode=script volume bitrot myvol "+
+		"scrub-throttle aggressive", cmds)
+
+	err = s.VolumeBitrotDisable("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 1)
+	tests.Assert(t, cmds[0] == "gluster --mode=script volume bitrot myvol disable", cmds)
+}
+
+func TestSshExecVolumeBitrotScrubStatus(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume bitrot "+
+			"myvol scrub status", commands)
+
+		return []string{`
+Volume name : myvol
+
+State of scrub: Active (Idle)
+
+Scrub impact: lazy
+
+Scrub frequency: biweekly
+
+Bitrot error log location: /var/log/glusterfs/bitd.log
+
+Scrubber error log location: /var/log/glusterfs/scrub.log
+
+
+=========================================================
+
+Node: localhost
+
+Number of Scrubbed files: 1024
+
+Number of Skipped files: 2
+
+Last completed scrub time: 2017-10-10 10:10:10
+
+Duration of last scrub (D:M:H:M:S): 0:0:12:5
+
+Error count: 0
+
+
+=========================================================
+
+Node: 192.168.10.101
+
+Number of Scrubbed files: 1022
+
+Number of Skipped files: 0
+
+Last completed scrub time: 2017-10-10 10:10:12
+
+Duration of last scrub (D:M:H:M:S): 0:0:12:7
+
+Error count: 2
+
+Corrupted object's [GFID]:
+
+3c5f1b0d-3bd5-4d3e-8e4b-4b8c0e2f7f7a ==> BRICK: /bricks/b1
+ path: /data/file1
+
+8e1d7c6e-59c2-4d9b-a2f1-07a0b2f1c3d4 ==> BRICK: /bricks/b1
+ path: /data/file2
+
+=========================================================
+`}, nil
+	}
+
+	status, err := s.VolumeBitrotScrubStatus("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, status.State == "Active (Idle)", status.State)
+	tests.Assert(t, status.Throttle == "lazy", status.Throttle)
+	tests.Assert(t, status.Frequency == "biweekly", status.Frequency)
+	tests.Assert(t, len(status.Nodes) == 2, status.Nodes)
+
+	node := status.Nodes[0]
+	tests.Assert(t, node.Node == "localhost")
+	tests.Assert(t, node.ScrubbedFiles == 1024)
+	tests.Assert(t, node.SkippedFiles == 2)
+	tests.Assert(t, node.LastScrubTime == "2017-10-10 10:10:10", node.LastScrubTime)
+	tests.Assert(t, node.LastScrubDuration == "0:0:12:5", node.LastScrubDuration)
+	tests.Assert(t, node.ErrorCount == 0)
+	tests.Assert(t, len(node.CorruptedObjects) == 0)
+
+	node = status.Nodes[1]
+	tests.Assert(t, node.Node == "192.168.10.101")
+	tests.Assert(t, node.ErrorCount == 2)
+	tests.Assert(t, len(node.CorruptedObjects) == 2)
+	tests.Assert(t, node.CorruptedObjects[0] == "3c5f1b0d-3bd5-4d3e-8e4b-4b8c0e2f7f7a")
+	tests.Assert(t, node.CorruptedObjects[1] == "8e1d7c6e-59c2-4d9b-a2f1-07a0b2f1c3d4")
+}
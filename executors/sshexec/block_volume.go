@@ -0,0 +1,183 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/lpabon/godbc"
+)
+
+func (s *SshExecutor) BlockVolumeCreate(host string,
+	volume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
+
+	godbc.Require(volume != nil)
+	godbc.Require(host != "")
+	godbc.Require(volume.Name != "")
+	godbc.Require(volume.GlusterVolumeName != "")
+	godbc.Require(len(volume.BlockHosts) > 0)
+
+	type CliOutput struct {
+		Iqn      string   `json:"IQN"`
+		Username string   `json:"USERNAME"`
+		Password string   `json:"PASSWORD"`
+		Portal   []string `json:"PORTAL(S)"`
+		Result   string   `json:"RESULT"`
+		ErrCode  int      `json:"errCode"`
+		ErrMsg   string   `json:"errMsg"`
+	}
+
+	auth := "disable"
+	if volume.Auth {
+		auth = "enable"
+	}
+
+	commands := []string{
+		fmt.Sprintf("gluster-block create %v/%v ha %v auth %v prealloc full %v %vGiB --json",
+			volume.GlusterVolumeName,
+			volume.Name,
+			volume.Hacount,
+			auth,
+			strings.Join(volume.BlockHosts, ","),
+			volume.Size),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to create block volume %v in volume %v: %v",
+			volume.Name, volume.GlusterVolumeName, err))
+	}
+
+	var blockVolumeCreate CliOutput
+	err = json.Unmarshal([]byte(output[0]), &blockVolumeCreate)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to determine block volume information of %v: %v",
+			volume.Name, err)
+	}
+	if blockVolumeCreate.Result == "FAIL" {
+		// gluster-block may leave a partially created block volume behind
+		s.BlockVolumeDestroy(host, volume.GlusterVolumeName, volume.Name)
+		return nil, logger.LogError("Unable to create block volume %v: %v",
+			volume.Name, blockVolumeCreate.ErrMsg)
+	}
+	logger.Debug("%+v\n", blockVolumeCreate)
+
+	info := &executors.BlockVolumeInfo{
+		Name:              volume.Name,
+		Size:              volume.Size,
+		GlusterVolumeName: volume.GlusterVolumeName,
+		Hacount:           volume.Hacount,
+		Iqn:               blockVolumeCreate.Iqn,
+		Username:          blockVolumeCreate.Username,
+		Password:          blockVolumeCreate.Password,
+	}
+
+	// Portals are reported as host:port
+	for _, portal := range blockVolumeCreate.Portal {
+		info.BlockHosts = append(info.BlockHosts, strings.Split(portal, ":")[0])
+	}
+
+	return info, nil
+}
+
+func (s *SshExecutor) BlockVolumeDestroy(host string,
+	blockHostingVolumeName string,
+	blockVolumeName string) error {
+
+	godbc.Require(host != "")
+	godbc.Require(blockHostingVolumeName != "")
+	godbc.Require(blockVolumeName != "")
+
+	type CliOutput struct {
+		Result  string `json:"RESULT"`
+		ErrCode int    `json:"errCode"`
+		ErrMsg  string `json:"errMsg"`
+	}
+
+	commands := []string{
+		fmt.Sprintf("gluster-block delete %v/%v --json",
+			blockHostingVolumeName, blockVolumeName),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to delete block volume %v in volume %v: %v",
+			blockVolumeName, blockHostingVolumeName, err))
+	}
+
+	var blockVolumeDelete CliOutput
+	err = json.Unmarshal([]byte(output[0]), &blockVolumeDelete)
+	if err != nil {
+		return fmt.Errorf("Unable to determine result of deleting block volume %v: %v",
+			blockVolumeName, err)
+	}
+	if blockVolumeDelete.Result == "FAIL" {
+		return logger.LogError("Unable to delete block volume %v: %v",
+			blockVolumeName, blockVolumeDelete.ErrMsg)
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) BlockVolumeInfo(host string,
+	blockHostingVolumeName string,
+	blockVolumeName string) (*executors.BlockVolumeInfo, error) {
+
+	godbc.Require(host != "")
+	godbc.Require(blockHostingVolumeName != "")
+	godbc.Require(blockVolumeName != "")
+
+	type CliOutput struct {
+		Name       string   `json:"NAME"`
+		Volume     string   `json:"VOLUME"`
+		Gbid       string   `json:"GBID"`
+		Ha         int      `json:"HA"`
+		Password   string   `json:"PASSWORD"`
+		ExportedOn []string `json:"EXPORTED ON"`
+		Result     string   `json:"RESULT"`
+		ErrCode    int      `json:"errCode"`
+		ErrMsg     string   `json:"errMsg"`
+	}
+
+	commands := []string{
+		fmt.Sprintf("gluster-block info %v/%v --json",
+			blockHostingVolumeName, blockVolumeName),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get information of block volume %v in volume %v: %v",
+			blockVolumeName, blockHostingVolumeName, err)
+	}
+
+	var blockVolumeInfo CliOutput
+	err = json.Unmarshal([]byte(output[0]), &blockVolumeInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to determine block volume information of %v: %v",
+			blockVolumeName, err)
+	}
+	if blockVolumeInfo.Result == "FAIL" {
+		return nil, fmt.Errorf("Unable to get information of block volume %v: %v",
+			blockVolumeName, blockVolumeInfo.ErrMsg)
+	}
+	logger.Debug("%+v\n", blockVolumeInfo)
+
+	return &executors.BlockVolumeInfo{
+		Name:              blockVolumeInfo.Name,
+		GlusterVolumeName: blockVolumeInfo.Volume,
+		Gbid:              blockVolumeInfo.Gbid,
+		Hacount:           blockVolumeInfo.Ha,
+		BlockHosts:        blockVolumeInfo.ExportedOn,
+		Password:          blockVolumeInfo.Password,
+	}, nil
+}
@@ -0,0 +1,193 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecBlockVolumeCreate(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster-block create hostvol/myblock "+
+			"ha 2 auth enable prealloc full h1,h2 10GiB --json", commands)
+
+		return []string{`{ "IQN": "iqn.2016-12.org.gluster-block:6f0e5e5a", ` +
+			`"USERNAME": "6f0e5e5a", "PASSWORD": "secret", ` +
+			`"PORTAL(S)": [ "h1:3260", "h2:3260" ], "RESULT": "SUCCESS" }`}, nil
+	}
+
+	// Call function
+	info, err := s.BlockVolumeCreate("host", &executors.BlockVolumeRequest{
+		Name:              "myblock",
+		Size:              10,
+		GlusterVolumeName: "hostvol",
+		Hacount:           2,
+		BlockHosts:        []string{"h1", "h2"},
+		Auth:              true,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.Name == "myblock")
+	tests.Assert(t, info.Size == 10)
+	tests.Assert(t, info.GlusterVolumeName == "hostvol")
+	tests.Assert(t, info.Iqn == "iqn.2016-12.org.gluster-block:6f0e5e5a")
+	tests.Assert(t, info.Username == "6f0e5e5a")
+	tests.Assert(t, info.Password == "secret")
+	tests.Assert(t, len(info.BlockHosts) == 2)
+	tests.Assert(t, info.BlockHosts[0] == "h1")
+	tests.Assert(t, info.BlockHosts[1] == "h2")
+
+	// gluster-block reports a failure, the block volume is removed
+	var deleted bool
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		if strings.HasPrefix(commands[0], "gluster-block delete") {
+			tests.Assert(t, commands[0] == "gluster-block delete hostvol/myblock --json")
+			deleted = true
+			return []string{`{ "RESULT": "SUCCESS" }`}, nil
+		}
+
+		tests.Assert(t, strings.Contains(commands[0], "auth disable"), commands)
+		return []string{`{ "RESULT": "FAIL", "errCode": 255, ` +
+			`"errMsg": "failed to configure on h1" }`}, nil
+	}
+	_, err = s.BlockVolumeCreate("host", &executors.BlockVolumeRequest{
+		Name:              "myblock",
+		Size:              10,
+		GlusterVolumeName: "hostvol",
+		Hacount:           1,
+		BlockHosts:        []string{"h1"},
+	})
+	tests.Assert(t, err != nil)
+	tests.Assert(t, strings.Contains(err.Error(), "failed to configure on h1"), err)
+	tests.Assert(t, deleted)
+}
+
+func TestSshExecBlockVolumeDestroy(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster-block delete hostvol/myblock --json", commands)
+
+		return []string{`{ "SUCCESSFUL ON": [ "h1", "h2" ], "RESULT": "SUCCESS" }`}, nil
+	}
+
+	// Call function
+	err = s.BlockVolumeDestroy("host", "hostvol", "myblock")
+	tests.Assert(t, err == nil, err)
+
+	// gluster-block reports a failure
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		return []string{`{ "RESULT": "FAIL", "errCode": 2, ` +
+			`"errMsg": "block myblock doesn't exist" }`}, nil
+	}
+	err = s.BlockVolumeDestroy("host", "hostvol", "myblock")
+	tests.Assert(t, err != nil)
+	tests.Assert(t, strings.Contains(err.Error(), "doesn't exist"), err)
+}
+
+func TestSshExecBlockVolumeInfo(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster-block info hostvol/myblock --json", commands)
+
+		return []string{`{ "NAME": "myblock", "VOLUME": "hostvol", ` +
+			`"GBID": "6f0e5e5a-4b3c-4d2e-8f1a-0c9b8a7d6e5f", "SIZE": "10.0 GiB", ` +
+			`"HA": 2, "PASSWORD": "", "EXPORTED ON": [ "h1", "h2" ] }`}, nil
+	}
+
+	// Call function
+	info, err := s.BlockVolumeInfo("host", "hostvol", "myblock")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.Name == "myblock")
+	tests.Assert(t, info.GlusterVolumeName == "hostvol")
+	tests.Assert(t, info.Gbid == "6f0e5e5a-4b3c-4d2e-8f1a-0c9b8a7d6e5f")
+	tests.Assert(t, info.Hacount == 2)
+	tests.Assert(t, len(info.BlockHosts) == 2)
+	tests.Assert(t, info.BlockHosts[0] == "h1")
+	tests.Assert(t, info.BlockHosts[1] == "h2")
+}
@@ -11,6 +11,8 @@ package sshexec
 
 import (
 	"fmt"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/heketi/heketi/executors"
@@ -90,16 +92,26 @@ func (s *SshExecutor) BrickCreate(host string,
 		fmt.Sprintf("mkdir %v/brick", mountpoint),
 	}
 
-	// Only set the GID if the value is other than root(gid 0).
-	// When no gid is set, root is the only one that can write to the volume
-	if 0 != brick.Gid {
-		commands = append(commands, []string{
-			// Set GID on brick
-			fmt.Sprintf("chown :%v %v/brick", brick.Gid, mountpoint),
+	// Only set the UID and GID if the values are other than root(0).
+	// When neither is set, root is the only one that can write to the volume
+	if 0 != brick.Uid {
+		// Set UID and GID on brick
+		commands = append(commands,
+			fmt.Sprintf("chown %v:%v %v/brick", brick.Uid, brick.Gid, mountpoint))
+	} else if 0 != brick.Gid {
+		// Set GID on brick
+		commands = append(commands,
+			fmt.Sprintf("chown :%v %v/brick", brick.Gid, mountpoint))
+	}
 
-			// Set writable by GID and UID
-			fmt.Sprintf("chmod 2775 %v/brick", mountpoint),
-		}...)
+	if brick.Mode != "" {
+		// Set the requested mode
+		commands = append(commands,
+			fmt.Sprintf("chmod %v %v/brick", brick.Mode, mountpoint))
+	} else if 0 != brick.Gid {
+		// Set writable by GID and UID
+		commands = append(commands,
+			fmt.Sprintf("chmod 2775 %v/brick", mountpoint))
 	}
 
 	// Execute commands
@@ -125,6 +137,18 @@ func (s *SshExecutor) BrickDestroy(host string,
 	godbc.Require(brick.Name != "")
 	godbc.Require(brick.VgId != "")
 
+	if brick.Imported {
+		return s.importedBrickDestroy(host, brick)
+	}
+	if brick.Restored {
+		err := s.restoredBrickDestroy(host, brick)
+		if err != nil {
+			return err
+		}
+	} else if brick.Path != "" {
+		return s.clonedBrickDestroy(host, brick)
+	}
+
 	// Try to unmount first
 	commands := []string{
 		fmt.Sprintf("umount %v", s.brickMountPoint(brick)),
@@ -166,6 +190,56 @@ func (s *SshExecutor) BrickDestroy(host string,
 	return nil
 }
 
+// Grows the brick to brick.Size, extending its thin pool to
+// brick.TpSize and the pool metadata to brick.PoolMetadataSize
+// first. The thin pool and its metadata are left alone when
+// their size in the request is zero.
+func (s *SshExecutor) BrickExpand(host string,
+	brick *executors.BrickRequest) error {
+
+	godbc.Require(brick != nil)
+	godbc.Require(host != "")
+	godbc.Require(brick.Name != "")
+	godbc.Require(brick.Size > 0)
+	godbc.Require(brick.VgId != "")
+	godbc.Require(brick.Path == "")
+
+	commands := []string{}
+	if brick.PoolMetadataSize > 0 {
+		commands = append(commands,
+			fmt.Sprintf("lvextend --poolmetadatasize %vK %v/%v",
+				brick.PoolMetadataSize,
+				s.vgName(brick.VgId),
+				s.tpName(brick.Name)))
+	}
+	if brick.TpSize > 0 {
+		godbc.Require(brick.TpSize >= brick.Size)
+		commands = append(commands,
+			fmt.Sprintf("lvextend -L %vK %v/%v",
+				brick.TpSize,
+				s.vgName(brick.VgId),
+				s.tpName(brick.Name)))
+	}
+	commands = append(commands, []string{
+		// Grow the logical volume
+		fmt.Sprintf("lvextend -L %vK %v/%v",
+			brick.Size,
+			s.vgName(brick.VgId),
+			s.brickName(brick.Name)),
+
+		// Grow the file system on the mounted brick
+		fmt.Sprintf("xfs_growfs %v", s.brickMountPoint(brick)),
+	}...)
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to expand brick %v on host %v: %v",
+			brick.Name, host, err))
+	}
+
+	return nil
+}
+
 func (s *SshExecutor) BrickDestroyCheck(host string,
 	brick *executors.BrickRequest) error {
 	godbc.Require(brick != nil)
@@ -173,6 +247,19 @@ func (s *SshExecutor) BrickDestroyCheck(host string,
 	godbc.Require(brick.Name != "")
 	godbc.Require(brick.VgId != "")
 
+	if brick.Imported {
+		return s.checkImportedThinPoolUsage(host, brick)
+	}
+	if brick.Restored {
+		return s.checkRestoredThinPoolUsage(host, brick)
+	}
+
+	// Cloned bricks are thin volumes in the thin pool of another
+	// brick, removing them does not remove the thin pool
+	if brick.Path != "" {
+		return nil
+	}
+
 	err := s.checkThinPoolUsage(host, brick)
 	if err != nil {
 		return err
@@ -181,6 +268,147 @@ func (s *SshExecutor) BrickDestroyCheck(host string,
 	return nil
 }
 
+// Bricks of cloned volumes are mounted and named by gluster, so the
+// logical volume is found from the mount of the brick.
+func (s *SshExecutor) clonedBrickDestroy(host string,
+	brick *executors.BrickRequest) error {
+
+	mountpoint := filepath.Dir(brick.Path)
+
+	// Get the logical volume
+	commands := []string{
+		fmt.Sprintf("findmnt -n -o SOURCE %v", mountpoint),
+	}
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to determine the logical volume "+
+			"of brick %v on host %v: %v", brick.Path, host, err))
+	}
+	devnode := strings.TrimSpace(output[0])
+
+	// Unmount
+	commands = []string{
+		fmt.Sprintf("umount %v", mountpoint),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Only remove the LV, the thin pool belongs to the original brick
+	commands = []string{
+		fmt.Sprintf("lvremove -f %v", devnode),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Now cleanup the mount point
+	commands = []string{
+		fmt.Sprintf("rmdir %v", mountpoint),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	return nil
+}
+
+// Restoring a snapshot moves the brick to the logical volume gluster
+// created for the snapshot, so it is found from the mount of the
+// brick.  Its thin pool, along with the original logical volume of
+// the brick, is removed afterwards like for any other brick.
+func (s *SshExecutor) restoredBrickDestroy(host string,
+	brick *executors.BrickRequest) error {
+
+	info, err := s.BrickLvInfo(host, brick.Path)
+	if err != nil {
+		return err
+	}
+
+	// Unmount
+	commands := []string{
+		fmt.Sprintf("umount %v", info.MountPoint),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Remove the LV
+	commands = []string{
+		fmt.Sprintf("lvremove -f %v/%v", info.VgName, info.LvName),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Now cleanup the mount point
+	commands = []string{
+		fmt.Sprintf("rmdir %v", info.MountPoint),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	return nil
+}
+
+// Restored bricks share the thin pool with the original logical
+// volume of the brick, which may be left behind by the restore.  Any
+// other logical volume in the pool belongs to a snapshot or a clone.
+func (s *SshExecutor) checkRestoredThinPoolUsage(host string,
+	brick *executors.BrickRequest) error {
+
+	info, err := s.BrickLvInfo(host, brick.Path)
+	if err != nil {
+		return err
+	}
+	if info.PoolName == "" {
+		return nil
+	}
+
+	// Sample output:
+	//		# lvs --noheadings --separator : -o lv_name,pool_lv vg_1
+	//		  brick_1:tp_1
+	//		  0f8e_0:tp_1
+	//		  tp_1:
+	commands := []string{
+		fmt.Sprintf("lvs --noheadings --separator : -o lv_name,pool_lv %v",
+			info.VgName),
+	}
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+		return fmt.Errorf("Unable to determine number of logical volumes in "+
+			"thin pool %v on host %v", info.PoolName, host)
+	}
+
+	others := 0
+	for _, line := range strings.Split(output[0], "\n") {
+		lv := strings.Split(strings.TrimSpace(line), ":")
+		if len(lv) != 2 || lv[1] != info.PoolName {
+			continue
+		}
+		if lv[0] != info.LvName && lv[0] != s.brickName(brick.Name) {
+			others++
+		}
+	}
+	if others > 0 {
+		return fmt.Errorf("Cannot delete thin pool %v on %v because it "+
+			"is used by [%v] snapshot(s) or cloned volume(s)",
+			info.PoolName,
+			host,
+			others)
+	}
+
+	return nil
+}
+
 // Determine if any other logical volumes are using the thin pool.
 // If they are, then either a clone volume or a snapshot is using that storage,
 // and we cannot delete the brick.
@@ -219,3 +447,307 @@ func (s *SshExecutor) checkThinPoolUsage(host string,
 
 	return nil
 }
+
+// Finds the logical volume, and its thin pool, of the file system
+// the brick at path is on.
+func (s *SshExecutor) BrickLvInfo(host string,
+	path string) (*executors.BrickLvInfo, error) {
+
+	godbc.Require(host != "")
+	godbc.Require(path != "")
+
+	// Sample output:
+	//		# findmnt -n -o SOURCE,TARGET --target /bricks/b1/brick
+	//		/dev/mapper/vg_b1-brick_b1 /bricks/b1
+	commands := []string{
+		fmt.Sprintf("findmnt -n -o SOURCE,TARGET --target %v", path),
+	}
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to determine the mount "+
+			"of brick %v on host %v: %v", path, host, err))
+	}
+	mount := strings.Fields(output[0])
+	if len(mount) != 2 {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the mount of "+
+			"brick %v on host %v: %v", path, host, output[0]))
+	}
+
+	// Sample output:
+	//		# lvs --noheadings --units k --nosuffix --separator : \
+	//			-o vg_name,lv_name,lv_size,pool_lv /dev/mapper/vg_b1-brick_b1
+	//		  vg_b1:brick_b1:2097152.00:tp_b1
+	commands = []string{
+		fmt.Sprintf("lvs --noheadings --units k --nosuffix --separator : "+
+			"-o vg_name,lv_name,lv_size,pool_lv %v", mount[0]),
+	}
+	output, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Brick %v on host %v is not on "+
+			"a logical volume: %v", path, host, err))
+	}
+	lv := strings.Split(strings.TrimSpace(output[0]), ":")
+	if len(lv) != 4 {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the logical volume "+
+			"of brick %v on host %v: %v", path, host, output[0]))
+	}
+
+	info := &executors.BrickLvInfo{
+		VgName:     lv[0],
+		LvName:     lv[1],
+		MountPoint: mount[1],
+		PoolName:   lv[3],
+	}
+	if strings.HasPrefix(info.VgName, "vg_") {
+		info.VgId = strings.TrimPrefix(info.VgName, "vg_")
+	}
+	info.Size, err = parseLvSize(lv[2])
+	if err != nil {
+		return nil, logger.Err(err)
+	}
+	if info.PoolName == "" {
+		return info, nil
+	}
+
+	// Sample output:
+	//		# lvs --noheadings --units k --nosuffix --separator : \
+	//			-o lv_size,lv_metadata_size vg_b1/tp_b1
+	//		  2097152.00:12288.00
+	commands = []string{
+		fmt.Sprintf("lvs --noheadings --units k --nosuffix --separator : "+
+			"-o lv_size,lv_metadata_size %v/%v", info.VgName, info.PoolName),
+	}
+	output, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to get the size of thin "+
+			"pool %v on host %v: %v", info.PoolName, host, err))
+	}
+	pool := strings.Split(strings.TrimSpace(output[0]), ":")
+	if len(pool) != 2 {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the size of thin "+
+			"pool %v on host %v: %v", info.PoolName, host, output[0]))
+	}
+	info.PoolSize, err = parseLvSize(pool[0])
+	if err != nil {
+		return nil, logger.Err(err)
+	}
+	info.PoolMetadataSize, err = parseLvSize(pool[1])
+	if err != nil {
+		return nil, logger.Err(err)
+	}
+
+	return info, nil
+}
+
+func (s *SshExecutor) BrickUsage(host string,
+	brick *executors.BrickRequest) (*executors.BrickUsage, error) {
+
+	godbc.Require(brick != nil)
+	godbc.Require(host != "")
+	godbc.Require(brick.Name != "")
+	godbc.Require(brick.VgId != "")
+
+	// Bricks not created by BrickCreate are found from their mount
+	path := brick.Path
+	vg, pool := s.vgName(brick.VgId), s.tpName(brick.Name)
+	if path == "" {
+		path = s.brickMountPoint(brick)
+	} else {
+		lv, err := s.BrickLvInfo(host, path)
+		if err != nil {
+			return nil, err
+		}
+		vg, pool = lv.VgName, lv.PoolName
+	}
+
+	// Sample output:
+	//		# df -B1 --output=size,used /var/lib/heketi/mounts/vg_1/brick_1
+	//		     1B-blocks     Used
+	//		    2136997888 35033088
+	commands := []string{
+		fmt.Sprintf("df -B1 --output=size,used %v", path),
+	}
+
+	// Sample output:
+	//		# lvs --noheadings --separator : \
+	//			-o data_percent,metadata_percent vg_1/tp_1
+	//		  12.50:3.20
+	if pool != "" {
+		commands = append(commands,
+			fmt.Sprintf("lvs --noheadings --separator : "+
+				"-o data_percent,metadata_percent %v/%v", vg, pool))
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to get the usage of "+
+			"brick %v on host %v: %v", path, host, err))
+	}
+
+	usage := &executors.BrickUsage{}
+	lines := strings.Split(strings.TrimSpace(output[0]), "\n")
+	df := strings.Fields(lines[len(lines)-1])
+	if len(df) != 2 {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the usage of "+
+			"brick %v on host %v: %v", path, host, output[0]))
+	}
+	usage.Size, err = strconv.ParseUint(df[0], 10, 64)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the size of "+
+			"brick %v on host %v: %v", path, host, df[0]))
+	}
+	usage.Used, err = strconv.ParseUint(df[1], 10, 64)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the used size of "+
+			"brick %v on host %v: %v", path, host, df[1]))
+	}
+	if pool == "" {
+		return usage, nil
+	}
+
+	percents := strings.Split(strings.TrimSpace(output[1]), ":")
+	if len(percents) != 2 {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the usage of thin "+
+			"pool %v on host %v: %v", pool, host, output[1]))
+	}
+	usage.PoolDataPercent, err = strconv.ParseFloat(strings.TrimSpace(percents[0]), 64)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the data usage of "+
+			"thin pool %v on host %v: %v", pool, host, percents[0]))
+	}
+	usage.PoolMetadataPercent, err = strconv.ParseFloat(strings.TrimSpace(percents[1]), 64)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to parse the metadata usage of "+
+			"thin pool %v on host %v: %v", pool, host, percents[1]))
+	}
+
+	return usage, nil
+}
+
+// Parses a size in KB as printed by lvs --units k --nosuffix
+func parseLvSize(size string) (uint64, error) {
+	kb, err := strconv.ParseFloat(strings.TrimSpace(size), 64)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to parse logical volume size %v: %v",
+			size, err)
+	}
+	return uint64(kb), nil
+}
+
+// Returns the number of thin volumes in the thin pool of an imported brick
+func (s *SshExecutor) thinPoolCount(host string,
+	info *executors.BrickLvInfo) (int, error) {
+
+	commands := []string{
+		fmt.Sprintf("lvs --noheadings -o thin_count %v/%v",
+			info.VgName, info.PoolName),
+	}
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to determine number of logical volumes "+
+			"in thin pool %v on host %v: %v", info.PoolName, host, err)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(output[0]))
+	if err != nil {
+		return 0, fmt.Errorf("Unable to parse number of logical volumes "+
+			"in thin pool %v on host %v: %v", info.PoolName, host, output[0])
+	}
+
+	return count, nil
+}
+
+// Imported bricks may only be removed when no snapshots or clones
+// use their thin pool
+func (s *SshExecutor) checkImportedThinPoolUsage(host string,
+	brick *executors.BrickRequest) error {
+
+	info, err := s.BrickLvInfo(host, brick.Path)
+	if err != nil {
+		return err
+	}
+	if info.PoolName == "" {
+		return nil
+	}
+
+	count, err := s.thinPoolCount(host, info)
+	if err != nil {
+		return logger.Err(err)
+	}
+	if count != 1 {
+		return fmt.Errorf("Cannot delete thin pool %v on %v because it "+
+			"is used by [%v] snapshot(s) or cloned volume(s)",
+			info.PoolName,
+			host,
+			count-1)
+	}
+
+	return nil
+}
+
+// Bricks created outside of heketi are named and mounted by whoever
+// created them, so the logical volume and thin pool are found from
+// the mount of the brick. The thin pool is only removed once empty.
+func (s *SshExecutor) importedBrickDestroy(host string,
+	brick *executors.BrickRequest) error {
+
+	info, err := s.BrickLvInfo(host, brick.Path)
+	if err != nil {
+		return err
+	}
+
+	// Unmount
+	commands := []string{
+		fmt.Sprintf("umount %v", info.MountPoint),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Remove the LV
+	commands = []string{
+		fmt.Sprintf("lvremove -f %v/%v", info.VgName, info.LvName),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Remove the thin pool, unless something else still uses it
+	if info.PoolName != "" {
+		count, err := s.thinPoolCount(host, info)
+		if err != nil {
+			logger.Err(err)
+		} else if count == 0 {
+			commands = []string{
+				fmt.Sprintf("lvremove -f %v/%v", info.VgName, info.PoolName),
+			}
+			_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+			if err != nil {
+				logger.Err(err)
+			}
+		}
+	}
+
+	// Now cleanup the mount point
+	commands = []string{
+		fmt.Sprintf("rmdir %v", info.MountPoint),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	// Remove from fstab
+	commands = []string{
+		fmt.Sprintf("sed -i.save \"\\|[[:space:]]%v[[:space:]]|d\" %v",
+			info.MountPoint,
+			s.Fstab),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 5)
+	if err != nil {
+		logger.Err(err)
+	}
+
+	return nil
+}
@@ -199,6 +199,79 @@ func TestSshExecBrickCreateWithGid(t *testing.T) {
 
 }
 
+func TestSshExecBrickCreateWithUidAndMode(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		Port:           "100",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Create a Brick
+	b := &executors.BrickRequest{
+		VgId:             "xvgid",
+		Name:             "id",
+		TpSize:           100,
+		Size:             10,
+		PoolMetadataSize: 5,
+		Gid:              1234,
+		Uid:              1001,
+		Mode:             "0770",
+	}
+
+	// Mock ssh function
+	var cmds []string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		cmds = commands
+		return nil, nil
+	}
+
+	// Only the owner and mode of the brick root differ
+	_, err = s.BrickCreate("myhost", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 8, cmds)
+	tests.Assert(t, cmds[6] == "chown 1001:1234 "+
+		"/var/lib/heketi/mounts/vg_xvgid/brick_id/brick", cmds[6])
+	tests.Assert(t, cmds[7] == "chmod 0770 "+
+		"/var/lib/heketi/mounts/vg_xvgid/brick_id/brick", cmds[7])
+
+	// A user without a group keeps the default mode of mkdir
+	b.Gid = 0
+	b.Mode = ""
+	_, err = s.BrickCreate("myhost", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 7, cmds)
+	tests.Assert(t, cmds[6] == "chown 1001:0 "+
+		"/var/lib/heketi/mounts/vg_xvgid/brick_id/brick", cmds[6])
+
+	// A mode alone
+	b.Uid = 0
+	b.Mode = "1777"
+	_, err = s.BrickCreate("myhost", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(cmds) == 7, cmds)
+	tests.Assert(t, cmds[6] == "chmod 1777 "+
+		"/var/lib/heketi/mounts/vg_xvgid/brick_id/brick", cmds[6])
+}
+
 func TestSshExecBrickCreateSudo(t *testing.T) {
 
 	f := NewFakeSsh()
@@ -355,3 +428,486 @@ func TestSshExecBrickDestroy(t *testing.T) {
 	err = s.BrickDestroy("myhost", b)
 	tests.Assert(t, err == nil, err)
 }
+
+func TestSshExecBrickExpand(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		Port:           "100",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Grow the brick, its thin pool and the pool metadata
+	b := &executors.BrickRequest{
+		VgId:             "xvgid",
+		Name:             "id",
+		TpSize:           200,
+		Size:             20,
+		PoolMetadataSize: 8,
+	}
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		tests.Assert(t, len(commands) == 4)
+
+		for i, cmd := range commands {
+			cmd = strings.Trim(cmd, " ")
+			switch i {
+			case 0:
+				tests.Assert(t,
+					cmd == "lvextend --poolmetadatasize 8K vg_xvgid/tp_id", cmd)
+
+			case 1:
+				tests.Assert(t,
+					cmd == "lvextend -L 200K vg_xvgid/tp_id", cmd)
+
+			case 2:
+				tests.Assert(t,
+					cmd == "lvextend -L 20K vg_xvgid/brick_id", cmd)
+
+			case 3:
+				tests.Assert(t,
+					cmd == "xfs_growfs /var/lib/heketi/mounts/vg_xvgid/brick_id", cmd)
+			}
+		}
+
+		return nil, nil
+	}
+
+	err = s.BrickExpand("myhost", b)
+	tests.Assert(t, err == nil, err)
+
+	// The thin pool already has room for the brick
+	b.TpSize = 0
+	b.PoolMetadataSize = 0
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, len(commands) == 2)
+		tests.Assert(t, commands[0] == "lvextend -L 20K vg_xvgid/brick_id", commands[0])
+		tests.Assert(t, commands[1] == "xfs_growfs /var/lib/heketi/mounts/vg_xvgid/brick_id",
+			commands[1])
+
+		return nil, nil
+	}
+
+	err = s.BrickExpand("myhost", b)
+	tests.Assert(t, err == nil, err)
+}
+
+func TestSshExecClonedBrickDestroy(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		Port:           "100",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Create a Brick
+	b := &executors.BrickRequest{
+		VgId:   "xvgid",
+		Name:   "id",
+		TpSize: 100,
+		Size:   10,
+		Path:   "/run/gluster/snaps/0f8e/brick1/brick",
+	}
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		tests.Assert(t, len(commands) == 1)
+
+		cmd := strings.Trim(commands[0], " ")
+		switch {
+		case strings.Contains(cmd, "findmnt"):
+			tests.Assert(t,
+				cmd == "findmnt -n -o SOURCE /run/gluster/snaps/0f8e/brick1", cmd)
+			return []string{"/dev/mapper/vg_xvgid-0f8e_0\n"}, nil
+
+		case strings.Contains(cmd, "umount"):
+			tests.Assert(t,
+				cmd == "umount /run/gluster/snaps/0f8e/brick1", cmd)
+
+		case strings.Contains(cmd, "lvremove"):
+			tests.Assert(t,
+				cmd == "lvremove -f /dev/mapper/vg_xvgid-0f8e_0", cmd)
+
+		case strings.Contains(cmd, "rmdir"):
+			tests.Assert(t,
+				cmd == "rmdir /run/gluster/snaps/0f8e/brick1", cmd)
+
+		default:
+			tests.Assert(t, false, "Unexpected command", cmd)
+		}
+
+		return nil, nil
+	}
+
+	// Destroy Brick
+	err = s.BrickDestroy("myhost", b)
+	tests.Assert(t, err == nil, err)
+
+	// The thin pool of a cloned brick is never checked
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, false, "Should not be reached")
+		return nil, nil
+	}
+	err = s.BrickDestroyCheck("myhost", b)
+	tests.Assert(t, err == nil, err)
+}
+
+func TestSshExecImportedBrickDestroy(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		Port:           "100",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Brick of a volume created outside of heketi
+	b := &executors.BrickRequest{
+		VgId:     "xvgid",
+		Name:     "id",
+		Path:     "/bricks/b1/brick",
+		Imported: true,
+	}
+
+	thinCount := "1"
+	removed := []string{}
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		tests.Assert(t, len(commands) == 1)
+
+		cmd := strings.Trim(commands[0], " ")
+		switch {
+		case strings.Contains(cmd, "findmnt"):
+			tests.Assert(t,
+				cmd == "findmnt -n -o SOURCE,TARGET --target /bricks/b1/brick", cmd)
+			return []string{"/dev/mapper/vg_xvgid-lv_b1 /bricks/b1\n"}, nil
+
+		case strings.Contains(cmd, "vg_name,lv_name,lv_size,pool_lv"):
+			tests.Assert(t, strings.HasSuffix(cmd, " /dev/mapper/vg_xvgid-lv_b1"), cmd)
+			return []string{"  vg_xvgid:lv_b1:2097152.00:pool_b1\n"}, nil
+
+		case strings.Contains(cmd, "lv_size,lv_metadata_size"):
+			tests.Assert(t, strings.HasSuffix(cmd, " vg_xvgid/pool_b1"), cmd)
+			return []string{"  2101248.00:12288.00\n"}, nil
+
+		case strings.Contains(cmd, "thin_count"):
+			tests.Assert(t,
+				cmd == "lvs --noheadings -o thin_count vg_xvgid/pool_b1", cmd)
+			return []string{"  " + thinCount + "\n"}, nil
+
+		case strings.Contains(cmd, "umount"):
+			tests.Assert(t, cmd == "umount /bricks/b1", cmd)
+
+		case strings.Contains(cmd, "lvremove"):
+			removed = append(removed, cmd)
+			thinCount = "0"
+
+		case strings.Contains(cmd, "rmdir"):
+			tests.Assert(t, cmd == "rmdir /bricks/b1", cmd)
+
+		case strings.Contains(cmd, "sed"):
+			tests.Assert(t,
+				cmd == "sed -i.save \"\\|[[:space:]]/bricks/b1[[:space:]]|d\" /my/fstab", cmd)
+
+		default:
+			tests.Assert(t, false, "Unexpected command", cmd)
+		}
+
+		return nil, nil
+	}
+
+	// Logical volume information
+	info, err := s.BrickLvInfo("myhost", b.Path)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.VgId == "xvgid")
+	tests.Assert(t, info.VgName == "vg_xvgid")
+	tests.Assert(t, info.LvName == "lv_b1")
+	tests.Assert(t, info.MountPoint == "/bricks/b1")
+	tests.Assert(t, info.PoolName == "pool_b1")
+	tests.Assert(t, info.Size == 2097152)
+	tests.Assert(t, info.PoolSize == 2101248)
+	tests.Assert(t, info.PoolMetadataSize == 12288)
+
+	// Only the brick uses the thin pool
+	err = s.BrickDestroyCheck("myhost", b)
+	tests.Assert(t, err == nil, err)
+
+	// A snapshot uses the thin pool
+	thinCount = "2"
+	err = s.BrickDestroyCheck("myhost", b)
+	tests.Assert(t, err != nil)
+
+	// Destroy the brick and its emptied thin pool
+	thinCount = "1"
+	err = s.BrickDestroy("myhost", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(removed) == 2, removed)
+	tests.Assert(t, removed[0] == "lvremove -f vg_xvgid/lv_b1", removed[0])
+	tests.Assert(t, removed[1] == "lvremove -f vg_xvgid/pool_b1", removed[1])
+}
+
+func TestSshExecRestoredBrickDestroy(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		Port:           "100",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Brick moved by a snapshot restore
+	b := &executors.BrickRequest{
+		VgId:     "xvgid",
+		Name:     "id",
+		Path:     "/run/gluster/snaps/0f8e/brick1/brick",
+		Restored: true,
+	}
+
+	lvs := "  brick_id:tp_id\n  0f8e_0:tp_id\n  tp_id:\n"
+	var umounted, removed, rmdirs []string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		tests.Assert(t, len(commands) == 1)
+
+		cmd := strings.Trim(commands[0], " ")
+		switch {
+		case strings.Contains(cmd, "findmnt"):
+			tests.Assert(t,
+				cmd == "findmnt -n -o SOURCE,TARGET --target "+b.Path, cmd)
+			return []string{"/dev/mapper/vg_xvgid-0f8e_0 /run/gluster/snaps/0f8e/brick1\n"}, nil
+
+		case strings.Contains(cmd, "vg_name,lv_name,lv_size,pool_lv"):
+			return []string{"  vg_xvgid:0f8e_0:2097152.00:tp_id\n"}, nil
+
+		case strings.Contains(cmd, "lv_size,lv_metadata_size"):
+			return []string{"  2101248.00:12288.00\n"}, nil
+
+		case strings.Contains(cmd, "lv_name,pool_lv"):
+			tests.Assert(t,
+				cmd == "lvs --noheadings --separator : -o lv_name,pool_lv vg_xvgid", cmd)
+			return []string{lvs}, nil
+
+		case strings.Contains(cmd, "umount"):
+			umounted = append(umounted, cmd)
+
+		case strings.Contains(cmd, "lvremove"):
+			removed = append(removed, cmd)
+
+		case strings.Contains(cmd, "rmdir"):
+			rmdirs = append(rmdirs, cmd)
+
+		case strings.Contains(cmd, "sed"):
+			tests.Assert(t, cmd == "sed -i.save \"/brick_id/d\" /my/fstab", cmd)
+
+		default:
+			tests.Assert(t, false, "Unexpected command", cmd)
+		}
+
+		return nil, nil
+	}
+
+	// The original logical volume of the brick does not count
+	err = s.BrickDestroyCheck("myhost", b)
+	tests.Assert(t, err == nil, err)
+
+	// A snapshot of the restored volume uses the thin pool
+	lvs += "  1a2b_0:tp_id\n"
+	err = s.BrickDestroyCheck("myhost", b)
+	tests.Assert(t, err != nil)
+
+	// Both the restored and the original brick are removed
+	err = s.BrickDestroy("myhost", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(umounted) == 2, umounted)
+	tests.Assert(t, umounted[0] == "umount /run/gluster/snaps/0f8e/brick1", umounted[0])
+	tests.Assert(t, umounted[1] == "umount /var/lib/heketi/mounts/vg_xvgid/brick_id", umounted[1])
+	tests.Assert(t, len(removed) == 2, removed)
+	tests.Assert(t, removed[0] == "lvremove -f vg_xvgid/0f8e_0", removed[0])
+	tests.Assert(t, removed[1] == "lvremove -f vg_xvgid/tp_id", removed[1])
+	tests.Assert(t, len(rmdirs) == 2, rmdirs)
+	tests.Assert(t, rmdirs[0] == "rmdir /run/gluster/snaps/0f8e/brick1", rmdirs[0])
+	tests.Assert(t, rmdirs[1] == "rmdir /var/lib/heketi/mounts/vg_xvgid/brick_id", rmdirs[1])
+}
+
+func TestSshExecBrickUsage(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	b := &executors.BrickRequest{
+		VgId: "xvgid",
+		Name: "id",
+	}
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 2)
+		tests.Assert(t, commands[0] == "df -B1 --output=size,used "+
+			"/var/lib/heketi/mounts/vg_xvgid/brick_id", commands[0])
+		tests.Assert(t, commands[1] == "lvs --noheadings --separator : "+
+			"-o data_percent,metadata_percent vg_xvgid/tp_id", commands[1])
+
+		return []string{
+			"    1B-blocks     Used\n  2136997888 35033088\n",
+			"  12.50:3.20\n",
+		}, nil
+	}
+
+	usage, err := s.BrickUsage("host", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, usage.Size == 2136997888, usage.Size)
+	tests.Assert(t, usage.Used == 35033088, usage.Used)
+	tests.Assert(t, usage.PoolDataPercent == 12.5, usage.PoolDataPercent)
+	tests.Assert(t, usage.PoolMetadataPercent == 3.2, usage.PoolMetadataPercent)
+
+	// The thin pool of imported bricks is found from their mount
+	b.Path = "/bricks/b1/brick"
+	b.Imported = true
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		cmd := commands[0]
+		switch {
+		case strings.Contains(cmd, "findmnt"):
+			return []string{"/dev/mapper/vg_other-lv_b1 /bricks/b1\n"}, nil
+
+		case strings.Contains(cmd, "vg_name,lv_name,lv_size,pool_lv"):
+			return []string{"  vg_other:lv_b1:2097152.00:pool_b1\n"}, nil
+
+		case strings.Contains(cmd, "lv_size,lv_metadata_size"):
+			return []string{"  2101248.00:12288.00\n"}, nil
+
+		case strings.Contains(cmd, "df"):
+			tests.Assert(t, len(commands) == 2)
+			tests.Assert(t, cmd == "df -B1 --output=size,used /bricks/b1/brick", cmd)
+			tests.Assert(t, strings.HasSuffix(commands[1], " vg_other/pool_b1"), commands[1])
+			return []string{
+				"    1B-blocks     Used\n  2136997888 1024\n",
+				"  0.01:1.00\n",
+			}, nil
+
+		default:
+			tests.Assert(t, false, "Unexpected command", cmd)
+		}
+
+		return nil, nil
+	}
+
+	usage, err = s.BrickUsage("host", b)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, usage.Used == 1024, usage.Used)
+	tests.Assert(t, usage.PoolDataPercent == 0.01, usage.PoolDataPercent)
+
+	// Unexpected output
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		if strings.Contains(commands[0], "df") {
+			return []string{"df: no such file\n", ""}, nil
+		}
+		return []string{""}, nil
+	}
+	b.Path = ""
+	b.Imported = false
+	_, err = s.BrickUsage("host", b)
+	tests.Assert(t, err != nil)
+}
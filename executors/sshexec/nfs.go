@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"fmt"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/lpabon/godbc"
+)
+
+const (
+	ganeshaConfig     = "/etc/ganesha/ganesha.conf"
+	ganeshaExportsDir = "/etc/ganesha/exports"
+)
+
+// Each exported volume has its own file included from the
+// Ganesha configuration
+func ganeshaExportFile(volume string) string {
+	return ganeshaExportsDir + "/export." + volume + ".conf"
+}
+
+func (s *SshExecutor) VolumeNfsExport(host string,
+	export *executors.NfsExportRequest) error {
+
+	godbc.Require(host != "")
+	godbc.Require(export != nil)
+	godbc.Require(export.Volume != "")
+	godbc.Require(export.ExportId > 0)
+
+	file := ganeshaExportFile(export.Volume)
+	commands := []string{
+		fmt.Sprintf("mkdir -p %v", ganeshaExportsDir),
+
+		// Export block using the Gluster FSAL
+		fmt.Sprintf(`echo "EXPORT { Export_Id = %v; `+
+			`Path = \"/%v\"; Pseudo = \"/%v\"; `+
+			`Access_Type = RW; Squash = No_root_squash; SecType = sys; `+
+			`Protocols = 3, 4; Transports = TCP; `+
+			`FSAL { Name = GLUSTER; Hostname = localhost; Volume = \"%v\"; } }" `+
+			`| tee %v > /dev/null`,
+			export.ExportId,
+			export.Volume,
+			export.Volume,
+			export.Volume,
+			file),
+
+		// Include it only once
+		fmt.Sprintf(`grep -qF "%v" %v || echo "%%include \"%v\"" | tee -a %v > /dev/null`,
+			file,
+			ganeshaConfig,
+			file,
+			ganeshaConfig),
+
+		"systemctl reload nfs-ganesha",
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to export volume %v over NFS "+
+			"on host %v: %v", export.Volume, host, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeNfsUnexport(host string, volume string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	file := ganeshaExportFile(volume)
+	commands := []string{
+		fmt.Sprintf(`sed -i.save "\|%v|d" %v`, file, ganeshaConfig),
+		fmt.Sprintf("rm -f %v", file),
+		"systemctl reload nfs-ganesha",
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to remove the NFS export of "+
+			"volume %v on host %v: %v", volume, host, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecVolumeNfsExport(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	var executed []string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		executed = append(executed, commands...)
+
+		return nil, nil
+	}
+
+	err = s.VolumeNfsExport("host", &executors.NfsExportRequest{
+		Volume:   "myvol",
+		ExportId: 7,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(executed) == 4, executed)
+	tests.Assert(t, executed[0] == "mkdir -p /etc/ganesha/exports", executed[0])
+	tests.Assert(t, strings.HasPrefix(executed[1], `echo "EXPORT { Export_Id = 7; `+
+		`Path = \"/myvol\"; Pseudo = \"/myvol\";`), executed[1])
+	tests.Assert(t, strings.Contains(executed[1],
+		`FSAL { Name = GLUSTER; Hostname = localhost; Volume = \"myvol\"; }`), executed[1])
+	tests.Assert(t, strings.HasSuffix(executed[1],
+		"| tee /etc/ganesha/exports/export.myvol.conf > /dev/null"), executed[1])
+	tests.Assert(t, executed[2] == `grep -qF "/etc/ganesha/exports/export.myvol.conf" `+
+		`/etc/ganesha/ganesha.conf || echo "%include \"/etc/ganesha/exports/export.myvol.conf\"" `+
+		`| tee -a /etc/ganesha/ganesha.conf > /dev/null`, executed[2])
+	tests.Assert(t, executed[3] == "systemctl reload nfs-ganesha", executed[3])
+
+	executed = nil
+	err = s.VolumeNfsUnexport("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(executed) == 3, executed)
+	tests.Assert(t, executed[0] == `sed -i.save "\|/etc/ganesha/exports/export.myvol.conf|d" `+
+		`/etc/ganesha/ganesha.conf`, executed[0])
+	tests.Assert(t, executed[1] == "rm -f /etc/ganesha/exports/export.myvol.conf", executed[1])
+	tests.Assert(t, executed[2] == "systemctl reload nfs-ganesha", executed[2])
+}
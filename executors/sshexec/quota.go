@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/lpabon/godbc"
+)
+
+func (s *SshExecutor) VolumeQuotaEnable(host string, volume string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume quota %v enable", volume),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to enable quota on volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+// VolumeQuotaDisable disables quota on the volume, which also
+// removes all of its limits
+func (s *SshExecutor) VolumeQuotaDisable(host string, volume string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume quota %v disable", volume),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to disable quota on volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeQuotaLimitSet(host string,
+	volume string,
+	limit *executors.QuotaLimit) error {
+
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+	godbc.Require(limit != nil)
+	godbc.Require(limit.Path != "")
+	godbc.Require(limit.HardLimit > 0)
+
+	cmd := fmt.Sprintf("gluster --mode=script volume quota %v limit-usage \"%v\" %v",
+		volume, limit.Path, limit.HardLimit)
+	if limit.SoftLimit > 0 {
+		cmd += fmt.Sprintf(" %v%%", limit.SoftLimit)
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, []string{cmd}, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to set quota limit on %v of volume %v: %v",
+			limit.Path, volume, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeQuotaLimitRemove(host string, volume string, path string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+	godbc.Require(path != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume quota %v remove \"%v\"", volume, path),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to remove quota limit on %v of volume %v: %v",
+			path, volume, err))
+	}
+
+	return nil
+}
+
+// VolumeQuotaList returns the limits of the volume with the space
+// currently used under each of them
+func (s *SshExecutor) VolumeQuotaList(host string, volume string) (*executors.QuotaList, error) {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	type CliOutput struct {
+		OpRet     int                 `xml:"opRet"`
+		OpErrno   int                 `xml:"opErrno"`
+		OpErrStr  string              `xml:"opErrstr"`
+		QuotaList executors.QuotaList `xml:"volQuota"`
+	}
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume quota %v list --xml", volume),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get quota list of volume %v: %v", volume, err)
+	}
+
+	var quotaList CliOutput
+	err = xml.Unmarshal([]byte(output[0]), &quotaList)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to determine quota list of volume %v: %v", volume, err)
+	}
+	if quotaList.OpRet != 0 {
+		return nil, fmt.Errorf("Unable to get quota list of volume %v: %v",
+			volume, quotaList.OpErrStr)
+	}
+	logger.Debug("%+v\n", quotaList)
+
+	return &quotaList.QuotaList, nil
+}
@@ -0,0 +1,168 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecVolumeQuota(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	var command string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		command = commands[0]
+
+		return nil, nil
+	}
+
+	err = s.VolumeQuotaEnable("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume quota myvol enable", command)
+
+	err = s.VolumeQuotaLimitSet("host", "myvol", &executors.QuotaLimit{
+		Path:      "/data",
+		HardLimit: 1073741824,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume quota myvol "+
+		"limit-usage \"/data\" 1073741824", command)
+
+	err = s.VolumeQuotaLimitSet("host", "myvol", &executors.QuotaLimit{
+		Path:      "/data",
+		HardLimit: 1073741824,
+		SoftLimit: 70,
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume quota myvol "+
+		"limit-usage \"/data\" 1073741824 70%", command)
+
+	err = s.VolumeQuotaLimitRemove("host", "myvol", "/data")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume quota myvol "+
+		"remove \"/data\"", command)
+
+	err = s.VolumeQuotaDisable("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume quota myvol disable", command)
+}
+
+func TestSshExecVolumeQuotaList(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume quota myvol list --xml", commands)
+
+		return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <volQuota>
+    <limit>
+      <path>/data</path>
+      <hard_limit>1073741824</hard_limit>
+      <soft_limit_percent>80%</soft_limit_percent>
+      <soft_limit_value>858993459</soft_limit_value>
+      <used_space>1048576</used_space>
+      <avail_space>1072693248</avail_space>
+      <sl_exceeded>No</sl_exceeded>
+      <hl_exceeded>No</hl_exceeded>
+    </limit>
+    <limit>
+      <path>/logs</path>
+      <hard_limit>10485760</hard_limit>
+      <soft_limit_percent>50%</soft_limit_percent>
+      <soft_limit_value>5242880</soft_limit_value>
+      <used_space>10485760</used_space>
+      <avail_space>0</avail_space>
+      <sl_exceeded>Yes</sl_exceeded>
+      <hl_exceeded>Yes</hl_exceeded>
+    </limit>
+  </volQuota>
+</cliOutput>`}, nil
+	}
+
+	// Call function
+	list, err := s.VolumeQuotaList("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(list.Limits) == 2)
+	tests.Assert(t, list.Limits[0].Path == "/data")
+	tests.Assert(t, list.Limits[0].HardLimit == 1073741824)
+	tests.Assert(t, list.Limits[0].SoftLimitPercent == "80%")
+	tests.Assert(t, list.Limits[0].UsedSpace == 1048576)
+	tests.Assert(t, list.Limits[0].AvailSpace == 1072693248)
+	tests.Assert(t, list.Limits[0].HardLimitExceeded == "No")
+	tests.Assert(t, list.Limits[1].Path == "/logs")
+	tests.Assert(t, list.Limits[1].SoftLimitExceeded == "Yes")
+	tests.Assert(t, list.Limits[1].HardLimitExceeded == "Yes")
+
+	// Errors reported by gluster
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>-1</opRet>
+  <opErrno>30800</opErrno>
+  <opErrstr>Quota is disabled, please enable quota</opErrstr>
+</cliOutput>`}, nil
+	}
+	_, err = s.VolumeQuotaList("host", "myvol")
+	tests.Assert(t, err != nil)
+}
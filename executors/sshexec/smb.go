@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"fmt"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/lpabon/godbc"
+)
+
+const (
+	sambaConfig    = "/etc/samba/smb.conf"
+	sambaSharesDir = "/etc/samba/heketi"
+)
+
+// Each share has its own file included from the Samba configuration
+func sambaShareFile(share string) string {
+	return sambaSharesDir + "/share." + share + ".conf"
+}
+
+func (s *SshExecutor) VolumeSmbShare(host string,
+	share *executors.SmbShareRequest) error {
+
+	godbc.Require(host != "")
+	godbc.Require(share != nil)
+	godbc.Require(share.Volume != "")
+	godbc.Require(share.ShareName != "")
+
+	file := sambaShareFile(share.ShareName)
+	commands := []string{
+		fmt.Sprintf("mkdir -p %v", sambaSharesDir),
+
+		// Share served by libgfapi instead of a local mount
+		fmt.Sprintf(`printf "[%v]\n`+
+			`comment = Gluster volume %v\n`+
+			`vfs objects = glusterfs\n`+
+			`glusterfs:volume = %v\n`+
+			`path = /\n`+
+			`read only = no\n`+
+			`kernel share modes = no\n" `+
+			`| tee %v > /dev/null`,
+			share.ShareName,
+			share.Volume,
+			share.Volume,
+			file),
+
+		// Include it only once
+		fmt.Sprintf(`grep -qF "%v" %v || echo "include = %v" | tee -a %v > /dev/null`,
+			file,
+			sambaConfig,
+			file,
+			sambaConfig),
+
+		"smbcontrol smbd reload-config",
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to share volume %v as %v "+
+			"on host %v: %v", share.Volume, share.ShareName, host, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeSmbUnshare(host string, share string) error {
+	godbc.Require(host != "")
+	godbc.Require(share != "")
+
+	file := sambaShareFile(share)
+	commands := []string{
+		fmt.Sprintf(`sed -i.save "\|%v|d" %v`, file, sambaConfig),
+		fmt.Sprintf("rm -f %v", file),
+		"smbcontrol smbd reload-config",
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to remove share %v on host %v: %v",
+			share, host, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecVolumeSmbShare(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	var executed []string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		executed = append(executed, commands...)
+
+		return nil, nil
+	}
+
+	err = s.VolumeSmbShare("host", &executors.SmbShareRequest{
+		Volume:    "myvol",
+		ShareName: "gluster-myvol",
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(executed) == 4, executed)
+	tests.Assert(t, executed[0] == "mkdir -p /etc/samba/heketi", executed[0])
+	tests.Assert(t, strings.HasPrefix(executed[1], `printf "[gluster-myvol]\n`), executed[1])
+	tests.Assert(t, strings.Contains(executed[1], `vfs objects = glusterfs\n`), executed[1])
+	tests.Assert(t, strings.Contains(executed[1], `glusterfs:volume = myvol\n`), executed[1])
+	tests.Assert(t, strings.HasSuffix(executed[1],
+		"| tee /etc/samba/heketi/share.gluster-myvol.conf > /dev/null"), executed[1])
+	tests.Assert(t, executed[2] == `grep -qF "/etc/samba/heketi/share.gluster-myvol.conf" `+
+		`/etc/samba/smb.conf || echo "include = /etc/samba/heketi/share.gluster-myvol.conf" `+
+		`| tee -a /etc/samba/smb.conf > /dev/null`, executed[2])
+	tests.Assert(t, executed[3] == "smbcontrol smbd reload-config", executed[3])
+
+	executed = nil
+	err = s.VolumeSmbUnshare("host", "gluster-myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(executed) == 3, executed)
+	tests.Assert(t, executed[0] == `sed -i.save "\|/etc/samba/heketi/share.gluster-myvol.conf|d" `+
+		`/etc/samba/smb.conf`, executed[0])
+	tests.Assert(t, executed[1] == "rm -f /etc/samba/heketi/share.gluster-myvol.conf", executed[1])
+	tests.Assert(t, executed[2] == "smbcontrol smbd reload-config", executed[2])
+}
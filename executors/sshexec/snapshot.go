@@ -0,0 +1,172 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/lpabon/godbc"
+)
+
+func (s *SshExecutor) SnapshotCreate(host string,
+	snapshot *executors.SnapshotRequest) (*executors.Snapshot, error) {
+
+	godbc.Require(snapshot != nil)
+	godbc.Require(host != "")
+	godbc.Require(snapshot.Volume != "")
+	godbc.Require(snapshot.Snapshot != "")
+
+	type CliOutput struct {
+		OpRet      int    `xml:"opRet"`
+		OpErrno    int    `xml:"opErrno"`
+		OpErrStr   string `xml:"opErrstr"`
+		SnapCreate struct {
+			Snapshot executors.Snapshot `xml:"snapshot"`
+		} `xml:"snapCreate"`
+	}
+
+	// Do not let gluster append a timestamp to the name, heketi
+	// must be able to find the snapshot later by the name it chose
+	cmd := fmt.Sprintf("gluster --mode=script snapshot create %v %v no-timestamp",
+		snapshot.Snapshot, snapshot.Volume)
+	if snapshot.Description != "" {
+		cmd += fmt.Sprintf(" description \"%v\"", snapshot.Description)
+	}
+	cmd += " --xml"
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, []string{cmd}, 10)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to create snapshot %v of volume %v: %v",
+			snapshot.Snapshot, snapshot.Volume, err))
+	}
+
+	var snapCreate CliOutput
+	err = xml.Unmarshal([]byte(output[0]), &snapCreate)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to determine snapshot information of snapshot %v: %v",
+			snapshot.Snapshot, err)
+	}
+	if snapCreate.OpRet != 0 {
+		return nil, fmt.Errorf("Unable to create snapshot %v: %v",
+			snapshot.Snapshot, snapCreate.OpErrStr)
+	}
+	logger.Debug("%+v\n", snapCreate)
+
+	return &snapCreate.SnapCreate.Snapshot, nil
+}
+
+func (s *SshExecutor) SnapshotList(host string, volume string) (*executors.SnapList, error) {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	type CliOutput struct {
+		OpRet    int                `xml:"opRet"`
+		OpErrno  int                `xml:"opErrno"`
+		OpErrStr string             `xml:"opErrstr"`
+		SnapList executors.SnapList `xml:"snapList"`
+	}
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script snapshot list %v --xml", volume),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get snapshot list of volume %v: %v", volume, err)
+	}
+
+	var snapList CliOutput
+	err = xml.Unmarshal([]byte(output[0]), &snapList)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to determine snapshot list of volume %v: %v", volume, err)
+	}
+	logger.Debug("%+v\n", snapList)
+
+	return &snapList.SnapList, nil
+}
+
+func (s *SshExecutor) SnapshotDelete(host string, snapshot string) error {
+	godbc.Require(host != "")
+	godbc.Require(snapshot != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script snapshot delete %v", snapshot),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to delete snapshot %v: %v", snapshot, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) SnapshotRestore(host string, volume string, snapshot string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+	godbc.Require(snapshot != "")
+
+	// The volume must be stopped before a snapshot can be restored
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume stop %v force", volume),
+		fmt.Sprintf("gluster --mode=script snapshot restore %v", snapshot),
+		fmt.Sprintf("gluster --mode=script volume start %v", volume),
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		// Try to bring the volume back online in its original state
+		commands = []string{
+			fmt.Sprintf("gluster --mode=script volume start %v force", volume),
+		}
+		if _, starterr := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10); starterr != nil {
+			logger.LogError("Unable to start volume %v: %v", volume, starterr)
+		}
+		return logger.Err(fmt.Errorf("Unable to restore volume %v from snapshot %v: %v",
+			volume, snapshot, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) SnapshotClone(host string,
+	clone *executors.SnapshotCloneRequest) (*executors.Volume, error) {
+
+	godbc.Require(clone != nil)
+	godbc.Require(host != "")
+	godbc.Require(clone.Snapshot != "")
+	godbc.Require(clone.Volume != "")
+
+	// Only activated snapshots can be cloned.  Activating a snapshot
+	// which is already active fails, so do not stop on this error.
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script snapshot activate %v", clone.Snapshot),
+	}
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		logger.Warning("Unable to activate snapshot %v: %v", clone.Snapshot, err)
+	}
+
+	commands = []string{
+		fmt.Sprintf("gluster --mode=script snapshot clone %v %v", clone.Volume, clone.Snapshot),
+		fmt.Sprintf("gluster --mode=script volume start %v", clone.Volume),
+	}
+	_, err = s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return nil, logger.Err(fmt.Errorf("Unable to clone snapshot %v into volume %v: %v",
+			clone.Snapshot, clone.Volume, err))
+	}
+
+	// The bricks of the clone are thin snapshots of the original bricks,
+	// get their location from gluster
+	return s.VolumeInfo(host, clone.Volume)
+}
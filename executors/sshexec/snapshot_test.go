@@ -0,0 +1,323 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecSnapshotCreate(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		CLICommandConfig: CLICommandConfig{
+			Fstab: "/my/fstab",
+		},
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script snapshot create "+
+			"mysnap myvol no-timestamp description \"my snapshot\" --xml", commands)
+
+		return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <snapCreate>
+    <snapshot>
+      <name>mysnap</name>
+      <uuid>0ba5b8a8-b6d6-4a73-a2a6-5e0d8a9e8a3c</uuid>
+    </snapshot>
+  </snapCreate>
+</cliOutput>`}, nil
+	}
+
+	// Call function
+	snap, err := s.SnapshotCreate("host", &executors.SnapshotRequest{
+		Volume:      "myvol",
+		Snapshot:    "mysnap",
+		Description: "my snapshot",
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, snap.Name == "mysnap")
+	tests.Assert(t, snap.UUID == "0ba5b8a8-b6d6-4a73-a2a6-5e0d8a9e8a3c")
+
+	// Gluster reports a failure
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>-1</opRet>
+  <opErrno>30800</opErrno>
+  <opErrstr>Snapshot mysnap already exists</opErrstr>
+</cliOutput>`}, nil
+	}
+
+	snap, err = s.SnapshotCreate("host", &executors.SnapshotRequest{
+		Volume:   "myvol",
+		Snapshot: "mysnap",
+	})
+	tests.Assert(t, err != nil)
+	tests.Assert(t, snap == nil)
+}
+
+func TestSshExecSnapshotList(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script snapshot list myvol --xml", commands)
+
+		return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <snapList>
+    <count>2</count>
+    <snapshot>snap1</snapshot>
+    <snapshot>snap2</snapshot>
+  </snapList>
+</cliOutput>`}, nil
+	}
+
+	// Call function
+	list, err := s.SnapshotList("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, list.Count == 2)
+	tests.Assert(t, len(list.SnapshotList) == 2)
+	tests.Assert(t, list.SnapshotList[0] == "snap1")
+	tests.Assert(t, list.SnapshotList[1] == "snap2")
+}
+
+func TestSshExecSnapshotDelete(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script snapshot delete mysnap", commands)
+
+		return nil, nil
+	}
+
+	// Call function
+	err = s.SnapshotDelete("host", "mysnap")
+	tests.Assert(t, err == nil, err)
+}
+
+func TestSshExecSnapshotRestore(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 3)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume stop myvol force", commands)
+		tests.Assert(t, commands[1] == "gluster --mode=script snapshot restore mysnap", commands)
+		tests.Assert(t, commands[2] == "gluster --mode=script volume start myvol", commands)
+
+		return nil, nil
+	}
+
+	// Call function
+	err = s.SnapshotRestore("host", "myvol", "mysnap")
+	tests.Assert(t, err == nil, err)
+
+	// On failure the volume must be started again
+	count := 0
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		defer func() { count++ }()
+
+		switch count {
+		case 0:
+			return nil, errors.New("restore failed")
+		case 1:
+			tests.Assert(t, len(commands) == 1)
+			tests.Assert(t, commands[0] == "gluster --mode=script volume start myvol force", commands)
+		default:
+			tests.Assert(t, false, "Should not be reached")
+		}
+
+		return nil, nil
+	}
+
+	err = s.SnapshotRestore("host", "myvol", "mysnap")
+	tests.Assert(t, err != nil)
+	tests.Assert(t, count == 2)
+}
+
+func TestSshExecSnapshotClone(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	count := 0
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		defer func() { count++ }()
+		tests.Assert(t, host == "host:22", host)
+
+		switch count {
+		case 0:
+			tests.Assert(t, len(commands) == 1)
+			tests.Assert(t, commands[0] == "gluster --mode=script snapshot activate mysnap", commands)
+
+			// Already activated snapshots are not an error
+			return nil, errors.New("Snapshot mysnap is already activated")
+		case 1:
+			tests.Assert(t, len(commands) == 2)
+			tests.Assert(t, commands[0] == "gluster --mode=script snapshot clone myclone mysnap", commands)
+			tests.Assert(t, commands[1] == "gluster --mode=script volume start myclone", commands)
+		case 2:
+			tests.Assert(t, len(commands) == 1)
+			tests.Assert(t, commands[0] == "gluster --mode=script volume info myclone --xml", commands)
+
+			return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <volInfo>
+    <volumes>
+      <volume>
+        <name>myclone</name>
+        <brickCount>2</brickCount>
+        <bricks>
+          <brick uuid="a1">host1:/run/gluster/snaps/0f8e/brick1/brick<name>host1:/run/gluster/snaps/0f8e/brick1/brick</name><hostUuid>a1</hostUuid></brick>
+          <brick uuid="a2">host2:/run/gluster/snaps/0f8e/brick2/brick<name>host2:/run/gluster/snaps/0f8e/brick2/brick</name><hostUuid>a2</hostUuid></brick>
+        </bricks>
+      </volume>
+      <count>1</count>
+    </volumes>
+  </volInfo>
+</cliOutput>`}, nil
+		default:
+			tests.Assert(t, false, "Should not be reached")
+		}
+
+		return nil, nil
+	}
+
+	// Call function
+	vol, err := s.SnapshotClone("host", &executors.SnapshotCloneRequest{
+		Snapshot: "mysnap",
+		Volume:   "myclone",
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, count == 3)
+	tests.Assert(t, vol.VolumeName == "myclone")
+	tests.Assert(t, len(vol.Bricks.BrickList) == 2)
+	tests.Assert(t, vol.Bricks.BrickList[1].Name == "host2:/run/gluster/snaps/0f8e/brick2/brick")
+}
@@ -12,6 +12,7 @@ package sshexec
 import (
 	"encoding/xml"
 	"fmt"
+	"strings"
 
 	"github.com/heketi/heketi/executors"
 	"github.com/lpabon/godbc"
@@ -41,6 +42,11 @@ func (s *SshExecutor) VolumeCreate(host string,
 		cmd += fmt.Sprintf("replica %v ", volume.Replica)
 		inSet = volume.Replica
 		maxPerSet = 5
+	case executors.DurabilityArbiter:
+		logger.Info("Creating volume %v replica %v arbiter 1", volume.Name, volume.Replica)
+		cmd += fmt.Sprintf("replica %v arbiter 1 ", volume.Replica)
+		inSet = volume.Replica
+		maxPerSet = 5
 	case executors.DurabilityDispersion:
 		logger.Info("Creating volume %v dispersion %v+%v",
 			volume.Name, volume.Data, volume.Redundancy)
@@ -91,7 +97,7 @@ func (s *SshExecutor) VolumeExpand(host string,
 	case executors.DurabilityNone:
 		inSet = 1
 		maxPerSet = 15
-	case executors.DurabilityReplica:
+	case executors.DurabilityReplica, executors.DurabilityArbiter:
 		inSet = volume.Replica
 		maxPerSet = 5
 	case executors.DurabilityDispersion:
@@ -172,6 +178,76 @@ func (s *SshExecutor) createVolumeOptionsCommand(volume *executors.VolumeRequest
 	return commands
 }
 
+// VolumeSetOptions sets the given "key value" options on the volume
+func (s *SshExecutor) VolumeSetOptions(host string, volume string, options []string) error {
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+	godbc.Require(len(options) > 0)
+
+	commands := s.createVolumeOptionsCommand(&executors.VolumeRequest{
+		Name:                 volume,
+		GlusterVolumeOptions: options,
+	})
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to set options on volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+// VolumeResetOptions resets the given option keys of the volume
+// to their default values
+func (s *SshExecutor) VolumeResetOptions(host string, volume string, options []string) error {
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+	godbc.Require(len(options) > 0)
+
+	commands := []string{}
+	for _, option := range options {
+		commands = append(commands,
+			fmt.Sprintf("gluster --mode=script volume reset %v %v", volume, option))
+	}
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to reset options on volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+// VolumeStop stops the volume without deleting it
+func (s *SshExecutor) VolumeStop(host string, volume string) error {
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume stop %v", volume),
+	}
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to stop volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
+// VolumeStart starts a stopped volume
+func (s *SshExecutor) VolumeStart(host string, volume string) error {
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster --mode=script volume start %v", volume),
+	}
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, commands, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to start volume %v: %v", volume, err))
+	}
+
+	return nil
+}
+
 func (s *SshExecutor) createAddBrickCommands(volume *executors.VolumeRequest,
 	start, inSet, maxPerSet int) []string {
 
@@ -259,6 +335,10 @@ func (s *SshExecutor) VolumeInfo(host string, volume string) (*executors.Volume,
 		return nil, fmt.Errorf("Unable to determine volume info of volume name: %v", volume)
 	}
 	logger.Debug("%+v\n", volumeInfo)
+	if volumeInfo.OpRet != 0 || len(volumeInfo.VolInfo.Volumes.VolumeList) == 0 {
+		return nil, fmt.Errorf("Unable to get volume info of volume name: %v: %v",
+			volume, volumeInfo.OpErrStr)
+	}
 	return &volumeInfo.VolInfo.Volumes.VolumeList[0], nil
 }
 
@@ -281,6 +361,76 @@ func (s *SshExecutor) VolumeReplaceBrick(host string, volume string, oldBrick *e
 
 }
 
+// VolumeRemoveBricks runs the given remove-brick action (start, stop
+// or commit) on the bricks of the volume
+func (s *SshExecutor) VolumeRemoveBricks(host string,
+	volume string,
+	bricks []executors.BrickInfo,
+	action string) error {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+	godbc.Require(len(bricks) > 0)
+	godbc.Require(action == "start" || action == "stop" || action == "commit")
+
+	command := []string{
+		fmt.Sprintf("gluster --mode=script volume remove-brick %v %v %v",
+			volume, removeBrickList(bricks), action),
+	}
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, command, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to %v removal of bricks from volume %v: %v",
+			action, volume, err))
+	}
+
+	return nil
+}
+
+func (s *SshExecutor) VolumeRemoveBricksStatus(host string,
+	volume string,
+	bricks []executors.BrickInfo) (*executors.RemoveBrickStatus, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+	godbc.Require(len(bricks) > 0)
+
+	type CliOutput struct {
+		OpRet          int                         `xml:"opRet"`
+		OpErrno        int                         `xml:"opErrno"`
+		OpErrStr       string                      `xml:"opErrstr"`
+		VolRemoveBrick executors.RemoveBrickStatus `xml:"volRemoveBrick"`
+	}
+
+	command := []string{
+		fmt.Sprintf("gluster --mode=script volume remove-brick %v %v status --xml",
+			volume, removeBrickList(bricks)),
+	}
+
+	output, err := s.RemoteExecutor.RemoteCommandExecute(host, command, 10)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get remove-brick status of volume %v: %v", volume, err)
+	}
+	var status CliOutput
+	err = xml.Unmarshal([]byte(output[0]), &status)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to determine remove-brick status of volume %v", volume)
+	}
+	if status.OpRet != 0 {
+		return nil, fmt.Errorf("Unable to get remove-brick status of volume %v: %v",
+			volume, status.OpErrStr)
+	}
+	logger.Debug("%+v\n", status)
+	return &status.VolRemoveBrick, nil
+}
+
+func removeBrickList(bricks []executors.BrickInfo) string {
+	list := make([]string, len(bricks))
+	for i, brick := range bricks {
+		list[i] = fmt.Sprintf("%v:%v", brick.Host, brick.Path)
+	}
+	return strings.Join(list, " ")
+}
+
 func (s *SshExecutor) HealInfo(host string, volume string) (*executors.HealInfo, error) {
 
 	godbc.Require(volume != "")
@@ -309,3 +459,22 @@ func (s *SshExecutor) HealInfo(host string, volume string) (*executors.HealInfo,
 	logger.Debug("%+v\n", healInfo)
 	return &healInfo.HealInfo, nil
 }
+
+// VolumeHeal starts healing the entries pending heal on the volume,
+// or crawls the whole volume when full is set
+func (s *SshExecutor) VolumeHeal(host string, volume string, full bool) error {
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	cmd := fmt.Sprintf("gluster --mode=script volume heal %v", volume)
+	if full {
+		cmd += " full"
+	}
+
+	_, err := s.RemoteExecutor.RemoteCommandExecute(host, []string{cmd}, 10)
+	if err != nil {
+		return logger.Err(fmt.Errorf("Unable to start heal of volume %v: %v", volume, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,339 @@
+//
+// Copyright (c) 2017 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/utils"
+	"github.com/heketi/tests"
+)
+
+func TestSshExecVolumeRemoveBricks(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	bricks := []executors.BrickInfo{
+		{Host: "host1", Path: "/brick1"},
+		{Host: "host2", Path: "/brick2"},
+	}
+
+	for _, action := range []string{"start", "stop", "commit"} {
+		f.FakeConnectAndExec = func(host string,
+			commands []string,
+			timeoutMinutes int,
+			useSudo bool) ([]string, error) {
+
+			tests.Assert(t, host == "host:22", host)
+			tests.Assert(t, len(commands) == 1)
+			tests.Assert(t, commands[0] == "gluster --mode=script volume remove-brick "+
+				"myvol host1:/brick1 host2:/brick2 "+action, commands)
+
+			return nil, nil
+		}
+
+		err = s.VolumeRemoveBricks("host", "myvol", bricks, action)
+		tests.Assert(t, err == nil, err)
+	}
+}
+
+func TestSshExecVolumeRemoveBricksStatus(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume remove-brick "+
+			"myvol host1:/brick1 status --xml", commands)
+
+		return []string{`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <volRemoveBrick>
+    <task-id>2bc2b4bd-9d0f-4b3a-8bb1-6b3e4d6e4d41</task-id>
+    <nodeCount>1</nodeCount>
+    <node>
+      <nodeName>host1</nodeName>
+      <id>a1</id>
+      <files>12</files>
+      <size>4096</size>
+      <lookups>20</lookups>
+      <failures>0</failures>
+      <skipped>0</skipped>
+      <status>1</status>
+      <statusStr>in progress</statusStr>
+    </node>
+    <aggregate>
+      <files>12</files>
+      <size>4096</size>
+      <lookups>20</lookups>
+      <failures>0</failures>
+      <skipped>0</skipped>
+      <status>1</status>
+      <statusStr>in progress</statusStr>
+    </aggregate>
+  </volRemoveBrick>
+</cliOutput>`}, nil
+	}
+
+	// Call function
+	status, err := s.VolumeRemoveBricksStatus("host", "myvol", []executors.BrickInfo{
+		{Host: "host1", Path: "/brick1"},
+	})
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, len(status.Nodes) == 1)
+	tests.Assert(t, status.Nodes[0].NodeName == "host1")
+	tests.Assert(t, status.Aggregate.Files == 12)
+	tests.Assert(t, status.Aggregate.Size == 4096)
+	tests.Assert(t, status.Aggregate.Status == executors.RemoveBrickInProgress)
+}
+
+func TestSshExecVolumeSetOptions(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 2)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume set myvol "+
+			"performance.cache-size 1GB", commands)
+		tests.Assert(t, commands[1] == "gluster --mode=script volume set myvol "+
+			"nfs.disable on", commands)
+
+		return nil, nil
+	}
+
+	// Call function
+	err = s.VolumeSetOptions("host", "myvol", []string{
+		"performance.cache-size 1GB",
+		"nfs.disable on",
+	})
+	tests.Assert(t, err == nil, err)
+}
+
+func TestSshExecVolumeResetOptions(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume reset myvol "+
+			"performance.cache-size", commands)
+
+		return nil, nil
+	}
+
+	// Call function
+	err = s.VolumeResetOptions("host", "myvol", []string{"performance.cache-size"})
+	tests.Assert(t, err == nil, err)
+}
+
+func TestSshExecVolumeStopStart(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	var command string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		command = commands[0]
+
+		return nil, nil
+	}
+
+	err = s.VolumeStop("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume stop myvol", command)
+
+	err = s.VolumeStart("host", "myvol")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume start myvol", command)
+}
+
+func TestSshExecVolumeHeal(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	var command string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		command = commands[0]
+
+		return nil, nil
+	}
+
+	err = s.VolumeHeal("host", "myvol", false)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume heal myvol", command)
+
+	err = s.VolumeHeal("host", "myvol", true)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, command == "gluster --mode=script volume heal myvol full", command)
+}
+
+func TestSshExecVolumeCreateArbiter(t *testing.T) {
+
+	f := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *utils.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+	}
+
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	// Mock ssh function
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) ([]string, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 3)
+		tests.Assert(t, commands[0] == "gluster --mode=script volume create myvol "+
+			"replica 3 arbiter 1 h1:/b1 h2:/b2 h3:/b3 ", commands)
+		tests.Assert(t, commands[1] == "gluster --mode=script volume add-brick myvol "+
+			"h4:/b4 h5:/b5 h6:/b6 ", commands)
+		tests.Assert(t, commands[2] == "gluster --mode=script volume start myvol", commands)
+
+		return nil, nil
+	}
+
+	// Call function
+	_, err = s.VolumeCreate("host", &executors.VolumeRequest{
+		Name:    "myvol",
+		Type:    executors.DurabilityArbiter,
+		Replica: 3,
+		Bricks: []executors.BrickInfo{
+			{Host: "h1", Path: "/b1"},
+			{Host: "h2", Path: "/b2"},
+			{Host: "h3", Path: "/b3"},
+			{Host: "h4", Path: "/b4"},
+			{Host: "h5", Path: "/b5"},
+			{Host: "h6", Path: "/b6"},
+		},
+	})
+	tests.Assert(t, err == nil, err)
+}
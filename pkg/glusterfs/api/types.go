@@ -19,6 +19,8 @@ package api
 import (
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 )
 
 // State
@@ -37,8 +39,14 @@ const (
 	DurabilityReplicate      DurabilityType = "replicate"
 	DurabilityDistributeOnly DurabilityType = "none"
 	DurabilityEC             DurabilityType = "disperse"
+	DurabilityArbiter        DurabilityType = "arbiter"
 )
 
+// Header holding the key a client picks to retry a request without
+// running it twice.  A request with a key which was already used gets
+// the result of the first request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 // Common
 type StateRequest struct {
 	State EntryState `json:"state"`
@@ -51,6 +59,48 @@ type StorageSize struct {
 	Used  uint64 `json:"used"`
 }
 
+// Space in use as reported by the nodes, as opposed to the
+// space heketi provisioned
+type StorageUsage struct {
+	// Sizes in bytes of the file systems of the bricks
+	Size uint64 `json:"size"`
+	Used uint64 `json:"used"`
+
+	// Fill percentages of the thin pools of the bricks, which
+	// include the space held by snapshots.  Volumes and devices
+	// report the fullest thin pool of their bricks
+	PoolDataPercent     float64 `json:"pool_data_percent"`
+	PoolMetadataPercent float64 `json:"pool_metadata_percent"`
+}
+
+// Thin pools
+type ThinPoolLevel string
+
+const (
+	ThinPoolLevelOk       ThinPoolLevel = "ok"
+	ThinPoolLevelWarning  ThinPoolLevel = "warning"
+	ThinPoolLevelCritical ThinPoolLevel = "critical"
+)
+
+// Last sample of the thin pool of a brick
+type ThinPoolStatus struct {
+	BrickId         string        `json:"brick"`
+	VolumeId        string        `json:"volume"`
+	NodeId          string        `json:"node"`
+	DeviceId        string        `json:"device"`
+	DataPercent     float64       `json:"data_percent"`
+	MetadataPercent float64       `json:"metadata_percent"`
+	Level           ThinPoolLevel `json:"level"`
+}
+
+// Thin pools over the warning threshold.  Volumes with a pool over
+// the critical threshold cannot take new snapshots or clones.
+type ThinPoolWarningsResponse struct {
+	WarningPercent  float64          `json:"warning_percent"`
+	CriticalPercent float64          `json:"critical_percent"`
+	Pools           []ThinPoolStatus `json:"pools"`
+}
+
 type HostAddresses struct {
 	Manage  sort.StringSlice `json:"manage"`
 	Storage sort.StringSlice `json:"storage"`
@@ -66,11 +116,18 @@ type BrickInfo struct {
 
 	// Size in KB
 	Size uint64 `json:"size"`
+
+	// Only set when the usage is requested
+	Usage *StorageUsage `json:"usage,omitempty"`
 }
 
 // Device
 type Device struct {
-	Name string `json:"name"`
+	Name string            `json:"name"`
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Class of the device, like ssd or hdd
+	Class string `json:"class,omitempty"`
 }
 
 type DeviceAddRequest struct {
@@ -88,13 +145,22 @@ type DeviceInfoResponse struct {
 	DeviceInfo
 	State  EntryState  `json:"state"`
 	Bricks []BrickInfo `json:"bricks"`
+
+	// Only set when the usage is requested
+	Usage *StorageUsage `json:"usage,omitempty"`
 }
 
 // Node
 type NodeAddRequest struct {
-	Zone      int           `json:"zone"`
+	Zone      int               `json:"zone"`
+	Hostnames HostAddresses     `json:"hostnames"`
+	ClusterId string            `json:"cluster"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// New hostnames of a node, like after renumbering its network
+type NodeHostnamesRequest struct {
 	Hostnames HostAddresses `json:"hostnames"`
-	ClusterId string        `json:"cluster"`
 }
 
 type NodeInfo struct {
@@ -113,16 +179,32 @@ type Cluster struct {
 	Volumes []VolumeInfoResponse `json:"volumes"`
 	Nodes   []NodeInfoResponse   `json:"nodes"`
 	Id      string               `json:"id"`
+	Tags    map[string]string    `json:"tags,omitempty"`
 }
 
 type TopologyInfoResponse struct {
 	ClusterList []Cluster `json:"clusters"`
 }
 
+// Limits on the bricks of a volume.  Unset limits fall back to
+// those of the cluster and then to the server configuration.
+type BrickLimits struct {
+	MinSize int `json:"min_size_gb,omitempty"`
+	MaxSize int `json:"max_size_gb,omitempty"`
+	MaxNum  int `json:"max_num,omitempty"`
+}
+
+type ClusterCreateRequest struct {
+	Tags        map[string]string `json:"tags,omitempty"`
+	BrickLimits BrickLimits       `json:"brick_limits"`
+}
+
 type ClusterInfoResponse struct {
-	Id      string           `json:"id"`
-	Nodes   sort.StringSlice `json:"nodes"`
-	Volumes sort.StringSlice `json:"volumes"`
+	Id          string            `json:"id"`
+	Nodes       sort.StringSlice  `json:"nodes"`
+	Volumes     sort.StringSlice  `json:"volumes"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	BrickLimits BrickLimits       `json:"brick_limits"`
 }
 
 type ClusterListResponse struct {
@@ -139,11 +221,29 @@ type DisperseDurability struct {
 	Redundancy int `json:"redundancy,omitempty"`
 }
 
+type ArbiterDurability struct {
+	// Size of the arbiter brick relative to the data bricks
+	Ratio float32 `json:"ratio,omitempty"`
+}
+
+// Restricts the zones, nodes and devices the bricks of a volume may be
+// placed on. Bricks are only placed on the included ones, or anywhere
+// when nothing is included, but never on the excluded ones.
+type PlacementConstraints struct {
+	Zones          []int    `json:"zones,omitempty"`
+	ExcludeZones   []int    `json:"exclude_zones,omitempty"`
+	Nodes          []string `json:"nodes,omitempty"`
+	ExcludeNodes   []string `json:"exclude_nodes,omitempty"`
+	Devices        []string `json:"devices,omitempty"`
+	ExcludeDevices []string `json:"exclude_devices,omitempty"`
+}
+
 // Volume
 type VolumeDurabilityInfo struct {
 	Type      DurabilityType     `json:"type,omitempty"`
 	Replicate ReplicaDurability  `json:"replicate,omitempty"`
 	Disperse  DisperseDurability `json:"disperse,omitempty"`
+	Arbiter   ArbiterDurability  `json:"arbiter,omitempty"`
 }
 
 type VolumeCreateRequest struct {
@@ -153,11 +253,46 @@ type VolumeCreateRequest struct {
 	Name                 string               `json:"name"`
 	Durability           VolumeDurabilityInfo `json:"durability,omitempty"`
 	Gid                  int64                `json:"gid,omitempty"`
+	Uid                  int64                `json:"uid,omitempty"`
 	GlusterVolumeOptions []string             `json:"glustervolumeoptions,omitempty"`
 	Snapshot             struct {
 		Enable bool    `json:"enable"`
 		Factor float32 `json:"factor"`
 	} `json:"snapshot"`
+
+	// Volume stores the files backing block volumes
+	Block bool `json:"block,omitempty"`
+
+	// Permission mode of the volume root in octal, like "0770"
+	Mode string `json:"mode,omitempty"`
+
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Only place bricks on devices of this class
+	DeviceClass string `json:"device_class,omitempty"`
+
+	// Export the volume over NFS with NFS-Ganesha
+	NfsExport bool `json:"nfs_export,omitempty"`
+
+	Placement PlacementConstraints `json:"placement,omitempty"`
+
+	BrickLimits BrickLimits `json:"brick_limits"`
+}
+
+// Shares a volume over SMB from the Samba servers of the nodes
+type VolumeSmbShareRequest struct {
+	Nodes []string `json:"nodes"`
+
+	// Defaults to gluster-<volume name>
+	ShareName string `json:"share_name,omitempty"`
+}
+
+// Adopts a gluster volume created outside of heketi
+type VolumeImportRequest struct {
+	Name string `json:"name"`
+
+	// Only look for the volume in this cluster
+	Cluster string `json:"cluster,omitempty"`
 }
 
 type VolumeInfo struct {
@@ -170,12 +305,52 @@ type VolumeInfo struct {
 			MountPoint string            `json:"device"`
 			Options    map[string]string `json:"options"`
 		} `json:"glusterfs"`
+
+		// Only set for volumes exported over NFS
+		Nfs struct {
+			Hosts      []string `json:"hosts,omitempty"`
+			MountPoint string   `json:"device,omitempty"`
+		} `json:"nfs,omitempty"`
+
+		// Only set for volumes shared over SMB
+		Smb struct {
+			ShareName string   `json:"share,omitempty"`
+			Hosts     []string `json:"hosts,omitempty"`
+		} `json:"smb,omitempty"`
 	} `json:"mount"`
+	BlockInfo struct {
+		// Space in GB not used by block volumes
+		FreeSize     int      `json:"freesize,omitempty"`
+		BlockVolumes []string `json:"blockvolume,omitempty"`
+	} `json:"blockinfo,omitempty"`
+
+	// Set while the volume is stopped for maintenance
+	Stopped bool `json:"stopped,omitempty"`
+
+	Quota VolumeQuotaInfo `json:"quota"`
+
+	Bitrot VolumeBitrotInfo `json:"bitrot"`
+
+	// Set while the volume is in the trash, stopped with its
+	// bricks kept until it is restored or its time expires
+	Trash *VolumeTrashInfo `json:"trash,omitempty"`
+}
+
+// Unix times at which the volume was deleted and at which
+// it is destroyed
+type VolumeTrashInfo struct {
+	DeletedAt int64 `json:"deleted_at"`
+	ExpiresAt int64 `json:"expires_at"`
 }
 
 type VolumeInfoResponse struct {
 	VolumeInfo
 	Bricks []BrickInfo `json:"bricks"`
+
+	// Only set when the usage is requested.  Sizes are those
+	// of the data the volume holds, not counting the copies
+	// kept for durability
+	Usage *StorageUsage `json:"usage,omitempty"`
 }
 
 type VolumeListResponse struct {
@@ -184,6 +359,237 @@ type VolumeListResponse struct {
 
 type VolumeExpandRequest struct {
 	Size int `json:"expand_size"`
+
+	// Grow the existing bricks when their devices have room
+	// for it, otherwise add bricks to the volume
+	InPlace bool `json:"in_place,omitempty"`
+}
+
+// Brick which would be created by a volume create or expand
+type BrickPlan struct {
+	DeviceId string `json:"device"`
+	NodeId   string `json:"node"`
+	Host     string `json:"host"`
+
+	// Size in KB
+	Size uint64 `json:"size"`
+}
+
+// Placement of the bricks for a volume create or expand,
+// computed without changing anything
+type VolumePlanResponse struct {
+	// Set if the bricks can be allocated, otherwise Reason
+	// explains why not
+	Feasible bool   `json:"feasible"`
+	Reason   string `json:"reason,omitempty"`
+
+	Cluster   string        `json:"cluster,omitempty"`
+	BrickSets [][]BrickPlan `json:"brick_sets"`
+
+	// Set if the existing bricks of the volume would be grown,
+	// in which case BrickSets lists them with their new size
+	InPlace bool `json:"in_place,omitempty"`
+}
+
+type VolumeShrinkRequest struct {
+	Size int `json:"shrink_size"`
+}
+
+type VolumeOptionsRequest struct {
+	// Options in "key value" form to set on the volume
+	Set []string `json:"set,omitempty"`
+
+	// Option keys to reset to their default value
+	Reset []string `json:"reset,omitempty"`
+}
+
+// Quota
+type QuotaLimit struct {
+	// Directory of the volume, starting with "/"
+	Path string `json:"path"`
+
+	// Hard limit in bytes
+	HardLimit uint64 `json:"hard_limit"`
+
+	// Percentage of the hard limit at which gluster starts to log
+	// warnings, zero for the gluster default
+	SoftLimit int `json:"soft_limit,omitempty"`
+}
+
+type VolumeQuotaInfo struct {
+	Enabled bool         `json:"enabled"`
+	Limits  []QuotaLimit `json:"limits,omitempty"`
+}
+
+type VolumeQuotaRequest struct {
+	// Limits to set, replacing the limits of the same paths
+	Set []QuotaLimit `json:"set,omitempty"`
+
+	// Paths whose limits are removed
+	Remove []string `json:"remove,omitempty"`
+}
+
+type QuotaUsage struct {
+	QuotaLimit
+
+	// Space in bytes
+	Used      uint64 `json:"used"`
+	Available uint64 `json:"available"`
+
+	SoftLimitExceeded bool `json:"soft_limit_exceeded"`
+	HardLimitExceeded bool `json:"hard_limit_exceeded"`
+}
+
+type VolumeQuotaResponse struct {
+	Enabled bool         `json:"enabled"`
+	Limits  []QuotaUsage `json:"limits"`
+}
+
+// Bitrot
+type VolumeBitrotInfo struct {
+	Enabled bool `json:"enabled"`
+
+	// Empty until set, in which case gluster uses its defaults
+	ScrubFrequency string `json:"scrub_frequency,omitempty"`
+	ScrubThrottle  string `json:"scrub_throttle,omitempty"`
+}
+
+type VolumeBitrotScrubRequest struct {
+	// One of hourly, daily, weekly, biweekly or monthly
+	Frequency string `json:"frequency,omitempty"`
+
+	// One of lazy, normal or aggressive
+	Throttle string `json:"throttle,omitempty"`
+}
+
+type BitrotScrubNodeStatus struct {
+	Node              string   `json:"node"`
+	ScrubbedFiles     uint64   `json:"scrubbed_files"`
+	SkippedFiles      uint64   `json:"skipped_files"`
+	LastScrubTime     string   `json:"last_scrub_time,omitempty"`
+	LastScrubDuration string   `json:"last_scrub_duration,omitempty"`
+	ErrorCount        uint64   `json:"error_count"`
+	CorruptedObjects  []string `json:"corrupted_objects,omitempty"`
+}
+
+type VolumeBitrotStatusResponse struct {
+	Enabled   bool                    `json:"enabled"`
+	State     string                  `json:"state,omitempty"`
+	Frequency string                  `json:"frequency,omitempty"`
+	Throttle  string                  `json:"throttle,omitempty"`
+	Nodes     []BitrotScrubNodeStatus `json:"nodes"`
+}
+
+// Heal
+type HealType string
+
+const (
+	// Heal the entries gluster recorded as pending heal
+	HealIndex HealType = "index"
+	// Crawl the whole volume for entries to heal
+	HealFull HealType = "full"
+)
+
+type VolumeHealRequest struct {
+	// Defaults to an index heal
+	Type HealType `json:"type,omitempty"`
+}
+
+type BrickHealInfo struct {
+	// Brick id, empty when gluster does not report the name
+	// of the brick, which it does not for bricks that are down
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// Number of entries pending heal, -1 when it is not known
+	PendingEntries int `json:"pending_entries"`
+}
+
+type VolumeHealInfoResponse struct {
+	Bricks []BrickHealInfo `json:"bricks"`
+
+	// Entries pending heal on all the bricks which reported them
+	PendingEntries int `json:"pending_entries"`
+}
+
+// Tags
+type TagsChangeType string
+
+const (
+	// Replace all the tags
+	SetTags TagsChangeType = "set"
+	// Add the tags, overwriting the values of existing keys
+	UpdateTags TagsChangeType = "update"
+	// Remove the tags with the given keys
+	DeleteTags TagsChangeType = "delete"
+)
+
+type TagsChangeRequest struct {
+	Tags   map[string]string `json:"tags"`
+	Change TagsChangeType    `json:"change_type"`
+}
+
+// Snapshot
+type SnapshotCreateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type SnapshotInfo struct {
+	SnapshotCreateRequest
+	Id       string `json:"id"`
+	VolumeId string `json:"volume"`
+
+	// Unix time when the snapshot was taken
+	Created int64 `json:"created"`
+}
+
+type SnapshotInfoResponse struct {
+	SnapshotInfo
+}
+
+type SnapshotListResponse struct {
+	Snapshots []string `json:"snapshots"`
+}
+
+type SnapshotCloneRequest struct {
+	// Name of the new volume
+	Name string `json:"name,omitempty"`
+}
+
+// Block volume
+type BlockVolumeCreateRequest struct {
+	// Size in GB
+	Size     int      `json:"size"`
+	Clusters []string `json:"clusters,omitempty"`
+	Name     string   `json:"name"`
+
+	// Number of nodes exporting the block volume
+	Hacount int  `json:"hacount,omitempty"`
+	Auth    bool `json:"auth,omitempty"`
+}
+
+type BlockVolumeInfo struct {
+	BlockVolumeCreateRequest
+	Id                 string `json:"id"`
+	Cluster            string `json:"cluster"`
+	BlockHostingVolume string `json:"blockhostingvolume"`
+	BlockVolume        struct {
+		Hosts    []string `json:"hosts"`
+		Iqn      string   `json:"iqn"`
+		Lun      int      `json:"lun"`
+		Username string   `json:"username"`
+		Password string   `json:"password"`
+	} `json:"blockvolume"`
+}
+
+type BlockVolumeInfoResponse struct {
+	BlockVolumeInfo
+}
+
+type BlockVolumeListResponse struct {
+	BlockVolumes []string `json:"blockvolumes"`
 }
 
 // GeoReplicationActionType defines the different actions relevant to geo-rep sessions, except for delete
@@ -310,6 +716,53 @@ func (v *GeoReplicationStatus) String() string {
 }
 
 // String functions
+func (s *SnapshotInfoResponse) String() string {
+	str := fmt.Sprintf("Name: %v\n"+
+		"Snapshot Id: %v\n"+
+		"Volume Id: %v\n"+
+		"Created: %v\n",
+		s.Name,
+		s.Id,
+		s.VolumeId,
+		time.Unix(s.Created, 0).UTC().Format(time.RFC3339))
+
+	if s.Description != "" {
+		str += fmt.Sprintf("Description: %v\n", s.Description)
+	}
+
+	return str
+}
+
+func (v *BlockVolumeInfoResponse) String() string {
+	s := fmt.Sprintf("Name: %v\n"+
+		"Size: %v\n"+
+		"Volume Id: %v\n"+
+		"Cluster Id: %v\n"+
+		"Hosts: %v\n"+
+		"IQN: %v\n"+
+		"LUN: %v\n"+
+		"Hacount: %v\n"+
+		"Block Hosting Volume: %v\n",
+		v.Name,
+		v.Size,
+		v.Id,
+		v.Cluster,
+		v.BlockVolume.Hosts,
+		v.BlockVolume.Iqn,
+		v.BlockVolume.Lun,
+		v.Hacount,
+		v.BlockHostingVolume)
+
+	if v.Auth {
+		s += fmt.Sprintf("Username: %v\n"+
+			"Password: %v\n",
+			v.BlockVolume.Username,
+			v.BlockVolume.Password)
+	}
+
+	return s
+}
+
 func (v *VolumeInfoResponse) String() string {
 	s := fmt.Sprintf("Name: %v\n"+
 		"Size: %v\n"+
@@ -335,6 +788,12 @@ func (v *VolumeInfoResponse) String() string {
 	case DurabilityReplicate:
 		s += fmt.Sprintf("Distributed+Replica: %v\n",
 			v.Durability.Replicate.Replica)
+	case DurabilityArbiter:
+		s += "Distributed+Replica: 3 (arbiter 1)\n"
+		if v.Durability.Arbiter.Ratio != 0 {
+			s += fmt.Sprintf("Arbiter Brick Ratio: %.2f\n",
+				v.Durability.Arbiter.Ratio)
+		}
 	}
 
 	if v.Snapshot.Enable {
@@ -342,6 +801,73 @@ func (v *VolumeInfoResponse) String() string {
 			v.Snapshot.Factor)
 	}
 
+	if len(v.GlusterVolumeOptions) > 0 {
+		s += fmt.Sprintf("Volume Options: %v\n",
+			strings.Join(v.GlusterVolumeOptions, ", "))
+	}
+
+	if v.Block {
+		s += fmt.Sprintf("Block: true\n"+
+			"Free Size: %v\n"+
+			"Block Volumes: %v\n",
+			v.BlockInfo.FreeSize,
+			v.BlockInfo.BlockVolumes)
+	}
+
+	if v.DeviceClass != "" {
+		s += fmt.Sprintf("Device Class: %v\n", v.DeviceClass)
+	}
+
+	if v.Stopped {
+		s += "Stopped: true\n"
+	}
+
+	if v.Trash != nil {
+		s += fmt.Sprintf("In Trash Until: %v\n",
+			time.Unix(v.Trash.ExpiresAt, 0).Format(time.RFC1123))
+	}
+
+	if v.NfsExport {
+		s += fmt.Sprintf("NFS Mount: %v\n", v.Mount.Nfs.MountPoint)
+	}
+
+	if v.Mount.Smb.ShareName != "" {
+		s += fmt.Sprintf("SMB Share: %v on %v\n",
+			v.Mount.Smb.ShareName,
+			strings.Join(v.Mount.Smb.Hosts, ", "))
+	}
+
+	if v.Quota.Enabled {
+		s += "Quota: enabled\n"
+		for _, l := range v.Quota.Limits {
+			s += fmt.Sprintf("Quota Limit: %v\n", l.String())
+		}
+	}
+
+	if v.Bitrot.Enabled {
+		s += "Bitrot: enabled\n"
+		if v.Bitrot.ScrubFrequency != "" {
+			s += fmt.Sprintf("Scrub Frequency: %v\n", v.Bitrot.ScrubFrequency)
+		}
+		if v.Bitrot.ScrubThrottle != "" {
+			s += fmt.Sprintf("Scrub Throttle: %v\n", v.Bitrot.ScrubThrottle)
+		}
+	}
+
+	if len(v.Tags) > 0 {
+		s += fmt.Sprintf("Tags: %v\n", tagsString(v.Tags))
+	}
+
+	if v.Usage != nil {
+		s += fmt.Sprintf("Used (GiB): %v of %v\n"+
+			"Thin Pool Data: %.2f%%\n"+
+			"Thin Pool Metadata: %.2f%%\n",
+			v.Usage.Used/(1024*1024*1024),
+			v.Usage.Size/(1024*1024*1024),
+			v.Usage.PoolDataPercent,
+			v.Usage.PoolMetadataPercent)
+	}
+
 	/*
 		s += "\nBricks:\n"
 		for _, b := range v.Bricks {
@@ -360,3 +886,130 @@ func (v *VolumeInfoResponse) String() string {
 
 	return s
 }
+
+// Formats tags as a sorted, comma separated list of key:value pairs
+func tagsString(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+":"+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+func (p *VolumePlanResponse) String() string {
+	if !p.Feasible {
+		return fmt.Sprintf("Feasible: false\nReason: %v\n", p.Reason)
+	}
+
+	s := fmt.Sprintf("Feasible: true\n"+
+		"Cluster Id: %v\n",
+		p.Cluster)
+	if p.InPlace {
+		s += "Bricks Grown In Place: true\n"
+	}
+	for i, set := range p.BrickSets {
+		s += fmt.Sprintf("\nBrick Set %v:\n", i)
+		for _, b := range set {
+			s += fmt.Sprintf("Node: %v (%v)\n"+
+				"Device: %v\n"+
+				"Size (GiB): %v\n",
+				b.NodeId,
+				b.Host,
+				b.DeviceId,
+				b.Size/(1024*1024))
+		}
+	}
+
+	return s
+}
+
+func (l *QuotaLimit) String() string {
+	s := fmt.Sprintf("%v %v bytes", l.Path, l.HardLimit)
+	if l.SoftLimit > 0 {
+		s += fmt.Sprintf(" (soft limit %v%%)", l.SoftLimit)
+	}
+	return s
+}
+
+func (q *VolumeQuotaResponse) String() string {
+	if !q.Enabled {
+		return "Quota: disabled\n"
+	}
+
+	s := "Quota: enabled\n"
+	for _, u := range q.Limits {
+		s += fmt.Sprintf("\nPath: %v\n"+
+			"Hard Limit: %v\n"+
+			"Used: %v\n"+
+			"Available: %v\n",
+			u.Path,
+			u.HardLimit,
+			u.Used,
+			u.Available)
+		if u.SoftLimit > 0 {
+			s += fmt.Sprintf("Soft Limit: %v%%\n", u.SoftLimit)
+		}
+		if u.HardLimitExceeded {
+			s += "Hard Limit Exceeded: true\n"
+		} else if u.SoftLimitExceeded {
+			s += "Soft Limit Exceeded: true\n"
+		}
+	}
+
+	return s
+}
+
+func (h *VolumeHealInfoResponse) String() string {
+	s := fmt.Sprintf("Pending Entries: %v\n", h.PendingEntries)
+	for _, b := range h.Bricks {
+		pending := "unknown"
+		if b.PendingEntries >= 0 {
+			pending = fmt.Sprintf("%v", b.PendingEntries)
+		}
+		s += fmt.Sprintf("\nBrick: %v\n"+
+			"Id: %v\n"+
+			"Status: %v\n"+
+			"Pending Entries: %v\n",
+			b.Name,
+			b.Id,
+			b.Status,
+			pending)
+	}
+
+	return s
+}
+
+func (b *VolumeBitrotStatusResponse) String() string {
+	if !b.Enabled {
+		return "Bitrot: disabled\n"
+	}
+
+	s := fmt.Sprintf("Bitrot: enabled\n"+
+		"Scrub State: %v\n"+
+		"Scrub Frequency: %v\n"+
+		"Scrub Throttle: %v\n",
+		b.State,
+		b.Frequency,
+		b.Throttle)
+	for _, n := range b.Nodes {
+		s += fmt.Sprintf("\nNode: %v\n"+
+			"Scrubbed Files: %v\n"+
+			"Skipped Files: %v\n"+
+			"Last Scrub: %v\n"+
+			"Last Scrub Duration: %v\n"+
+			"Errors: %v\n",
+			n.Node,
+			n.ScrubbedFiles,
+			n.SkippedFiles,
+			n.LastScrubTime,
+			n.LastScrubDuration,
+			n.ErrorCount)
+		for _, gfid := range n.CorruptedObjects {
+			s += fmt.Sprintf("Corrupted: %v\n", gfid)
+		}
+	}
+
+	return s
+}